@@ -1,10 +1,38 @@
 USE `umkm`;
+CREATE TABLE `categories` (
+    `id` int(11) NOT NULL AUTO_INCREMENT,
+    `name` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_categories_name` (`name`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
 CREATE TABLE `goods` (
     `id` int(11) NOT NULL AUTO_INCREMENT,
     `name` varchar(255) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
     `stocks` int(11) DEFAULT 0,
-    `price` double DEFAULT NULL,
-    PRIMARY KEY (`id`)
+    `reserved_stocks` int(11) NOT NULL DEFAULT 0,
+    -- amounts of money are whole rupiah, see entity.Money
+    `price` bigint(20) DEFAULT NULL,
+    `id_category` int(11) DEFAULT NULL,
+    -- raw material is not sold, it's consumed by the recipes
+    `is_raw_material` tinyint(1) NOT NULL DEFAULT 0,
+    `unit` varchar(32) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    -- zero reorder point means the stocks is not monitored
+    `reorder_point` int(11) NOT NULL DEFAULT 0,
+    -- the price already contains the PPN, otherwise the PPN is added on top of the price
+    `price_includes_tax` tinyint(1) NOT NULL DEFAULT 0,
+    -- moving average of the purchase price per unit, zero means the cost is unknown yet
+    `average_cost` double NOT NULL DEFAULT 0,
+    `deleted_at` bigint(20) DEFAULT NULL,
+    -- only the goods which still sold must have unique name
+    `active_name` varchar(255) COLLATE utf8mb4_unicode_ci GENERATED ALWAYS AS (IF(`deleted_at` IS NULL, `name`, NULL)) STORED,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_goods_active_name` (`active_name`),
+    -- (column, id) indexes serve the cursor paging of each sortable column
+    KEY `idx_goods_name` (`name`, `id`),
+    KEY `idx_goods_price` (`price`, `id`),
+    KEY `idx_goods_stocks` (`stocks`, `id`),
+    KEY `idx_goods_category` (`id_category`)
 ) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
 
 INSERT INTO `goods` (`id`, `name`, `stocks`, `price`) VALUES
@@ -16,18 +44,293 @@ INSERT INTO `goods` (`id`, `name`, `stocks`, `price`) VALUES
 (6, 'Pisang Keju', 25, 2500),
 (7, 'Lumpia Udang', 30, 2500);
 
+CREATE TABLE `goods_variants` (
+    `id` int(11) NOT NULL AUTO_INCREMENT,
+    `id_goods` int(11) NOT NULL,
+    `name` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `price_delta` bigint(20) NOT NULL DEFAULT 0,
+    `stocks` int(11) NOT NULL DEFAULT 0,
+    `reserved_stocks` int(11) NOT NULL DEFAULT 0,
+    `average_cost` double NOT NULL DEFAULT 0,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_goods_variants_name` (`id_goods`, `name`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- goods which has recipe is made to order from the raw materials stocks
+CREATE TABLE `recipes` (
+    `id_goods` int(11) NOT NULL,
+    `id_ingredient` int(11) NOT NULL,
+    `quantity` int(11) NOT NULL,
+    PRIMARY KEY (`id_goods`, `id_ingredient`),
+    KEY `idx_recipes_ingredient` (`id_ingredient`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- append-only ledger of every goods & variant stocks change, stocks of the goods equal to the sum of its quantity
+CREATE TABLE `stock_movements` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `id_goods` int(11) NOT NULL,
+    `id_variant` int(11) NOT NULL DEFAULT 0,
+    `type` varchar(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `quantity` int(11) NOT NULL,
+    -- purchase price of the received goods, average cost of the goods otherwise
+    `unit_cost` double NOT NULL DEFAULT 0,
+    `stocks_before` int(11) NOT NULL,
+    `stocks_after` int(11) NOT NULL,
+    `actor` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `reason` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    `id_transaction` bigint(20) DEFAULT NULL,
+    -- expiry time of the received goods, null when it doesn't expire or for stocks out
+    `expires_at` bigint(20) DEFAULT NULL,
+    `created_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_stock_movements_goods` (`id_goods`, `id_variant`, `created_at`),
+    KEY `idx_stock_movements_transaction` (`id_transaction`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TRIGGER `stock_movements_no_update` BEFORE UPDATE ON `stock_movements`
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'stock movements is append-only';
+
+CREATE TRIGGER `stock_movements_no_delete` BEFORE DELETE ON `stock_movements`
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'stock movements is append-only';
+
+INSERT INTO `stock_movements` (`id_goods`, `type`, `quantity`, `stocks_before`, `stocks_after`, `actor`, `reason`, `created_at`)
+SELECT `id`, 'ADJUSTMENT', `stocks`, 0, `stocks`, 'system', 'opening balance', UNIX_TIMESTAMP() FROM `goods` WHERE `stocks` <> 0;
+
+-- goods received at the same time, the stocks of goods or variant is the sum of its batches remaining quantity
+CREATE TABLE `stock_batches` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `id_goods` int(11) NOT NULL,
+    `id_variant` int(11) NOT NULL DEFAULT 0,
+    `received_quantity` int(11) NOT NULL,
+    -- remaining stocks of the batch
+    `quantity` int(11) NOT NULL,
+    `unit_cost` double NOT NULL DEFAULT 0,
+    `received_at` bigint(20) NOT NULL,
+    -- null when the goods doesn't expire
+    `expires_at` bigint(20) DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_stock_batches_goods` (`id_goods`, `id_variant`, `quantity`),
+    KEY `idx_stock_batches_expires_at` (`expires_at`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+INSERT INTO `stock_batches` (`id_goods`, `received_quantity`, `quantity`, `received_at`)
+SELECT `id`, `stocks`, `stocks`, UNIX_TIMESTAMP() FROM `goods` WHERE `stocks` > 0;
+
+-- stock opname session, the stocks is adjusted to the counted goods when it's approved
+CREATE TABLE `stock_counts` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `status` varchar(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `opened_by` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `approved_by` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    `created_at` bigint(20) NOT NULL,
+    `approved_at` bigint(20) DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_stock_counts_status` (`status`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- system_stocks & price is empty until the stock count approved, the current goods is used meanwhile
+CREATE TABLE `stock_count_items` (
+    `id_stock_count` bigint(20) NOT NULL,
+    `id_goods` int(11) NOT NULL,
+    `id_variant` int(11) NOT NULL DEFAULT 0,
+    `counted_stocks` int(11) NOT NULL,
+    `system_stocks` int(11) DEFAULT NULL,
+    `price` bigint(20) DEFAULT NULL,
+    `counted_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id_stock_count`, `id_goods`, `id_variant`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE `suppliers` (
+    `id` int(11) NOT NULL AUTO_INCREMENT,
+    `name` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `phone` varchar(32) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    `address` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    `created_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_suppliers_name` (`name`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE `purchase_orders` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `id_supplier` int(11) NOT NULL,
+    `status` varchar(24) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `note` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    `created_by` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `created_at` bigint(20) NOT NULL,
+    `updated_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_purchase_orders_supplier` (`id_supplier`),
+    KEY `idx_purchase_orders_status` (`status`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE `purchase_order_items` (
+    `id_purchase_order` bigint(20) NOT NULL,
+    `id_goods` int(11) NOT NULL,
+    `id_variant` int(11) NOT NULL DEFAULT 0,
+    `quantity` int(11) NOT NULL,
+    `received_quantity` int(11) NOT NULL DEFAULT 0,
+    `unit_cost` double NOT NULL DEFAULT 0,
+    PRIMARY KEY (`id_purchase_order`, `id_goods`, `id_variant`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- each receipt is the ordered goods received at once, the stocks is increased at the unit cost of the order
+CREATE TABLE `purchase_order_receipts` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `id_purchase_order` bigint(20) NOT NULL,
+    `received_by` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `received_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_purchase_order_receipts_order` (`id_purchase_order`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE `purchase_order_receipt_items` (
+    `id_receipt` bigint(20) NOT NULL,
+    `id_goods` int(11) NOT NULL,
+    `id_variant` int(11) NOT NULL DEFAULT 0,
+    `quantity` int(11) NOT NULL,
+    `unit_cost` double NOT NULL DEFAULT 0,
+    `expires_at` bigint(20) DEFAULT NULL,
+    PRIMARY KEY (`id_receipt`, `id_goods`, `id_variant`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE `promotions` (
+    `id` int(11) NOT NULL AUTO_INCREMENT,
+    `name` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `type` varchar(24) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `id_goods` int(11) NOT NULL DEFAULT 0,
+    -- percentage of PERCENTAGE promotion
+    `value` double NOT NULL DEFAULT 0,
+    -- discount amount of FIXED promotion
+    `amount` bigint(20) NOT NULL DEFAULT 0,
+    `buy_quantity` int(11) NOT NULL DEFAULT 0,
+    `free_quantity` int(11) NOT NULL DEFAULT 0,
+    `min_purchase` bigint(20) NOT NULL DEFAULT 0,
+    `happy_hour_start` smallint(6) NOT NULL DEFAULT 0,
+    `happy_hour_end` smallint(6) NOT NULL DEFAULT 0,
+    `starts_at` bigint(20) NOT NULL DEFAULT 0,
+    `ends_at` bigint(20) NOT NULL DEFAULT 0,
+    `voucher_code` varchar(32) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+    `usage_limit` int(11) NOT NULL DEFAULT 0,
+    `used_count` int(11) NOT NULL DEFAULT 0,
+    `created_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_promotions_voucher_code` (`voucher_code`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
 CREATE TABLE `transaction_details` (
     `id_transaction` bigint(20) NOT NULL,
     `id_goods` int(11) NOT NULL,
+    -- zero for goods which has no variants
+    `id_variant` int(11) NOT NULL DEFAULT 0,
     `total_goods` int(11) NOT NULL DEFAULT '1',
-    `created_at` bigint(20) NOT NULL
+    `refunded_goods` int(11) NOT NULL DEFAULT 0,
+    `price` bigint(20) NOT NULL DEFAULT 0,
+    `price_includes_tax` tinyint(1) NOT NULL DEFAULT 0,
+    `created_at` bigint(20) NOT NULL,
+    UNIQUE KEY `uniq_transaction_details_goods` (`id_transaction`, `id_goods`, `id_variant`)
 ) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
 
 CREATE TABLE `transactions` (
     `id` bigint(20) NOT NULL AUTO_INCREMENT,
     `id_user` int(11) DEFAULT NULL,
-    `total_amount` double NOT NULL,
-    `payment_amount` double DEFAULT NULL,
+    -- grand total of the bill once it's paid
+    `total_amount` bigint(20) NOT NULL,
+    `subtotal_amount` bigint(20) NOT NULL DEFAULT 0,
+    `discount_amount` bigint(20) NOT NULL DEFAULT 0,
+    `service_charge_amount` bigint(20) NOT NULL DEFAULT 0,
+    -- DPP, the amount the PPN charged from
+    `tax_base_amount` bigint(20) NOT NULL DEFAULT 0,
+    `tax_amount` bigint(20) NOT NULL DEFAULT 0,
+    `tax_rate` double NOT NULL DEFAULT 0,
+    `service_charge_rate` double NOT NULL DEFAULT 0,
+    `voucher_code` varchar(32) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+    `payment_amount` bigint(20) DEFAULT NULL,
+    `refunded_amount` bigint(20) NOT NULL DEFAULT 0,
     `status` tinyint(4) DEFAULT NULL,
-    PRIMARY KEY (`id`)
-) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
\ No newline at end of file
+    `idempotency_key` varchar(64) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+    `created_at` bigint(20) NOT NULL DEFAULT 0,
+    `updated_at` bigint(20) NOT NULL DEFAULT 0,
+    `paid_at` bigint(20) DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_transactions_idempotency_key` (`idempotency_key`),
+    KEY `idx_transactions_status_updated_at` (`status`, `updated_at`),
+    KEY `idx_transactions_paid_at` (`paid_at`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE `transaction_discounts` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `id_transaction` bigint(20) NOT NULL,
+    `id_promotion` int(11) NOT NULL,
+    `name` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `voucher_code` varchar(32) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    `amount` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_transaction_discounts_transaction` (`id_transaction`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- portion of the payment of each payment method, the change is always given from the cash tender
+CREATE TABLE `transaction_tenders` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `id_transaction` bigint(20) NOT NULL,
+    `method` varchar(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `amount` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_transaction_tenders_method` (`id_transaction`, `method`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- cashless payment issued by the payment provider, the transaction is paid once the provider confirm it
+CREATE TABLE `payments` (
+    `reference` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `id_transaction` bigint(20) NOT NULL,
+    `method` varchar(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `amount` bigint(20) NOT NULL,
+    `status` varchar(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `qr_payload` text COLLATE utf8mb4_unicode_ci NOT NULL,
+    `voucher_code` varchar(32) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    `created_at` bigint(20) NOT NULL,
+    `expires_at` bigint(20) NOT NULL,
+    `paid_at` bigint(20) NOT NULL DEFAULT 0,
+    PRIMARY KEY (`reference`),
+    KEY `idx_payments_transaction` (`id_transaction`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE `transaction_status_histories` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `id_transaction` bigint(20) NOT NULL,
+    `from_status` tinyint(4) DEFAULT NULL,
+    `to_status` tinyint(4) NOT NULL,
+    `created_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_transaction_status_histories_transaction` (`id_transaction`, `id`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE `refunds` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `id_transaction` bigint(20) NOT NULL,
+    `amount` bigint(20) NOT NULL,
+    `reason` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    `created_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_refunds_transaction` (`id_transaction`),
+    KEY `idx_refunds_created_at` (`created_at`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE `refund_details` (
+    `id_refund` bigint(20) NOT NULL,
+    `id_goods` int(11) NOT NULL,
+    `id_variant` int(11) NOT NULL DEFAULT 0,
+    `total_goods` int(11) NOT NULL,
+    `price` bigint(20) NOT NULL,
+    PRIMARY KEY (`id_refund`, `id_goods`, `id_variant`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE `deliveries` (
+    `id_transaction` bigint(20) NOT NULL,
+    `tracking_number` varchar(64) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+    `destination` int(11) NOT NULL,
+    `price` bigint(20) NOT NULL DEFAULT 0,
+    `status` varchar(32) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `created_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id_transaction`),
+    UNIQUE KEY `uniq_deliveries_tracking_number` (`tracking_number`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
@@ -0,0 +1,17 @@
+-- Stocks reserved by the shopping carts, for database created before db.sql has them.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+ALTER TABLE `goods`
+    ADD COLUMN `reserved_stocks` int(11) NOT NULL DEFAULT 0 AFTER `stocks`;
+
+-- the stocks is deducted from the reservation once paid, so the unpaid carts must hold their goods
+UPDATE `goods` g
+JOIN (
+    SELECT td.`id_goods`, SUM(td.`total_goods`) AS `total_goods`
+    FROM `transaction_details` td
+    JOIN `transactions` trx ON trx.`id` = td.`id_transaction`
+    WHERE trx.`status` = 0
+    GROUP BY td.`id_goods`
+) cart ON cart.`id_goods` = g.`id`
+SET g.`reserved_stocks` = cart.`total_goods`;
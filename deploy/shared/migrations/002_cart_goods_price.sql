@@ -0,0 +1,11 @@
+-- Goods price snapshot of the shopping cart details.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+ALTER TABLE `transaction_details`
+    ADD COLUMN `price` double NOT NULL DEFAULT 0 AFTER `total_goods`;
+
+-- the existing details only know the current goods price
+UPDATE `transaction_details` td
+JOIN `goods` g ON g.`id` = td.`id_goods`
+SET td.`price` = g.`price`;
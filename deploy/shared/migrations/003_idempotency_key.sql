@@ -0,0 +1,7 @@
+-- Idempotency key of the payment.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+ALTER TABLE `transactions`
+    ADD COLUMN `idempotency_key` varchar(64) COLLATE utf8mb4_unicode_ci DEFAULT NULL AFTER `status`,
+    ADD UNIQUE KEY `uniq_transactions_idempotency_key` (`idempotency_key`);
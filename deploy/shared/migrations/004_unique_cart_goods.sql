@@ -0,0 +1,27 @@
+-- One shopping cart detail for each goods.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+-- the same goods added more than once is merged into one detail before the unique key is added
+CREATE TEMPORARY TABLE `merged_transaction_details` AS
+SELECT
+    `id_transaction`,
+    `id_goods`,
+    SUM(`total_goods`) AS `total_goods`,
+    MAX(`price`) AS `price`,
+    MIN(`created_at`) AS `created_at`
+FROM `transaction_details`
+GROUP BY `id_transaction`, `id_goods`
+HAVING COUNT(*) > 1;
+
+DELETE td FROM `transaction_details` td
+JOIN `merged_transaction_details` merged
+    ON merged.`id_transaction` = td.`id_transaction` AND merged.`id_goods` = td.`id_goods`;
+
+INSERT INTO `transaction_details` (`id_transaction`, `id_goods`, `total_goods`, `price`, `created_at`)
+SELECT `id_transaction`, `id_goods`, `total_goods`, `price`, `created_at` FROM `merged_transaction_details`;
+
+DROP TEMPORARY TABLE `merged_transaction_details`;
+
+ALTER TABLE `transaction_details`
+    ADD UNIQUE KEY `uniq_transaction_details_goods` (`id_transaction`, `id_goods`);
@@ -0,0 +1,17 @@
+-- Last activity of the shopping carts, so the abandoned ones could be expired.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+ALTER TABLE `transactions`
+    ADD COLUMN `created_at` bigint(20) NOT NULL DEFAULT 0 AFTER `idempotency_key`,
+    ADD COLUMN `updated_at` bigint(20) NOT NULL DEFAULT 0 AFTER `created_at`,
+    ADD KEY `idx_transactions_status_updated_at` (`status`, `updated_at`);
+
+-- the details is the only activity known of the existing transactions
+UPDATE `transactions` trx
+JOIN (
+    SELECT `id_transaction`, MIN(`created_at`) AS `created_at`, MAX(`created_at`) AS `updated_at`
+    FROM `transaction_details`
+    GROUP BY `id_transaction`
+) td ON td.`id_transaction` = trx.`id`
+SET trx.`created_at` = td.`created_at`, trx.`updated_at` = td.`updated_at`;
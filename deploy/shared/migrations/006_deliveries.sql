@@ -0,0 +1,14 @@
+-- Deliveries picked up by the courier.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+CREATE TABLE `deliveries` (
+    `id_transaction` bigint(20) NOT NULL,
+    `tracking_number` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `destination` int(11) NOT NULL,
+    `price` double NOT NULL DEFAULT 0,
+    `status` varchar(32) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `created_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id_transaction`),
+    UNIQUE KEY `uniq_deliveries_tracking_number` (`tracking_number`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
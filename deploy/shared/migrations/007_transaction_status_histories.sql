@@ -0,0 +1,14 @@
+-- Status history of the orders.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+-- the existing orders has no history, only their current status
+CREATE TABLE `transaction_status_histories` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `id_transaction` bigint(20) NOT NULL,
+    `from_status` tinyint(4) DEFAULT NULL,
+    `to_status` tinyint(4) NOT NULL,
+    `created_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_transaction_status_histories_transaction` (`id_transaction`, `id`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
@@ -0,0 +1,35 @@
+-- Refunds and the paid time of the transactions for the daily sales report.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+ALTER TABLE `transaction_details`
+    ADD COLUMN `refunded_goods` int(11) NOT NULL DEFAULT 0 AFTER `total_goods`;
+
+ALTER TABLE `transactions`
+    ADD COLUMN `refunded_amount` double NOT NULL DEFAULT 0 AFTER `payment_amount`,
+    ADD COLUMN `paid_at` bigint(20) DEFAULT NULL AFTER `updated_at`,
+    ADD KEY `idx_transactions_paid_at` (`paid_at`);
+
+-- the paid time of the existing transactions is unknown, their last activity is the closest to it
+UPDATE `transactions`
+SET `paid_at` = `updated_at`
+WHERE `status` NOT IN (0, 2);
+
+CREATE TABLE `refunds` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `id_transaction` bigint(20) NOT NULL,
+    `amount` double NOT NULL,
+    `reason` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    `created_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_refunds_transaction` (`id_transaction`),
+    KEY `idx_refunds_created_at` (`created_at`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE `refund_details` (
+    `id_refund` bigint(20) NOT NULL,
+    `id_goods` int(11) NOT NULL,
+    `total_goods` int(11) NOT NULL,
+    `price` double NOT NULL,
+    PRIMARY KEY (`id_refund`, `id_goods`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
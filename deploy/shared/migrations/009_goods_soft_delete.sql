@@ -0,0 +1,9 @@
+-- Soft deleted goods, only the goods which still sold must have unique name.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+-- goods with the same name must be renamed before running this
+ALTER TABLE `goods`
+    ADD COLUMN `deleted_at` bigint(20) DEFAULT NULL AFTER `price`,
+    ADD COLUMN `active_name` varchar(255) COLLATE utf8mb4_unicode_ci GENERATED ALWAYS AS (IF(`deleted_at` IS NULL, `name`, NULL)) STORED AFTER `deleted_at`,
+    ADD UNIQUE KEY `uniq_goods_active_name` (`active_name`);
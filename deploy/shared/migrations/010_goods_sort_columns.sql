@@ -0,0 +1,8 @@
+-- Indexes of the sortable columns of goods.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+ALTER TABLE `goods`
+    ADD KEY `idx_goods_name` (`name`),
+    ADD KEY `idx_goods_price` (`price`),
+    ADD KEY `idx_goods_stocks` (`stocks`);
@@ -0,0 +1,36 @@
+-- Goods categories and variants, for database created before db.sql has them.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+CREATE TABLE `categories` (
+    `id` int(11) NOT NULL AUTO_INCREMENT,
+    `name` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_categories_name` (`name`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+ALTER TABLE `goods`
+    ADD COLUMN `id_category` int(11) DEFAULT NULL AFTER `price`,
+    ADD KEY `idx_goods_category` (`id_category`);
+
+CREATE TABLE `goods_variants` (
+    `id` int(11) NOT NULL AUTO_INCREMENT,
+    `id_goods` int(11) NOT NULL,
+    `name` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `price_delta` double NOT NULL DEFAULT 0,
+    `stocks` int(11) NOT NULL DEFAULT 0,
+    `reserved_stocks` int(11) NOT NULL DEFAULT 0,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_goods_variants_name` (`id_goods`, `name`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- the same goods could be in one transaction more than once as different variants
+ALTER TABLE `transaction_details`
+    ADD COLUMN `id_variant` int(11) NOT NULL DEFAULT 0 AFTER `id_goods`,
+    DROP KEY `uniq_transaction_details_goods`,
+    ADD UNIQUE KEY `uniq_transaction_details_goods` (`id_transaction`, `id_goods`, `id_variant`);
+
+ALTER TABLE `refund_details`
+    ADD COLUMN `id_variant` int(11) NOT NULL DEFAULT 0 AFTER `id_goods`,
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (`id_refund`, `id_goods`, `id_variant`);
@@ -0,0 +1,15 @@
+-- Raw material goods and recipes of the goods made to order.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+ALTER TABLE `goods`
+    ADD COLUMN `is_raw_material` tinyint(1) NOT NULL DEFAULT 0 AFTER `id_category`,
+    ADD COLUMN `unit` varchar(32) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '' AFTER `is_raw_material`;
+
+CREATE TABLE `recipes` (
+    `id_goods` int(11) NOT NULL,
+    `id_ingredient` int(11) NOT NULL,
+    `quantity` int(11) NOT NULL,
+    PRIMARY KEY (`id_goods`, `id_ingredient`),
+    KEY `idx_recipes_ingredient` (`id_ingredient`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
@@ -0,0 +1,32 @@
+-- Append-only stock movement ledger, the current stocks is recorded as opening balance.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+CREATE TABLE `stock_movements` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `id_goods` int(11) NOT NULL,
+    `id_variant` int(11) NOT NULL DEFAULT 0,
+    `type` varchar(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `quantity` int(11) NOT NULL,
+    `stocks_before` int(11) NOT NULL,
+    `stocks_after` int(11) NOT NULL,
+    `actor` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `reason` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    `id_transaction` bigint(20) DEFAULT NULL,
+    `created_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_stock_movements_goods` (`id_goods`, `id_variant`, `created_at`),
+    KEY `idx_stock_movements_transaction` (`id_transaction`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TRIGGER `stock_movements_no_update` BEFORE UPDATE ON `stock_movements`
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'stock movements is append-only';
+
+CREATE TRIGGER `stock_movements_no_delete` BEFORE DELETE ON `stock_movements`
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'stock movements is append-only';
+
+INSERT INTO `stock_movements` (`id_goods`, `type`, `quantity`, `stocks_before`, `stocks_after`, `actor`, `reason`, `created_at`)
+SELECT `id`, 'ADJUSTMENT', `stocks`, 0, `stocks`, 'system', 'opening balance', UNIX_TIMESTAMP() FROM `goods` WHERE `stocks` <> 0;
+
+INSERT INTO `stock_movements` (`id_goods`, `id_variant`, `type`, `quantity`, `stocks_before`, `stocks_after`, `actor`, `reason`, `created_at`)
+SELECT `id_goods`, `id`, 'ADJUSTMENT', `stocks`, 0, `stocks`, 'system', 'opening balance', UNIX_TIMESTAMP() FROM `goods_variants` WHERE `stocks` <> 0;
@@ -0,0 +1,26 @@
+-- Stock opname (physical count) sessions.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+CREATE TABLE `stock_counts` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `status` varchar(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `opened_by` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `approved_by` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    `created_at` bigint(20) NOT NULL,
+    `approved_at` bigint(20) DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_stock_counts_status` (`status`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- system_stocks & price is empty until the stock count approved, the current goods is used meanwhile
+CREATE TABLE `stock_count_items` (
+    `id_stock_count` bigint(20) NOT NULL,
+    `id_goods` int(11) NOT NULL,
+    `id_variant` int(11) NOT NULL DEFAULT 0,
+    `counted_stocks` int(11) NOT NULL,
+    `system_stocks` int(11) DEFAULT NULL,
+    `price` double DEFAULT NULL,
+    `counted_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id_stock_count`, `id_goods`, `id_variant`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
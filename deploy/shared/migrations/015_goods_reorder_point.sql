@@ -0,0 +1,6 @@
+-- Reorder point of the goods for low stock alerts.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+ALTER TABLE `goods`
+    ADD COLUMN `reorder_point` int(11) NOT NULL DEFAULT 0 AFTER `unit`;
@@ -0,0 +1,64 @@
+-- Suppliers, purchase orders and the cost of the goods.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+ALTER TABLE `goods`
+    ADD COLUMN `average_cost` double NOT NULL DEFAULT 0 AFTER `reorder_point`;
+
+ALTER TABLE `goods_variants`
+    ADD COLUMN `average_cost` double NOT NULL DEFAULT 0 AFTER `reserved_stocks`;
+
+-- the past movements cost is unknown, so it's left as zero
+ALTER TABLE `stock_movements`
+    ADD COLUMN `unit_cost` double NOT NULL DEFAULT 0 AFTER `quantity`;
+
+CREATE TABLE `suppliers` (
+    `id` int(11) NOT NULL AUTO_INCREMENT,
+    `name` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `phone` varchar(32) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    `address` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    `created_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_suppliers_name` (`name`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE `purchase_orders` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `id_supplier` int(11) NOT NULL,
+    `status` varchar(24) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `note` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    `created_by` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `created_at` bigint(20) NOT NULL,
+    `updated_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_purchase_orders_supplier` (`id_supplier`),
+    KEY `idx_purchase_orders_status` (`status`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE `purchase_order_items` (
+    `id_purchase_order` bigint(20) NOT NULL,
+    `id_goods` int(11) NOT NULL,
+    `id_variant` int(11) NOT NULL DEFAULT 0,
+    `quantity` int(11) NOT NULL,
+    `received_quantity` int(11) NOT NULL DEFAULT 0,
+    `unit_cost` double NOT NULL DEFAULT 0,
+    PRIMARY KEY (`id_purchase_order`, `id_goods`, `id_variant`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE `purchase_order_receipts` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `id_purchase_order` bigint(20) NOT NULL,
+    `received_by` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `received_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_purchase_order_receipts_order` (`id_purchase_order`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE `purchase_order_receipt_items` (
+    `id_receipt` bigint(20) NOT NULL,
+    `id_goods` int(11) NOT NULL,
+    `id_variant` int(11) NOT NULL DEFAULT 0,
+    `quantity` int(11) NOT NULL,
+    `unit_cost` double NOT NULL DEFAULT 0,
+    PRIMARY KEY (`id_receipt`, `id_goods`, `id_variant`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
@@ -0,0 +1,31 @@
+-- Stock batches with their expiry date.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+ALTER TABLE `stock_movements`
+    ADD COLUMN `expires_at` bigint(20) DEFAULT NULL AFTER `id_transaction`;
+
+ALTER TABLE `purchase_order_receipt_items`
+    ADD COLUMN `expires_at` bigint(20) DEFAULT NULL AFTER `unit_cost`;
+
+CREATE TABLE `stock_batches` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `id_goods` int(11) NOT NULL,
+    `id_variant` int(11) NOT NULL DEFAULT 0,
+    `received_quantity` int(11) NOT NULL,
+    `quantity` int(11) NOT NULL,
+    `unit_cost` double NOT NULL DEFAULT 0,
+    `received_at` bigint(20) NOT NULL,
+    `expires_at` bigint(20) DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_stock_batches_goods` (`id_goods`, `id_variant`, `quantity`),
+    KEY `idx_stock_batches_expires_at` (`expires_at`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- the existing stocks expiry is unknown, so it's kept as single batch which doesn't expire
+INSERT INTO `stock_batches` (`id_goods`, `id_variant`, `received_quantity`, `quantity`, `unit_cost`, `received_at`)
+SELECT `id`, 0, `stocks`, `stocks`, `average_cost`, UNIX_TIMESTAMP() FROM `goods`
+WHERE `stocks` > 0 AND NOT EXISTS (SELECT 1 FROM `goods_variants` v WHERE v.`id_goods` = `goods`.`id`);
+
+INSERT INTO `stock_batches` (`id_goods`, `id_variant`, `received_quantity`, `quantity`, `unit_cost`, `received_at`)
+SELECT `id_goods`, `id`, `stocks`, `stocks`, `average_cost`, UNIX_TIMESTAMP() FROM `goods_variants` WHERE `stocks` > 0;
@@ -0,0 +1,39 @@
+-- Promotions, vouchers and the discount lines of the transactions.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+CREATE TABLE `promotions` (
+    `id` int(11) NOT NULL AUTO_INCREMENT,
+    `name` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `type` varchar(24) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `id_goods` int(11) NOT NULL DEFAULT 0,
+    `value` double NOT NULL DEFAULT 0,
+    `buy_quantity` int(11) NOT NULL DEFAULT 0,
+    `free_quantity` int(11) NOT NULL DEFAULT 0,
+    `min_purchase` double NOT NULL DEFAULT 0,
+    `happy_hour_start` smallint(6) NOT NULL DEFAULT 0,
+    `happy_hour_end` smallint(6) NOT NULL DEFAULT 0,
+    `starts_at` bigint(20) NOT NULL DEFAULT 0,
+    `ends_at` bigint(20) NOT NULL DEFAULT 0,
+    `voucher_code` varchar(32) COLLATE utf8mb4_unicode_ci DEFAULT NULL,
+    `usage_limit` int(11) NOT NULL DEFAULT 0,
+    `used_count` int(11) NOT NULL DEFAULT 0,
+    `created_at` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_promotions_voucher_code` (`voucher_code`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+ALTER TABLE `transactions`
+    ADD COLUMN `discount_amount` double NOT NULL DEFAULT 0 AFTER `total_amount`,
+    ADD COLUMN `voucher_code` varchar(32) COLLATE utf8mb4_unicode_ci DEFAULT NULL AFTER `discount_amount`;
+
+CREATE TABLE `transaction_discounts` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `id_transaction` bigint(20) NOT NULL,
+    `id_promotion` int(11) NOT NULL,
+    `name` varchar(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `voucher_code` varchar(32) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    `amount` double NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_transaction_discounts_transaction` (`id_transaction`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
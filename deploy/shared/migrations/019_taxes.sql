@@ -0,0 +1,24 @@
+-- PPN and service charge breakdown of the transactions.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+ALTER TABLE `goods`
+    ADD COLUMN `price_includes_tax` tinyint(1) NOT NULL DEFAULT 0 AFTER `reorder_point`;
+
+ALTER TABLE `transaction_details`
+    ADD COLUMN `price_includes_tax` tinyint(1) NOT NULL DEFAULT 0 AFTER `price`;
+
+ALTER TABLE `transactions`
+    ADD COLUMN `subtotal_amount` double NOT NULL DEFAULT 0 AFTER `total_amount`,
+    ADD COLUMN `service_charge_amount` double NOT NULL DEFAULT 0 AFTER `discount_amount`,
+    ADD COLUMN `tax_base_amount` double NOT NULL DEFAULT 0 AFTER `service_charge_amount`,
+    ADD COLUMN `tax_amount` double NOT NULL DEFAULT 0 AFTER `tax_base_amount`,
+    ADD COLUMN `tax_rate` double NOT NULL DEFAULT 0 AFTER `tax_amount`,
+    ADD COLUMN `service_charge_rate` double NOT NULL DEFAULT 0 AFTER `tax_rate`;
+
+-- the past transactions have neither service charge nor PPN
+UPDATE `transactions`
+SET
+    `subtotal_amount` = `total_amount` + `discount_amount`,
+    `tax_base_amount` = `total_amount`
+WHERE `paid_at` IS NOT NULL;
@@ -0,0 +1,42 @@
+-- Amounts of money stored as whole rupiah instead of double.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+-- converting the columns round the existing amounts to the nearest rupiah, the rates and costs stay double
+
+ALTER TABLE `goods`
+    MODIFY COLUMN `price` bigint(20) DEFAULT NULL;
+
+ALTER TABLE `goods_variants`
+    MODIFY COLUMN `price_delta` bigint(20) NOT NULL DEFAULT 0;
+
+ALTER TABLE `stock_count_items`
+    MODIFY COLUMN `price` bigint(20) DEFAULT NULL;
+
+ALTER TABLE `promotions`
+    MODIFY COLUMN `min_purchase` bigint(20) NOT NULL DEFAULT 0;
+
+ALTER TABLE `transaction_details`
+    MODIFY COLUMN `price` bigint(20) NOT NULL DEFAULT 0;
+
+ALTER TABLE `transactions`
+    MODIFY COLUMN `total_amount` bigint(20) NOT NULL,
+    MODIFY COLUMN `subtotal_amount` bigint(20) NOT NULL DEFAULT 0,
+    MODIFY COLUMN `discount_amount` bigint(20) NOT NULL DEFAULT 0,
+    MODIFY COLUMN `service_charge_amount` bigint(20) NOT NULL DEFAULT 0,
+    MODIFY COLUMN `tax_base_amount` bigint(20) NOT NULL DEFAULT 0,
+    MODIFY COLUMN `tax_amount` bigint(20) NOT NULL DEFAULT 0,
+    MODIFY COLUMN `payment_amount` bigint(20) DEFAULT NULL,
+    MODIFY COLUMN `refunded_amount` bigint(20) NOT NULL DEFAULT 0;
+
+ALTER TABLE `transaction_discounts`
+    MODIFY COLUMN `amount` bigint(20) NOT NULL;
+
+ALTER TABLE `refunds`
+    MODIFY COLUMN `amount` bigint(20) NOT NULL;
+
+ALTER TABLE `refund_details`
+    MODIFY COLUMN `price` bigint(20) NOT NULL;
+
+ALTER TABLE `deliveries`
+    MODIFY COLUMN `price` bigint(20) NOT NULL DEFAULT 0;
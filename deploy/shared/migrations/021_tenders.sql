@@ -0,0 +1,18 @@
+-- Split payments by payment method.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+CREATE TABLE `transaction_tenders` (
+    `id` bigint(20) NOT NULL AUTO_INCREMENT,
+    `id_transaction` bigint(20) NOT NULL,
+    `method` varchar(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `amount` bigint(20) NOT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uniq_transaction_tenders_method` (`id_transaction`, `method`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
+
+-- the past transactions were paid in cash
+INSERT INTO `transaction_tenders` (`id_transaction`, `method`, `amount`)
+SELECT `id`, 'CASH', `payment_amount`
+FROM `transactions`
+WHERE `payment_amount` IS NOT NULL;
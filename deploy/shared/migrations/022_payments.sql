@@ -0,0 +1,18 @@
+-- Cashless payment through the payment provider.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+CREATE TABLE `payments` (
+    `reference` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `id_transaction` bigint(20) NOT NULL,
+    `method` varchar(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `amount` bigint(20) NOT NULL,
+    `status` varchar(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+    `qr_payload` text COLLATE utf8mb4_unicode_ci NOT NULL,
+    `voucher_code` varchar(32) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+    `created_at` bigint(20) NOT NULL,
+    `expires_at` bigint(20) NOT NULL,
+    `paid_at` bigint(20) NOT NULL DEFAULT 0,
+    PRIMARY KEY (`reference`),
+    KEY `idx_payments_transaction` (`id_transaction`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci;
@@ -0,0 +1,6 @@
+-- Pending delivery which pickup is being requested to the courier, it has no tracking number yet.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+ALTER TABLE `deliveries`
+    MODIFY COLUMN `tracking_number` varchar(64) COLLATE utf8mb4_unicode_ci DEFAULT NULL;
@@ -0,0 +1,11 @@
+-- Include the primary key in the indexes of the sortable columns of goods for cursor paging.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+ALTER TABLE `goods`
+    DROP INDEX `idx_goods_name`,
+    DROP INDEX `idx_goods_price`,
+    DROP INDEX `idx_goods_stocks`,
+    ADD KEY `idx_goods_name` (`name`, `id`),
+    ADD KEY `idx_goods_price` (`price`, `id`),
+    ADD KEY `idx_goods_stocks` (`stocks`, `id`);
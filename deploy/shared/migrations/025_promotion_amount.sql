@@ -0,0 +1,9 @@
+-- Discount amount of FIXED promotions stored as whole rupiah, the value only keeps the percentage.
+-- Fresh database doesn't need this, db.sql already contains the same schema.
+USE `umkm`;
+
+ALTER TABLE `promotions`
+    ADD COLUMN `amount` bigint(20) NOT NULL DEFAULT 0 AFTER `value`;
+
+-- exact value is rounded half away from zero, the same as the application
+UPDATE `promotions` SET `amount` = ROUND(CAST(`value` AS DECIMAL(20, 4))), `value` = 0 WHERE `type` = 'FIXED';
@@ -0,0 +1,204 @@
+// Courier is a stand-in of third party logistic service for big UMKM scenario. It calculate the
+// delivery price, accept pickup request then advance the shipment status as the time goes by.
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gosidekick/goconfig"
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/service"
+	deliverycourier "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/driven/delivery/courier"
+	deliverylocal "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/driven/delivery/local"
+)
+
+// shipmentStatuses is the order of shipment status, the status advance to the next one every status step
+var shipmentStatuses = []entity.DeliveryStatus{
+	entity.DeliveryStatusRequested,
+	entity.DeliveryStatusPickedUp,
+	entity.DeliveryStatusInTransit,
+	entity.DeliveryStatusDelivered,
+}
+
+func main() {
+	var cfg config
+	if err := goconfig.Parse(&cfg); err != nil {
+		log.Fatalf("unable to parse courier config: %v", err)
+	}
+
+	c := &courier{
+		pricing:    deliverylocal.NewSupportService(),
+		statusStep: time.Duration(cfg.StatusStepSeconds) * time.Second,
+		shipments:  map[string]shipment{},
+	}
+
+	router := gin.Default()
+	router.GET("/delivery-price", c.serveDeliveryPrice)
+	router.POST("/pickups", c.servePickup)
+	router.GET("/pickups/:tracking_number", c.serveTracking)
+
+	// Create context that listens for the interrupt signal from the OS.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.Port),
+		Handler: router,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("listen: %s\n", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Fatal("Courier forced to shutdown: ", err)
+	}
+
+	log.Println("Courier exiting")
+}
+
+type config struct {
+	Port int `cfg:"port" cfgDefault:"8081"`
+	// shipment status advance to the next one every this seconds
+	StatusStepSeconds int `cfg:"status_step_seconds" cfgDefault:"30"`
+}
+
+type shipment struct {
+	TransactionID int64
+	Price         entity.Money
+	CreatedAt     time.Time
+}
+
+type courier struct {
+	pricing    service.SupportService
+	statusStep time.Duration
+
+	mu        sync.RWMutex
+	shipments map[string]shipment
+}
+
+func (c *courier) serveDeliveryPrice(ctx *gin.Context) {
+	origin, _ := strconv.Atoi(ctx.Query("origin"))
+	destination, _ := strconv.Atoi(ctx.Query("destination"))
+	weight, _ := strconv.ParseFloat(ctx.Query("weight"), 32)
+	volume, _ := strconv.ParseFloat(ctx.Query("volume"), 32)
+
+	quote, err := c.pricing.CalculateDeliveryPrice(ctx, service.CalculateDeliveryPriceInput{
+		Origin:      origin,
+		Destination: destination,
+		Package:     service.GoodsSpecification{Weight: float32(weight), Volume: float32(volume)},
+	})
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, deliverycourier.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliverycourier.DeliveryPriceResponse{
+		Origin:           quote.Origin,
+		Destination:      quote.Destination,
+		Zone:             quote.Zone,
+		ChargeableWeight: quote.ChargeableWeight,
+		Price:            quote.Price,
+	})
+}
+
+func (c *courier) servePickup(ctx *gin.Context) {
+	var reqBody deliverycourier.PickupRequest
+	if err := ctx.ShouldBindJSON(&reqBody); err != nil {
+		ctx.JSON(http.StatusBadRequest, deliverycourier.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if reqBody.TransactionID <= 0 {
+		ctx.JSON(http.StatusBadRequest, deliverycourier.ErrorResponse{Error: "transaction_id is required"})
+		return
+	}
+
+	quote, err := c.pricing.CalculateDeliveryPrice(ctx, service.CalculateDeliveryPriceInput{
+		Origin:      reqBody.Origin,
+		Destination: reqBody.Destination,
+		Package:     service.GoodsSpecification{Weight: reqBody.Weight, Volume: reqBody.Volume},
+	})
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, deliverycourier.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	trackingNumber, err := newTrackingNumber()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, deliverycourier.ErrorResponse{Error: err.Error()})
+		return
+	}
+	shp := shipment{
+		TransactionID: reqBody.TransactionID,
+		Price:         quote.Price,
+		CreatedAt:     time.Now(),
+	}
+
+	c.mu.Lock()
+	c.shipments[trackingNumber] = shp
+	c.mu.Unlock()
+
+	ctx.JSON(http.StatusCreated, c.toPickupResponse(trackingNumber, shp))
+}
+
+func (c *courier) serveTracking(ctx *gin.Context) {
+	trackingNumber := ctx.Param("tracking_number")
+
+	c.mu.RLock()
+	shp, ok := c.shipments[trackingNumber]
+	c.mu.RUnlock()
+	if !ok {
+		ctx.JSON(http.StatusNotFound, deliverycourier.ErrorResponse{Error: "shipment not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, c.toPickupResponse(trackingNumber, shp))
+}
+
+func (c *courier) toPickupResponse(trackingNumber string, shp shipment) deliverycourier.PickupResponse {
+	return deliverycourier.PickupResponse{
+		TrackingNumber: trackingNumber,
+		TransactionID:  shp.TransactionID,
+		Status:         string(c.shipmentStatus(shp)),
+		Price:          shp.Price,
+		CreatedAt:      shp.CreatedAt.Unix(),
+	}
+}
+
+// shipmentStatus derive the status from the time elapsed since the pickup requested
+func (c *courier) shipmentStatus(shp shipment) entity.DeliveryStatus {
+	if c.statusStep <= 0 {
+		return shipmentStatuses[0]
+	}
+	step := int(time.Since(shp.CreatedAt) / c.statusStep)
+	if step >= len(shipmentStatuses) {
+		step = len(shipmentStatuses) - 1
+	}
+	return shipmentStatuses[step]
+}
+
+func newTrackingNumber() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate tracking number due: %w", err)
+	}
+	return "NRM" + strings.ToUpper(hex.EncodeToString(b)), nil
+}
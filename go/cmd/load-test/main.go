@@ -169,8 +169,8 @@ func (t *addToCartTargeter) newTargeter() vegeta.Targeter {
 				tgt.URL = fmt.Sprintf("%s/api/small/pay", serverAddr)
 
 				payReqBody := payReqBody{
-					CartID:      atcResp.Data.CartID,
-					TotalAmount: atcResp.Data.TotalAmount,
+					CartID:        atcResp.Data.CartID,
+					PaymentAmount: atcResp.Data.GrandTotalAmount,
 				}
 
 				strPayReqBody, err := json.Marshal(payReqBody)
@@ -1,11 +1,14 @@
 package main
 
+import "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+
 type respBodyAddToCart struct {
 	Code   int    `json:"code"`
 	Status string `json:"status"`
 	Data   struct {
-		CartID      int     `json:"cart_id"`
-		TotalAmount float64 `json:"total_amount"`
+		CartID int `json:"cart_id"`
+		// GrandTotalAmount is the amount to pay, it includes the service charge and PPN
+		GrandTotalAmount entity.Money `json:"grand_total_amount"`
 	} `json:"data,omitempty"`
 	Errors interface{} `json:"errors,omitempty"`
 }
@@ -19,6 +22,6 @@ type addToCartReqBody struct {
 }
 
 type payReqBody struct {
-	CartID        int     `json:"cart_id"`
-	PaymentAmount float64 `json:"payment_amount"`
+	CartID        int          `json:"cart_id"`
+	PaymentAmount entity.Money `json:"payment_amount"`
 }
@@ -0,0 +1,351 @@
+// Payment simulator is a stand-in of QRIS / e-wallet payment provider, so the cashless flow could be tested and
+// benchmarked offline. It issue the QR payload of the payment, accept the simulated "customer paid" request then
+// deliver the signed callback to the UMKM app asynchronously.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gosidekick/goconfig"
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+	paymentsimulator "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/driven/payment/simulator"
+)
+
+// paymentStatusExpired is only known by the simulator, the app just never receive callback of expired payment
+const paymentStatusExpired = "EXPIRED"
+
+// maxCallbackAttempts is how many times the callback is delivered before the simulator give up, the wait
+// between the attempts is doubled each time
+const maxCallbackAttempts = 5
+
+func main() {
+	var cfg config
+	if err := goconfig.Parse(&cfg); err != nil {
+		log.Fatalf("unable to parse payment simulator config: %v", err)
+	}
+
+	s := &simulator{
+		secret:       cfg.Secret,
+		merchantName: cfg.MerchantName,
+		merchantCity: cfg.MerchantCity,
+		paymentTTL:   time.Duration(cfg.PaymentTTLSeconds) * time.Second,
+		autoPay:      time.Duration(cfg.AutoPaySeconds) * time.Second,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		payments:     map[string]*payment{},
+	}
+
+	router := gin.Default()
+	router.POST("/payments", s.serveCreatePayment)
+	router.GET("/payments/:reference", s.servePayment)
+	router.POST("/payments/:reference/pay", s.servePay)
+
+	// Create context that listens for the interrupt signal from the OS.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.Port),
+		Handler: router,
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("listen: %s\n", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Fatal("Payment simulator forced to shutdown: ", err)
+	}
+
+	log.Println("Payment simulator exiting")
+}
+
+type config struct {
+	Port int `cfg:"port" cfgDefault:"8082"`
+	// Secret is shared with the UMKM app to sign the callback
+	Secret       string `cfg:"secret" cfgRequired:"true"`
+	MerchantName string `cfg:"merchant_name" cfgDefault:"NORMA UMKM"`
+	MerchantCity string `cfg:"merchant_city" cfgDefault:"YOGYAKARTA"`
+	// payment which not paid within this seconds is expired
+	PaymentTTLSeconds int `cfg:"payment_ttl_seconds" cfgDefault:"900"`
+	// AutoPaySeconds pay every payment automatically after this seconds, so the load test doesn't need to pay
+	// each payment, zero means the payment wait for the pay request
+	AutoPaySeconds int `cfg:"auto_pay_seconds" cfgDefault:"0"`
+}
+
+type payment struct {
+	paymentsimulator.PaymentResponse
+	CallbackURL string
+}
+
+type simulator struct {
+	secret       string
+	merchantName string
+	merchantCity string
+	paymentTTL   time.Duration
+	autoPay      time.Duration
+	httpClient   *http.Client
+
+	mu       sync.RWMutex
+	payments map[string]*payment
+}
+
+func (s *simulator) serveCreatePayment(ctx *gin.Context) {
+	var reqBody paymentsimulator.CreatePaymentRequest
+	if err := ctx.ShouldBindJSON(&reqBody); err != nil {
+		ctx.JSON(http.StatusBadRequest, paymentsimulator.ErrorResponse{Error: err.Error()})
+		return
+	}
+	switch {
+	case reqBody.TransactionID <= 0:
+		ctx.JSON(http.StatusBadRequest, paymentsimulator.ErrorResponse{Error: "transaction_id is required"})
+		return
+	case reqBody.Amount <= 0:
+		ctx.JSON(http.StatusBadRequest, paymentsimulator.ErrorResponse{Error: "amount must be positive"})
+		return
+	case len(reqBody.CallbackURL) == 0:
+		ctx.JSON(http.StatusBadRequest, paymentsimulator.ErrorResponse{Error: "callback_url is required"})
+		return
+	}
+
+	reference, err := newReference()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, paymentsimulator.ErrorResponse{Error: err.Error()})
+		return
+	}
+	now := time.Now()
+	p := &payment{
+		PaymentResponse: paymentsimulator.PaymentResponse{
+			Reference:     reference,
+			TransactionID: reqBody.TransactionID,
+			Method:        reqBody.Method,
+			Amount:        reqBody.Amount,
+			Status:        string(entity.PaymentStatusPending),
+			QRPayload:     s.newQRPayload(reference, reqBody.Amount),
+			CreatedAt:     now.Unix(),
+			ExpiresAt:     now.Add(s.paymentTTL).Unix(),
+		},
+		CallbackURL: reqBody.CallbackURL,
+	}
+
+	s.mu.Lock()
+	s.payments[reference] = p
+	s.mu.Unlock()
+
+	if s.autoPay > 0 {
+		time.AfterFunc(s.autoPay, func() {
+			if _, err := s.pay(reference); err != nil {
+				log.Printf("unable to auto pay payment %s due: %v", reference, err)
+			}
+		})
+	}
+
+	ctx.JSON(http.StatusCreated, p.PaymentResponse)
+}
+
+func (s *simulator) servePayment(ctx *gin.Context) {
+	s.mu.RLock()
+	p, ok := s.payments[ctx.Param("reference")]
+	var resp paymentsimulator.PaymentResponse
+	if ok {
+		resp = s.toPaymentResponse(p)
+	}
+	s.mu.RUnlock()
+	if !ok {
+		ctx.JSON(http.StatusNotFound, paymentsimulator.ErrorResponse{Error: "payment not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// servePay simulate the customer scan the QR code then pay it
+func (s *simulator) servePay(ctx *gin.Context) {
+	resp, err := s.pay(ctx.Param("reference"))
+	if err != nil {
+		ctx.JSON(err.status, paymentsimulator.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type payError struct {
+	status  int
+	message string
+}
+
+func (e *payError) Error() string {
+	return e.message
+}
+
+// pay mark the payment as paid then deliver its callback in the background
+func (s *simulator) pay(reference string) (*paymentsimulator.PaymentResponse, *payError) {
+	s.mu.Lock()
+	p, ok := s.payments[reference]
+	if !ok {
+		s.mu.Unlock()
+		return nil, &payError{status: http.StatusNotFound, message: "payment not found"}
+	}
+	resp := s.toPaymentResponse(p)
+	switch resp.Status {
+	case paymentStatusExpired:
+		s.mu.Unlock()
+		return nil, &payError{status: http.StatusGone, message: "payment already expired"}
+	case string(entity.PaymentStatusPaid):
+		s.mu.Unlock()
+		return nil, &payError{status: http.StatusConflict, message: "payment already paid"}
+	}
+	p.Status = string(entity.PaymentStatusPaid)
+	p.PaidAt = time.Now().Unix()
+	resp = s.toPaymentResponse(p)
+	callbackURL := p.CallbackURL
+	s.mu.Unlock()
+
+	go s.deliverCallback(callbackURL, paymentsimulator.CallbackRequest{
+		Reference:     resp.Reference,
+		TransactionID: resp.TransactionID,
+		Method:        resp.Method,
+		Amount:        resp.Amount,
+		Status:        resp.Status,
+		PaidAt:        resp.PaidAt,
+	})
+
+	return &resp, nil
+}
+
+// deliverCallback send the signed callback until the app accept it, the app reject the callback which it
+// never accept, e.g. the shopping cart already paid in cash, so only server error is retried
+func (s *simulator) deliverCallback(callbackURL string, callback paymentsimulator.CallbackRequest) {
+	payload, err := json.Marshal(callback)
+	if err != nil {
+		log.Printf("unable to marshal callback of payment %s due: %v", callback.Reference, err)
+		return
+	}
+	signature := paymentsimulator.Sign(s.secret, payload)
+
+	wait := time.Second
+	for attempt := 1; attempt <= maxCallbackAttempts; attempt++ {
+		status, err := s.sendCallback(callbackURL, payload, signature)
+		switch {
+		case err == nil && status < http.StatusMultipleChoices:
+			return
+		case err == nil && status < http.StatusInternalServerError:
+			log.Printf("callback of payment %s rejected with status %d", callback.Reference, status)
+			return
+		case err != nil:
+			log.Printf("unable to deliver callback of payment %s due: %v", callback.Reference, err)
+		default:
+			log.Printf("callback of payment %s failed with status %d", callback.Reference, status)
+		}
+		if attempt < maxCallbackAttempts {
+			time.Sleep(wait)
+			wait *= 2
+		}
+	}
+	log.Printf("give up delivering callback of payment %s", callback.Reference)
+}
+
+func (s *simulator) sendCallback(callbackURL string, payload []byte, signature string) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, callbackURL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(paymentsimulator.SignatureHeader, signature)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+// toPaymentResponse derive the expired status from the time, the caller must hold the lock
+func (s *simulator) toPaymentResponse(p *payment) paymentsimulator.PaymentResponse {
+	resp := p.PaymentResponse
+	if resp.Status == string(entity.PaymentStatusPending) && time.Now().Unix() >= resp.ExpiresAt {
+		resp.Status = paymentStatusExpired
+	}
+	return resp
+}
+
+// newQRPayload create dynamic QRIS payload, it follows EMV QR code TLV format so the QR code scanner could
+// read it, but the merchant account is made up
+func (s *simulator) newQRPayload(reference string, amount entity.Money) string {
+	var b strings.Builder
+	writeTLV(&b, "00", "01")
+	// dynamic QR code, it could only be paid once
+	writeTLV(&b, "01", "12")
+	writeTLV(&b, "26", tlv("00", "ID.CO.QRIS.WWW")+tlv("01", reference))
+	writeTLV(&b, "52", "5812")
+	// IDR
+	writeTLV(&b, "53", "360")
+	writeTLV(&b, "54", strconv.FormatInt(int64(amount), 10))
+	writeTLV(&b, "58", "ID")
+	writeTLV(&b, "59", truncate(s.merchantName, 25))
+	writeTLV(&b, "60", truncate(s.merchantCity, 15))
+	writeTLV(&b, "62", tlv("05", reference))
+	b.WriteString("6304")
+	return b.String() + fmt.Sprintf("%04X", crc16CCITT(b.String()))
+}
+
+func tlv(tag, value string) string {
+	return fmt.Sprintf("%s%02d%s", tag, len(value), value)
+}
+
+func writeTLV(b *strings.Builder, tag, value string) {
+	b.WriteString(tlv(tag, value))
+}
+
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
+
+// crc16CCITT is the checksum of QRIS payload, polynomial 0x1021 with initial value 0xFFFF
+func crc16CCITT(data string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func newReference() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate payment reference due: %w", err)
+	}
+	return "SIM" + strings.ToUpper(hex.EncodeToString(b)), nil
+}
@@ -12,8 +12,14 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/gosidekick/goconfig"
 	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/service"
+	deliverycourier "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/driven/delivery/courier"
+	deliverylocal "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/driven/delivery/local"
+	notifierlocal "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/driven/notifier/local"
+	notifierwebhook "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/driven/notifier/webhook"
+	paymentsimulator "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/driven/payment/simulator"
 	storagemysql "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/driven/storage/mysql"
 	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/driver/rest"
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/driver/worker"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -31,10 +37,57 @@ func main() {
 	})
 	handleError(err, fmt.Sprintf("unable to initialize mysql storage due: %v", err))
 
+	// init. support service, use the courier service when its address is given
+	var supportService service.SupportService = deliverylocal.NewSupportService()
+	if cfg.CourierAddr != "" {
+		supportService, err = deliverycourier.NewSupportService(deliverycourier.SupportServiceConfig{
+			BaseURL: cfg.CourierAddr,
+		})
+		handleError(err, fmt.Sprintf("unable to initialize courier support service due: %v", err))
+	}
+
+	// init. notifier, use the webhook when its URL is given
+	var notifier service.Notifier = notifierlocal.NewNotifier(notifierlocal.NotifierConfig{
+		FilePath: cfg.AlertFile,
+	})
+	if cfg.AlertWebhookURL != "" {
+		notifier, err = notifierwebhook.NewNotifier(notifierwebhook.NotifierConfig{
+			URL: cfg.AlertWebhookURL,
+		})
+		handleError(err, fmt.Sprintf("unable to initialize webhook notifier due: %v", err))
+	}
+
+	// init. payment provider, cashless payment is only available when the payment provider address is given
+	var paymentProvider service.PaymentProvider
+	if cfg.PaymentProviderAddr != "" {
+		if cfg.PaymentSecret == "" {
+			log.Fatalf("unable to initialize payment provider due: PAYMENT_SECRET is required along with PAYMENT_PROVIDER_ADDR")
+		}
+		paymentProvider, err = paymentsimulator.NewPaymentProvider(paymentsimulator.PaymentProviderConfig{
+			BaseURL:     cfg.PaymentProviderAddr,
+			CallbackURL: cfg.PaymentCallbackURL,
+			Secret:      cfg.PaymentSecret,
+		})
+		handleError(err, fmt.Sprintf("unable to initialize payment provider due: %v", err))
+	}
+
+	// init. alert dispatcher, it's closed after the server shutdown so the pending alerts still sent
+	alertDispatcher, err := worker.NewAlertDispatcher(worker.AlertDispatcherConfig{
+		QueueSize: 256,
+		Timeout:   30 * time.Second,
+	})
+	handleError(err, fmt.Sprintf("unable to initialize alert dispatcher due: %v", err))
+
 	// init. service
 	svc, err := service.NewService(service.ServiceConfig{
-		Storage:        strg,
-		SupportService: &mockSupportService{},
+		Storage:           strg,
+		SupportService:    supportService,
+		Notifier:          notifier,
+		AlertDispatcher:   alertDispatcher,
+		PaymentProvider:   paymentProvider,
+		ShopLocation:      cfg.ShopLocation,
+		TaxRate:           cfg.TaxRate,
+		ServiceChargeRate: cfg.ServiceChargeRate,
 	})
 	handleError(err, fmt.Sprintf("unable to initialize core service due: %v", err))
 
@@ -48,6 +101,29 @@ func main() {
 	})
 	handleError(err, fmt.Sprintf("unable to initialize rest api due: %v", err))
 
+	// init. background worker for expiring abandoned shopping carts
+	if cfg.CartTTLSeconds > 0 {
+		cartSweeper, err := worker.NewCartSweeper(worker.CartSweeperConfig{
+			Service:  svc,
+			Interval: time.Duration(cfg.CartSweepIntervalSeconds) * time.Second,
+			CartTTL:  time.Duration(cfg.CartTTLSeconds) * time.Second,
+		})
+		handleError(err, fmt.Sprintf("unable to initialize cart sweeper due: %v", err))
+
+		go cartSweeper.Run(ctx)
+	}
+
+	// init. background worker for verifying the stocks against the stock ledger
+	if cfg.StockReconcileIntervalSeconds > 0 {
+		stockReconciler, err := worker.NewStockReconciler(worker.StockReconcilerConfig{
+			Service:  svc,
+			Interval: time.Duration(cfg.StockReconcileIntervalSeconds) * time.Second,
+		})
+		handleError(err, fmt.Sprintf("unable to initialize stock reconciler due: %v", err))
+
+		go stockReconciler.Run(ctx)
+	}
+
 	srv := &http.Server{
 		Addr:    ":8080",
 		Handler: api.Handler(),
@@ -75,6 +151,7 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown: ", err)
 	}
+	alertDispatcher.Close()
 
 	log.Println("Server exiting")
 }
@@ -87,14 +164,26 @@ func handleError(err error, message string) {
 
 type config struct {
 	SQLDSN string `cfg:"db_sqldsn" cfgRequired:"true" cfgDefault:"root:test1234@tcp(localhost:23306)/umkm?timeout=5s"`
-}
-
-type mockSupportService struct{}
-
-func (m *mockSupportService) CalculateDeliveryPrice(ctx context.Context) (float64, error) {
-	return 0, nil
-}
-
-func (m *mockSupportService) PickupDelivery(ctx context.Context) (bool, error) {
-	return true, nil
+	// shopping cart which not updated longer than its TTL will be expired, set to 0 for disable it
+	CartTTLSeconds           int `cfg:"cart_ttl_seconds" cfgDefault:"1800"`
+	CartSweepIntervalSeconds int `cfg:"cart_sweep_interval_seconds" cfgDefault:"60"`
+	// interval of verifying the stocks against the stock ledger, set to 0 for disable it
+	StockReconcileIntervalSeconds int `cfg:"stock_reconcile_interval_seconds" cfgDefault:"3600"`
+	// regency / city code of the shop, default is Kota Yogyakarta
+	ShopLocation int `cfg:"shop_location" cfgDefault:"3471"`
+	// PPN and service charge percentage e.g. 11 and 5, set to 0 when the shop doesn't charge them
+	TaxRate           float64 `cfg:"tax_rate" cfgDefault:"0"`
+	ServiceChargeRate float64 `cfg:"service_charge_rate" cfgDefault:"0"`
+	// base URL of the courier service e.g. http://courier:8081, when empty delivery handled locally
+	CourierAddr string `cfg:"courier_addr"`
+	// URL of the webhook which receive the alerts e.g. low stock, when empty the alerts written into AlertFile
+	AlertWebhookURL string `cfg:"alert_webhook_url"`
+	// file which the alerts appended into as JSON lines, when empty the alerts written into the log
+	AlertFile string `cfg:"alert_file"`
+	// base URL of the payment simulator e.g. http://payment-simulator:8082, when empty only cash is accepted
+	PaymentProviderAddr string `cfg:"payment_provider_addr"`
+	// URL of the payment callback endpoint which reachable by the payment provider
+	PaymentCallbackURL string `cfg:"payment_callback_url" cfgDefault:"http://localhost:8080/api/payments/callback"`
+	// secret shared with the payment provider to sign the callback, required when the payment provider address is given
+	PaymentSecret string `cfg:"payment_secret"`
 }
@@ -0,0 +1,55 @@
+package entity
+
+// TaxRates is the percentage of PPN and service charge of the shop, zero rate means it's not charged
+type TaxRates struct {
+	TaxRate           float64
+	ServiceChargeRate float64
+}
+
+// Bill is the breakdown of the amount to pay, each of the amounts taken by the rates is rounded to whole rupiah
+type Bill struct {
+	// SubtotalAmount is the price of all goods, the price of tax inclusive goods already contains the PPN
+	SubtotalAmount Money
+	DiscountAmount Money
+	// ServiceChargeAmount is charged from the goods price after the discounts, excluding the PPN
+	ServiceChargeAmount Money
+	// TaxBaseAmount is the DPP, the amount the PPN charged from i.e. the goods price after the discounts
+	// excluding the PPN plus the service charge
+	TaxBaseAmount Money
+	// TaxAmount is the PPN of the bill, including the PPN within the price of tax inclusive goods
+	TaxAmount Money
+	// GrandTotalAmount is the amount to pay
+	GrandTotalAmount Money
+}
+
+// NewBill compute the service charge and PPN of the goods. The discount is spread into the goods proportionally
+// to their price, then the PPN within the price of tax inclusive goods is separated from its tax base while the
+// tax exclusive goods get the PPN on top of their price.
+func NewBill(details []ShoppingCartDetail, discountAmount Money, rates TaxRates) Bill {
+	var subtotal, inclusiveAmount Money
+	for _, detail := range details {
+		amount := detail.GoodsPrice.Mul(detail.TotalGoods)
+		subtotal += amount
+		if detail.PriceIncludesTax {
+			inclusiveAmount += amount
+		}
+	}
+	bill := Bill{
+		SubtotalAmount: subtotal,
+		DiscountAmount: discountAmount.Min(subtotal),
+	}
+	netAmount := subtotal - bill.DiscountAmount
+	inclusiveAmount = inclusiveAmount.Prorate(netAmount, subtotal)
+	exclusiveAmount := netAmount - inclusiveAmount
+
+	includedTax := NewMoney(inclusiveAmount.Float64() * rates.TaxRate / (100 + rates.TaxRate))
+	goodsTaxBase := netAmount - includedTax
+	bill.ServiceChargeAmount = goodsTaxBase.Percent(rates.ServiceChargeRate)
+	addedTax := (exclusiveAmount + bill.ServiceChargeAmount).Percent(rates.TaxRate)
+
+	bill.TaxBaseAmount = goodsTaxBase + bill.ServiceChargeAmount
+	bill.TaxAmount = includedTax + addedTax
+	bill.GrandTotalAmount = netAmount + bill.ServiceChargeAmount + addedTax
+
+	return bill
+}
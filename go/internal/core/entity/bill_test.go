@@ -0,0 +1,57 @@
+package entity_test
+
+import (
+	"testing"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBill(mainT *testing.T) {
+	exclusive := []entity.ShoppingCartDetail{{GoodsID: 1, TotalGoods: 1, GoodsPrice: 10000}}
+	inclusive := []entity.ShoppingCartDetail{{GoodsID: 1, TotalGoods: 1, GoodsPrice: 11100, PriceIncludesTax: true}}
+
+	mainT.Run("Without tax rates", func(t *testing.T) {
+		bill := entity.NewBill(exclusive, 1000, entity.TaxRates{})
+		require.Equal(t, entity.Bill{
+			SubtotalAmount:   10000,
+			DiscountAmount:   1000,
+			TaxBaseAmount:    9000,
+			GrandTotalAmount: 9000,
+		}, bill)
+	})
+
+	mainT.Run("Tax exclusive price", func(t *testing.T) {
+		bill := entity.NewBill(exclusive, 0, entity.TaxRates{TaxRate: 11, ServiceChargeRate: 10})
+		require.Equal(t, entity.Bill{
+			SubtotalAmount:      10000,
+			ServiceChargeAmount: 1000,
+			TaxBaseAmount:       11000,
+			TaxAmount:           1210,
+			GrandTotalAmount:    12210,
+		}, bill)
+	})
+
+	mainT.Run("Tax inclusive price", func(t *testing.T) {
+		bill := entity.NewBill(inclusive, 0, entity.TaxRates{TaxRate: 11})
+		require.Equal(t, entity.Bill{
+			SubtotalAmount:   11100,
+			TaxBaseAmount:    10000,
+			TaxAmount:        1100,
+			GrandTotalAmount: 11100,
+		}, bill)
+	})
+
+	mainT.Run("Discount reduce the tax base", func(t *testing.T) {
+		bill := entity.NewBill(inclusive, 1110, entity.TaxRates{TaxRate: 11, ServiceChargeRate: 10})
+		// service charge 900 is added on top of the discounted price with its PPN 99
+		require.Equal(t, entity.Bill{
+			SubtotalAmount:      11100,
+			DiscountAmount:      1110,
+			ServiceChargeAmount: 900,
+			TaxBaseAmount:       9900,
+			TaxAmount:           1089,
+			GrandTotalAmount:    10989,
+		}, bill)
+	})
+}
@@ -1,17 +1,29 @@
 package entity
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"gopkg.in/validator.v2"
 )
 
+var ErrGoodsNotInCart = errors.New("goods not found in shopping cart")
+
 type ShoppingCart struct {
-	ID          int64
-	UserID      int
-	TotalAmount float64
+	ID     int64
+	UserID int
+	// TotalAmount is the subtotal after the discounts
+	TotalAmount Money
 	Details     []ShoppingCartDetail
+	// VoucherCode is empty when the buyer doesn't use any voucher
+	VoucherCode string
+	// Promotions is the active promotions which applied when the total amount computed
+	Promotions []Promotion
+	// Discounts is the discount lines given by the promotions, it's updated along with the total amount
+	Discounts []Discount
+	// TaxRates is the PPN and service charge rates of the shop, it's set along with the promotions
+	TaxRates TaxRates
 }
 
 type ShoppingCartConfig struct {
@@ -29,9 +41,13 @@ func NewShoppingCart(config ShoppingCartConfig) (*ShoppingCart, error) {
 }
 
 type AddGoodsInput struct {
-	GoodsID    int     `validate:"nonzero"`
-	TotalGoods int     `validate:"nonzero"`
-	GoodsPrice float64 `validate:"nonzero"`
+	GoodsID int `validate:"nonzero"`
+	// VariantID is zero for goods which has no variants
+	VariantID  int
+	TotalGoods int   `validate:"nonzero"`
+	GoodsPrice Money `validate:"nonzero"`
+	// PriceIncludesTax tell the goods price already contains the PPN
+	PriceIncludesTax bool
 }
 
 func (c *ShoppingCart) AddGoods(input AddGoodsInput) error {
@@ -39,18 +55,68 @@ func (c *ShoppingCart) AddGoods(input AddGoodsInput) error {
 		return fmt.Errorf("unable to add goods into cart due: %w", err)
 	}
 
-	c.Details = append(c.Details, ShoppingCartDetail{
-		GoodsID:    input.GoodsID,
-		TotalGoods: input.TotalGoods,
-		GoodsPrice: input.GoodsPrice,
-		CreatedAt:  time.Now().Unix(),
-	})
+	// the same goods variant merged into one line using the latest price
+	if i := c.findGoods(input.GoodsID, input.VariantID); i >= 0 {
+		c.Details[i].TotalGoods += input.TotalGoods
+		c.Details[i].GoodsPrice = input.GoodsPrice
+		c.Details[i].PriceIncludesTax = input.PriceIncludesTax
+	} else {
+		c.Details = append(c.Details, ShoppingCartDetail{
+			GoodsID:          input.GoodsID,
+			VariantID:        input.VariantID,
+			TotalGoods:       input.TotalGoods,
+			GoodsPrice:       input.GoodsPrice,
+			CreatedAt:        time.Now().Unix(),
+			PriceIncludesTax: input.PriceIncludesTax,
+		})
+	}
 	// update the shopping cart total amount as well
 	c.TotalAmount = c.GetTotalAmount()
 
 	return nil
 }
 
+func (c *ShoppingCart) UpdateGoodsQuantity(goodsID int, variantID int, totalGoods int) error {
+	if totalGoods <= 0 {
+		return fmt.Errorf("unable to update goods quantity due: total goods must be greater than zero")
+	}
+	i := c.findGoods(goodsID, variantID)
+	if i < 0 {
+		return ErrGoodsNotInCart
+	}
+	c.Details[i].TotalGoods = totalGoods
+	c.TotalAmount = c.GetTotalAmount()
+
+	return nil
+}
+
+func (c *ShoppingCart) RemoveGoods(goodsID int, variantID int) error {
+	i := c.findGoods(goodsID, variantID)
+	if i < 0 {
+		return ErrGoodsNotInCart
+	}
+	c.Details = append(c.Details[:i], c.Details[i+1:]...)
+	c.TotalAmount = c.GetTotalAmount()
+
+	return nil
+}
+
+// Clear remove all goods from shopping cart
+func (c *ShoppingCart) Clear() {
+	c.Details = nil
+	c.Discounts = nil
+	c.TotalAmount = 0
+}
+
+func (c ShoppingCart) findGoods(goodsID int, variantID int) int {
+	for i, detail := range c.Details {
+		if detail.GoodsID == goodsID && detail.VariantID == variantID {
+			return i
+		}
+	}
+	return -1
+}
+
 func (c ShoppingCart) GetTotalGoods() int {
 	var totalGoods int
 	for _, detail := range c.Details {
@@ -59,18 +125,66 @@ func (c ShoppingCart) GetTotalGoods() int {
 	return totalGoods
 }
 
-func (c *ShoppingCart) GetTotalAmount() float64 {
-	var newAmount float64
+// GetSubtotalAmount is the price of all goods in the cart before the discounts
+func (c ShoppingCart) GetSubtotalAmount() Money {
+	var newAmount Money
 	for _, detail := range c.Details {
-		newAmount += float64(detail.TotalGoods) * detail.GoodsPrice
+		newAmount += detail.GoodsPrice.Mul(detail.TotalGoods)
 	}
 
 	return newAmount
 }
 
+// GetTotalAmount apply the promotions of the cart into the discount lines then return the subtotal after
+// the discounts, the total discount never exceeds the subtotal
+func (c *ShoppingCart) GetTotalAmount() Money {
+	subtotal := c.GetSubtotalAmount()
+	remaining := subtotal
+	c.Discounts = nil
+	for _, promotion := range c.Promotions {
+		amount := promotion.Discount(c.Details).Min(remaining)
+		if amount <= 0 {
+			continue
+		}
+		c.Discounts = append(c.Discounts, Discount{
+			PromotionID: promotion.ID,
+			Name:        promotion.Name,
+			VoucherCode: promotion.VoucherCode,
+			Amount:      amount,
+		})
+		remaining -= amount
+	}
+
+	return remaining
+}
+
+// GetDiscountAmount is the total of the discount lines
+func (c ShoppingCart) GetDiscountAmount() Money {
+	var discountAmount Money
+	for _, discount := range c.Discounts {
+		discountAmount += discount.Amount
+	}
+	return discountAmount
+}
+
+// ApplyPromotions replace the promotions of the cart then compute the total amount again
+func (c *ShoppingCart) ApplyPromotions(promotions []Promotion) {
+	c.Promotions = promotions
+	c.TotalAmount = c.GetTotalAmount()
+}
+
+// GetBill compute the service charge and PPN of the cart after the discounts
+func (c *ShoppingCart) GetBill() Bill {
+	return NewBill(c.Details, c.GetSubtotalAmount()-c.GetTotalAmount(), c.TaxRates)
+}
+
 type ShoppingCartDetail struct {
-	GoodsID    int
+	GoodsID int
+	// VariantID is zero for goods which has no variants
+	VariantID  int
 	TotalGoods int
-	GoodsPrice float64
+	GoodsPrice Money
 	CreatedAt  int64
+	// PriceIncludesTax is the snapshot of the goods when it's added into the cart
+	PriceIncludesTax bool
 }
@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"fmt"
+
+	"gopkg.in/validator.v2"
+)
+
+// Category group the goods in the menu, e.g. drinks or snacks
+type Category struct {
+	ID   int
+	Name string
+}
+
+type CategoryConfig struct {
+	// ID is empty for new category, it's assigned by the storage
+	ID   int
+	Name string `validate:"nonzero"`
+}
+
+func NewCategory(cfg CategoryConfig) (*Category, error) {
+	if err := validator.Validate(cfg); err != nil {
+		return nil, fmt.Errorf("unable to create category entity due: %w", err)
+	}
+
+	return &Category{
+		ID:   cfg.ID,
+		Name: cfg.Name,
+	}, nil
+}
@@ -0,0 +1,24 @@
+package entity
+
+type DeliveryStatus string
+
+const (
+	// DeliveryStatusPending is delivery which pickup is being requested to the courier, it hold the transaction
+	// so the courier is only asked once
+	DeliveryStatusPending   DeliveryStatus = "PENDING"
+	DeliveryStatusRequested DeliveryStatus = "REQUESTED"
+	DeliveryStatusPickedUp  DeliveryStatus = "PICKED_UP"
+	DeliveryStatusInTransit DeliveryStatus = "IN_TRANSIT"
+	DeliveryStatusDelivered DeliveryStatus = "DELIVERED"
+)
+
+// Delivery is shipment of paid transaction which picked up by the courier
+type Delivery struct {
+	TransactionID int64
+	// TrackingNumber is empty while the delivery is pending
+	TrackingNumber string
+	Destination    int
+	Price          Money
+	Status         DeliveryStatus
+	CreatedAt      int64
+}
@@ -7,24 +7,174 @@ import (
 )
 
 type Goods struct {
-	ID     int
-	Name   string
-	Stocks int
-	Price  float64
+	ID             int
+	Name           string
+	Stocks         int
+	ReservedStocks int
+	Price          Money
+	// CategoryID is zero for goods which not categorized yet
+	CategoryID int
+	// Variants is only loaded when the goods is fetched one by one or for the menu
+	Variants []GoodsVariant
+	// IsRawMaterial tell the goods is not sold, it's consumed by the recipe of the sold goods instead.
+	// Price of raw material is its cost per unit.
+	IsRawMaterial bool
+	// Unit of the stocks, e.g. gram or ml for raw material
+	Unit string
+	// ReorderPoint is the minimum stocks before the goods need to be restocked, zero means it's not monitored.
+	// Goods which has variants use the same reorder point for each of its variants.
+	ReorderPoint int
+	// PriceIncludesTax tell the price already contains the PPN, otherwise the PPN is added on top of the price
+	PriceIncludesTax bool
+	// AverageCost is the moving average of the purchase price per unit, it's recalculated whenever the stocks
+	// received with its cost e.g. from purchase order. Zero means the cost is unknown yet.
+	AverageCost float64
+	// Recipe is nil for goods which hold its own stocks
+	Recipe *Recipe
+	// DeletedAt is unix time when the goods removed from the catalog, 0 means it's still sold.
+	// Deleted goods is kept so the past transactions still refer to it.
+	DeletedAt int64
 }
 
 type GoodsConfig struct {
-	ID     int    `validate:"nonzero"`
-	Name   string `validate:"nonzero"`
-	Stocks int
-	Price  float64 `validate:"nonzero"`
+	// ID is empty for new goods, it's assigned by the storage
+	ID            int
+	Name          string `validate:"nonzero"`
+	Stocks        int
+	Price         Money `validate:"nonzero,min=0"`
+	CategoryID    int
+	IsRawMaterial bool
+	Unit          string
+	ReorderPoint  int `validate:"min=0"`
+	// PriceIncludesTax tell the price already contains the PPN
+	PriceIncludesTax bool
+}
+
+// InsufficientStockError returned when requested total of goods is more than the available stocks
+type InsufficientStockError struct {
+	GoodsID int
+	// VariantID is zero when the stocks is held by the goods itself
+	VariantID int
+	Stocks    int
+	Requested int
+}
+
+func (e InsufficientStockError) Error() string {
+	if e.VariantID > 0 {
+		return fmt.Sprintf(
+			"insufficient stock for goods %d variant %d: requested %d, available %d",
+			e.GoodsID,
+			e.VariantID,
+			e.Requested,
+			e.Stocks,
+		)
+	}
+	return fmt.Sprintf("insufficient stock for goods %d: requested %d, available %d", e.GoodsID, e.Requested, e.Stocks)
+}
+
+func (g Goods) IsDeleted() bool {
+	return g.DeletedAt > 0
+}
+
+// HasVariants tell whether the goods must be sold through one of its variants
+func (g Goods) HasVariants() bool {
+	return len(g.Variants) > 0
+}
+
+func (g Goods) FindVariant(variantID int) (*GoodsVariant, bool) {
+	for _, variant := range g.Variants {
+		if variant.ID == variantID {
+			return &variant, true
+		}
+	}
+	return nil, false
+}
+
+// IsMadeToOrder tell whether the goods is made from its recipe, so its raw materials stocks is used instead
+func (g Goods) IsMadeToOrder() bool {
+	return g.Recipe != nil && len(g.Recipe.Ingredients) > 0
+}
+
+// SellableStocks is the total of goods which still could be sold, goods made to order is limited by its recipe servings
+func (g Goods) SellableStocks() int {
+	if g.IsMadeToOrder() {
+		return g.Recipe.Servings
+	}
+	return g.AvailableStocks()
+}
+
+// IsCrossingReorderPoint tell whether the stocks change make the stocks drop to the reorder point or below it
+func (g Goods) IsCrossingReorderPoint(stocksBefore int, stocksAfter int) bool {
+	return g.ReorderPoint > 0 && stocksBefore > g.ReorderPoint && stocksAfter <= g.ReorderPoint
 }
 
 func (g *Goods) IncreaseStock(total int) {
 	g.Stocks += total
 }
 
-func (g *Goods) DecreaseStock(total int) {
+// ReceiveStock increase the stocks which bought at the unit cost, zero unit cost keep the average cost as is
+func (g *Goods) ReceiveStock(total int, unitCost float64) {
+	g.AverageCost = averageCost(g.Stocks, g.AverageCost, total, unitCost)
+	g.IncreaseStock(total)
+}
+
+// UnitCost is the cost of one goods, raw material which never purchased yet is valued at its price
+func (g Goods) UnitCost() float64 {
+	if g.AverageCost == 0 && g.IsRawMaterial {
+		return g.Price.Float64()
+	}
+	return g.AverageCost
+}
+
+// AvailableStocks is the stocks that not reserved yet by any shopping cart
+func (g Goods) AvailableStocks() int {
+	return g.Stocks - g.ReservedStocks
+}
+
+func (g *Goods) DecreaseStock(total int) error {
+	if total > g.AvailableStocks() {
+		return InsufficientStockError{
+			GoodsID:   g.ID,
+			Stocks:    g.AvailableStocks(),
+			Requested: total,
+		}
+	}
+	g.Stocks -= total
+
+	return nil
+}
+
+// SetCountedStock set the stocks into the physically counted quantity. Unlike DecreaseStock the reserved stocks
+// is not checked, the shrinkage found by stock opname is recorded even when open shopping carts reserve more.
+func (g *Goods) SetCountedStock(counted int) {
+	g.Stocks = counted
+}
+
+// ReserveStock hold the stocks for shopping cart so it can't be sold to another cart
+func (g *Goods) ReserveStock(total int) error {
+	if total > g.AvailableStocks() {
+		return InsufficientStockError{
+			GoodsID:   g.ID,
+			Stocks:    g.AvailableStocks(),
+			Requested: total,
+		}
+	}
+	g.ReservedStocks += total
+
+	return nil
+}
+
+// ReleaseStock give back the reserved stocks, e.g. when shopping cart abandoned
+func (g *Goods) ReleaseStock(total int) {
+	g.ReservedStocks -= total
+	if g.ReservedStocks < 0 {
+		g.ReservedStocks = 0
+	}
+}
+
+// DeductReservedStock turn the reserved stocks into permanent deduction when shopping cart paid
+func (g *Goods) DeductReservedStock(total int) {
+	g.ReleaseStock(total)
 	g.Stocks -= total
 }
 
@@ -38,11 +188,28 @@ func NewGoods(cfg GoodsConfig) (*Goods, error) {
 		stocks = cfg.Stocks
 	}
 	goods := &Goods{
-		ID:     cfg.ID,
-		Name:   cfg.Name,
-		Stocks: stocks,
-		Price:  cfg.Price,
+		ID:               cfg.ID,
+		Name:             cfg.Name,
+		Stocks:           stocks,
+		Price:            cfg.Price,
+		CategoryID:       cfg.CategoryID,
+		IsRawMaterial:    cfg.IsRawMaterial,
+		Unit:             cfg.Unit,
+		ReorderPoint:     cfg.ReorderPoint,
+		PriceIncludesTax: cfg.PriceIncludesTax,
 	}
 
 	return goods, nil
 }
+
+// averageCost weigh the current cost of the stocks with the cost of the received stocks, the stocks which cost
+// is unknown is valued at the received cost
+func averageCost(stocks int, currentCost float64, total int, unitCost float64) float64 {
+	if unitCost <= 0 || total <= 0 {
+		return currentCost
+	}
+	if stocks <= 0 || currentCost <= 0 {
+		return unitCost
+	}
+	return (float64(stocks)*currentCost + float64(total)*unitCost) / float64(stocks+total)
+}
@@ -0,0 +1,12 @@
+package entity
+
+// LowStock is goods or variant which stocks already at its reorder point or below it
+type LowStock struct {
+	GoodsID int
+	// VariantID is zero when the stocks is held by the goods itself
+	VariantID    int
+	Name         string
+	Unit         string
+	Stocks       int
+	ReorderPoint int
+}
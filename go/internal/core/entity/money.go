@@ -0,0 +1,67 @@
+package entity
+
+import "math"
+
+// Money is an amount of rupiah. IDR has no minor unit in circulation, so the rupiah itself is the minor unit and
+// every amount is a whole number of rupiah. Adding, subtracting and multiplying by quantity are exact, the rounding
+// only happens when the amount is taken by a rate or a proportion:
+//   - Percent and Prorate round half away from zero to the nearest rupiah
+//   - amount from outside e.g. float computation is rounded the same way by NewMoney
+//
+// The cost of the stocks (unit cost and average cost) is not Money, it's a per unit average which may have
+// fraction of rupiah e.g. coffee beans per gram. It only become Money when it's summed up in a report or the total
+// of a purchase order, the sum is rounded once by NewMoney instead of rounding the cost of each goods.
+type Money int64
+
+// NewMoney round the amount half away from zero to the nearest rupiah
+func NewMoney(amount float64) Money {
+	return Money(math.Round(amount))
+}
+
+// Mul is the amount of the given quantity, e.g. price of the goods times the total goods
+func (m Money) Mul(quantity int) Money {
+	return m * Money(quantity)
+}
+
+// Percent take the rate percent of the amount, e.g. PPN 11 percent of the tax base
+func (m Money) Percent(rate float64) Money {
+	return NewMoney(float64(m) * rate / 100)
+}
+
+// Prorate take the part of the amount proportional to part / whole, e.g. share of the discount of a goods.
+// Zero whole means there's nothing to share.
+func (m Money) Prorate(part Money, whole Money) Money {
+	if whole == 0 {
+		return 0
+	}
+	numerator := int64(m) * int64(part)
+	quotient, remainder := numerator/int64(whole), numerator%int64(whole)
+	// round half away from zero
+	if remainder != 0 && 2*abs(remainder) >= abs(int64(whole)) {
+		if (numerator < 0) != (whole < 0) {
+			quotient--
+		} else {
+			quotient++
+		}
+	}
+	return Money(quotient)
+}
+
+// Min return the smaller amount
+func (m Money) Min(other Money) Money {
+	if other < m {
+		return other
+	}
+	return m
+}
+
+func (m Money) Float64() float64 {
+	return float64(m)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
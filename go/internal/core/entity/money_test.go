@@ -0,0 +1,36 @@
+package entity_test
+
+import (
+	"testing"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoney(mainT *testing.T) {
+	mainT.Run("Round half away from zero", func(t *testing.T) {
+		require.Equal(t, entity.Money(3), entity.NewMoney(2.5))
+		require.Equal(t, entity.Money(-3), entity.NewMoney(-2.5))
+		require.Equal(t, entity.Money(2), entity.NewMoney(2.49))
+	})
+
+	mainT.Run("Percent", func(t *testing.T) {
+		require.Equal(t, entity.Money(670), entity.Money(6092).Percent(11))
+		require.Equal(t, entity.Money(291), entity.Money(5810).Percent(5))
+	})
+
+	mainT.Run("Prorate", func(t *testing.T) {
+		require.Equal(t, entity.Money(3), entity.Money(5).Prorate(1, 2))
+		require.Equal(t, entity.Money(-3), entity.Money(-5).Prorate(1, 2))
+		require.Equal(t, entity.Money(2), entity.Money(5).Prorate(1, 3))
+		require.Equal(t, entity.Money(3333), entity.Money(10000).Prorate(1, 3))
+		require.Equal(t, entity.Money(0), entity.Money(10000).Prorate(1, 0))
+	})
+
+	mainT.Run("Large amount is exact", func(t *testing.T) {
+		// float64 can't hold every rupiah beyond 2^53
+		price := entity.Money(1 << 53)
+		require.Equal(t, entity.Money(1<<54+1), price.Mul(2)+1)
+		require.Equal(t, price+1, (price.Mul(2)+2).Prorate(1, 2))
+	})
+}
@@ -0,0 +1,41 @@
+package entity
+
+// PaymentStatus is the state of cashless payment. The values are persisted, so never change the existing ones.
+type PaymentStatus string
+
+const (
+	// PaymentStatusPending is payment which issued to the buyer but not confirmed by the payment provider yet
+	PaymentStatusPending PaymentStatus = "PENDING"
+	PaymentStatusPaid    PaymentStatus = "PAID"
+)
+
+// Payment is cashless payment of a shopping cart issued by the payment provider, e.g. QRIS. The shopping cart
+// is paid once the provider confirm the buyer paid it.
+type Payment struct {
+	// Reference is the ID of the payment given by the payment provider
+	Reference     string
+	TransactionID int64
+	Method        PaymentMethod
+	// Amount is the grand total of the bill when the payment issued
+	Amount Money
+	Status PaymentStatus
+	// QRPayload is the content of the QR code shown to the buyer
+	QRPayload string
+	// VoucherCode is the voucher applied to the bill, the bill is computed again with the promotions which
+	// active when the payment issued
+	VoucherCode string
+	CreatedAt   int64
+	ExpiresAt   int64
+	// PaidAt is zero until the payment confirmed
+	PaidAt int64
+}
+
+func (p Payment) IsPending() bool {
+	return p.Status == PaymentStatusPending
+}
+
+// IsAwaited return true when the buyer can still pay the payment, the shopping cart of awaited payment is frozen
+// so the paid amount always match the bill
+func (p Payment) IsAwaited(now int64) bool {
+	return p.IsPending() && now < p.ExpiresAt
+}
@@ -0,0 +1,201 @@
+package entity
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/validator.v2"
+)
+
+type PromotionType string
+
+const (
+	// PromotionPercentage cut the price by percentage of the goods or the whole cart
+	PromotionPercentage PromotionType = "PERCENTAGE"
+	// PromotionFixed cut fixed amount from the price of each goods, or once from the whole cart
+	PromotionFixed PromotionType = "FIXED"
+	// PromotionBuyXGetY give the cheapest Y of every X + Y goods for free
+	PromotionBuyXGetY PromotionType = "BUY_X_GET_Y"
+)
+
+// Promotion is the discount given to the shopping cart, it's applied automatically unless it has voucher code
+type Promotion struct {
+	ID   int
+	Name string
+	Type PromotionType
+	// GoodsID is zero when the discount is for the whole cart
+	GoodsID int
+	// Value is the percentage of PERCENTAGE promotion
+	Value float64
+	// Amount is the discount amount of FIXED promotion
+	Amount       Money
+	BuyQuantity  int
+	FreeQuantity int
+	// MinPurchase is the minimum subtotal of the cart before the promotion applied
+	MinPurchase Money
+	// HappyHourStart & HappyHourEnd is the minutes since midnight of the daily time window in the server local
+	// time, both are zero when the promotion applies all day. The window could pass midnight, e.g. 22:00 - 02:00.
+	HappyHourStart int
+	HappyHourEnd   int
+	// StartsAt & EndsAt is the period the promotion applies, zero means it's not limited
+	StartsAt int64
+	EndsAt   int64
+	// VoucherCode is empty when the promotion applied automatically
+	VoucherCode string
+	// UsageLimit is the number of transactions could use the voucher, zero means unlimited
+	UsageLimit int
+	UsedCount  int
+	CreatedAt  int64
+}
+
+type PromotionConfig struct {
+	// ID is empty for new promotion, it's assigned by the storage
+	ID             int
+	Name           string        `validate:"nonzero"`
+	Type           PromotionType `validate:"nonzero"`
+	GoodsID        int           `validate:"min=0"`
+	Value          float64
+	Amount         Money `validate:"min=0"`
+	BuyQuantity    int   `validate:"min=0"`
+	FreeQuantity   int   `validate:"min=0"`
+	MinPurchase    Money `validate:"min=0"`
+	HappyHourStart int   `validate:"min=0,max=1439"`
+	HappyHourEnd   int   `validate:"min=0,max=1439"`
+	StartsAt       int64
+	EndsAt         int64
+	VoucherCode    string
+	UsageLimit     int   `validate:"min=0"`
+	CreatedAt      int64 `validate:"nonzero"`
+}
+
+func NewPromotion(cfg PromotionConfig) (*Promotion, error) {
+	if err := validator.Validate(cfg); err != nil {
+		return nil, fmt.Errorf("unable to create promotion entity due: %w", err)
+	}
+	switch cfg.Type {
+	case PromotionPercentage:
+		if cfg.Value <= 0 || cfg.Value > 100 {
+			return nil, fmt.Errorf("unable to create promotion entity due: percentage must be between 0 and 100")
+		}
+	case PromotionFixed:
+		if cfg.Amount <= 0 {
+			return nil, fmt.Errorf("unable to create promotion entity due: discount amount must be positive")
+		}
+	case PromotionBuyXGetY:
+		if cfg.GoodsID <= 0 {
+			return nil, fmt.Errorf("unable to create promotion entity due: buy X get Y promotion need the goods")
+		}
+		if cfg.BuyQuantity <= 0 || cfg.FreeQuantity <= 0 {
+			return nil, fmt.Errorf("unable to create promotion entity due: buy and free quantity must be positive")
+		}
+	default:
+		return nil, fmt.Errorf("unable to create promotion entity due: unknown promotion type %s", cfg.Type)
+	}
+	if cfg.EndsAt > 0 && cfg.EndsAt <= cfg.StartsAt {
+		return nil, fmt.Errorf("unable to create promotion entity due: promotion must end after it starts")
+	}
+
+	return &Promotion{
+		ID:             cfg.ID,
+		Name:           cfg.Name,
+		Type:           cfg.Type,
+		GoodsID:        cfg.GoodsID,
+		Value:          cfg.Value,
+		Amount:         cfg.Amount,
+		BuyQuantity:    cfg.BuyQuantity,
+		FreeQuantity:   cfg.FreeQuantity,
+		MinPurchase:    cfg.MinPurchase,
+		HappyHourStart: cfg.HappyHourStart,
+		HappyHourEnd:   cfg.HappyHourEnd,
+		StartsAt:       cfg.StartsAt,
+		EndsAt:         cfg.EndsAt,
+		VoucherCode:    NormalizeVoucherCode(cfg.VoucherCode),
+		UsageLimit:     cfg.UsageLimit,
+		CreatedAt:      cfg.CreatedAt,
+	}, nil
+}
+
+// NormalizeVoucherCode make the voucher code case insensitive
+func NormalizeVoucherCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// IsUsedUp tell whether the voucher already reach its usage limit
+func (p Promotion) IsUsedUp() bool {
+	return p.UsageLimit > 0 && p.UsedCount >= p.UsageLimit
+}
+
+// IsActiveAt tell whether the promotion applies at the given time, i.e. within its period and happy hour
+// and not used up yet
+func (p Promotion) IsActiveAt(at time.Time) bool {
+	if p.IsUsedUp() {
+		return false
+	}
+	if p.StartsAt > 0 && at.Unix() < p.StartsAt {
+		return false
+	}
+	if p.EndsAt > 0 && at.Unix() >= p.EndsAt {
+		return false
+	}
+	if p.HappyHourStart == p.HappyHourEnd {
+		return true
+	}
+	local := at.In(time.Local)
+	minute := local.Hour()*60 + local.Minute()
+	if p.HappyHourStart < p.HappyHourEnd {
+		return minute >= p.HappyHourStart && minute < p.HappyHourEnd
+	}
+	return minute >= p.HappyHourStart || minute < p.HappyHourEnd
+}
+
+// Discount calculate the discount given to the goods in the cart, it never exceeds the price of the discounted goods.
+// The discount is rounded to whole rupiah.
+func (p Promotion) Discount(details []ShoppingCartDetail) Money {
+	var subtotal, goodsAmount Money
+	var goodsPrices []Money
+	for _, detail := range details {
+		amount := detail.GoodsPrice.Mul(detail.TotalGoods)
+		subtotal += amount
+		if detail.GoodsID == p.GoodsID {
+			goodsAmount += amount
+			for i := 0; i < detail.TotalGoods; i++ {
+				goodsPrices = append(goodsPrices, detail.GoodsPrice)
+			}
+		}
+	}
+	if subtotal < p.MinPurchase {
+		return 0
+	}
+	if p.GoodsID == 0 {
+		goodsAmount = subtotal
+	}
+
+	var discount Money
+	switch p.Type {
+	case PromotionPercentage:
+		discount = goodsAmount.Percent(p.Value)
+	case PromotionFixed:
+		discount = p.Amount
+		if p.GoodsID > 0 {
+			discount = discount.Mul(len(goodsPrices))
+		}
+	case PromotionBuyXGetY:
+		free := len(goodsPrices) / (p.BuyQuantity + p.FreeQuantity) * p.FreeQuantity
+		sort.Slice(goodsPrices, func(i, j int) bool { return goodsPrices[i] < goodsPrices[j] })
+		for _, price := range goodsPrices[:free] {
+			discount += price
+		}
+	}
+	return discount.Min(goodsAmount)
+}
+
+// Discount is a line of the bill which reduce the total amount, given by a promotion
+type Discount struct {
+	PromotionID int
+	Name        string
+	// VoucherCode is empty for promotion applied automatically
+	VoucherCode string
+	Amount      Money
+}
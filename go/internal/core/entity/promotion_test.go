@@ -0,0 +1,54 @@
+package entity_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromotionDiscount(mainT *testing.T) {
+	details := []entity.ShoppingCartDetail{
+		{GoodsID: 1, TotalGoods: 2, GoodsPrice: 2000},
+		{GoodsID: 3, TotalGoods: 5, GoodsPrice: 1500},
+	}
+
+	mainT.Run("Fixed discount per goods", func(t *testing.T) {
+		promotion := entity.Promotion{Type: entity.PromotionFixed, GoodsID: 1, Amount: 500}
+		require.Equal(t, entity.Money(1000), promotion.Discount(details))
+	})
+
+	mainT.Run("Fixed discount never exceeds the goods price", func(t *testing.T) {
+		promotion := entity.Promotion{Type: entity.PromotionFixed, GoodsID: 1, Amount: 5000}
+		require.Equal(t, entity.Money(4000), promotion.Discount(details))
+	})
+
+	mainT.Run("Buy X get Y", func(t *testing.T) {
+		promotion := entity.Promotion{Type: entity.PromotionBuyXGetY, GoodsID: 3, BuyQuantity: 2, FreeQuantity: 1}
+		require.Equal(t, entity.Money(1500), promotion.Discount(details))
+	})
+
+	mainT.Run("Minimum purchase", func(t *testing.T) {
+		promotion := entity.Promotion{Type: entity.PromotionPercentage, Value: 10, MinPurchase: 20000}
+		require.Zero(t, promotion.Discount(details))
+	})
+
+	mainT.Run("Happy hour passing midnight", func(t *testing.T) {
+		promotion := entity.Promotion{Type: entity.PromotionPercentage, Value: 10, HappyHourStart: 22 * 60, HappyHourEnd: 2 * 60}
+		require.True(t, promotion.IsActiveAt(time.Date(2024, 1, 1, 23, 0, 0, 0, time.Local)))
+		require.True(t, promotion.IsActiveAt(time.Date(2024, 1, 1, 1, 59, 0, 0, time.Local)))
+		require.False(t, promotion.IsActiveAt(time.Date(2024, 1, 1, 2, 0, 0, 0, time.Local)))
+		require.False(t, promotion.IsActiveAt(time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)))
+	})
+
+	mainT.Run("Discounts never exceed the subtotal", func(t *testing.T) {
+		cart := entity.ShoppingCart{Details: details}
+		cart.ApplyPromotions([]entity.Promotion{
+			{ID: 1, Type: entity.PromotionPercentage, Value: 80},
+			{ID: 2, Type: entity.PromotionFixed, Amount: 5000},
+		})
+		require.Zero(t, cart.TotalAmount)
+		require.Equal(t, cart.GetSubtotalAmount(), cart.GetDiscountAmount())
+	})
+}
@@ -0,0 +1,223 @@
+package entity
+
+import (
+	"fmt"
+
+	"gopkg.in/validator.v2"
+)
+
+type PurchaseOrderStatus string
+
+const (
+	PurchaseOrderOpen              PurchaseOrderStatus = "OPEN"
+	PurchaseOrderPartiallyReceived PurchaseOrderStatus = "PARTIALLY_RECEIVED"
+	PurchaseOrderReceived          PurchaseOrderStatus = "RECEIVED"
+	PurchaseOrderCancelled         PurchaseOrderStatus = "CANCELLED"
+)
+
+// PurchaseOrder is goods ordered from the supplier, the stocks is increased as the ordered goods received
+// which could be done in several receipts
+type PurchaseOrder struct {
+	ID         int64
+	SupplierID int
+	Status     PurchaseOrderStatus
+	Note       string
+	CreatedBy  string
+	Items      []PurchaseOrderItem
+	// Receipts is the history of receiving the ordered goods, ordered from the oldest one
+	Receipts  []PurchaseOrderReceipt
+	CreatedAt int64
+	UpdatedAt int64
+}
+
+type PurchaseOrderConfig struct {
+	// ID is empty for new purchase order, it's assigned by the storage
+	ID         int64
+	SupplierID int `validate:"nonzero"`
+	Note       string
+	CreatedBy  string
+	// Items only need the goods ID, the variant ID, the ordered quantity and the unit cost
+	Items     []PurchaseOrderItem `validate:"nonzero"`
+	CreatedAt int64               `validate:"nonzero"`
+}
+
+func NewPurchaseOrder(cfg PurchaseOrderConfig) (*PurchaseOrder, error) {
+	if err := validator.Validate(cfg); err != nil {
+		return nil, fmt.Errorf("unable to create purchase order entity due: %w", err)
+	}
+
+	items := []PurchaseOrderItem{}
+	isOrdered := map[[2]int]bool{}
+	for _, item := range cfg.Items {
+		switch {
+		case item.GoodsID <= 0:
+			return nil, fmt.Errorf("goods ID of purchase order item is required")
+		case item.VariantID < 0:
+			return nil, fmt.Errorf("variant ID of goods %d is invalid", item.GoodsID)
+		case item.Quantity <= 0:
+			return nil, fmt.Errorf("ordered quantity of goods %d must be positive", item.GoodsID)
+		case item.UnitCost < 0:
+			return nil, fmt.Errorf("unit cost of goods %d can't be negative", item.GoodsID)
+		case isOrdered[[2]int{item.GoodsID, item.VariantID}]:
+			return nil, fmt.Errorf("goods %d is ordered more than once", item.GoodsID)
+		}
+		isOrdered[[2]int{item.GoodsID, item.VariantID}] = true
+		items = append(items, PurchaseOrderItem{
+			GoodsID:   item.GoodsID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			UnitCost:  item.UnitCost,
+		})
+	}
+
+	createdBy := cfg.CreatedBy
+	if len(createdBy) == 0 {
+		createdBy = StockActorSystem
+	}
+	return &PurchaseOrder{
+		ID:         cfg.ID,
+		SupplierID: cfg.SupplierID,
+		Status:     PurchaseOrderOpen,
+		Note:       cfg.Note,
+		CreatedBy:  createdBy,
+		Items:      items,
+		Receipts:   []PurchaseOrderReceipt{},
+		CreatedAt:  cfg.CreatedAt,
+		UpdatedAt:  cfg.CreatedAt,
+	}, nil
+}
+
+// IsClosed tell whether the purchase order can't receive any goods anymore
+func (o PurchaseOrder) IsClosed() bool {
+	return o.Status == PurchaseOrderReceived || o.Status == PurchaseOrderCancelled
+}
+
+// TotalCost is the cost of all ordered goods
+func (o PurchaseOrder) TotalCost() Money {
+	total := float64(0)
+	for _, item := range o.Items {
+		total += float64(item.Quantity) * item.UnitCost
+	}
+	return NewMoney(total)
+}
+
+// ReceivedCost is the cost of the goods which already received
+func (o PurchaseOrder) ReceivedCost() Money {
+	total := float64(0)
+	for _, item := range o.Items {
+		total += float64(item.ReceivedQuantity) * item.UnitCost
+	}
+	return NewMoney(total)
+}
+
+func (o PurchaseOrder) FindItem(goodsID int, variantID int) (*PurchaseOrderItem, bool) {
+	for _, item := range o.Items {
+		if item.GoodsID == goodsID && item.VariantID == variantID {
+			return &item, true
+		}
+	}
+	return nil, false
+}
+
+// Receive add the received quantity of the ordered goods then update the status, the received goods is priced
+// at the unit cost of its order item. Goods which not ordered or received more than the remaining quantity
+// is rejected, nothing is changed in that case.
+func (o *PurchaseOrder) Receive(receipt PurchaseOrderReceipt) (*PurchaseOrderReceipt, error) {
+	if o.IsClosed() {
+		return nil, fmt.Errorf("purchase order %d is already %s", o.ID, o.Status)
+	}
+	if len(receipt.Items) == 0 {
+		return nil, fmt.Errorf("received goods is required")
+	}
+
+	items := make([]PurchaseOrderItem, len(o.Items))
+	copy(items, o.Items)
+	receivedItems := []PurchaseOrderReceiptItem{}
+	for _, receivedItem := range receipt.Items {
+		idx := -1
+		for i, item := range items {
+			if item.GoodsID == receivedItem.GoodsID && item.VariantID == receivedItem.VariantID {
+				idx = i
+				break
+			}
+		}
+		switch {
+		case idx < 0:
+			return nil, fmt.Errorf("goods %d variant %d is not ordered", receivedItem.GoodsID, receivedItem.VariantID)
+		case receivedItem.Quantity <= 0:
+			return nil, fmt.Errorf("received quantity of goods %d must be positive", receivedItem.GoodsID)
+		case receivedItem.ExpiresAt < 0:
+			return nil, fmt.Errorf("invalid expiry date of goods %d", receivedItem.GoodsID)
+		case receivedItem.Quantity > items[idx].RemainingQuantity():
+			return nil, fmt.Errorf(
+				"received quantity of goods %d is more than the remaining %d",
+				receivedItem.GoodsID,
+				items[idx].RemainingQuantity(),
+			)
+		}
+		items[idx].ReceivedQuantity += receivedItem.Quantity
+		receivedItem.UnitCost = items[idx].UnitCost
+		receivedItems = append(receivedItems, receivedItem)
+	}
+
+	o.Items = items
+	o.Status = PurchaseOrderReceived
+	for _, item := range o.Items {
+		if item.RemainingQuantity() > 0 {
+			o.Status = PurchaseOrderPartiallyReceived
+			break
+		}
+	}
+	receipt.OrderID = o.ID
+	receipt.Items = receivedItems
+	if len(receipt.ReceivedBy) == 0 {
+		receipt.ReceivedBy = StockActorSystem
+	}
+	o.Receipts = append(o.Receipts, receipt)
+	o.UpdatedAt = receipt.ReceivedAt
+
+	return &receipt, nil
+}
+
+// Cancel close the purchase order, the goods which already received is kept in the stocks
+func (o *PurchaseOrder) Cancel(cancelledAt int64) error {
+	if o.IsClosed() {
+		return fmt.Errorf("purchase order %d is already %s", o.ID, o.Status)
+	}
+	o.Status = PurchaseOrderCancelled
+	o.UpdatedAt = cancelledAt
+	return nil
+}
+
+type PurchaseOrderItem struct {
+	GoodsID int
+	// VariantID is zero when the stocks is held by the goods itself
+	VariantID        int
+	Name             string
+	Quantity         int
+	ReceivedQuantity int
+	// UnitCost is the purchase price of one goods agreed with the supplier
+	UnitCost float64
+}
+
+func (i PurchaseOrderItem) RemainingQuantity() int {
+	return i.Quantity - i.ReceivedQuantity
+}
+
+// PurchaseOrderReceipt is the ordered goods received at once, e.g. one delivery from the supplier
+type PurchaseOrderReceipt struct {
+	ID         int64
+	OrderID    int64
+	ReceivedBy string
+	Items      []PurchaseOrderReceiptItem
+	ReceivedAt int64
+}
+
+type PurchaseOrderReceiptItem struct {
+	GoodsID   int
+	VariantID int
+	Quantity  int
+	UnitCost  float64
+	// ExpiresAt is the expiry time of the received goods, zero when it doesn't expire
+	ExpiresAt int64
+}
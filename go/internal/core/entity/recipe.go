@@ -0,0 +1,85 @@
+package entity
+
+import (
+	"fmt"
+
+	"gopkg.in/validator.v2"
+)
+
+// Recipe define the raw materials consumed to make one serving of the goods. Goods which has recipe
+// doesn't hold its own stocks, it's made to order and the raw materials stocks is deducted instead.
+type Recipe struct {
+	GoodsID     int
+	Ingredients []RecipeIngredient
+	// Servings is how many the goods still could be made from the available stocks of its raw materials,
+	// it's only calculated when the recipe is loaded along with the raw materials stocks
+	Servings int
+}
+
+type RecipeIngredient struct {
+	IngredientID int
+	Name         string
+	Unit         string
+	// Quantity is the amount of raw material consumed by one serving, in the unit of the raw material
+	Quantity int
+	// AvailableStocks of the raw material when the recipe is loaded
+	AvailableStocks int
+}
+
+type RecipeConfig struct {
+	GoodsID int `validate:"nonzero"`
+	// Ingredients is empty when the goods is not made from recipe anymore
+	Ingredients []RecipeIngredient
+}
+
+func NewRecipe(cfg RecipeConfig) (*Recipe, error) {
+	if err := validator.Validate(cfg); err != nil {
+		return nil, fmt.Errorf("unable to create recipe entity due: %w", err)
+	}
+
+	recipe := &Recipe{GoodsID: cfg.GoodsID, Ingredients: []RecipeIngredient{}}
+	isListed := map[int]bool{}
+	for _, ingredient := range cfg.Ingredients {
+		switch {
+		case ingredient.IngredientID <= 0:
+			return nil, fmt.Errorf("ingredient ID is required")
+		case ingredient.IngredientID == cfg.GoodsID:
+			return nil, fmt.Errorf("goods %d can't be the ingredient of itself", cfg.GoodsID)
+		case ingredient.Quantity <= 0:
+			return nil, fmt.Errorf("quantity of ingredient %d must be greater than zero", ingredient.IngredientID)
+		case isListed[ingredient.IngredientID]:
+			return nil, fmt.Errorf("ingredient %d is listed more than once", ingredient.IngredientID)
+		}
+		isListed[ingredient.IngredientID] = true
+		recipe.Ingredients = append(recipe.Ingredients, RecipeIngredient{
+			IngredientID: ingredient.IngredientID,
+			Quantity:     ingredient.Quantity,
+		})
+	}
+
+	return recipe, nil
+}
+
+// CalculateServings count the servings from the available stocks of the raw materials,
+// the scarcest raw material limits the servings
+func (r *Recipe) CalculateServings() {
+	r.Servings = 0
+	for i, ingredient := range r.Ingredients {
+		servings := 0
+		if ingredient.AvailableStocks > 0 {
+			servings = ingredient.AvailableStocks / ingredient.Quantity
+		}
+		if i == 0 || servings < r.Servings {
+			r.Servings = servings
+		}
+	}
+}
+
+// Requirements return total of each raw material needed to make the servings, keyed by the raw material ID
+func (r Recipe) Requirements(servings int) map[int]int {
+	requirements := map[int]int{}
+	for _, ingredient := range r.Ingredients {
+		requirements[ingredient.IngredientID] += ingredient.Quantity * servings
+	}
+	return requirements
+}
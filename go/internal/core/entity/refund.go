@@ -0,0 +1,145 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrNothingToRefund = errors.New("nothing left to refund in the transaction")
+
+// RefundItem is goods returned by the buyer
+type RefundItem struct {
+	GoodsID    int
+	VariantID  int
+	TotalGoods int
+}
+
+// PurchasedGoods is goods bought in a transaction along with how many of it already refunded
+type PurchasedGoods struct {
+	GoodsID       int
+	VariantID     int
+	TotalGoods    int
+	RefundedGoods int
+	GoodsPrice    Money
+}
+
+func (g PurchasedGoods) RefundableGoods() int {
+	return g.TotalGoods - g.RefundedGoods
+}
+
+type RefundDetail struct {
+	GoodsID    int
+	VariantID  int
+	TotalGoods int
+	// GoodsPrice is the price paid by the buyer, taken from the transaction
+	GoodsPrice Money
+}
+
+type Refund struct {
+	ID            int64
+	TransactionID int64
+	Amount        Money
+	Reason        string
+	Details       []RefundDetail
+	CreatedAt     int64
+}
+
+// RefundExceedsPurchaseError returned when the returned goods is more than the one bought and not refunded yet
+type RefundExceedsPurchaseError struct {
+	GoodsID    int
+	VariantID  int
+	Refundable int
+	Requested  int
+}
+
+func (e RefundExceedsPurchaseError) Error() string {
+	return fmt.Sprintf(
+		"refund exceeds purchase: goods ID %d, variant ID %d, refundable %d, requested %d",
+		e.GoodsID,
+		e.VariantID,
+		e.Refundable,
+		e.Requested,
+	)
+}
+
+// RefundExceedsPaymentError returned when total refund of the transaction is more than what was paid
+type RefundExceedsPaymentError struct {
+	PaidAmount     Money
+	RefundedAmount Money
+	RefundAmount   Money
+}
+
+func (e RefundExceedsPaymentError) Error() string {
+	return fmt.Sprintf(
+		"refund exceeds payment: paid amount %v, refunded amount %v, refund amount %v",
+		e.PaidAmount,
+		e.RefundedAmount,
+		e.RefundAmount,
+	)
+}
+
+// NewRefund create refund of the returned goods priced as it was bought, empty items means all of the goods
+// which not refunded yet. The refunded total of the purchased goods is updated accordingly.
+func NewRefund(transactionID int64, purchased []PurchasedGoods, items []RefundItem, reason string) (*Refund, error) {
+	if len(items) == 0 {
+		for _, goods := range purchased {
+			if goods.RefundableGoods() > 0 {
+				items = append(items, RefundItem{
+					GoodsID:    goods.GoodsID,
+					VariantID:  goods.VariantID,
+					TotalGoods: goods.RefundableGoods(),
+				})
+			}
+		}
+	}
+	if len(items) == 0 {
+		return nil, ErrNothingToRefund
+	}
+
+	refund := &Refund{
+		TransactionID: transactionID,
+		Reason:        reason,
+	}
+	for _, item := range items {
+		idx := -1
+		for i := range purchased {
+			if purchased[i].GoodsID == item.GoodsID && purchased[i].VariantID == item.VariantID {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 || item.TotalGoods > purchased[idx].RefundableGoods() {
+			refundable := 0
+			if idx >= 0 {
+				refundable = purchased[idx].RefundableGoods()
+			}
+			return nil, RefundExceedsPurchaseError{
+				GoodsID:    item.GoodsID,
+				VariantID:  item.VariantID,
+				Refundable: refundable,
+				Requested:  item.TotalGoods,
+			}
+		}
+
+		purchased[idx].RefundedGoods += item.TotalGoods
+		refund.Details = append(refund.Details, RefundDetail{
+			GoodsID:    item.GoodsID,
+			VariantID:  item.VariantID,
+			TotalGoods: item.TotalGoods,
+			GoodsPrice: purchased[idx].GoodsPrice,
+		})
+		refund.Amount += purchased[idx].GoodsPrice.Mul(item.TotalGoods)
+	}
+
+	return refund, nil
+}
+
+// IsAllGoodsRefunded tell whether every purchased goods already returned
+func IsAllGoodsRefunded(purchased []PurchasedGoods) bool {
+	for _, goods := range purchased {
+		if goods.RefundableGoods() > 0 {
+			return false
+		}
+	}
+	return true
+}
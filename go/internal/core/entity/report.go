@@ -0,0 +1,47 @@
+package entity
+
+// SalesSummary is the sales within a period, refunds are counted in the period they're made
+// instead of the period of their transactions
+type SalesSummary struct {
+	From              int64
+	To                int64
+	TotalTransactions int
+	GrossAmount       Money
+	TotalRefunds      int
+	RefundAmount      Money
+	// CostOfGoodsSold is the cost of the sold goods minus the cost of the refunded goods which returned to stocks,
+	// rounded to whole rupiah once it's summed up
+	CostOfGoodsSold Money
+	// Tenders is the amount received with each payment method by the transactions paid within the period,
+	// the refunds are not broken down by payment method
+	Tenders []TenderTotal
+}
+
+func (s SalesSummary) NetAmount() Money {
+	return s.GrossAmount - s.RefundAmount
+}
+
+func (s SalesSummary) GrossProfit() Money {
+	return s.NetAmount() - s.CostOfGoodsSold
+}
+
+// TaxRecap is the PPN and service charge collected within a period, the PPN of the refunds is counted in the
+// period they're made proportionally to the refunded amount
+type TaxRecap struct {
+	From                int64
+	To                  int64
+	TotalTransactions   int
+	SubtotalAmount      Money
+	DiscountAmount      Money
+	ServiceChargeAmount Money
+	TaxBaseAmount       Money
+	TaxAmount           Money
+	GrandTotalAmount    Money
+	RefundAmount        Money
+	RefundedTaxAmount   Money
+}
+
+// NetTaxAmount is the PPN which need to be paid for the period
+func (r TaxRecap) NetTaxAmount() Money {
+	return r.TaxAmount - r.RefundedTaxAmount
+}
@@ -0,0 +1,94 @@
+package entity
+
+import "sort"
+
+// StockBatch is the goods received at the same time, the stocks of goods or variant is the sum of its batches
+// so the perishable goods could be sold by their expiry date
+type StockBatch struct {
+	ID      int64
+	GoodsID int
+	// VariantID is zero when the stocks is held by the goods itself
+	VariantID int
+	// Name is the goods name followed by the variant name, it's only for display
+	Name             string
+	Unit             string
+	ReceivedQuantity int
+	// Quantity is the remaining stocks of the batch
+	Quantity   int
+	UnitCost   float64
+	ReceivedAt int64
+	// ExpiresAt is the time the batch no longer sellable, zero when the goods doesn't expire
+	ExpiresAt int64
+}
+
+// IsExpired tell whether the batch already expired at the given time
+func (b StockBatch) IsExpired(at int64) bool {
+	return b.ExpiresAt > 0 && b.ExpiresAt <= at
+}
+
+// SortStockBatchesFEFO sort the batches in the order they should be consumed: first expired first out, then
+// batches which doesn't expire from the oldest received one
+func SortStockBatchesFEFO(batches []StockBatch) {
+	sort.SliceStable(batches, func(i, j int) bool {
+		bi, bj := batches[i], batches[j]
+		if (bi.ExpiresAt == 0) != (bj.ExpiresAt == 0) {
+			return bi.ExpiresAt > 0
+		}
+		if bi.ExpiresAt != bj.ExpiresAt {
+			return bi.ExpiresAt < bj.ExpiresAt
+		}
+		if bi.ReceivedAt != bj.ReceivedAt {
+			return bi.ReceivedAt < bj.ReceivedAt
+		}
+		return bi.ID < bj.ID
+	})
+}
+
+// ConsumeStockBatches take the total stocks from the batches in FEFO order, only the batches which quantity
+// changed is returned. Total which can't be covered by the batches is left unconsumed.
+func ConsumeStockBatches(batches []StockBatch, total int) []StockBatch {
+	sorted := make([]StockBatch, len(batches))
+	copy(sorted, batches)
+	SortStockBatchesFEFO(sorted)
+
+	consumed := []StockBatch{}
+	for _, batch := range sorted {
+		if total <= 0 {
+			break
+		}
+		if batch.Quantity <= 0 {
+			continue
+		}
+		taken := batch.Quantity
+		if taken > total {
+			taken = total
+		}
+		batch.Quantity -= taken
+		total -= taken
+		consumed = append(consumed, batch)
+	}
+	return consumed
+}
+
+// ConsumeSellableStockBatches is ConsumeStockBatches for a sale, the batches already expired at the given time
+// is skipped since they're left to be written off. The stocks is the stocks of the stock holder before the sale,
+// it's including the expired batches and the stocks which doesn't belong to any batch, so the sale is rejected
+// when the stocks which isn't expired yet is less than the total.
+func ConsumeSellableStockBatches(batches []StockBatch, stocks, total int, at int64) ([]StockBatch, error) {
+	sellable := []StockBatch{}
+	for _, batch := range batches {
+		if batch.IsExpired(at) {
+			stocks -= batch.Quantity
+			continue
+		}
+		sellable = append(sellable, batch)
+	}
+	if stocks < total {
+		err := InsufficientStockError{Stocks: stocks, Requested: total}
+		if len(batches) > 0 {
+			err.GoodsID, err.VariantID = batches[0].GoodsID, batches[0].VariantID
+		}
+		return nil, err
+	}
+	return ConsumeStockBatches(sellable, total), nil
+}
@@ -0,0 +1,54 @@
+package entity_test
+
+import (
+	"testing"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumeStockBatches(t *testing.T) {
+	batches := []entity.StockBatch{
+		{ID: 1, Quantity: 5, ReceivedAt: 100},
+		{ID: 2, Quantity: 5, ReceivedAt: 200, ExpiresAt: 1000},
+		{ID: 3, Quantity: 5, ReceivedAt: 300, ExpiresAt: 500},
+		{ID: 4, Quantity: 5, ReceivedAt: 50},
+	}
+	consumed := entity.ConsumeStockBatches(batches, 12)
+	// the earliest expiry first, then the batches which doesn't expire from the oldest one
+	require.Len(t, consumed, 3)
+	require.Equal(t, int64(3), consumed[0].ID)
+	require.Zero(t, consumed[0].Quantity)
+	require.Equal(t, int64(2), consumed[1].ID)
+	require.Zero(t, consumed[1].Quantity)
+	require.Equal(t, int64(4), consumed[2].ID)
+	require.Equal(t, 3, consumed[2].Quantity)
+	// the given batches is left untouched
+	require.Equal(t, 5, batches[2].Quantity)
+}
+
+func TestConsumeSellableStockBatches(t *testing.T) {
+	batches := []entity.StockBatch{
+		{ID: 1, GoodsID: 1, Quantity: 10, ReceivedAt: 100, ExpiresAt: 500},
+		{ID: 2, GoodsID: 1, Quantity: 5, ReceivedAt: 200, ExpiresAt: 1000},
+	}
+	// the expired batch is skipped although it expires first
+	consumed, err := entity.ConsumeSellableStockBatches(batches, 15, 3, 600)
+	require.NoError(t, err)
+	require.Len(t, consumed, 1)
+	require.Equal(t, int64(2), consumed[0].ID)
+	require.Equal(t, 2, consumed[0].Quantity)
+
+	// the expired quantity can't be sold
+	_, err = entity.ConsumeSellableStockBatches(batches, 15, 6, 600)
+	var insufficientStockErr entity.InsufficientStockError
+	require.ErrorAs(t, err, &insufficientStockErr)
+	require.Equal(t, 1, insufficientStockErr.GoodsID)
+	require.Equal(t, 5, insufficientStockErr.Stocks)
+	require.Equal(t, 6, insufficientStockErr.Requested)
+
+	// nothing expired yet, so it's the same as ConsumeStockBatches
+	consumed, err = entity.ConsumeSellableStockBatches(batches, 15, 12, 400)
+	require.NoError(t, err)
+	require.Equal(t, entity.ConsumeStockBatches(batches, 12), consumed)
+}
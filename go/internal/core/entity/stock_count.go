@@ -0,0 +1,109 @@
+package entity
+
+import (
+	"fmt"
+
+	"gopkg.in/validator.v2"
+)
+
+type StockCountStatus string
+
+const (
+	StockCountOpen     StockCountStatus = "OPEN"
+	StockCountApproved StockCountStatus = "APPROVED"
+)
+
+// StockCount is a stock opname session, the goods is physically counted then the stocks is adjusted
+// to the counted quantity when the session approved
+type StockCount struct {
+	ID         int64
+	Status     StockCountStatus
+	OpenedBy   string
+	ApprovedBy string
+	Items      []StockCountItem
+	CreatedAt  int64
+	// ApprovedAt is zero while the session still open
+	ApprovedAt int64
+}
+
+type StockCountConfig struct {
+	// ID is empty for new stock count, it's assigned by the storage
+	ID        int64
+	OpenedBy  string
+	CreatedAt int64 `validate:"nonzero"`
+}
+
+func NewStockCount(cfg StockCountConfig) (*StockCount, error) {
+	if err := validator.Validate(cfg); err != nil {
+		return nil, fmt.Errorf("unable to create stock count entity due: %w", err)
+	}
+
+	openedBy := cfg.OpenedBy
+	if len(openedBy) == 0 {
+		openedBy = StockActorSystem
+	}
+	return &StockCount{
+		ID:        cfg.ID,
+		Status:    StockCountOpen,
+		OpenedBy:  openedBy,
+		Items:     []StockCountItem{},
+		CreatedAt: cfg.CreatedAt,
+	}, nil
+}
+
+func (c StockCount) IsOpen() bool {
+	return c.Status == StockCountOpen
+}
+
+// TotalVarianceValue is the value of all variances, negative means the counted goods is worth less than recorded
+func (c StockCount) TotalVarianceValue() Money {
+	total := Money(0)
+	for _, item := range c.Items {
+		total += item.VarianceValue()
+	}
+	return total
+}
+
+// Adjustments is the stock movements of the items which counted different from the system stocks, they're
+// posted when the session approved
+func (c StockCount) Adjustments() []StockMovement {
+	movements := []StockMovement{}
+	for _, item := range c.Items {
+		if item.Variance() == 0 {
+			continue
+		}
+		movements = append(movements, StockMovement{
+			GoodsID:   item.GoodsID,
+			VariantID: item.VariantID,
+			Type:      StockMovementAdjustment,
+			Quantity:  item.Variance(),
+			Before:    item.SystemStocks,
+			After:     item.CountedStocks,
+			Actor:     c.ApprovedBy,
+			Reason:    fmt.Sprintf("stock count #%d", c.ID),
+			CreatedAt: c.ApprovedAt,
+		})
+	}
+	return movements
+}
+
+// StockCountItem is the counted quantity of goods or variant. SystemStocks & Price follow the current goods
+// while the session still open, they're kept as is once the session approved.
+type StockCountItem struct {
+	GoodsID int
+	// VariantID is zero when the stocks is held by the goods itself
+	VariantID     int
+	Name          string
+	CountedStocks int
+	SystemStocks  int
+	Price         Money
+}
+
+// Variance is positive when the counted quantity is more than the system stocks
+func (i StockCountItem) Variance() int {
+	return i.CountedStocks - i.SystemStocks
+}
+
+func (i StockCountItem) VarianceValue() Money {
+	return i.Price.Mul(i.Variance())
+}
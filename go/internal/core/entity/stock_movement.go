@@ -0,0 +1,55 @@
+package entity
+
+type StockMovementType string
+
+const (
+	StockMovementSale       StockMovementType = "SALE"
+	StockMovementRestock    StockMovementType = "RESTOCK"
+	StockMovementAdjustment StockMovementType = "ADJUSTMENT"
+	StockMovementRefund     StockMovementType = "REFUND"
+	StockMovementWastage    StockMovementType = "WASTAGE"
+)
+
+// StockActorSystem is the actor of stock movements which not done by a person directly, e.g. sale and refund
+const StockActorSystem = "system"
+
+// StockMovement is an entry of append-only stock ledger, every change of goods or variant stocks is recorded
+// so the current stocks always equal to the sum of its movements
+type StockMovement struct {
+	ID      int64
+	GoodsID int
+	// VariantID is zero when the stocks is held by the goods itself
+	VariantID int
+	Type      StockMovementType
+	// Quantity is positive for stocks in and negative for stocks out
+	Quantity int
+	// UnitCost is the purchase price of the received goods, the average cost of the goods otherwise
+	UnitCost float64
+	Before   int
+	After    int
+	Actor    string
+	Reason   string
+	// TransactionID is zero when the movement is not caused by any transaction
+	TransactionID int64
+	// ExpiresAt is the expiry time of the received stocks, zero when it doesn't expire or for stocks out
+	ExpiresAt int64
+	CreatedAt int64
+}
+
+// Cost is the value of the moved stocks, negative for stocks out
+func (m StockMovement) Cost() float64 {
+	return float64(m.Quantity) * m.UnitCost
+}
+
+// StockDrift is goods or variant which stocks is not equal to the sum of its stock movements
+type StockDrift struct {
+	GoodsID      int
+	VariantID    int
+	Stocks       int
+	LedgerStocks int
+}
+
+// Drift is positive when the stocks is more than what the ledger recorded
+func (d StockDrift) Drift() int {
+	return d.Stocks - d.LedgerStocks
+}
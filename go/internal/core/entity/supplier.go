@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"fmt"
+
+	"gopkg.in/validator.v2"
+)
+
+// Supplier is the party which the goods and raw materials purchased from
+type Supplier struct {
+	ID        int
+	Name      string
+	Phone     string
+	Address   string
+	CreatedAt int64
+}
+
+type SupplierConfig struct {
+	// ID is empty for new supplier, it's assigned by the storage
+	ID        int
+	Name      string `validate:"nonzero"`
+	Phone     string
+	Address   string
+	CreatedAt int64 `validate:"nonzero"`
+}
+
+func NewSupplier(cfg SupplierConfig) (*Supplier, error) {
+	if err := validator.Validate(cfg); err != nil {
+		return nil, fmt.Errorf("unable to create supplier entity due: %w", err)
+	}
+
+	return &Supplier{
+		ID:        cfg.ID,
+		Name:      cfg.Name,
+		Phone:     cfg.Phone,
+		Address:   cfg.Address,
+		CreatedAt: cfg.CreatedAt,
+	}, nil
+}
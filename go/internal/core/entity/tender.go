@@ -0,0 +1,79 @@
+package entity
+
+import "fmt"
+
+// PaymentMethod is how the buyer pay a portion of the transaction. The values are persisted, so never change
+// the existing ones.
+type PaymentMethod string
+
+const (
+	PaymentMethodCash     PaymentMethod = "CASH"
+	PaymentMethodEWallet  PaymentMethod = "EWALLET"
+	PaymentMethodTransfer PaymentMethod = "TRANSFER"
+	PaymentMethodCard     PaymentMethod = "CARD"
+	// PaymentMethodQRIS is paid through the payment provider, see Payment
+	PaymentMethodQRIS PaymentMethod = "QRIS"
+)
+
+func (m PaymentMethod) IsValid() bool {
+	switch m {
+	case PaymentMethodCash, PaymentMethodEWallet, PaymentMethodTransfer, PaymentMethodCard, PaymentMethodQRIS:
+		return true
+	default:
+		return false
+	}
+}
+
+// Tender is the portion of the payment paid with one payment method
+type Tender struct {
+	Method PaymentMethod
+	Amount Money
+}
+
+// NewTenders validate the tenders of a payment and merge the tenders with the same method, so a transaction
+// has at most one tender of each method
+func NewTenders(tenders []Tender) ([]Tender, error) {
+	if len(tenders) == 0 {
+		return nil, fmt.Errorf("unable to create tenders due: tenders can't be empty")
+	}
+	merged := []Tender{}
+	indexes := map[PaymentMethod]int{}
+	for _, tender := range tenders {
+		if !tender.Method.IsValid() {
+			return nil, fmt.Errorf("unable to create tenders due: unknown payment method %s", tender.Method)
+		}
+		if tender.Amount <= 0 {
+			return nil, fmt.Errorf("unable to create tenders due: amount of %s must be positive", tender.Method)
+		}
+		if i, ok := indexes[tender.Method]; ok {
+			merged[i].Amount += tender.Amount
+			continue
+		}
+		indexes[tender.Method] = len(merged)
+		merged = append(merged, tender)
+	}
+	return merged, nil
+}
+
+// NonCashOverpaymentError returned when the non cash tenders exceed the transaction total amount, only cash
+// could be given change
+type NonCashOverpaymentError struct {
+	TotalAmount   Money
+	NonCashAmount Money
+}
+
+func (e NonCashOverpaymentError) Error() string {
+	return fmt.Sprintf(
+		"non cash overpayment: total amount %v, non cash amount %v",
+		e.TotalAmount,
+		e.NonCashAmount,
+	)
+}
+
+// TenderTotal is the amount received with a payment method within a period, the cash is after the change
+// returned to the buyer
+type TenderTotal struct {
+	Method            PaymentMethod
+	TotalTransactions int
+	Amount            Money
+}
@@ -1,13 +1,205 @@
 package entity
 
+import (
+	"fmt"
+)
+
+// TransactionStatus is the state of an order, from shopping cart until it's completed. The values are
+// persisted, so never change the existing ones.
+type TransactionStatus int
+
+const (
+	// TransactionStatusCart is transaction which not paid yet a.k.a shopping cart
+	TransactionStatusCart TransactionStatus = 0
+	TransactionStatusPaid TransactionStatus = 1
+	// TransactionStatusExpired is shopping cart which abandoned for too long, it can't be used anymore
+	TransactionStatusExpired   TransactionStatus = 2
+	TransactionStatusPreparing TransactionStatus = 3
+	// TransactionStatusReady is order which ready to be picked up by the buyer or the courier
+	TransactionStatusReady     TransactionStatus = 4
+	TransactionStatusShipped   TransactionStatus = 5
+	TransactionStatusCompleted TransactionStatus = 6
+	TransactionStatusCancelled TransactionStatus = 7
+	TransactionStatusRefunded  TransactionStatus = 8
+)
+
+var transactionStatusNames = map[TransactionStatus]string{
+	TransactionStatusCart:      "CART",
+	TransactionStatusPaid:      "PAID",
+	TransactionStatusExpired:   "EXPIRED",
+	TransactionStatusPreparing: "PREPARING",
+	TransactionStatusReady:     "READY",
+	TransactionStatusShipped:   "SHIPPED",
+	TransactionStatusCompleted: "COMPLETED",
+	TransactionStatusCancelled: "CANCELLED",
+	TransactionStatusRefunded:  "REFUNDED",
+}
+
+// transactionStatusTransitions is the allowed next statuses of each status, status without next one is final
+var transactionStatusTransitions = map[TransactionStatus][]TransactionStatus{
+	TransactionStatusCart:      {TransactionStatusPaid, TransactionStatusExpired},
+	TransactionStatusPaid:      {TransactionStatusPreparing, TransactionStatusCancelled, TransactionStatusRefunded},
+	TransactionStatusPreparing: {TransactionStatusReady, TransactionStatusCancelled, TransactionStatusRefunded},
+	TransactionStatusReady:     {TransactionStatusShipped, TransactionStatusCompleted, TransactionStatusCancelled, TransactionStatusRefunded},
+	TransactionStatusShipped:   {TransactionStatusCompleted, TransactionStatusRefunded},
+	TransactionStatusCompleted: {TransactionStatusRefunded},
+}
+
+func (s TransactionStatus) String() string {
+	if name, ok := transactionStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", int(s))
+}
+
+// ParseTransactionStatus get the transaction status from its name e.g. `PREPARING`
+func ParseTransactionStatus(name string) (TransactionStatus, bool) {
+	for status, statusName := range transactionStatusNames {
+		if statusName == name {
+			return status, true
+		}
+	}
+	return 0, false
+}
+
+func (s TransactionStatus) CanTransitionTo(next TransactionStatus) bool {
+	for _, allowed := range transactionStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinal tell whether the order can't move to any other status anymore
+func (s TransactionStatus) IsFinal() bool {
+	return len(transactionStatusTransitions[s]) == 0
+}
+
+// IsAwaitingFulfillment tell whether the order is paid but not handed over to the buyer or the courier yet
+func (s TransactionStatus) IsAwaitingFulfillment() bool {
+	switch s {
+	case TransactionStatusPaid, TransactionStatusPreparing, TransactionStatusReady:
+		return true
+	default:
+		return false
+	}
+}
+
+// TransactionStatusChange is a record of order status transition. From is nil for newly created order.
+type TransactionStatusChange struct {
+	From      *TransactionStatus
+	To        TransactionStatus
+	ChangedAt int64
+}
+
+// InvalidStatusTransitionError returned when the order is moved into status which not allowed from its current one
+type InvalidStatusTransitionError struct {
+	From TransactionStatus
+	To   TransactionStatus
+}
+
+func (e InvalidStatusTransitionError) Error() string {
+	return fmt.Sprintf("invalid status transition: order status %s can't be changed into %s", e.From, e.To)
+}
+
 type Transaction struct {
-	ID            int64
-	TotalAmount   float64
-	PaymentAmount float64
-	ReturnAmount  float64
+	ID         int64
+	Status     TransactionStatus
+	TotalGoods int
+	// TotalAmount is the amount to pay, once the transaction paid it's the grand total of the bill i.e. after
+	// the discounts and including the service charge and PPN
+	TotalAmount Money
+	// SubtotalAmount is the price of all goods, zero for transaction which not paid yet
+	SubtotalAmount Money
+	// DiscountAmount is the total of the discount lines
+	DiscountAmount      Money
+	ServiceChargeAmount Money
+	TaxBaseAmount       Money
+	TaxAmount           Money
+	// TaxRate and ServiceChargeRate is the rates of the shop when the transaction paid
+	TaxRate           float64
+	ServiceChargeRate float64
+	// Discounts only filled when the order is requested with its discount lines
+	Discounts []Discount
+	// Tenders only filled once the transaction paid, the payment amount is the sum of the tenders
+	Tenders       []Tender
+	PaymentAmount Money
+	// ReturnAmount is the change, it's always given from the cash tender
+	ReturnAmount Money
+	// RefundedAmount is total amount refunded to the buyer, recorded separately from the payment
+	RefundedAmount Money
+	IdempotencyKey string
+	// StatusHistory only filled when the order is requested with its history
+	StatusHistory []TransactionStatusChange
+}
+
+// InsufficientPaymentError returned when payment amount is less than the transaction total amount
+type InsufficientPaymentError struct {
+	TotalAmount   Money
+	PaymentAmount Money
+}
+
+func (e InsufficientPaymentError) Error() string {
+	return fmt.Sprintf("insufficient payment: total amount %v, payment amount %v", e.TotalAmount, e.PaymentAmount)
 }
 
-func (t *Transaction) SetPaymentAndReturnAmount(payAmount float64) {
+// SetTenders set the payment of the transaction, the change is only given from the cash tender
+// so the non cash tenders can't exceed the total amount
+func (t *Transaction) SetTenders(tenders []Tender) error {
+	var payAmount, nonCashAmount Money
+	for _, tender := range tenders {
+		payAmount += tender.Amount
+		if tender.Method != PaymentMethodCash {
+			nonCashAmount += tender.Amount
+		}
+	}
+	if payAmount < t.TotalAmount {
+		return InsufficientPaymentError{
+			TotalAmount:   t.TotalAmount,
+			PaymentAmount: payAmount,
+		}
+	}
+	if nonCashAmount > t.TotalAmount {
+		return NonCashOverpaymentError{
+			TotalAmount:   t.TotalAmount,
+			NonCashAmount: nonCashAmount,
+		}
+	}
+	t.Tenders = tenders
 	t.PaymentAmount = payAmount
 	t.ReturnAmount = payAmount - t.TotalAmount
+
+	return nil
+}
+
+// TransitionTo move the order into the next status when it's allowed
+func (t *Transaction) TransitionTo(next TransactionStatus) error {
+	if !t.Status.CanTransitionTo(next) {
+		return InvalidStatusTransitionError{
+			From: t.Status,
+			To:   next,
+		}
+	}
+	t.Status = next
+	return nil
+}
+
+// ApplyRefund add the refund amount into the transaction, total refund never exceed what was paid
+// i.e. the total amount since the change already returned to the buyer. The discount, service charge and PPN
+// of the transaction are spread into the returned goods proportionally to their price.
+func (t *Transaction) ApplyRefund(refund *Refund) error {
+	if t.SubtotalAmount > 0 && t.SubtotalAmount != t.TotalAmount {
+		// the rounding may leave the last refund a rupiah more than what's left
+		refund.Amount = refund.Amount.Prorate(t.TotalAmount, t.SubtotalAmount).Min(t.TotalAmount - t.RefundedAmount)
+	}
+	if t.RefundedAmount+refund.Amount > t.TotalAmount {
+		return RefundExceedsPaymentError{
+			PaidAmount:     t.TotalAmount,
+			RefundedAmount: t.RefundedAmount,
+			RefundAmount:   refund.Amount,
+		}
+	}
+	t.RefundedAmount += refund.Amount
+	return nil
 }
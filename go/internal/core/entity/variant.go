@@ -0,0 +1,115 @@
+package entity
+
+import (
+	"fmt"
+
+	"gopkg.in/validator.v2"
+)
+
+// GoodsVariant is an option of the goods, e.g. hot/iced or size S/M/L. Goods which has variants is sold
+// through its variants, each of them holds its own stocks and priced relative to the goods price.
+type GoodsVariant struct {
+	ID      int
+	GoodsID int
+	Name    string
+	// PriceDelta is added to the goods price, it could be negative for cheaper variant
+	PriceDelta     Money
+	Stocks         int
+	ReservedStocks int
+	// AverageCost is the moving average of the purchase price per unit, zero means the cost is unknown yet
+	AverageCost float64
+}
+
+type GoodsVariantConfig struct {
+	// ID is empty for new variant, it's assigned by the storage
+	ID         int
+	GoodsID    int    `validate:"nonzero"`
+	Name       string `validate:"nonzero"`
+	PriceDelta Money
+	Stocks     int
+}
+
+func NewGoodsVariant(cfg GoodsVariantConfig) (*GoodsVariant, error) {
+	if err := validator.Validate(cfg); err != nil {
+		return nil, fmt.Errorf("unable to create goods variant entity due: %w", err)
+	}
+
+	stocks := 0
+	if cfg.Stocks > 0 {
+		stocks = cfg.Stocks
+	}
+	return &GoodsVariant{
+		ID:         cfg.ID,
+		GoodsID:    cfg.GoodsID,
+		Name:       cfg.Name,
+		PriceDelta: cfg.PriceDelta,
+		Stocks:     stocks,
+	}, nil
+}
+
+// Price is the selling price of the variant based on price of its goods
+func (v GoodsVariant) Price(goodsPrice Money) Money {
+	return goodsPrice + v.PriceDelta
+}
+
+func (v *GoodsVariant) IncreaseStock(total int) {
+	v.Stocks += total
+}
+
+// ReceiveStock increase the stocks which bought at the unit cost, zero unit cost keep the average cost as is
+func (v *GoodsVariant) ReceiveStock(total int, unitCost float64) {
+	v.AverageCost = averageCost(v.Stocks, v.AverageCost, total, unitCost)
+	v.IncreaseStock(total)
+}
+
+// AvailableStocks is the stocks that not reserved yet by any shopping cart
+func (v GoodsVariant) AvailableStocks() int {
+	return v.Stocks - v.ReservedStocks
+}
+
+func (v *GoodsVariant) DecreaseStock(total int) error {
+	if total > v.AvailableStocks() {
+		return v.insufficientStockError(total)
+	}
+	v.Stocks -= total
+
+	return nil
+}
+
+// SetCountedStock set the stocks into the physically counted quantity, see Goods.SetCountedStock
+func (v *GoodsVariant) SetCountedStock(counted int) {
+	v.Stocks = counted
+}
+
+// ReserveStock hold the stocks for shopping cart so it can't be sold to another cart
+func (v *GoodsVariant) ReserveStock(total int) error {
+	if total > v.AvailableStocks() {
+		return v.insufficientStockError(total)
+	}
+	v.ReservedStocks += total
+
+	return nil
+}
+
+// ReleaseStock give back the reserved stocks, e.g. when shopping cart abandoned
+func (v *GoodsVariant) ReleaseStock(total int) {
+	v.ReservedStocks -= total
+	if v.ReservedStocks < 0 {
+		v.ReservedStocks = 0
+	}
+}
+
+// DeductReservedStock turn the reserved stocks into permanent deduction when shopping cart paid
+func (v *GoodsVariant) DeductReservedStock(total int) {
+	v.ReleaseStock(total)
+	v.Stocks -= total
+}
+
+func (v GoodsVariant) insufficientStockError(requested int) InsufficientStockError {
+	return InsufficientStockError{
+		GoodsID:   v.GoodsID,
+		VariantID: v.ID,
+		Stocks:    v.AvailableStocks(),
+		Requested: requested,
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+import "errors"
+
+var (
+	ErrInvalidInput             = errors.New("invalid input")
+	ErrGoodsNotFound            = errors.New("goods not found")
+	ErrCartNotFound             = errors.New("shopping cart not found")
+	ErrPriceMismatch            = errors.New("goods price is different from the current price")
+	ErrCartAlreadyPaid          = errors.New("shopping cart already paid")
+	ErrCartExpired              = errors.New("shopping cart already expired")
+	ErrEmptyCart                = errors.New("shopping cart is empty")
+	ErrIdempotencyKeyReused     = errors.New("idempotency key already used for another shopping cart")
+	ErrTransactionNotPaid       = errors.New("transaction not paid yet")
+	ErrDeliveryAlreadyRequested = errors.New("delivery already requested for the transaction")
+	ErrGoodsNameAlreadyExists   = errors.New("goods with the same name already exists")
+	ErrCategoryNotFound         = errors.New("category not found")
+	ErrCategoryAlreadyExists    = errors.New("category with the same name already exists")
+	ErrVariantNotFound          = errors.New("goods variant not found")
+	ErrVariantAlreadyExists     = errors.New("goods variant with the same name already exists")
+	ErrStockCountNotFound       = errors.New("stock count not found")
+	ErrStockCountApproved       = errors.New("stock count already approved")
+	ErrSupplierNotFound         = errors.New("supplier not found")
+	ErrSupplierAlreadyExists    = errors.New("supplier with the same name already exists")
+	ErrPurchaseOrderNotFound    = errors.New("purchase order not found")
+	ErrPurchaseOrderClosed      = errors.New("purchase order already received or cancelled")
+	ErrVoucherNotApplicable     = errors.New("voucher not found, expired or not applicable to the shopping cart")
+	ErrVoucherAlreadyExists     = errors.New("promotion with the same voucher code already exists")
+	ErrCartChanged              = errors.New("shopping cart changed while being paid")
+	ErrPaymentNotFound          = errors.New("payment not found")
+	ErrPaymentPending           = errors.New("shopping cart is waiting for its cashless payment")
+	ErrPaymentUnavailable       = errors.New("cashless payment is not available")
+	ErrInvalidSignature         = errors.New("invalid signature of the payment callback")
+)
@@ -1,90 +1,1152 @@
 package service
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+)
+
 type UpdateStockAction string
 
 type Sort string
 
+// GoodsSortField is the property of goods which list of goods can be sorted by
+type GoodsSortField string
+
 const (
 	IncreaseStock UpdateStockAction = "INCR"
 	DecreaseStock UpdateStockAction = "DECR"
-	SortAsc       Sort              = "ASC"
-	SortDesc      Sort              = "DESC"
+	// WasteStock decrease the stocks which is wasted, e.g. spoiled or broken goods
+	WasteStock UpdateStockAction = "WASTE"
+	SortAsc    Sort              = "ASC"
+	SortDesc   Sort              = "DESC"
+
+	GoodsSortByID     GoodsSortField = "id"
+	GoodsSortByName   GoodsSortField = "name"
+	GoodsSortByPrice  GoodsSortField = "price"
+	GoodsSortByStocks GoodsSortField = "stocks"
 )
 
+func (s Sort) IsValid() bool {
+	return s == SortAsc || s == SortDesc
+}
+
+func (f GoodsSortField) IsValid() bool {
+	switch f {
+	case GoodsSortByID, GoodsSortByName, GoodsSortByPrice, GoodsSortByStocks:
+		return true
+	}
+	return false
+}
+
 type ShowListOfGoodsInput struct {
 	Page       int
 	TotalGoods int
 	Sort       Sort
-	SortBy     string
+	SortBy     GoodsSortField
+	// Name filter the goods which name contains this value, empty means no filter
+	Name string
+	// MinPrice & MaxPrice filter the goods by price range, zero means no limit
+	MinPrice entity.Money
+	MaxPrice entity.Money
+	// InStockOnly filter out the goods which has no available stocks
+	InStockOnly bool
+	// CursorPaging use keyset paging instead of page number, Page is ignored. Cursor is the
+	// NextCursor or PrevCursor of the current page, empty means the first page.
+	CursorPaging bool
+	Cursor       string
 }
 
-func (i ShowListOfGoodsInput) ToGetGoodsStorageInput() GetGoodsInput {
+func (i ShowListOfGoodsInput) ToGetGoodsStorageInput() (GetGoodsInput, error) {
 	// default values
 	input := GetGoodsInput{
-		Offset: 0,
-		Limit:  10,
-		Sort:   SortDesc,
-		SortBy: "id",
-	}
-	if i.Page > 0 {
-		input.Offset = i.Page - 1
+		Offset:      0,
+		Limit:       10,
+		Sort:        SortDesc,
+		SortBy:      GoodsSortByID,
+		Name:        strings.TrimSpace(i.Name),
+		MinPrice:    i.MinPrice,
+		MaxPrice:    i.MaxPrice,
+		InStockOnly: i.InStockOnly,
 	}
 	if i.TotalGoods > 0 {
 		input.Limit = i.TotalGoods
 	}
+	if i.Page > 0 && !i.CursorPaging {
+		input.Offset = (i.Page - 1) * input.Limit
+	}
 	if len(i.Sort) > 0 {
-		input.Sort = i.Sort
+		input.Sort = Sort(strings.ToUpper(string(i.Sort)))
+		if !input.Sort.IsValid() {
+			return input, fmt.Errorf("%w: unknown sort %s", ErrInvalidInput, i.Sort)
+		}
 	}
 	if len(i.SortBy) > 0 {
-		input.SortBy = i.SortBy
+		input.SortBy = GoodsSortField(strings.ToLower(string(i.SortBy)))
+		if !input.SortBy.IsValid() {
+			return input, fmt.Errorf("%w: unable to sort goods by %s", ErrInvalidInput, i.SortBy)
+		}
+	}
+	if i.MinPrice < 0 || i.MaxPrice < 0 {
+		return input, fmt.Errorf("%w: price range must not be negative", ErrInvalidInput)
 	}
-	return input
+	if i.MaxPrice > 0 && i.MinPrice > i.MaxPrice {
+		return input, fmt.Errorf("%w: min price must not be greater than max price", ErrInvalidInput)
+	}
+	if i.CursorPaging && len(i.Cursor) > 0 {
+		cursor, err := ParseGoodsCursor(i.Cursor)
+		if err != nil {
+			return input, err
+		}
+		// the order is kept from the first page, otherwise the next page is meaningless
+		input.Sort = cursor.Sort
+		input.SortBy = cursor.SortBy
+		if cursor.Backward {
+			input.Before = cursor
+		} else {
+			input.After = cursor
+		}
+	}
+	return input, nil
+}
+
+// GoodsCursor is the position of the last goods on the previous page in cursor paging, or the first goods
+// on the next page when it's Backward. Keyset paging seek the next goods through the index instead of skipping
+// the rows one by one like offset does, so it stays fast no matter how deep the page is.
+type GoodsCursor struct {
+	Sort   Sort           `json:"s"`
+	SortBy GoodsSortField `json:"b"`
+	ID     int            `json:"i"`
+	Name   string         `json:"n,omitempty"`
+	Price  entity.Money   `json:"p,omitempty"`
+	Stocks int            `json:"k,omitempty"`
+	// Backward cursor point to the page before the goods
+	Backward bool `json:"r,omitempty"`
+}
+
+func NewGoodsCursor(goods entity.Goods, sort Sort, sortBy GoodsSortField) GoodsCursor {
+	cursor := GoodsCursor{Sort: sort, SortBy: sortBy, ID: goods.ID}
+	switch sortBy {
+	case GoodsSortByName:
+		cursor.Name = goods.Name
+	case GoodsSortByPrice:
+		cursor.Price = goods.Price
+	case GoodsSortByStocks:
+		cursor.Stocks = goods.Stocks
+	}
+	return cursor
+}
+
+// SortValue is the value of the sorted property of the last goods
+func (c GoodsCursor) SortValue() interface{} {
+	switch c.SortBy {
+	case GoodsSortByName:
+		return c.Name
+	case GoodsSortByPrice:
+		return c.Price
+	case GoodsSortByStocks:
+		return c.Stocks
+	}
+	return c.ID
+}
+
+// Encode turn the cursor into opaque token which safe to be put in URL
+func (c GoodsCursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func ParseGoodsCursor(token string) (*GoodsCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidInput)
+	}
+	var cursor GoodsCursor
+	if err = json.Unmarshal(b, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidInput)
+	}
+	if !cursor.Sort.IsValid() || !cursor.SortBy.IsValid() {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidInput)
+	}
+	return &cursor, nil
+}
+
+// Pagination describe the position of the returned page in the whole list
+type Pagination struct {
+	// Page is zero in cursor paging
+	Page  int
+	Limit int
+	// TotalItems & TotalPages only counted in page paging, counting the whole catalog on every
+	// request is what cursor paging avoids
+	TotalItems int
+	TotalPages int
+	HasNext    bool
+	HasPrev    bool
+	NextCursor string
+	PrevCursor string
+}
+
+func NewPagination(page, limit, totalItems int) Pagination {
+	totalPages := 0
+	if limit > 0 {
+		totalPages = (totalItems + limit - 1) / limit
+	}
+	return Pagination{
+		Page:       page,
+		Limit:      limit,
+		TotalItems: totalItems,
+		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+	}
+}
+
+type ShowListOfGoodsOutput struct {
+	Goods      []entity.Goods
+	Pagination Pagination
 }
 
 type AddToCartInput struct {
-	CartID     int64
-	UserID     int
-	GoodsID    int
-	GoodsPrice float64
+	CartID  int64
+	UserID  int
+	GoodsID int
+	// VariantID is required when the goods has variants
+	VariantID  int
+	GoodsPrice entity.Money
 	Total      int
+	// VoucherCode is optional, it's kept by the shopping cart until it's paid
+	VoucherCode string
 }
 
 type AddToCartOutput struct {
-	CartID      int64
-	UserID      int
-	TotalGoods  int
-	TotalAmount float64
+	CartID         int64
+	UserID         int
+	TotalGoods     int
+	SubtotalAmount entity.Money
+	// TotalAmount is the subtotal after the discounts
+	TotalAmount entity.Money
+	Discounts   []entity.Discount
+	// Bill is the service charge and PPN of the cart, its grand total is the amount to pay
+	Bill entity.Bill
+}
+
+type UpdateCartGoodsInput struct {
+	CartID    int64
+	GoodsID   int
+	VariantID int
+	Total     int
 }
 
 type PayInput struct {
-	CartID        int64
-	PaymentAmount float64
+	CartID int64
+	// Tenders is the payment split by payment method, PaymentAmount is paid in cash when there's no tenders
+	Tenders        []entity.Tender
+	PaymentAmount  entity.Money
+	IdempotencyKey string
+	// VoucherCode replace the voucher kept by the shopping cart when it's not empty
+	VoucherCode string
+}
+
+// GetTenders validate the tenders of the payment, the tenders with the same method are merged
+func (i PayInput) GetTenders() ([]entity.Tender, error) {
+	tenders := []entity.Tender{}
+	for _, tender := range i.Tenders {
+		tenders = append(tenders, entity.Tender{
+			Method: entity.PaymentMethod(strings.ToUpper(string(tender.Method))),
+			Amount: tender.Amount,
+		})
+	}
+	if len(tenders) == 0 {
+		tenders = append(tenders, entity.Tender{Method: entity.PaymentMethodCash, Amount: i.PaymentAmount})
+	}
+	tenders, err := entity.NewTenders(tenders)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return tenders, nil
+}
+
+type RequestPaymentInput struct {
+	CartID int64
+	// Method is QRIS when it's empty, cash is paid through Pay instead
+	Method      entity.PaymentMethod
+	VoucherCode string
+}
+
+// GetMethod validate the payment method, only non cash method could be paid through the payment provider
+func (i RequestPaymentInput) GetMethod() (entity.PaymentMethod, error) {
+	method := entity.PaymentMethod(strings.ToUpper(string(i.Method)))
+	if len(method) == 0 {
+		method = entity.PaymentMethodQRIS
+	}
+	if !method.IsValid() || method == entity.PaymentMethodCash {
+		return "", fmt.Errorf("%w: payment method %s is not available for cashless payment", ErrInvalidInput, method)
+	}
+	return method, nil
+}
+
+type ConfirmPaymentInput struct {
+	// Payload is the raw body of the callback, the signature is computed from it
+	Payload   []byte
+	Signature string
+}
+
+type CreatePaymentInput struct {
+	TransactionID int64
+	Method        entity.PaymentMethod
+	Amount        entity.Money
+}
+
+type CreatePaymentOutput struct {
+	Reference string
+	QRPayload string
+	ExpiresAt int64
+}
+
+// PaymentCallback is the payment status sent by the payment provider
+type PaymentCallback struct {
+	Reference string
+	Status    entity.PaymentStatus
+	Amount    entity.Money
+	PaidAt    int64
+}
+
+type ShowListOfOrdersInput struct {
+	// Status of the orders, empty means all orders which still being handled
+	Status      string
+	Page        int
+	TotalOrders int
+}
+
+// activeOrderStatuses is the statuses of orders which still need to be handled by the staff
+var activeOrderStatuses = []entity.TransactionStatus{
+	entity.TransactionStatusPaid,
+	entity.TransactionStatusPreparing,
+	entity.TransactionStatusReady,
+	entity.TransactionStatusShipped,
+}
+
+func (i ShowListOfOrdersInput) ToGetTransactionsStorageInput() (GetTransactionsInput, error) {
+	// default values
+	input := GetTransactionsInput{
+		Statuses: activeOrderStatuses,
+		Offset:   0,
+		Limit:    10,
+	}
+	if len(i.Status) > 0 {
+		status, ok := entity.ParseTransactionStatus(i.Status)
+		if !ok {
+			return input, fmt.Errorf("%w: unknown order status %s", ErrInvalidInput, i.Status)
+		}
+		input.Statuses = []entity.TransactionStatus{status}
+	}
+	if i.TotalOrders > 0 {
+		input.Limit = i.TotalOrders
+	}
+	if i.Page > 0 {
+		input.Offset = (i.Page - 1) * input.Limit
+	}
+	return input, nil
+}
+
+type UpdateOrderStatusInput struct {
+	TransactionID int64
+	Status        entity.TransactionStatus
+}
+
+// Validate make sure the order only moved forward through the fulfillment statuses, payment and expiry
+// have their own flow
+func (i UpdateOrderStatusInput) Validate() error {
+	if i.TransactionID <= 0 {
+		return fmt.Errorf("%w: order ID is required", ErrInvalidInput)
+	}
+	switch i.Status {
+	case entity.TransactionStatusPreparing,
+		entity.TransactionStatusReady,
+		entity.TransactionStatusShipped,
+		entity.TransactionStatusCompleted:
+		return nil
+	default:
+		return fmt.Errorf("%w: order status can't be changed into %s", ErrInvalidInput, i.Status)
+	}
+}
+
+type RefundOrderInput struct {
+	TransactionID int64
+	// Items is the returned goods, empty means all of the goods which not refunded yet
+	Items  []entity.RefundItem
+	Reason string
+}
+
+func (i RefundOrderInput) Validate() error {
+	if i.TransactionID <= 0 {
+		return fmt.Errorf("%w: order ID is required", ErrInvalidInput)
+	}
+	refundedItems := map[[2]int]bool{}
+	for _, item := range i.Items {
+		if item.GoodsID <= 0 || item.TotalGoods <= 0 {
+			return fmt.Errorf("%w: goods ID and total goods of refund item must be positive", ErrInvalidInput)
+		}
+		key := [2]int{item.GoodsID, item.VariantID}
+		if refundedItems[key] {
+			return fmt.Errorf("%w: goods ID %d variant ID %d refunded more than once", ErrInvalidInput, item.GoodsID, item.VariantID)
+		}
+		refundedItems[key] = true
+	}
+	return nil
+}
+
+func (i RefundOrderInput) ToCreateRefundStorageInput(status entity.TransactionStatus) CreateRefundInput {
+	return CreateRefundInput{
+		TransactionID: i.TransactionID,
+		Items:         i.Items,
+		Reason:        i.Reason,
+		Status:        status,
+		CreatedAt:     time.Now().Unix(),
+	}
+}
+
+type ShowStockMovementsInput struct {
+	GoodsID   int
+	VariantID int
+	// From & To is the first and the last day of the movements in the server local time
+	From time.Time
+	To   time.Time
+}
+
+func (i ShowStockMovementsInput) ToGetStockMovementsStorageInput() (GetStockMovementsInput, error) {
+	if i.GoodsID <= 0 {
+		return GetStockMovementsInput{}, fmt.Errorf("%w: goods ID is required", ErrInvalidInput)
+	}
+	fromYear, fromMonth, fromDay := i.From.In(time.Local).Date()
+	toYear, toMonth, toDay := i.To.In(time.Local).Date()
+	from := time.Date(fromYear, fromMonth, fromDay, 0, 0, 0, 0, time.Local)
+	to := time.Date(toYear, toMonth, toDay, 0, 0, 0, 0, time.Local).AddDate(0, 0, 1)
+	if !from.Before(to) {
+		return GetStockMovementsInput{}, fmt.Errorf("%w: from date must not be after to date", ErrInvalidInput)
+	}
+
+	return GetStockMovementsInput{
+		GoodsID:   i.GoodsID,
+		VariantID: i.VariantID,
+		From:      from.Unix(),
+		To:        to.Unix(),
+	}, nil
+}
+
+type GetDailySalesReportInput struct {
+	// Date is the day of the report in the server local time
+	Date time.Time
+}
+
+type GetMonthlyTaxRecapInput struct {
+	// Month is any time within the month of the recap in the server local time
+	Month time.Time
 }
 
 type ReqCalculateDeliveryPriceInput struct {
-	Location  float64
+	// Location is the buyer's regency / city code (kode wilayah BPS), e.g. 3471 for Kota Yogyakarta
+	Location  int
 	GoodsSpec GoodsSpecification
 }
 
+func (i ReqCalculateDeliveryPriceInput) Validate() error {
+	if !IsValidLocation(i.Location) {
+		return fmt.Errorf("%w: location must be 4 digits regency / city code", ErrInvalidInput)
+	}
+	if !isValidMeasure(i.GoodsSpec.Weight, MaxPackageWeight) {
+		return fmt.Errorf("%w: weight must be between 0 and %d kilogram", ErrInvalidInput, MaxPackageWeight)
+	}
+	if !isValidMeasure(i.GoodsSpec.Volume, MaxPackageVolume) {
+		return fmt.Errorf("%w: volume must be between 0 and %d cubic centimeter", ErrInvalidInput, MaxPackageVolume)
+	}
+	if i.GoodsSpec.Weight == 0 && i.GoodsSpec.Volume == 0 {
+		return fmt.Errorf("%w: weight or volume is required", ErrInvalidInput)
+	}
+	return nil
+}
+
+// isValidMeasure check the weight or volume is a number within zero and the maximum, NaN and infinity are invalid
+func isValidMeasure(measure float32, max float64) bool {
+	value := float64(measure)
+	return !math.IsNaN(value) && !math.IsInf(value, 0) && value >= 0 && value <= max
+}
+
+// IsValidLocation check whether the location is valid regency / city code
+func IsValidLocation(location int) bool {
+	return location >= 1100 && location <= 9999
+}
+
+type CalculateDeliveryPriceInput struct {
+	Origin      int
+	Destination int
+	Package     GoodsSpecification
+}
+
+type DeliveryQuote struct {
+	Origin      int
+	Destination int
+	Zone        string
+	// ChargeableWeight is the greater one between actual weight and volumetric weight, in kilogram
+	ChargeableWeight float32
+	Price            entity.Money
+}
+
+type ReqPickupDeliveryInput struct {
+	TransactionID int64
+	// Location is the buyer's regency / city code
+	Location  int
+	GoodsSpec GoodsSpecification
+}
+
+func (i ReqPickupDeliveryInput) Validate() error {
+	if i.TransactionID <= 0 {
+		return fmt.Errorf("%w: transaction ID is required", ErrInvalidInput)
+	}
+	return ReqCalculateDeliveryPriceInput{
+		Location:  i.Location,
+		GoodsSpec: i.GoodsSpec,
+	}.Validate()
+}
+
+type PickupDeliveryInput struct {
+	TransactionID int64
+	Origin        int
+	Destination   int
+	Package       GoodsSpecification
+}
+
+type PickupDeliveryOutput struct {
+	TrackingNumber string
+	Status         entity.DeliveryStatus
+	Price          entity.Money
+}
+
+type CreateGoodsInput struct {
+	Name   string
+	Stocks int
+	Price  entity.Money
+	// CategoryID is optional, zero means the goods not categorized
+	CategoryID int
+	// IsRawMaterial is set for goods which is not sold but consumed by recipe, e.g. coffee beans
+	IsRawMaterial bool
+	Unit          string
+	// ReorderPoint is optional, zero means the stocks of the goods is not monitored
+	ReorderPoint int
+	// PriceIncludesTax is set when the price already contains the PPN
+	PriceIncludesTax bool
+}
+
+func (i CreateGoodsInput) ToGoodsEntity() (*entity.Goods, error) {
+	goods, err := entity.NewGoods(entity.GoodsConfig{
+		Name:             strings.TrimSpace(i.Name),
+		Stocks:           i.Stocks,
+		Price:            i.Price,
+		CategoryID:       i.CategoryID,
+		IsRawMaterial:    i.IsRawMaterial,
+		Unit:             strings.TrimSpace(i.Unit),
+		ReorderPoint:     i.ReorderPoint,
+		PriceIncludesTax: i.PriceIncludesTax,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return goods, nil
+}
+
+// UpdateGoodsInput change the goods info, the stocks is changed through UpdateStock instead
+type UpdateGoodsInput struct {
+	ID           int
+	Name         string
+	Price        entity.Money
+	CategoryID   int
+	ReorderPoint int
+	// PriceIncludesTax is set when the price already contains the PPN
+	PriceIncludesTax bool
+}
+
+func (i UpdateGoodsInput) ToGoodsEntity() (*entity.Goods, error) {
+	if i.ID <= 0 {
+		return nil, fmt.Errorf("%w: goods ID is required", ErrInvalidInput)
+	}
+	goods, err := entity.NewGoods(entity.GoodsConfig{
+		ID:               i.ID,
+		Name:             strings.TrimSpace(i.Name),
+		Price:            i.Price,
+		CategoryID:       i.CategoryID,
+		ReorderPoint:     i.ReorderPoint,
+		PriceIncludesTax: i.PriceIncludesTax,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return goods, nil
+}
+
+type SetRecipeInput struct {
+	GoodsID int
+	// Ingredients only need the ingredient ID and its quantity
+	Ingredients []entity.RecipeIngredient
+}
+
+func (i SetRecipeInput) ToRecipeEntity() (*entity.Recipe, error) {
+	recipe, err := entity.NewRecipe(entity.RecipeConfig{
+		GoodsID:     i.GoodsID,
+		Ingredients: i.Ingredients,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return recipe, nil
+}
+
+type CreateCategoryInput struct {
+	Name string
+}
+
+func (i CreateCategoryInput) ToCategoryEntity() (*entity.Category, error) {
+	category, err := entity.NewCategory(entity.CategoryConfig{
+		Name: strings.TrimSpace(i.Name),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return category, nil
+}
+
+type CreateGoodsVariantInput struct {
+	GoodsID    int
+	Name       string
+	PriceDelta entity.Money
+	Stocks     int
+}
+
+func (i CreateGoodsVariantInput) ToGoodsVariantEntity() (*entity.GoodsVariant, error) {
+	variant, err := entity.NewGoodsVariant(entity.GoodsVariantConfig{
+		GoodsID:    i.GoodsID,
+		Name:       strings.TrimSpace(i.Name),
+		PriceDelta: i.PriceDelta,
+		Stocks:     i.Stocks,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return variant, nil
+}
+
+// MenuSection is the goods of one category in the menu
+type MenuSection struct {
+	Category entity.Category
+	Goods    []entity.Goods
+}
+
+// UncategorizedMenuName is the name of menu section for the goods which not categorized yet
+const UncategorizedMenuName = "Lainnya"
+
 type UpdateStockInput struct {
-	Action  UpdateStockAction
+	Action    UpdateStockAction
+	GoodsID   int
+	GoodsName string
+	// VariantID is set when the stocks of the goods variant is updated instead
+	VariantID int
+	Total     int
+	// Actor & Reason is recorded in the stock movements, actor is system when it's empty
+	Actor  string
+	Reason string
+	// UnitCost is the purchase price of the increased stocks, it's only for INCR. Zero means the cost is unknown
+	// and the average cost of the goods is kept as is.
+	UnitCost float64
+	// ExpiresAt is the expiry time of the increased stocks, it's only for INCR. Zero means the goods doesn't expire.
+	ExpiresAt int64
+}
+
+func (i UpdateStockInput) Validate() error {
+	if i.Action != IncreaseStock && i.Action != DecreaseStock && i.Action != WasteStock {
+		return fmt.Errorf("%w: action must be %s, %s or %s", ErrInvalidInput, IncreaseStock, DecreaseStock, WasteStock)
+	}
+	if i.GoodsID <= 0 && len(i.GoodsName) == 0 {
+		return fmt.Errorf("%w: goods ID or goods name is required", ErrInvalidInput)
+	}
+	if i.Total <= 0 {
+		return fmt.Errorf("%w: total must be greater than zero", ErrInvalidInput)
+	}
+	if i.UnitCost < 0 {
+		return fmt.Errorf("%w: unit cost can't be negative", ErrInvalidInput)
+	}
+	if i.UnitCost > 0 && i.Action != IncreaseStock {
+		return fmt.Errorf("%w: unit cost is only for %s action", ErrInvalidInput, IncreaseStock)
+	}
+	if i.ExpiresAt != 0 && i.Action != IncreaseStock {
+		return fmt.Errorf("%w: expiry date is only for %s action", ErrInvalidInput, IncreaseStock)
+	}
+	if i.ExpiresAt < 0 {
+		return fmt.Errorf("%w: invalid expiry date", ErrInvalidInput)
+	}
+	return nil
+}
+
+func (i UpdateStockInput) ToUpdateGoodsStockStorageInput() UpdateGoodsStockInput {
+	return UpdateGoodsStockInput(i)
+}
+
+type ShowExpiringBatchesInput struct {
+	// Days is the number of days from today, zero means only the batches which expire today or already expired
+	Days int
+}
+
+func (i ShowExpiringBatchesInput) ToGetExpiringStockBatchesStorageInput() (GetExpiringStockBatchesInput, error) {
+	if i.Days < 0 {
+		return GetExpiringStockBatchesInput{}, fmt.Errorf("%w: days can't be negative", ErrInvalidInput)
+	}
+	// batch expires at the end of its expiry date, so the last day is included
+	year, month, day := time.Now().Date()
+	until := time.Date(year, month, day, 0, 0, 0, 0, time.Local).AddDate(0, 0, i.Days+1)
+
+	return GetExpiringStockBatchesInput{
+		ExpiresUntil: until.Unix(),
+	}, nil
+}
+
+type WriteOffExpiredBatchesInput struct {
+	// GoodsID is zero when the expired batches of all goods is written off
 	GoodsID int
-	Total   int
+	// Actor & Reason is recorded in the wastage movements, actor is system when it's empty
+	Actor  string
+	Reason string
+}
+
+func (i WriteOffExpiredBatchesInput) ToWriteOffStockBatchesStorageInput() (WriteOffStockBatchesInput, error) {
+	reason := strings.TrimSpace(i.Reason)
+	if len(reason) == 0 {
+		return WriteOffStockBatchesInput{}, fmt.Errorf("%w: reason is required", ErrInvalidInput)
+	}
+	if i.GoodsID < 0 {
+		return WriteOffStockBatchesInput{}, fmt.Errorf("%w: invalid goods ID", ErrInvalidInput)
+	}
+	actor := strings.TrimSpace(i.Actor)
+	if len(actor) == 0 {
+		actor = entity.StockActorSystem
+	}
+
+	return WriteOffStockBatchesInput{
+		GoodsID:   i.GoodsID,
+		ExpiredAt: time.Now().Unix(),
+		Actor:     actor,
+		Reason:    reason,
+	}, nil
+}
+
+type OpenStockCountInput struct {
+	OpenedBy string
+}
+
+func (i OpenStockCountInput) ToStockCountEntity() (*entity.StockCount, error) {
+	stockCount, err := entity.NewStockCount(entity.StockCountConfig{
+		OpenedBy:  strings.TrimSpace(i.OpenedBy),
+		CreatedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return stockCount, nil
+}
+
+type SubmitStockCountInput struct {
+	CountID int64
+	// Items only need the goods ID, the variant ID and the counted quantity
+	Items []entity.StockCountItem
+}
+
+func (i SubmitStockCountInput) ToSetStockCountItemsStorageInput() (SetStockCountItemsInput, error) {
+	if i.CountID <= 0 {
+		return SetStockCountItemsInput{}, fmt.Errorf("%w: stock count ID is required", ErrInvalidInput)
+	}
+	if len(i.Items) == 0 {
+		return SetStockCountItemsInput{}, fmt.Errorf("%w: counted goods is required", ErrInvalidInput)
+	}
+	items := []entity.StockCountItem{}
+	isCounted := map[[2]int]bool{}
+	for _, item := range i.Items {
+		switch {
+		case item.GoodsID <= 0:
+			return SetStockCountItemsInput{}, fmt.Errorf("%w: goods ID is required", ErrInvalidInput)
+		case item.VariantID < 0:
+			return SetStockCountItemsInput{}, fmt.Errorf("%w: variant ID of goods %d is invalid", ErrInvalidInput, item.GoodsID)
+		case item.CountedStocks < 0:
+			return SetStockCountItemsInput{}, fmt.Errorf("%w: counted stocks of goods %d can't be negative", ErrInvalidInput, item.GoodsID)
+		case isCounted[[2]int{item.GoodsID, item.VariantID}]:
+			return SetStockCountItemsInput{}, fmt.Errorf("%w: goods %d is counted more than once", ErrInvalidInput, item.GoodsID)
+		}
+		isCounted[[2]int{item.GoodsID, item.VariantID}] = true
+		items = append(items, entity.StockCountItem{
+			GoodsID:       item.GoodsID,
+			VariantID:     item.VariantID,
+			CountedStocks: item.CountedStocks,
+		})
+	}
+
+	return SetStockCountItemsInput{
+		CountID: i.CountID,
+		Items:   items,
+	}, nil
+}
+
+type ApproveStockCountInput struct {
+	CountID    int64
+	ApprovedBy string
+}
+
+func (i ApproveStockCountInput) ToApplyStockCountStorageInput() (ApplyStockCountInput, error) {
+	if i.CountID <= 0 {
+		return ApplyStockCountInput{}, fmt.Errorf("%w: stock count ID is required", ErrInvalidInput)
+	}
+	approvedBy := strings.TrimSpace(i.ApprovedBy)
+	if len(approvedBy) == 0 {
+		approvedBy = entity.StockActorSystem
+	}
+
+	return ApplyStockCountInput{
+		CountID:    i.CountID,
+		ApprovedBy: approvedBy,
+		ApprovedAt: time.Now().Unix(),
+	}, nil
+}
+
+type CreateSupplierInput struct {
+	Name    string
+	Phone   string
+	Address string
+}
+
+func (i CreateSupplierInput) ToSupplierEntity() (*entity.Supplier, error) {
+	supplier, err := entity.NewSupplier(entity.SupplierConfig{
+		Name:      strings.TrimSpace(i.Name),
+		Phone:     strings.TrimSpace(i.Phone),
+		Address:   strings.TrimSpace(i.Address),
+		CreatedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return supplier, nil
+}
+
+type CreatePurchaseOrderInput struct {
+	SupplierID int
+	Note       string
+	CreatedBy  string
+	// Items only need the goods ID, the variant ID, the ordered quantity and the unit cost
+	Items []entity.PurchaseOrderItem
 }
 
+func (i CreatePurchaseOrderInput) ToPurchaseOrderEntity() (*entity.PurchaseOrder, error) {
+	purchaseOrder, err := entity.NewPurchaseOrder(entity.PurchaseOrderConfig{
+		SupplierID: i.SupplierID,
+		Note:       strings.TrimSpace(i.Note),
+		CreatedBy:  strings.TrimSpace(i.CreatedBy),
+		Items:      i.Items,
+		CreatedAt:  time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return purchaseOrder, nil
+}
+
+type ShowPurchaseOrdersInput struct {
+	// SupplierID & Status filter the purchase orders, the filter is not applied when it's empty
+	SupplierID int
+	Status     entity.PurchaseOrderStatus
+}
+
+func (i ShowPurchaseOrdersInput) ToGetPurchaseOrdersStorageInput() (GetPurchaseOrdersInput, error) {
+	switch i.Status {
+	case "", entity.PurchaseOrderOpen, entity.PurchaseOrderPartiallyReceived, entity.PurchaseOrderReceived,
+		entity.PurchaseOrderCancelled:
+	default:
+		return GetPurchaseOrdersInput{}, fmt.Errorf("%w: unknown purchase order status %s", ErrInvalidInput, i.Status)
+	}
+	return GetPurchaseOrdersInput(i), nil
+}
+
+type ReceivePurchaseOrderInput struct {
+	OrderID    int64
+	ReceivedBy string
+	// Items only need the goods ID, the variant ID and the received quantity, the unit cost follow the order
+	Items []entity.PurchaseOrderReceiptItem
+}
+
+func (i ReceivePurchaseOrderInput) ToPurchaseOrderReceiptEntity() (*entity.PurchaseOrderReceipt, error) {
+	if i.OrderID <= 0 {
+		return nil, fmt.Errorf("%w: purchase order ID is required", ErrInvalidInput)
+	}
+	if len(i.Items) == 0 {
+		return nil, fmt.Errorf("%w: received goods is required", ErrInvalidInput)
+	}
+	items := []entity.PurchaseOrderReceiptItem{}
+	isReceived := map[[2]int]bool{}
+	for _, item := range i.Items {
+		switch {
+		case item.GoodsID <= 0:
+			return nil, fmt.Errorf("%w: goods ID is required", ErrInvalidInput)
+		case item.Quantity <= 0:
+			return nil, fmt.Errorf("%w: received quantity of goods %d must be positive", ErrInvalidInput, item.GoodsID)
+		case isReceived[[2]int{item.GoodsID, item.VariantID}]:
+			return nil, fmt.Errorf("%w: goods %d is received more than once", ErrInvalidInput, item.GoodsID)
+		}
+		isReceived[[2]int{item.GoodsID, item.VariantID}] = true
+		items = append(items, entity.PurchaseOrderReceiptItem{
+			GoodsID:   item.GoodsID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+		})
+	}
+	receivedBy := strings.TrimSpace(i.ReceivedBy)
+	if len(receivedBy) == 0 {
+		receivedBy = entity.StockActorSystem
+	}
+
+	return &entity.PurchaseOrderReceipt{
+		OrderID:    i.OrderID,
+		ReceivedBy: receivedBy,
+		Items:      items,
+		ReceivedAt: time.Now().Unix(),
+	}, nil
+}
+
+const (
+	// MaxPackageWeight is the heaviest package in kilogram which can be delivered
+	MaxPackageWeight = 1000
+	// MaxPackageVolume is the biggest package in cubic centimeter which can be delivered, i.e. 6 cubic meter
+	// which volumetric weight is the same as the heaviest package
+	MaxPackageVolume = 6000000
+)
+
 type GoodsSpecification struct {
+	// Weight in kilogram
 	Weight float32
+	// Volume in cubic centimeter
 	Volume float32
 }
 
 type GetGoodsInput struct {
-	Offset int
-	Limit  int
-	Sort   Sort
-	SortBy string
+	Offset      int
+	Limit       int
+	Sort        Sort
+	SortBy      GoodsSortField
+	Name        string
+	MinPrice    entity.Money
+	MaxPrice    entity.Money
+	InStockOnly bool
+	// After & Before is only set in cursor paging, Offset is ignored when one of them is set.
+	// Before get the last goods before the cursor, they're still returned in the requested order.
+	After  *GoodsCursor
+	Before *GoodsCursor
+}
+
+type GetTransactionsInput struct {
+	Statuses []entity.TransactionStatus
+	Offset   int
+	Limit    int
+}
+
+type UpdateTransactionStatusInput struct {
+	TransactionID int64
+	Status        entity.TransactionStatus
+	ChangedAt     int64
+}
+
+type CreateRefundInput struct {
+	TransactionID int64
+	Items         []entity.RefundItem
+	Reason        string
+	// Status is the status of transaction after its goods refunded, i.e. refunded or cancelled. Partial refund
+	// keep the current status until all of the goods refunded.
+	Status    entity.TransactionStatus
+	CreatedAt int64
+}
+
+type GetSalesSummaryInput struct {
+	From int64
+	To   int64
+}
+
+type GetTaxRecapInput struct {
+	From int64
+	To   int64
+}
+
+type UpdateGoodsStockInput struct {
+	Action    UpdateStockAction
+	GoodsID   int
+	GoodsName string
+	VariantID int
+	Total     int
+	Actor     string
+	Reason    string
+	UnitCost  float64
+	ExpiresAt int64
+}
+
+type GetExpiringStockBatchesInput struct {
+	ExpiresUntil int64
+}
+
+// WriteOffStockBatchesInput write off the batches which already expired at the given time
+type WriteOffStockBatchesInput struct {
+	GoodsID   int
+	ExpiredAt int64
+	Actor     string
+	Reason    string
+}
+
+// SetStockCountItemsInput add the counted goods into the stock count, goods which already counted is replaced
+type SetStockCountItemsInput struct {
+	CountID int64
+	Items   []entity.StockCountItem
+}
+
+type GetPurchaseOrdersInput struct {
+	SupplierID int
+	Status     entity.PurchaseOrderStatus
+}
+
+type CancelPurchaseOrderInput struct {
+	OrderID     int64
+	CancelledAt int64
+}
+
+type ApplyStockCountInput struct {
+	CountID    int64
+	ApprovedBy string
+	ApprovedAt int64
+}
+
+// GetStockMovementsInput filter the stock movements, the filter is not applied when it's zero
+type GetStockMovementsInput struct {
+	GoodsID int
+	// VariantID is zero for all movements of the goods including its variants
+	VariantID     int
+	TransactionID int64
+	// From & To is unix timestamp, To is exclusive
+	From int64
+	To   int64
+}
+
+// SetCartGoodsQuantityInput set total of goods in the shopping cart, zero total remove the goods from the cart
+type SetCartGoodsQuantityInput struct {
+	CartID    int64
+	GoodsID   int
+	VariantID int
+	Total     int
+}
+
+type ExpireShoppingCartsInput struct {
+	// LastActivityBefore is unix timestamp, unpaid shopping cart which not updated since then will be expired
+	LastActivityBefore int64
+	Limit              int
 }
 
 type CreateTransactionInput struct {
-	CartID        int64
-	PaymentAmount float64
+	CartID int64
+	// PaymentAmount is paid in cash when there's no tenders, otherwise the payment amount is the sum of the tenders.
+	// The payment is checked against the total amount while the shopping cart is locked.
+	PaymentAmount  entity.Money
+	Tenders        []entity.Tender
+	IdempotencyKey string
+	// Bill is computed from the shopping cart, the payment is rejected when the shopping cart changed in
+	// the meantime. The subtotal of the cart is used as the total amount when the bill is empty.
+	Bill     entity.Bill
+	TaxRates entity.TaxRates
+	// VoucherCode is empty when there's no voucher applied
+	VoucherCode string
+	Discounts   []entity.Discount
+	// PaymentReference is set when the transaction is paid through the payment provider, the payment is marked
+	// as paid along with the transaction
+	PaymentReference string
+}
+
+// GetTenders get the tenders of the payment, the payment amount is paid in cash when there's no tenders
+func (i CreateTransactionInput) GetTenders() []entity.Tender {
+	if len(i.Tenders) == 0 {
+		return []entity.Tender{{Method: entity.PaymentMethodCash, Amount: i.PaymentAmount}}
+	}
+	return i.Tenders
+}
+
+type GetPromotionsInput struct {
+	// ActiveAt is unix timestamp, only promotions within their period at that time are returned when it's set
+	ActiveAt int64
+	// VoucherCode is the only voucher returned along with the automatic promotions, all vouchers are returned
+	// when ActiveAt is not set
+	VoucherCode string
+}
+
+type CreatePromotionInput struct {
+	Name    string
+	Type    string
+	GoodsID int
+	// Value is the percentage of PERCENTAGE promotion, while Amount is the discount of FIXED promotion
+	Value        float64
+	Amount       entity.Money
+	BuyQuantity  int
+	FreeQuantity int
+	MinPurchase  entity.Money
+	// HappyHourStart & HappyHourEnd is in HH:MM format, both are empty when the promotion applies all day
+	HappyHourStart string
+	HappyHourEnd   string
+	// StartsAt & EndsAt is unix timestamp, zero means it's not limited
+	StartsAt    int64
+	EndsAt      int64
+	VoucherCode string
+	UsageLimit  int
+}
+
+func (i CreatePromotionInput) ToPromotionEntity() (*entity.Promotion, error) {
+	happyHourStart, err := parseMinuteOfDay(i.HappyHourStart)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid happy hour start: %v", ErrInvalidInput, err)
+	}
+	happyHourEnd, err := parseMinuteOfDay(i.HappyHourEnd)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid happy hour end: %v", ErrInvalidInput, err)
+	}
+	promotion, err := entity.NewPromotion(entity.PromotionConfig{
+		Name:           strings.TrimSpace(i.Name),
+		Type:           entity.PromotionType(strings.ToUpper(i.Type)),
+		GoodsID:        i.GoodsID,
+		Value:          i.Value,
+		Amount:         i.Amount,
+		BuyQuantity:    i.BuyQuantity,
+		FreeQuantity:   i.FreeQuantity,
+		MinPurchase:    i.MinPurchase,
+		HappyHourStart: happyHourStart,
+		HappyHourEnd:   happyHourEnd,
+		StartsAt:       i.StartsAt,
+		EndsAt:         i.EndsAt,
+		VoucherCode:    i.VoucherCode,
+		UsageLimit:     i.UsageLimit,
+		CreatedAt:      time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return promotion, nil
+}
+
+// parseMinuteOfDay parse HH:MM into minutes since midnight, empty value is midnight
+func parseMinuteOfDay(value string) (int, error) {
+	if len(value) == 0 {
+		return 0, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
 }
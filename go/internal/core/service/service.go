@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
 	"gopkg.in/validator.v2"
@@ -10,38 +13,168 @@ import (
 
 type Service interface {
 	// small UMKM
-	ShowListOfGoods(ctx context.Context, input ShowListOfGoodsInput) ([]entity.Goods, error)
+	ShowListOfGoods(ctx context.Context, input ShowListOfGoodsInput) (*ShowListOfGoodsOutput, error)
+	ShowMenu(ctx context.Context) ([]MenuSection, error)
 	AddToCart(ctx context.Context, input AddToCartInput) (*AddToCartOutput, error)
+	AbandonCart(ctx context.Context, cartID int64) error
+	GetCart(ctx context.Context, cartID int64) (*entity.ShoppingCart, error)
+	UpdateCartGoods(ctx context.Context, input UpdateCartGoodsInput) (*entity.ShoppingCart, error)
+	RemoveGoodsFromCart(ctx context.Context, cartID int64, goodsID int, variantID int) (*entity.ShoppingCart, error)
+	ClearCart(ctx context.Context, cartID int64) (*entity.ShoppingCart, error)
 	Pay(ctx context.Context, input PayInput) (*entity.Transaction, error)
+	RequestPayment(ctx context.Context, input RequestPaymentInput) (*entity.Payment, error)
+	GetPayment(ctx context.Context, reference string) (*entity.Payment, error)
+	ConfirmPayment(ctx context.Context, input ConfirmPaymentInput) (*entity.Transaction, error)
+	ShowListOfOrders(ctx context.Context, input ShowListOfOrdersInput) ([]entity.Transaction, error)
+	GetOrder(ctx context.Context, transactionID int64) (*entity.Transaction, error)
+	UpdateOrderStatus(ctx context.Context, input UpdateOrderStatusInput) (*entity.Transaction, error)
+	RefundOrder(ctx context.Context, input RefundOrderInput) (*entity.Refund, error)
+	CancelOrder(ctx context.Context, transactionID int64, reason string) (*entity.Refund, error)
+	GetDailySalesReport(ctx context.Context, input GetDailySalesReportInput) (*entity.SalesSummary, error)
+	GetMonthlyTaxRecap(ctx context.Context, input GetMonthlyTaxRecapInput) (*entity.TaxRecap, error)
 	// huge UMKM
-	ReqCalculateDeliveryPrice(ctx context.Context, input ReqCalculateDeliveryPriceInput) error
-	ReqPickupDelivery(ctx context.Context, transactionID int) error
-	UpdateStock(ctx context.Context, input UpdateStockInput) error
+	ReqCalculateDeliveryPrice(ctx context.Context, input ReqCalculateDeliveryPriceInput) (*DeliveryQuote, error)
+	ReqPickupDelivery(ctx context.Context, input ReqPickupDeliveryInput) (*entity.Delivery, error)
+	UpdateStock(ctx context.Context, input UpdateStockInput) (*entity.Goods, error)
+	GetGoodsByID(ctx context.Context, goodsID int) (*entity.Goods, error)
+	CreateGoods(ctx context.Context, input CreateGoodsInput) (*entity.Goods, error)
+	UpdateGoods(ctx context.Context, input UpdateGoodsInput) (*entity.Goods, error)
+	DeleteGoods(ctx context.Context, goodsID int) error
+	CreateCategory(ctx context.Context, input CreateCategoryInput) (*entity.Category, error)
+	CreateGoodsVariant(ctx context.Context, input CreateGoodsVariantInput) (*entity.GoodsVariant, error)
+	SetRecipe(ctx context.Context, input SetRecipeInput) (*entity.Recipe, error)
+	ShowStockMovements(ctx context.Context, input ShowStockMovementsInput) ([]entity.StockMovement, error)
+	ShowLowStocks(ctx context.Context) ([]entity.LowStock, error)
+	ShowExpiringBatches(ctx context.Context, input ShowExpiringBatchesInput) ([]entity.StockBatch, error)
+	WriteOffExpiredBatches(ctx context.Context, input WriteOffExpiredBatchesInput) ([]entity.StockMovement, error)
+	OpenStockCount(ctx context.Context, input OpenStockCountInput) (*entity.StockCount, error)
+	SubmitStockCount(ctx context.Context, input SubmitStockCountInput) (*entity.StockCount, error)
+	GetStockCount(ctx context.Context, countID int64) (*entity.StockCount, error)
+	ApproveStockCount(ctx context.Context, input ApproveStockCountInput) (*entity.StockCount, error)
+	CreateSupplier(ctx context.Context, input CreateSupplierInput) (*entity.Supplier, error)
+	ShowSuppliers(ctx context.Context) ([]entity.Supplier, error)
+	CreatePurchaseOrder(ctx context.Context, input CreatePurchaseOrderInput) (*entity.PurchaseOrder, error)
+	ShowPurchaseOrders(ctx context.Context, input ShowPurchaseOrdersInput) ([]entity.PurchaseOrder, error)
+	GetPurchaseOrder(ctx context.Context, orderID int64) (*entity.PurchaseOrder, error)
+	ReceivePurchaseOrder(ctx context.Context, input ReceivePurchaseOrderInput) (*entity.PurchaseOrder, error)
+	CancelPurchaseOrder(ctx context.Context, orderID int64) (*entity.PurchaseOrder, error)
+	CreatePromotion(ctx context.Context, input CreatePromotionInput) (*entity.Promotion, error)
+	ShowPromotions(ctx context.Context) ([]entity.Promotion, error)
+	// background jobs
+	ExpireAbandonedCarts(ctx context.Context, ttl time.Duration) (int, error)
+	ReconcileStocks(ctx context.Context) ([]entity.StockDrift, error)
 	// for testing
 	ClearDatabase(ctx context.Context) error
 }
 
 type Storage interface {
 	GetGoods(ctx context.Context, input GetGoodsInput) ([]entity.Goods, error)
+	CountGoods(ctx context.Context, input GetGoodsInput) (int, error)
+	GetGoodsByID(ctx context.Context, goodsID int) (*entity.Goods, error)
 	GetExistingShoppingCart(ctx context.Context, shoppingCartID int64) (*entity.ShoppingCart, error)
 	AddGoodToCart(ctx context.Context, shoppingCart *entity.ShoppingCart) (*entity.ShoppingCart, error)
+	SetCartGoodsQuantity(ctx context.Context, input SetCartGoodsQuantityInput) (*entity.ShoppingCart, error)
+	ClearShoppingCart(ctx context.Context, shoppingCartID int64) (*entity.ShoppingCart, error)
+	DeleteShoppingCart(ctx context.Context, shoppingCartID int64) error
+	ExpireShoppingCarts(ctx context.Context, input ExpireShoppingCartsInput) (int, error)
+	GetTransaction(ctx context.Context, transactionID int64) (*entity.Transaction, error)
+	GetTransactionByIdempotencyKey(ctx context.Context, idempotencyKey string) (*entity.Transaction, error)
 	CreateTransaction(ctx context.Context, input CreateTransactionInput) (*entity.Transaction, error)
+	GetTransactions(ctx context.Context, input GetTransactionsInput) ([]entity.Transaction, error)
+	CreatePayment(ctx context.Context, payment entity.Payment) (*entity.Payment, error)
+	GetPayment(ctx context.Context, reference string) (*entity.Payment, error)
+	GetTransactionStatusHistory(ctx context.Context, transactionID int64) ([]entity.TransactionStatusChange, error)
+	UpdateTransactionStatus(ctx context.Context, input UpdateTransactionStatusInput) (*entity.Transaction, error)
+	CreateRefund(ctx context.Context, input CreateRefundInput) (*entity.Refund, error)
+	GetSalesSummary(ctx context.Context, input GetSalesSummaryInput) (*entity.SalesSummary, error)
+	GetTaxRecap(ctx context.Context, input GetTaxRecapInput) (*entity.TaxRecap, error)
+	UpdateGoodsStock(ctx context.Context, input UpdateGoodsStockInput) (*entity.Goods, error)
+	CreateGoods(ctx context.Context, goods entity.Goods) (*entity.Goods, error)
+	UpdateGoods(ctx context.Context, goods entity.Goods) (*entity.Goods, error)
+	DeleteGoods(ctx context.Context, goodsID int, deletedAt int64) error
+	CreateCategory(ctx context.Context, category entity.Category) (*entity.Category, error)
+	GetCategories(ctx context.Context) ([]entity.Category, error)
+	CreateGoodsVariant(ctx context.Context, variant entity.GoodsVariant) (*entity.GoodsVariant, error)
+	GetMenuGoods(ctx context.Context) ([]entity.Goods, error)
+	SetRecipe(ctx context.Context, recipe entity.Recipe) (*entity.Recipe, error)
+	GetStockMovements(ctx context.Context, input GetStockMovementsInput) ([]entity.StockMovement, error)
+	GetStockDrifts(ctx context.Context) ([]entity.StockDrift, error)
+	GetLowStocks(ctx context.Context) ([]entity.LowStock, error)
+	GetExpiringStockBatches(ctx context.Context, input GetExpiringStockBatchesInput) ([]entity.StockBatch, error)
+	WriteOffExpiredStockBatches(ctx context.Context, input WriteOffStockBatchesInput) ([]entity.StockMovement, error)
+	CreateStockCount(ctx context.Context, stockCount entity.StockCount) (*entity.StockCount, error)
+	SetStockCountItems(ctx context.Context, input SetStockCountItemsInput) (*entity.StockCount, error)
+	GetStockCount(ctx context.Context, countID int64) (*entity.StockCount, error)
+	ApplyStockCount(ctx context.Context, input ApplyStockCountInput) (*entity.StockCount, error)
+	CreateSupplier(ctx context.Context, supplier entity.Supplier) (*entity.Supplier, error)
+	GetSuppliers(ctx context.Context) ([]entity.Supplier, error)
+	CreatePurchaseOrder(ctx context.Context, purchaseOrder entity.PurchaseOrder) (*entity.PurchaseOrder, error)
+	GetPurchaseOrders(ctx context.Context, input GetPurchaseOrdersInput) ([]entity.PurchaseOrder, error)
+	GetPurchaseOrder(ctx context.Context, orderID int64) (*entity.PurchaseOrder, error)
+	ReceivePurchaseOrder(ctx context.Context, receipt entity.PurchaseOrderReceipt) (*entity.PurchaseOrder, error)
+	CancelPurchaseOrder(ctx context.Context, input CancelPurchaseOrderInput) (*entity.PurchaseOrder, error)
+	CreatePromotion(ctx context.Context, promotion entity.Promotion) (*entity.Promotion, error)
+	GetPromotions(ctx context.Context, input GetPromotionsInput) ([]entity.Promotion, error)
+	// CreateDelivery return ErrDeliveryAlreadyRequested when the transaction already has delivery, including
+	// the pending one
+	CreateDelivery(ctx context.Context, delivery entity.Delivery) error
+	// UpdateDelivery set the courier pickup of the pending delivery
+	UpdateDelivery(ctx context.Context, delivery entity.Delivery) error
+	// DeletePendingDelivery remove the pending delivery, so the pickup can be requested again
+	DeletePendingDelivery(ctx context.Context, transactionID int64) error
+	// TruncateAllData remove all transactions, restore the stocks moved by them, then start the stock ledger and
+	// batches over from the current stocks as opening balance
 	TruncateAllData(ctx context.Context) error
 }
 
 type SupportService interface {
-	CalculateDeliveryPrice(ctx context.Context) (float64, error)
-	PickupDelivery(ctx context.Context) (bool, error)
+	CalculateDeliveryPrice(ctx context.Context, input CalculateDeliveryPriceInput) (*DeliveryQuote, error)
+	PickupDelivery(ctx context.Context, input PickupDeliveryInput) (*PickupDeliveryOutput, error)
+}
+
+// Notifier send the alerts of the shop, e.g. into webhook of the shop owner chat app
+type Notifier interface {
+	NotifyLowStock(ctx context.Context, lowStock entity.LowStock) error
+}
+
+// AlertDispatcher run the alert checks in the background, e.g. the reorder points after the stocks decreased
+type AlertDispatcher interface {
+	Dispatch(check func(ctx context.Context))
+}
+
+// PaymentProvider issue cashless payment e.g. QRIS, the buyer pay it outside of the shop then the provider
+// confirm it through the callback
+type PaymentProvider interface {
+	CreatePayment(ctx context.Context, input CreatePaymentInput) (*CreatePaymentOutput, error)
+	// ParseCallback verify the signature of the callback then decode its payload, ErrInvalidSignature is
+	// returned when the callback is not sent by the provider
+	ParseCallback(payload []byte, signature string) (*PaymentCallback, error)
 }
 
 type service struct {
-	storage        Storage
-	supportService SupportService
+	storage         Storage
+	supportService  SupportService
+	notifier        Notifier
+	alertDispatcher AlertDispatcher
+	paymentProvider PaymentProvider
+	shopLocation    int
+	taxRates        entity.TaxRates
 }
 
 type ServiceConfig struct {
 	Storage        Storage        `validate:"nonnil"`
 	SupportService SupportService `validate:"nonnil"`
+	Notifier       Notifier       `validate:"nonnil"`
+	// AlertDispatcher run the checks which notify the alerts after the request is done
+	AlertDispatcher AlertDispatcher `validate:"nonnil"`
+	// PaymentProvider is optional, only cash register payment is accepted without it
+	PaymentProvider PaymentProvider
+	// ShopLocation is regency / city code of the shop, used as origin of the delivery
+	ShopLocation int
+	// TaxRate is the PPN percentage, zero means the shop doesn't collect PPN
+	TaxRate float64
+	// ServiceChargeRate is the service charge percentage, zero means there's no service charge
+	ServiceChargeRate float64
 }
 
 func NewService(config ServiceConfig) (Service, error) {
@@ -49,36 +182,93 @@ func NewService(config ServiceConfig) (Service, error) {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
+	if !IsValidLocation(config.ShopLocation) {
+		return nil, fmt.Errorf("invalid config: shop location must be 4 digits regency / city code")
+	}
+	if config.TaxRate < 0 || config.ServiceChargeRate < 0 {
+		return nil, fmt.Errorf("invalid config: tax rate and service charge rate must not be negative")
+	}
+
 	return &service{
-		storage:        config.Storage,
-		supportService: config.SupportService,
+		storage:         config.Storage,
+		supportService:  config.SupportService,
+		notifier:        config.Notifier,
+		alertDispatcher: config.AlertDispatcher,
+		paymentProvider: config.PaymentProvider,
+		shopLocation:    config.ShopLocation,
+		taxRates: entity.TaxRates{
+			TaxRate:           config.TaxRate,
+			ServiceChargeRate: config.ServiceChargeRate,
+		},
 	}, nil
 }
 
-func (s *service) ShowListOfGoods(ctx context.Context, input ShowListOfGoodsInput) ([]entity.Goods, error) {
-	goods, err := s.storage.GetGoods(ctx, input.ToGetGoodsStorageInput())
+func (s *service) ShowListOfGoods(ctx context.Context, input ShowListOfGoodsInput) (*ShowListOfGoodsOutput, error) {
+	storageInput, err := input.ToGetGoodsStorageInput()
 	if err != nil {
 		return nil, fmt.Errorf("unable to get list of goods due: %w", err)
 	}
 
-	return goods, nil
+	if input.CursorPaging {
+		// fetch one more goods to find out whether there is next or previous page without counting the catalog
+		limit := storageInput.Limit
+		storageInput.Limit++
+		goods, err := s.storage.GetGoods(ctx, storageInput)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get list of goods from storage due: %w", err)
+		}
+		pagination := Pagination{
+			Limit:   limit,
+			HasNext: storageInput.Before != nil,
+			HasPrev: storageInput.After != nil,
+		}
+		if len(goods) > limit {
+			// the extra goods is at the opposite end of the paging direction
+			if storageInput.Before != nil {
+				goods = goods[1:]
+				pagination.HasPrev = true
+			} else {
+				goods = goods[:limit]
+				pagination.HasNext = true
+			}
+		}
+		if len(goods) > 0 && pagination.HasNext {
+			nextCursor := NewGoodsCursor(goods[len(goods)-1], storageInput.Sort, storageInput.SortBy)
+			pagination.NextCursor = nextCursor.Encode()
+		}
+		if len(goods) > 0 && pagination.HasPrev {
+			prevCursor := NewGoodsCursor(goods[0], storageInput.Sort, storageInput.SortBy)
+			prevCursor.Backward = true
+			pagination.PrevCursor = prevCursor.Encode()
+		}
+		return &ShowListOfGoodsOutput{Goods: goods, Pagination: pagination}, nil
+	}
+
+	goods, err := s.storage.GetGoods(ctx, storageInput)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get list of goods from storage due: %w", err)
+	}
+	totalGoods, err := s.storage.CountGoods(ctx, storageInput)
+	if err != nil {
+		return nil, fmt.Errorf("unable to count goods from storage due: %w", err)
+	}
+
+	return &ShowListOfGoodsOutput{
+		Goods:      goods,
+		Pagination: NewPagination(storageInput.Offset/storageInput.Limit+1, storageInput.Limit, totalGoods),
+	}, nil
 }
 
 func (s *service) AddToCart(ctx context.Context, input AddToCartInput) (*AddToCartOutput, error) {
 	// if there's shopping cart ID in the input, then just get it and update the existing cart
-	var shoppingCart entity.ShoppingCart
+	var shoppingCart *entity.ShoppingCart
 	switch input.CartID > 0 {
 	case true:
-		existShoppingCart, err := s.storage.GetExistingShoppingCart(ctx, input.CartID)
+		existShoppingCart, err := s.getExistingCart(ctx, input.CartID)
 		if err != nil {
 			return nil, fmt.Errorf("unable to add goods to shopping cart due: %w", err)
 		}
-		existShoppingCart.AddGoods(entity.AddGoodsInput{
-			GoodsID:    input.GoodsID,
-			GoodsPrice: input.GoodsPrice,
-			TotalGoods: input.Total,
-		})
-		shoppingCart = *existShoppingCart
+		shoppingCart = existShoppingCart
 	default:
 		newShoppingCart, err := entity.NewShoppingCart(entity.ShoppingCartConfig{
 			UserID: input.UserID,
@@ -86,49 +276,1187 @@ func (s *service) AddToCart(ctx context.Context, input AddToCartInput) (*AddToCa
 		if err != nil {
 			return nil, fmt.Errorf("unable to add goods to shopping cart due: %w", err)
 		}
-		newShoppingCart.AddGoods(entity.AddGoodsInput{
-			GoodsID:    input.GoodsID,
-			GoodsPrice: input.GoodsPrice,
-			TotalGoods: input.Total,
-		})
-		shoppingCart = *newShoppingCart
+		shoppingCart = newShoppingCart
 	}
 
-	simpleCart, err := s.storage.AddGoodToCart(ctx, &shoppingCart)
+	// the goods price always taken from storage, price sent by client only used for verification
+	goods, err := s.storage.GetGoodsByID(ctx, input.GoodsID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to add goods to shopping cart due: %w", err)
+	}
+	if goods.IsDeleted() {
+		return nil, fmt.Errorf("unable to add goods to shopping cart due: %w", ErrGoodsNotFound)
+	}
+	if goods.IsRawMaterial {
+		return nil, fmt.Errorf("unable to add goods to shopping cart due: %w: raw material %s is not sold", ErrInvalidInput, goods.Name)
+	}
+	goodsPrice, err := s.getCartGoodsPrice(goods, input.VariantID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to add goods to shopping cart due: %w", err)
+	}
+	// goods made to order reserve its raw materials, the servings is checked first so the buyer is told about
+	// the goods instead of its raw materials. The servings already exclude the raw materials reserved by any
+	// shopping cart including this one.
+	if goods.IsMadeToOrder() && input.Total > goods.Recipe.Servings {
+		return nil, fmt.Errorf(
+			"unable to add goods to shopping cart due: %w",
+			entity.InsufficientStockError{GoodsID: goods.ID, Stocks: goods.Recipe.Servings, Requested: input.Total},
+		)
+	}
+	if input.GoodsPrice > 0 && input.GoodsPrice != goodsPrice {
+		return nil, fmt.Errorf(
+			"unable to add goods to shopping cart due: %w (submitted %v, current %v)",
+			ErrPriceMismatch,
+			input.GoodsPrice,
+			goodsPrice,
+		)
+	}
+
+	// storage only need the newly added goods, it will reserve the stocks for them
+	addGoodsInput := entity.AddGoodsInput{
+		GoodsID:          goods.ID,
+		VariantID:        input.VariantID,
+		GoodsPrice:       goodsPrice,
+		TotalGoods:       input.Total,
+		PriceIncludesTax: goods.PriceIncludesTax,
+	}
+	addedGoodsCart := &entity.ShoppingCart{
+		ID:     shoppingCart.ID,
+		UserID: shoppingCart.UserID,
+	}
+	if err := addedGoodsCart.AddGoods(addGoodsInput); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	shoppingCart.AddGoods(addGoodsInput)
+
+	// the voucher is checked before the stocks reserved, the discount may still be zero until the cart
+	// reach the minimum purchase of the voucher
+	if len(input.VoucherCode) > 0 {
+		shoppingCart.VoucherCode = entity.NormalizeVoucherCode(input.VoucherCode)
+		addedGoodsCart.VoucherCode = shoppingCart.VoucherCode
+	}
+	voucherApplicable, err := s.applyPromotions(ctx, shoppingCart)
+	if err != nil {
+		return nil, fmt.Errorf("unable to add goods to shopping cart due: %w", err)
+	}
+	if !voucherApplicable {
+		if len(input.VoucherCode) > 0 {
+			return nil, fmt.Errorf("unable to add goods to shopping cart due: %w", ErrVoucherNotApplicable)
+		}
+		// voucher of the existing cart could expire in the meantime, it's just not applied anymore
+		shoppingCart.VoucherCode = ""
+		shoppingCart.ApplyPromotions(shoppingCart.Promotions)
+	}
+
+	simpleCart, err := s.storage.AddGoodToCart(ctx, addedGoodsCart)
 	if err != nil {
 		return nil, fmt.Errorf("unable to store shopping cart info to storage due: %w", err)
 	}
 
 	return &AddToCartOutput{
-		CartID:      simpleCart.ID,
-		UserID:      simpleCart.UserID,
-		TotalGoods:  shoppingCart.GetTotalGoods(),
-		TotalAmount: simpleCart.TotalAmount,
+		CartID:         simpleCart.ID,
+		UserID:         simpleCart.UserID,
+		TotalGoods:     shoppingCart.GetTotalGoods(),
+		SubtotalAmount: simpleCart.TotalAmount,
+		TotalAmount:    simpleCart.TotalAmount - shoppingCart.GetDiscountAmount(),
+		Discounts:      shoppingCart.Discounts,
+		Bill:           shoppingCart.GetBill(),
 	}, nil
 }
 
+// applyPromotions apply the active automatic promotions and the voucher of the shopping cart into its total amount
+// along with the tax rates of the shop, it tells whether the voucher of the shopping cart is still active. Shopping cart without voucher is always
+// applicable.
+func (s *service) applyPromotions(ctx context.Context, cart *entity.ShoppingCart) (bool, error) {
+	return s.applyPromotionsAt(ctx, cart, time.Now())
+}
+
+// applyPromotionsAt apply the promotions which active at the given time, see applyPromotions
+func (s *service) applyPromotionsAt(ctx context.Context, cart *entity.ShoppingCart, now time.Time) (bool, error) {
+	promotions, err := s.storage.GetPromotions(ctx, GetPromotionsInput{
+		ActiveAt:    now.Unix(),
+		VoucherCode: cart.VoucherCode,
+	})
+	if err != nil {
+		return false, fmt.Errorf("unable to get promotions from storage due: %w", err)
+	}
+
+	voucherApplicable := len(cart.VoucherCode) == 0
+	activePromotions := []entity.Promotion{}
+	for _, promotion := range promotions {
+		if !promotion.IsActiveAt(now) {
+			continue
+		}
+		if len(promotion.VoucherCode) > 0 {
+			if promotion.VoucherCode != cart.VoucherCode {
+				continue
+			}
+			voucherApplicable = true
+		}
+		activePromotions = append(activePromotions, promotion)
+	}
+	cart.TaxRates = s.taxRates
+	cart.ApplyPromotions(activePromotions)
+
+	return voucherApplicable, nil
+}
+
+// withPromotions apply the promotions into shopping cart returned by the storage, voucher which no longer
+// active is dropped from the cart
+func (s *service) withPromotions(ctx context.Context, cart *entity.ShoppingCart) (*entity.ShoppingCart, error) {
+	voucherApplicable, err := s.applyPromotions(ctx, cart)
+	if err != nil {
+		return nil, err
+	}
+	if !voucherApplicable {
+		cart.VoucherCode = ""
+	}
+
+	return cart, nil
+}
+
+// getCartGoodsPrice get the selling price of the goods, goods which has variants must be sold through one of them
+func (s *service) getCartGoodsPrice(goods *entity.Goods, variantID int) (entity.Money, error) {
+	switch {
+	case goods.HasVariants() && variantID <= 0:
+		return 0, fmt.Errorf("%w: variant of goods %s must be chosen", ErrInvalidInput, goods.Name)
+	case !goods.HasVariants() && variantID > 0:
+		return 0, ErrVariantNotFound
+	case !goods.HasVariants():
+		return goods.Price, nil
+	}
+
+	variant, ok := goods.FindVariant(variantID)
+	if !ok {
+		return 0, ErrVariantNotFound
+	}
+	return variant.Price(goods.Price), nil
+}
+
+func (s *service) AbandonCart(ctx context.Context, cartID int64) error {
+	if err := s.storage.DeleteShoppingCart(ctx, cartID); err != nil {
+		return fmt.Errorf("unable to abandon shopping cart due: %w", err)
+	}
+
+	return nil
+}
+
+func (s *service) GetCart(ctx context.Context, cartID int64) (*entity.ShoppingCart, error) {
+	cart, err := s.getExistingCart(ctx, cartID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get shopping cart due: %w", err)
+	}
+	if cart, err = s.withPromotions(ctx, cart); err != nil {
+		return nil, fmt.Errorf("unable to get shopping cart due: %w", err)
+	}
+
+	return cart, nil
+}
+
+func (s *service) UpdateCartGoods(ctx context.Context, input UpdateCartGoodsInput) (*entity.ShoppingCart, error) {
+	cart, err := s.getExistingCart(ctx, input.CartID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to update goods in shopping cart due: %w", err)
+	}
+	if err = cart.UpdateGoodsQuantity(input.GoodsID, input.VariantID, input.Total); err != nil {
+		if !errors.Is(err, entity.ErrGoodsNotInCart) {
+			err = fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		return nil, fmt.Errorf("unable to update goods in shopping cart due: %w", err)
+	}
+
+	updatedCart, err := s.storage.SetCartGoodsQuantity(ctx, SetCartGoodsQuantityInput(input))
+	if err != nil {
+		return nil, fmt.Errorf("unable to update goods in shopping cart due: %w", err)
+	}
+	if updatedCart, err = s.withPromotions(ctx, updatedCart); err != nil {
+		return nil, fmt.Errorf("unable to update goods in shopping cart due: %w", err)
+	}
+
+	return updatedCart, nil
+}
+
+func (s *service) RemoveGoodsFromCart(ctx context.Context, cartID int64, goodsID int, variantID int) (*entity.ShoppingCart, error) {
+	cart, err := s.getExistingCart(ctx, cartID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to remove goods from shopping cart due: %w", err)
+	}
+	if err = cart.RemoveGoods(goodsID, variantID); err != nil {
+		return nil, fmt.Errorf("unable to remove goods from shopping cart due: %w", err)
+	}
+
+	updatedCart, err := s.storage.SetCartGoodsQuantity(ctx, SetCartGoodsQuantityInput{
+		CartID:    cartID,
+		GoodsID:   goodsID,
+		VariantID: variantID,
+		Total:     0,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to remove goods from shopping cart due: %w", err)
+	}
+	if updatedCart, err = s.withPromotions(ctx, updatedCart); err != nil {
+		return nil, fmt.Errorf("unable to remove goods from shopping cart due: %w", err)
+	}
+
+	return updatedCart, nil
+}
+
+func (s *service) ClearCart(ctx context.Context, cartID int64) (*entity.ShoppingCart, error) {
+	cart, err := s.storage.ClearShoppingCart(ctx, cartID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to clear shopping cart due: %w", err)
+	}
+
+	return cart, nil
+}
+
+// getExistingCart get unpaid shopping cart, return ErrCartNotFound when there's no such cart
+func (s *service) getExistingCart(ctx context.Context, cartID int64) (*entity.ShoppingCart, error) {
+	cart, err := s.storage.GetExistingShoppingCart(ctx, cartID)
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil {
+		return nil, ErrCartNotFound
+	}
+
+	return cart, nil
+}
+
 func (s *service) Pay(ctx context.Context, input PayInput) (*entity.Transaction, error) {
-	currTrx, err := s.storage.CreateTransaction(ctx, CreateTransactionInput(input))
+	return s.pay(ctx, input, time.Now(), "")
+}
+
+// pay the shopping cart with the promotions which active at the given time, the payment reference is set when
+// the shopping cart is paid through the payment provider
+func (s *service) pay(ctx context.Context, input PayInput, promotionsAt time.Time, paymentReference string) (*entity.Transaction, error) {
+	tenders, err := input.GetTenders()
 	if err != nil {
 		return nil, fmt.Errorf("unable to pay the goods in shopping cart due: %w", err)
 	}
-	currTrx.SetPaymentAndReturnAmount(input.PaymentAmount)
 
-	return currTrx, nil
+	// retried payment with the same idempotency key just get the original receipt
+	if len(input.IdempotencyKey) > 0 {
+		paidTrx, err := s.getPaidTransactionByIdempotencyKey(ctx, input)
+		if err != nil || paidTrx != nil {
+			return paidTrx, err
+		}
+	}
+
+	_, cart, err := s.getPayableCart(ctx, input.CartID, input.VoucherCode, promotionsAt)
+	if err != nil {
+		return s.handlePayError(ctx, input, err)
+	}
+	bill := cart.GetBill()
+
+	// the payment is checked against the total amount by the storage while the shopping cart is locked
+	paidTrx, err := s.storage.CreateTransaction(ctx, CreateTransactionInput{
+		CartID:           input.CartID,
+		Tenders:          tenders,
+		IdempotencyKey:   input.IdempotencyKey,
+		PaymentReference: paymentReference,
+		Bill:             bill,
+		TaxRates:         cart.TaxRates,
+		VoucherCode:      cart.VoucherCode,
+		Discounts:        cart.Discounts,
+	})
+	if err != nil {
+		return s.handlePayError(ctx, input, err)
+	}
+	if err = paidTrx.SetTenders(tenders); err != nil {
+		return nil, fmt.Errorf("unable to pay the goods in shopping cart due: %w", err)
+	}
+	s.alertDispatcher.Dispatch(func(ctx context.Context) {
+		s.checkSoldGoodsReorderPoints(ctx, paidTrx.ID)
+	})
+
+	return paidTrx, nil
 }
 
-func (s *service) ReqCalculateDeliveryPrice(ctx context.Context, input ReqCalculateDeliveryPriceInput) error {
-	return nil
+// getPayableCart get the unpaid transaction of the shopping cart, its total amount is the grand total of the bill.
+// The discounts is computed again from the promotions which active at the given time, voucher sent along with
+// the payment must be applicable while voucher kept by the shopping cart is dropped when it's no longer active.
+func (s *service) getPayableCart(ctx context.Context, cartID int64, voucherCode string, promotionsAt time.Time) (*entity.Transaction, *entity.ShoppingCart, error) {
+	currTrx, err := s.storage.GetTransaction(ctx, cartID)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case currTrx.Status == entity.TransactionStatusExpired:
+		return nil, nil, ErrCartExpired
+	case currTrx.Status != entity.TransactionStatusCart:
+		return nil, nil, ErrCartAlreadyPaid
+	case currTrx.TotalGoods == 0:
+		return nil, nil, ErrEmptyCart
+	}
+
+	cart, err := s.storage.GetExistingShoppingCart(ctx, cartID)
+	if err == nil && cart == nil {
+		// concurrent payment already pay the shopping cart after its status checked
+		err = ErrCartAlreadyPaid
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(voucherCode) > 0 {
+		cart.VoucherCode = entity.NormalizeVoucherCode(voucherCode)
+	}
+	voucherApplicable, err := s.applyPromotionsAt(ctx, cart, promotionsAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !voucherApplicable {
+		if len(voucherCode) > 0 {
+			return nil, nil, ErrVoucherNotApplicable
+		}
+		cart.VoucherCode = ""
+	}
+	currTrx.TotalAmount = cart.GetBill().GrandTotalAmount
+
+	return currTrx, cart, nil
+}
+
+// RequestPayment issue cashless payment of the shopping cart through the payment provider, the shopping cart
+// stays unpaid until the provider confirm the payment
+func (s *service) RequestPayment(ctx context.Context, input RequestPaymentInput) (*entity.Payment, error) {
+	if s.paymentProvider == nil {
+		return nil, fmt.Errorf("unable to request payment due: %w", ErrPaymentUnavailable)
+	}
+	method, err := input.GetMethod()
+	if err != nil {
+		return nil, fmt.Errorf("unable to request payment due: %w", err)
+	}
+
+	now := time.Now()
+	currTrx, cart, err := s.getPayableCart(ctx, input.CartID, input.VoucherCode, now)
+	if err != nil {
+		return nil, fmt.Errorf("unable to request payment due: %w", err)
+	}
+	output, err := s.paymentProvider.CreatePayment(ctx, CreatePaymentInput{
+		TransactionID: currTrx.ID,
+		Method:        method,
+		Amount:        currTrx.TotalAmount,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create payment in payment provider due: %w", err)
+	}
+
+	payment, err := s.storage.CreatePayment(ctx, entity.Payment{
+		Reference:     output.Reference,
+		TransactionID: currTrx.ID,
+		Method:        method,
+		Amount:        currTrx.TotalAmount,
+		Status:        entity.PaymentStatusPending,
+		QRPayload:     output.QRPayload,
+		VoucherCode:   cart.VoucherCode,
+		CreatedAt:     now.Unix(),
+		ExpiresAt:     output.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to store payment due: %w", err)
+	}
+
+	return payment, nil
+}
+
+func (s *service) GetPayment(ctx context.Context, reference string) (*entity.Payment, error) {
+	payment, err := s.storage.GetPayment(ctx, reference)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get payment due: %w", err)
+	}
+
+	return payment, nil
 }
 
-func (s *service) ReqPickupDelivery(ctx context.Context, transactionID int) error {
+// ConfirmPayment pay the shopping cart of the payment once the payment provider confirm the buyer paid it.
+// The provider could deliver the same callback more than once, the payment reference is used as the idempotency
+// key so the retried callback just get the paid transaction.
+func (s *service) ConfirmPayment(ctx context.Context, input ConfirmPaymentInput) (*entity.Transaction, error) {
+	if s.paymentProvider == nil {
+		return nil, fmt.Errorf("unable to confirm payment due: %w", ErrPaymentUnavailable)
+	}
+	callback, err := s.paymentProvider.ParseCallback(input.Payload, input.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("unable to confirm payment due: %w", err)
+	}
+	payment, err := s.storage.GetPayment(ctx, callback.Reference)
+	if err != nil {
+		return nil, fmt.Errorf("unable to confirm payment due: %w", err)
+	}
+	switch {
+	case callback.Status != entity.PaymentStatusPaid:
+		return nil, fmt.Errorf("unable to confirm payment due: %w: payment status is %s", ErrInvalidInput, callback.Status)
+	case callback.Amount != payment.Amount:
+		return nil, fmt.Errorf(
+			"unable to confirm payment due: %w: paid amount %v is different from the payment amount %v",
+			ErrInvalidInput,
+			callback.Amount,
+			payment.Amount,
+		)
+	}
+
+	return s.pay(
+		ctx,
+		PayInput{
+			CartID:         payment.TransactionID,
+			Tenders:        []entity.Tender{{Method: payment.Method, Amount: payment.Amount}},
+			IdempotencyKey: payment.Reference,
+			VoucherCode:    payment.VoucherCode,
+		},
+		time.Unix(payment.CreatedAt, 0),
+		payment.Reference,
+	)
+}
+
+// handlePayError return the original receipt when the error is caused by concurrent retry with the same
+// idempotency key which already pay the shopping cart
+func (s *service) handlePayError(ctx context.Context, input PayInput, err error) (*entity.Transaction, error) {
+	if errors.Is(err, ErrCartAlreadyPaid) && len(input.IdempotencyKey) > 0 {
+		paidTrx, lookupErr := s.getPaidTransactionByIdempotencyKey(ctx, input)
+		if lookupErr == nil && paidTrx != nil {
+			return paidTrx, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to pay the goods in shopping cart due: %w", err)
+}
+
+// getPaidTransactionByIdempotencyKey get transaction which already paid using the idempotency key of the input,
+// return nil when there's no such transaction
+func (s *service) getPaidTransactionByIdempotencyKey(ctx context.Context, input PayInput) (*entity.Transaction, error) {
+	paidTrx, err := s.storage.GetTransactionByIdempotencyKey(ctx, input.IdempotencyKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get transaction by idempotency key due: %w", err)
+	}
+	if paidTrx == nil {
+		return nil, nil
+	}
+	if paidTrx.ID != input.CartID {
+		return nil, fmt.Errorf("unable to pay the goods in shopping cart due: %w", ErrIdempotencyKeyReused)
+	}
+
+	return paidTrx, nil
+}
+
+func (s *service) ShowListOfOrders(ctx context.Context, input ShowListOfOrdersInput) ([]entity.Transaction, error) {
+	storageInput, err := input.ToGetTransactionsStorageInput()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get list of orders due: %w", err)
+	}
+	orders, err := s.storage.GetTransactions(ctx, storageInput)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get list of orders from storage due: %w", err)
+	}
+
+	return orders, nil
+}
+
+func (s *service) GetOrder(ctx context.Context, transactionID int64) (*entity.Transaction, error) {
+	order, err := s.storage.GetTransaction(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get order due: %w", err)
+	}
+	order.StatusHistory, err = s.storage.GetTransactionStatusHistory(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get order status history due: %w", err)
+	}
+
+	return order, nil
+}
+
+func (s *service) UpdateOrderStatus(ctx context.Context, input UpdateOrderStatusInput) (*entity.Transaction, error) {
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("unable to update order status due: %w", err)
+	}
+
+	// check the transition early, storage check it again on the locked order
+	order, err := s.storage.GetTransaction(ctx, input.TransactionID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to update order status due: %w", err)
+	}
+	if err = order.TransitionTo(input.Status); err != nil {
+		return nil, fmt.Errorf("unable to update order status due: %w", err)
+	}
+
+	order, err = s.storage.UpdateTransactionStatus(ctx, UpdateTransactionStatusInput{
+		TransactionID: input.TransactionID,
+		Status:        input.Status,
+		ChangedAt:     time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to update order status due: %w", err)
+	}
+
+	return order, nil
+}
+
+// RefundOrder refund the returned goods of paid order and put them back into the stocks
+func (s *service) RefundOrder(ctx context.Context, input RefundOrderInput) (*entity.Refund, error) {
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("unable to refund order due: %w", err)
+	}
+	if err := s.checkRefundableOrder(ctx, input.TransactionID, entity.TransactionStatusRefunded); err != nil {
+		return nil, fmt.Errorf("unable to refund order due: %w", err)
+	}
+
+	refund, err := s.storage.CreateRefund(ctx, input.ToCreateRefundStorageInput(entity.TransactionStatusRefunded))
+	if err != nil {
+		return nil, fmt.Errorf("unable to refund order due: %w", err)
+	}
+
+	return refund, nil
+}
+
+// CancelOrder cancel paid order which not handed over yet, all of its goods refunded
+func (s *service) CancelOrder(ctx context.Context, transactionID int64, reason string) (*entity.Refund, error) {
+	input := RefundOrderInput{
+		TransactionID: transactionID,
+		Reason:        reason,
+	}
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("unable to cancel order due: %w", err)
+	}
+	if err := s.checkRefundableOrder(ctx, transactionID, entity.TransactionStatusCancelled); err != nil {
+		return nil, fmt.Errorf("unable to cancel order due: %w", err)
+	}
+
+	refund, err := s.storage.CreateRefund(ctx, input.ToCreateRefundStorageInput(entity.TransactionStatusCancelled))
+	if err != nil {
+		return nil, fmt.Errorf("unable to cancel order due: %w", err)
+	}
+
+	return refund, nil
+}
+
+// checkRefundableOrder check early whether the order can be moved into the refund status,
+// storage check it again on the locked order
+func (s *service) checkRefundableOrder(ctx context.Context, transactionID int64, status entity.TransactionStatus) error {
+	order, err := s.storage.GetTransaction(ctx, transactionID)
+	if err != nil {
+		return err
+	}
+	if order.Status == entity.TransactionStatusCart || order.Status == entity.TransactionStatusExpired {
+		return ErrTransactionNotPaid
+	}
+	if !order.Status.CanTransitionTo(status) {
+		return entity.InvalidStatusTransitionError{
+			From: order.Status,
+			To:   status,
+		}
+	}
 	return nil
 }
 
-func (s *service) UpdateStock(ctx context.Context, input UpdateStockInput) error {
+func (s *service) GetDailySalesReport(ctx context.Context, input GetDailySalesReportInput) (*entity.SalesSummary, error) {
+	year, month, day := input.Date.In(time.Local).Date()
+	startOfDay := time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+
+	summary, err := s.storage.GetSalesSummary(ctx, GetSalesSummaryInput{
+		From: startOfDay.Unix(),
+		To:   startOfDay.AddDate(0, 0, 1).Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get daily sales report due: %w", err)
+	}
+
+	return summary, nil
+}
+
+func (s *service) GetMonthlyTaxRecap(ctx context.Context, input GetMonthlyTaxRecapInput) (*entity.TaxRecap, error) {
+	year, month, _ := input.Month.In(time.Local).Date()
+	startOfMonth := time.Date(year, month, 1, 0, 0, 0, 0, time.Local)
+
+	recap, err := s.storage.GetTaxRecap(ctx, GetTaxRecapInput{
+		From: startOfMonth.Unix(),
+		To:   startOfMonth.AddDate(0, 1, 0).Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get monthly tax recap due: %w", err)
+	}
+
+	return recap, nil
+}
+
+func (s *service) ReqCalculateDeliveryPrice(ctx context.Context, input ReqCalculateDeliveryPriceInput) (*DeliveryQuote, error) {
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("unable to calculate delivery price due: %w", err)
+	}
+
+	quote, err := s.supportService.CalculateDeliveryPrice(ctx, CalculateDeliveryPriceInput{
+		Origin:      s.shopLocation,
+		Destination: input.Location,
+		Package:     input.GoodsSpec,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to calculate delivery price due: %w", err)
+	}
+
+	return quote, nil
+}
+
+func (s *service) ReqPickupDelivery(ctx context.Context, input ReqPickupDeliveryInput) (*entity.Delivery, error) {
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("unable to request pickup delivery due: %w", err)
+	}
+
+	// only paid order which not handed over yet can be delivered, and only once
+	trx, err := s.storage.GetTransaction(ctx, input.TransactionID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to request pickup delivery due: %w", err)
+	}
+	switch {
+	case trx.Status == entity.TransactionStatusCart, trx.Status == entity.TransactionStatusExpired:
+		return nil, fmt.Errorf("unable to request pickup delivery due: %w", ErrTransactionNotPaid)
+	case !trx.Status.IsAwaitingFulfillment():
+		// shipped, completed or cancelled order can't be picked up anymore
+		return nil, fmt.Errorf("unable to request pickup delivery due: %w", entity.InvalidStatusTransitionError{
+			From: trx.Status,
+			To:   entity.TransactionStatusShipped,
+		})
+	}
+
+	// store the pending delivery before asking the courier, so concurrent requests for the same transaction
+	// can't ask the courier to pick it up twice
+	delivery := entity.Delivery{
+		TransactionID: input.TransactionID,
+		Destination:   input.Location,
+		Status:        entity.DeliveryStatusPending,
+		CreatedAt:     time.Now().Unix(),
+	}
+	if err = s.storage.CreateDelivery(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("unable to request pickup delivery due: %w", err)
+	}
+
+	pickup, err := s.supportService.PickupDelivery(ctx, PickupDeliveryInput{
+		TransactionID: input.TransactionID,
+		Origin:        s.shopLocation,
+		Destination:   input.Location,
+		Package:       input.GoodsSpec,
+	})
+	if err != nil {
+		if deleteErr := s.storage.DeletePendingDelivery(ctx, input.TransactionID); deleteErr != nil {
+			log.Printf("[ERROR] unable to remove pending delivery of transaction %d: %v", input.TransactionID, deleteErr)
+		}
+		return nil, fmt.Errorf("unable to request pickup delivery due: %w", err)
+	}
+
+	delivery.TrackingNumber = pickup.TrackingNumber
+	delivery.Price = pickup.Price
+	delivery.Status = pickup.Status
+	if err = s.storage.UpdateDelivery(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("unable to store delivery info to storage due: %w", err)
+	}
+
+	return &delivery, nil
+}
+
+func (s *service) UpdateStock(ctx context.Context, input UpdateStockInput) (*entity.Goods, error) {
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("unable to update stock due: %w", err)
+	}
+
+	goods, err := s.storage.UpdateGoodsStock(ctx, input.ToUpdateGoodsStockStorageInput())
+	if err != nil {
+		return nil, fmt.Errorf("unable to update stock due: %w", err)
+	}
+	if input.Action != IncreaseStock {
+		stocksAfter := goods.Stocks
+		if variant, ok := goods.FindVariant(input.VariantID); ok {
+			stocksAfter = variant.Stocks
+		}
+		movements := []entity.StockMovement{{
+			GoodsID:   goods.ID,
+			VariantID: input.VariantID,
+			Quantity:  -input.Total,
+			Before:    stocksAfter + input.Total,
+			After:     stocksAfter,
+		}}
+		s.alertDispatcher.Dispatch(func(ctx context.Context) {
+			s.checkReorderPoints(ctx, movements)
+		})
+	}
+
+	return goods, nil
+}
+
+// ShowStockMovements list the stock ledger of the goods within the date range, the oldest movement first
+func (s *service) ShowStockMovements(ctx context.Context, input ShowStockMovementsInput) ([]entity.StockMovement, error) {
+	storageInput, err := input.ToGetStockMovementsStorageInput()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get stock movements due: %w", err)
+	}
+
+	movements, err := s.storage.GetStockMovements(ctx, storageInput)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get stock movements due: %w", err)
+	}
+
+	return movements, nil
+}
+
+func (s *service) OpenStockCount(ctx context.Context, input OpenStockCountInput) (*entity.StockCount, error) {
+	stockCount, err := input.ToStockCountEntity()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open stock count due: %w", err)
+	}
+
+	newStockCount, err := s.storage.CreateStockCount(ctx, *stockCount)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open stock count due: %w", err)
+	}
+
+	return newStockCount, nil
+}
+
+// SubmitStockCount record the counted quantity of the goods, it could be submitted many times while the session
+// still open and the latest counted quantity of the same goods is used
+func (s *service) SubmitStockCount(ctx context.Context, input SubmitStockCountInput) (*entity.StockCount, error) {
+	storageInput, err := input.ToSetStockCountItemsStorageInput()
+	if err != nil {
+		return nil, fmt.Errorf("unable to submit stock count due: %w", err)
+	}
+
+	stockCount, err := s.storage.SetStockCountItems(ctx, storageInput)
+	if err != nil {
+		return nil, fmt.Errorf("unable to submit stock count due: %w", err)
+	}
+
+	return stockCount, nil
+}
+
+// GetStockCount get the stock count along with the variance of each counted goods
+func (s *service) GetStockCount(ctx context.Context, countID int64) (*entity.StockCount, error) {
+	stockCount, err := s.storage.GetStockCount(ctx, countID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get stock count due: %w", err)
+	}
+
+	return stockCount, nil
+}
+
+// ApproveStockCount adjust the stocks of all counted goods to their counted quantity at once, none of them
+// is adjusted when one of them failed
+func (s *service) ApproveStockCount(ctx context.Context, input ApproveStockCountInput) (*entity.StockCount, error) {
+	storageInput, err := input.ToApplyStockCountStorageInput()
+	if err != nil {
+		return nil, fmt.Errorf("unable to approve stock count due: %w", err)
+	}
+
+	stockCount, err := s.storage.ApplyStockCount(ctx, storageInput)
+	if err != nil {
+		return nil, fmt.Errorf("unable to approve stock count due: %w", err)
+	}
+	// shrinkage found by the stock count could drop the stocks to the reorder point like any other stocks out
+	adjustments := stockCount.Adjustments()
+	if len(adjustments) > 0 {
+		s.alertDispatcher.Dispatch(func(ctx context.Context) {
+			s.checkReorderPoints(ctx, adjustments)
+		})
+	}
+
+	return stockCount, nil
+}
+
+func (s *service) CreateSupplier(ctx context.Context, input CreateSupplierInput) (*entity.Supplier, error) {
+	supplier, err := input.ToSupplierEntity()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create supplier due: %w", err)
+	}
+
+	newSupplier, err := s.storage.CreateSupplier(ctx, *supplier)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create supplier due: %w", err)
+	}
+
+	return newSupplier, nil
+}
+
+func (s *service) ShowSuppliers(ctx context.Context) ([]entity.Supplier, error) {
+	suppliers, err := s.storage.GetSuppliers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get suppliers due: %w", err)
+	}
+
+	return suppliers, nil
+}
+
+func (s *service) CreatePurchaseOrder(ctx context.Context, input CreatePurchaseOrderInput) (*entity.PurchaseOrder, error) {
+	purchaseOrder, err := input.ToPurchaseOrderEntity()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create purchase order due: %w", err)
+	}
+
+	newPurchaseOrder, err := s.storage.CreatePurchaseOrder(ctx, *purchaseOrder)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create purchase order due: %w", err)
+	}
+
+	return newPurchaseOrder, nil
+}
+
+// ShowPurchaseOrders list the purchase orders without their receipts, the latest order first
+func (s *service) ShowPurchaseOrders(ctx context.Context, input ShowPurchaseOrdersInput) ([]entity.PurchaseOrder, error) {
+	storageInput, err := input.ToGetPurchaseOrdersStorageInput()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get purchase orders due: %w", err)
+	}
+
+	purchaseOrders, err := s.storage.GetPurchaseOrders(ctx, storageInput)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get purchase orders due: %w", err)
+	}
+
+	return purchaseOrders, nil
+}
+
+// GetPurchaseOrder get the purchase order along with its receipts
+func (s *service) GetPurchaseOrder(ctx context.Context, orderID int64) (*entity.PurchaseOrder, error) {
+	purchaseOrder, err := s.storage.GetPurchaseOrder(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get purchase order due: %w", err)
+	}
+
+	return purchaseOrder, nil
+}
+
+// ReceivePurchaseOrder increase the stocks of the received goods at the cost of their order, the goods could be
+// received in several times until all of the ordered quantity received
+func (s *service) ReceivePurchaseOrder(ctx context.Context, input ReceivePurchaseOrderInput) (*entity.PurchaseOrder, error) {
+	receipt, err := input.ToPurchaseOrderReceiptEntity()
+	if err != nil {
+		return nil, fmt.Errorf("unable to receive purchase order due: %w", err)
+	}
+
+	purchaseOrder, err := s.storage.ReceivePurchaseOrder(ctx, *receipt)
+	if err != nil {
+		return nil, fmt.Errorf("unable to receive purchase order due: %w", err)
+	}
+
+	return purchaseOrder, nil
+}
+
+// CancelPurchaseOrder close the purchase order so the remaining goods is not received anymore
+func (s *service) CancelPurchaseOrder(ctx context.Context, orderID int64) (*entity.PurchaseOrder, error) {
+	if orderID <= 0 {
+		return nil, fmt.Errorf("unable to cancel purchase order due: %w: purchase order ID is required", ErrInvalidInput)
+	}
+
+	purchaseOrder, err := s.storage.CancelPurchaseOrder(ctx, CancelPurchaseOrderInput{
+		OrderID:     orderID,
+		CancelledAt: time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to cancel purchase order due: %w", err)
+	}
+
+	return purchaseOrder, nil
+}
+
+func (s *service) CreatePromotion(ctx context.Context, input CreatePromotionInput) (*entity.Promotion, error) {
+	promotion, err := input.ToPromotionEntity()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create promotion due: %w", err)
+	}
+
+	createdPromotion, err := s.storage.CreatePromotion(ctx, *promotion)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create promotion due: %w", err)
+	}
+
+	return createdPromotion, nil
+}
+
+func (s *service) ShowPromotions(ctx context.Context) ([]entity.Promotion, error) {
+	promotions, err := s.storage.GetPromotions(ctx, GetPromotionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get promotions due: %w", err)
+	}
+
+	return promotions, nil
+}
+
+func (s *service) ShowLowStocks(ctx context.Context) ([]entity.LowStock, error) {
+	lowStocks, err := s.storage.GetLowStocks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get low stocks due: %w", err)
+	}
+
+	return lowStocks, nil
+}
+
+// ShowExpiringBatches list the remaining batches which expire within the given days including the expired one,
+// the earliest expiry first
+func (s *service) ShowExpiringBatches(ctx context.Context, input ShowExpiringBatchesInput) ([]entity.StockBatch, error) {
+	storageInput, err := input.ToGetExpiringStockBatchesStorageInput()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get expiring batches due: %w", err)
+	}
+
+	batches, err := s.storage.GetExpiringStockBatches(ctx, storageInput)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get expiring batches due: %w", err)
+	}
+
+	return batches, nil
+}
+
+// WriteOffExpiredBatches remove the expired batches from the stocks as wastage, the returned movements is the
+// written off stocks of each goods or variant
+func (s *service) WriteOffExpiredBatches(ctx context.Context, input WriteOffExpiredBatchesInput) ([]entity.StockMovement, error) {
+	storageInput, err := input.ToWriteOffStockBatchesStorageInput()
+	if err != nil {
+		return nil, fmt.Errorf("unable to write off expired batches due: %w", err)
+	}
+
+	movements, err := s.storage.WriteOffExpiredStockBatches(ctx, storageInput)
+	if err != nil {
+		return nil, fmt.Errorf("unable to write off expired batches due: %w", err)
+	}
+	if len(movements) > 0 {
+		s.alertDispatcher.Dispatch(func(ctx context.Context) {
+			s.checkReorderPoints(ctx, movements)
+		})
+	}
+
+	return movements, nil
+}
+
+// checkSoldGoodsReorderPoints check the reorder points of the goods and the raw materials sold by the transaction,
+// it's run in the background after the transaction paid
+func (s *service) checkSoldGoodsReorderPoints(ctx context.Context, transactionID int64) {
+	movements, err := s.storage.GetStockMovements(ctx, GetStockMovementsInput{TransactionID: transactionID})
+	if err != nil {
+		log.Printf("[ERROR] unable to get stock movements of transaction %d for reorder check: %v", transactionID, err)
+		return
+	}
+	s.checkReorderPoints(ctx, movements)
+}
+
+// checkReorderPoints notify the goods which stocks dropped to its reorder point by the stock movements, goods which
+// already below its reorder point before the movement is not notified again
+func (s *service) checkReorderPoints(ctx context.Context, movements []entity.StockMovement) {
+	for _, movement := range movements {
+		if movement.Quantity >= 0 {
+			continue
+		}
+		goods, err := s.storage.GetGoodsByID(ctx, movement.GoodsID)
+		if err != nil {
+			log.Printf("[ERROR] unable to get goods %d for reorder check: %v", movement.GoodsID, err)
+			continue
+		}
+		if !goods.IsCrossingReorderPoint(movement.Before, movement.After) {
+			continue
+		}
+
+		lowStock := entity.LowStock{
+			GoodsID:      goods.ID,
+			VariantID:    movement.VariantID,
+			Name:         goods.Name,
+			Unit:         goods.Unit,
+			Stocks:       movement.After,
+			ReorderPoint: goods.ReorderPoint,
+		}
+		if variant, ok := goods.FindVariant(movement.VariantID); movement.VariantID > 0 && ok {
+			lowStock.Name = fmt.Sprintf("%s - %s", goods.Name, variant.Name)
+		}
+		if err = s.notifier.NotifyLowStock(ctx, lowStock); err != nil {
+			log.Printf("[ERROR] unable to notify low stock of goods %d: %v", goods.ID, err)
+		}
+	}
+}
+
+// ReconcileStocks verify the stocks of every goods and variant equal to the sum of its stock movements,
+// the returned drifts is empty when all of them are balanced
+func (s *service) ReconcileStocks(ctx context.Context) ([]entity.StockDrift, error) {
+	drifts, err := s.storage.GetStockDrifts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to reconcile stocks due: %w", err)
+	}
+
+	return drifts, nil
+}
+
+// GetGoodsByID get the goods including the deleted one, so goods of past transactions still can be found
+func (s *service) GetGoodsByID(ctx context.Context, goodsID int) (*entity.Goods, error) {
+	goods, err := s.storage.GetGoodsByID(ctx, goodsID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get goods due: %w", err)
+	}
+
+	return goods, nil
+}
+
+func (s *service) CreateGoods(ctx context.Context, input CreateGoodsInput) (*entity.Goods, error) {
+	goods, err := input.ToGoodsEntity()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create goods due: %w", err)
+	}
+
+	newGoods, err := s.storage.CreateGoods(ctx, *goods)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create goods due: %w", err)
+	}
+
+	return newGoods, nil
+}
+
+func (s *service) UpdateGoods(ctx context.Context, input UpdateGoodsInput) (*entity.Goods, error) {
+	goods, err := input.ToGoodsEntity()
+	if err != nil {
+		return nil, fmt.Errorf("unable to update goods due: %w", err)
+	}
+
+	updatedGoods, err := s.storage.UpdateGoods(ctx, *goods)
+	if err != nil {
+		return nil, fmt.Errorf("unable to update goods due: %w", err)
+	}
+
+	return updatedGoods, nil
+}
+
+// DeleteGoods remove the goods from the catalog, the goods is kept for the past transactions
+func (s *service) DeleteGoods(ctx context.Context, goodsID int) error {
+	if goodsID <= 0 {
+		return fmt.Errorf("unable to delete goods due: %w: goods ID is required", ErrInvalidInput)
+	}
+	if err := s.storage.DeleteGoods(ctx, goodsID, time.Now().Unix()); err != nil {
+		return fmt.Errorf("unable to delete goods due: %w", err)
+	}
+
 	return nil
 }
 
+func (s *service) CreateCategory(ctx context.Context, input CreateCategoryInput) (*entity.Category, error) {
+	category, err := input.ToCategoryEntity()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create category due: %w", err)
+	}
+
+	newCategory, err := s.storage.CreateCategory(ctx, *category)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create category due: %w", err)
+	}
+
+	return newCategory, nil
+}
+
+func (s *service) CreateGoodsVariant(ctx context.Context, input CreateGoodsVariantInput) (*entity.GoodsVariant, error) {
+	variant, err := input.ToGoodsVariantEntity()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create goods variant due: %w", err)
+	}
+
+	goods, err := s.storage.GetGoodsByID(ctx, input.GoodsID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create goods variant due: %w", err)
+	}
+	if goods.IsDeleted() {
+		return nil, fmt.Errorf("unable to create goods variant due: %w", ErrGoodsNotFound)
+	}
+	if variant.Price(goods.Price) <= 0 {
+		return nil, fmt.Errorf("unable to create goods variant due: %w: variant price must be greater than zero", ErrInvalidInput)
+	}
+
+	newVariant, err := s.storage.CreateGoodsVariant(ctx, *variant)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create goods variant due: %w", err)
+	}
+
+	return newVariant, nil
+}
+
+// SetRecipe replace the recipe of the goods, the goods is not made to order anymore when the ingredients is empty
+func (s *service) SetRecipe(ctx context.Context, input SetRecipeInput) (*entity.Recipe, error) {
+	recipe, err := input.ToRecipeEntity()
+	if err != nil {
+		return nil, fmt.Errorf("unable to set recipe due: %w", err)
+	}
+
+	goods, err := s.storage.GetGoodsByID(ctx, recipe.GoodsID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to set recipe due: %w", err)
+	}
+	if goods.IsDeleted() {
+		return nil, fmt.Errorf("unable to set recipe due: %w", ErrGoodsNotFound)
+	}
+	if goods.IsRawMaterial {
+		return nil, fmt.Errorf("unable to set recipe due: %w: raw material %s can't have recipe", ErrInvalidInput, goods.Name)
+	}
+	for _, ingredient := range recipe.Ingredients {
+		rawMaterial, err := s.storage.GetGoodsByID(ctx, ingredient.IngredientID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to set recipe due: %w", err)
+		}
+		if rawMaterial.IsDeleted() {
+			return nil, fmt.Errorf("unable to set recipe due: %w", ErrGoodsNotFound)
+		}
+		if !rawMaterial.IsRawMaterial {
+			return nil, fmt.Errorf("unable to set recipe due: %w: %s is not a raw material", ErrInvalidInput, rawMaterial.Name)
+		}
+	}
+
+	newRecipe, err := s.storage.SetRecipe(ctx, *recipe)
+	if err != nil {
+		return nil, fmt.Errorf("unable to set recipe due: %w", err)
+	}
+
+	return newRecipe, nil
+}
+
+// ShowMenu list all goods which still sold grouped by their category, the uncategorized goods is put last
+func (s *service) ShowMenu(ctx context.Context) ([]MenuSection, error) {
+	categories, err := s.storage.GetCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get categories from storage due: %w", err)
+	}
+	goods, err := s.storage.GetMenuGoods(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get menu goods from storage due: %w", err)
+	}
+
+	sections := []MenuSection{}
+	sectionIdx := map[int]int{}
+	for _, category := range categories {
+		sectionIdx[category.ID] = len(sections)
+		sections = append(sections, MenuSection{Category: category, Goods: []entity.Goods{}})
+	}
+	uncategorized := MenuSection{
+		Category: entity.Category{Name: UncategorizedMenuName},
+		Goods:    []entity.Goods{},
+	}
+	for _, g := range goods {
+		idx, ok := sectionIdx[g.CategoryID]
+		if !ok {
+			uncategorized.Goods = append(uncategorized.Goods, g)
+			continue
+		}
+		sections[idx].Goods = append(sections[idx].Goods, g)
+	}
+	if len(uncategorized.Goods) > 0 {
+		sections = append(sections, uncategorized)
+	}
+
+	return sections, nil
+}
+
+// expireCartsBatchSize is maximum shopping carts expired in single sweep, the rest will be expired in the next sweep
+const expireCartsBatchSize = 500
+
+func (s *service) ExpireAbandonedCarts(ctx context.Context, ttl time.Duration) (int, error) {
+	if ttl <= 0 {
+		return 0, fmt.Errorf("%w: shopping cart TTL must be greater than zero", ErrInvalidInput)
+	}
+
+	totalExpired, err := s.storage.ExpireShoppingCarts(ctx, ExpireShoppingCartsInput{
+		LastActivityBefore: time.Now().Add(-ttl).Unix(),
+		Limit:              expireCartsBatchSize,
+	})
+	if err != nil {
+		return totalExpired, fmt.Errorf("unable to expire abandoned shopping carts due: %w", err)
+	}
+
+	return totalExpired, nil
+}
+
 func (s *service) ClearDatabase(ctx context.Context) error {
 	return s.storage.TruncateAllData(ctx)
 }
@@ -2,8 +2,14 @@ package service_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"math/rand"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -24,24 +30,78 @@ func TestNewService(mainT *testing.T) {
 		{
 			Name: "Test valid config",
 			Config: service.ServiceConfig{
-				Storage:        deps.Storage,
-				SupportService: deps.SupportService,
+				Storage:         deps.Storage,
+				SupportService:  deps.SupportService,
+				Notifier:        deps.Notifier,
+				AlertDispatcher: deps.AlertDispatcher,
+				ShopLocation:    deps.ShopLocation,
 			},
 			IsError: false,
 		},
+		{
+			Name: "Test invalid shop location",
+			Config: service.ServiceConfig{
+				Storage:         deps.Storage,
+				SupportService:  deps.SupportService,
+				Notifier:        deps.Notifier,
+				AlertDispatcher: deps.AlertDispatcher,
+				ShopLocation:    0,
+			},
+			IsError: true,
+		},
+		{
+			Name: "Test negative tax rate",
+			Config: service.ServiceConfig{
+				Storage:         deps.Storage,
+				SupportService:  deps.SupportService,
+				Notifier:        deps.Notifier,
+				AlertDispatcher: deps.AlertDispatcher,
+				ShopLocation:    deps.ShopLocation,
+				TaxRate:         -11,
+			},
+			IsError: true,
+		},
 		{
 			Name: "Test missing storage",
 			Config: service.ServiceConfig{
-				Storage:        nil,
-				SupportService: deps.SupportService,
+				Storage:         nil,
+				SupportService:  deps.SupportService,
+				Notifier:        deps.Notifier,
+				AlertDispatcher: deps.AlertDispatcher,
+				ShopLocation:    deps.ShopLocation,
 			},
 			IsError: true,
 		},
 		{
 			Name: "Test missing support service",
 			Config: service.ServiceConfig{
-				Storage:        deps.Storage,
-				SupportService: nil,
+				Storage:         deps.Storage,
+				SupportService:  nil,
+				Notifier:        deps.Notifier,
+				AlertDispatcher: deps.AlertDispatcher,
+				ShopLocation:    deps.ShopLocation,
+			},
+			IsError: true,
+		},
+		{
+			Name: "Test missing notifier",
+			Config: service.ServiceConfig{
+				Storage:         deps.Storage,
+				SupportService:  deps.SupportService,
+				Notifier:        nil,
+				AlertDispatcher: deps.AlertDispatcher,
+				ShopLocation:    deps.ShopLocation,
+			},
+			IsError: true,
+		},
+		{
+			Name: "Test missing alert dispatcher",
+			Config: service.ServiceConfig{
+				Storage:         deps.Storage,
+				SupportService:  deps.SupportService,
+				Notifier:        deps.Notifier,
+				AlertDispatcher: nil,
+				ShopLocation:    deps.ShopLocation,
 			},
 			IsError: true,
 		},
@@ -62,23 +122,71 @@ func TestShowStocks(mainT *testing.T) {
 		Input                service.ShowListOfGoodsInput
 		DummyGoodsCollection []entity.Goods
 		ExpectedGoods        []entity.Goods
+		ExpectedPagination   service.Pagination
 	}{
 		{
 			Name:                 "Test show list of goods with default input",
 			Input:                service.ShowListOfGoodsInput{},
 			DummyGoodsCollection: dummyGoods,
 			ExpectedGoods:        dummyGoods,
+			ExpectedPagination: service.Pagination{
+				Page:       1,
+				Limit:      10,
+				TotalItems: 10,
+				TotalPages: 1,
+			},
 		},
 		{
 			Name: "Test show list of goods with modified input",
 			Input: service.ShowListOfGoodsInput{
 				Page:       2,
 				TotalGoods: 5,
+				Sort:       service.SortAsc,
 			},
 			DummyGoodsCollection: dummyGoods,
 			ExpectedGoods:        dummyGoods[5:],
+			ExpectedPagination: service.Pagination{
+				Page:       2,
+				Limit:      5,
+				TotalItems: 10,
+				TotalPages: 2,
+				HasPrev:    true,
+			},
+		},
+		{
+			Name: "Test show middle page of goods skip the whole previous pages",
+			Input: service.ShowListOfGoodsInput{
+				Page:       2,
+				TotalGoods: 3,
+				Sort:       service.SortAsc,
+			},
+			DummyGoodsCollection: dummyGoods,
+			ExpectedGoods:        dummyGoods[3:6],
+			ExpectedPagination: service.Pagination{
+				Page:       2,
+				Limit:      3,
+				TotalItems: 10,
+				TotalPages: 4,
+				HasNext:    true,
+				HasPrev:    true,
+			},
+		},
+		{
+			Name: "Test show page beyond the last page",
+			Input: service.ShowListOfGoodsInput{
+				Page:       5,
+				TotalGoods: 3,
+			},
+			DummyGoodsCollection: dummyGoods,
+			ExpectedGoods:        []entity.Goods{},
+			ExpectedPagination: service.Pagination{
+				Page:       5,
+				Limit:      3,
+				TotalItems: 10,
+				TotalPages: 4,
+				HasPrev:    true,
+			},
 		},
-		// TODO: Sort list of goods
 	}
 
 	for _, testCase := range testCases {
@@ -90,9 +198,190 @@ func TestShowStocks(mainT *testing.T) {
 			svc, err := service.NewService(service.ServiceConfig(deps))
 			require.NoError(t, err)
 
-			goods, err := svc.ShowListOfGoods(context.Background(), testCase.Input)
+			output, err := svc.ShowListOfGoods(context.Background(), testCase.Input)
+			require.NoError(t, err)
+			require.ElementsMatch(t, testCase.ExpectedGoods, output.Goods)
+			require.Equal(t, testCase.ExpectedPagination, output.Pagination)
+		})
+	}
+}
+
+func TestShowStocksWithCursor(mainT *testing.T) {
+	catalog := []entity.Goods{
+		{ID: 1, Name: "Kopi", Stocks: 100, Price: 3000},
+		{ID: 2, Name: "Pisang Goreng", Stocks: 45, Price: 1500},
+		{ID: 3, Name: "Bakwan", Stocks: 50, Price: 1500},
+		{ID: 4, Name: "Teh manis", Stocks: 100, Price: 2000},
+		{ID: 5, Name: "Pisang Keju", Stocks: 25, Price: 2500},
+	}
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: catalog,
+	})
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	mainT.Run("Walk through all pages", func(t *testing.T) {
+		input := service.ShowListOfGoodsInput{
+			TotalGoods:   2,
+			Sort:         service.SortAsc,
+			SortBy:       service.GoodsSortByPrice,
+			CursorPaging: true,
+		}
+		goodsIDs := []int{}
+		for i := 0; i < len(catalog); i++ {
+			output, err := svc.ShowListOfGoods(context.Background(), input)
+			require.NoError(t, err)
+			require.Zero(t, output.Pagination.Page)
+			require.Zero(t, output.Pagination.TotalItems)
+			require.Equal(t, len(input.Cursor) > 0, output.Pagination.HasPrev)
+			for _, g := range output.Goods {
+				goodsIDs = append(goodsIDs, g.ID)
+			}
+			if !output.Pagination.HasNext {
+				require.Empty(t, output.Pagination.NextCursor)
+				break
+			}
+			input.Cursor = output.Pagination.NextCursor
+			// the cursor keep the order of the first page
+			input.Sort = service.SortDesc
+			input.SortBy = service.GoodsSortByName
+		}
+		require.Equal(t, []int{2, 3, 4, 5, 1}, goodsIDs)
+	})
+
+	mainT.Run("Walk back from the last page", func(t *testing.T) {
+		input := service.ShowListOfGoodsInput{
+			TotalGoods:   2,
+			Sort:         service.SortAsc,
+			SortBy:       service.GoodsSortByPrice,
+			CursorPaging: true,
+		}
+		for {
+			output, err := svc.ShowListOfGoods(context.Background(), input)
+			require.NoError(t, err)
+			if !output.Pagination.HasNext {
+				require.Equal(t, []int{1}, goodsIDsOf(output.Goods))
+				input.Cursor = output.Pagination.PrevCursor
+				break
+			}
+			input.Cursor = output.Pagination.NextCursor
+		}
+
+		pages := [][]int{}
+		for {
+			output, err := svc.ShowListOfGoods(context.Background(), input)
+			require.NoError(t, err)
+			require.True(t, output.Pagination.HasNext)
+			require.NotEmpty(t, output.Pagination.NextCursor)
+			pages = append(pages, goodsIDsOf(output.Goods))
+			if !output.Pagination.HasPrev {
+				require.Empty(t, output.Pagination.PrevCursor)
+				break
+			}
+			input.Cursor = output.Pagination.PrevCursor
+		}
+		require.Equal(t, [][]int{{4, 5}, {2, 3}}, pages)
+	})
+
+	mainT.Run("Malformed cursor is rejected", func(t *testing.T) {
+		_, err := svc.ShowListOfGoods(context.Background(), service.ShowListOfGoodsInput{
+			CursorPaging: true,
+			Cursor:       "not-a-cursor",
+		})
+		require.ErrorIs(t, err, service.ErrInvalidInput)
+	})
+}
+
+func goodsIDsOf(goods []entity.Goods) []int {
+	ids := []int{}
+	for _, g := range goods {
+		ids = append(ids, g.ID)
+	}
+	return ids
+}
+
+func TestSortAndFilterStocks(mainT *testing.T) {
+	catalog := []entity.Goods{
+		{ID: 1, Name: "Kopi", Stocks: 100, Price: 3000},
+		{ID: 2, Name: "Pisang Goreng", Stocks: 45, Price: 1500},
+		{ID: 3, Name: "Bakwan", Stocks: 0, Price: 1500},
+		{ID: 4, Name: "Teh manis", Stocks: 10, ReservedStocks: 10, Price: 2000},
+		{ID: 5, Name: "Pisang Keju", Stocks: 25, Price: 2500},
+	}
+	testCases := []struct {
+		Name          string
+		Input         service.ShowListOfGoodsInput
+		ExpectedIDs   []int
+		ExpectedError error
+	}{
+		{
+			Name:        "Sort by price ascending, same price ordered by ID",
+			Input:       service.ShowListOfGoodsInput{Sort: service.SortAsc, SortBy: service.GoodsSortByPrice},
+			ExpectedIDs: []int{2, 3, 4, 5, 1},
+		},
+		{
+			Name:        "Sort by name descending",
+			Input:       service.ShowListOfGoodsInput{Sort: service.SortDesc, SortBy: service.GoodsSortByName},
+			ExpectedIDs: []int{4, 5, 2, 1, 3},
+		},
+		{
+			Name:        "Sort value is case insensitive",
+			Input:       service.ShowListOfGoodsInput{Sort: "asc", SortBy: "STOCKS"},
+			ExpectedIDs: []int{3, 4, 5, 2, 1},
+		},
+		{
+			Name:        "Filter by name substring",
+			Input:       service.ShowListOfGoodsInput{Name: "pisang", Sort: service.SortAsc},
+			ExpectedIDs: []int{2, 5},
+		},
+		{
+			Name:        "Filter by price range",
+			Input:       service.ShowListOfGoodsInput{MinPrice: 2000, MaxPrice: 3000, Sort: service.SortAsc},
+			ExpectedIDs: []int{1, 4, 5},
+		},
+		{
+			Name:        "Filter goods which still have available stocks",
+			Input:       service.ShowListOfGoodsInput{InStockOnly: true, Sort: service.SortAsc},
+			ExpectedIDs: []int{1, 2, 5},
+		},
+		{
+			Name:          "Unknown sort is rejected",
+			Input:         service.ShowListOfGoodsInput{Sort: "RANDOM"},
+			ExpectedError: service.ErrInvalidInput,
+		},
+		{
+			Name:          "Unknown sort field is rejected",
+			Input:         service.ShowListOfGoodsInput{SortBy: "reserved_stocks"},
+			ExpectedError: service.ErrInvalidInput,
+		},
+		{
+			Name:          "Inverted price range is rejected",
+			Input:         service.ShowListOfGoodsInput{MinPrice: 3000, MaxPrice: 1000},
+			ExpectedError: service.ErrInvalidInput,
+		},
+	}
+
+	for _, testCase := range testCases {
+		mainT.Run(testCase.Name, func(t *testing.T) {
+			deps := newMockDependencies(mockDependenciesConfig{
+				mockStorageDummyGoods: catalog,
+			})
+
+			svc, err := service.NewService(service.ServiceConfig(deps))
+			require.NoError(t, err)
+
+			output, err := svc.ShowListOfGoods(context.Background(), testCase.Input)
+			if testCase.ExpectedError != nil {
+				require.ErrorIs(t, err, testCase.ExpectedError)
+				return
+			}
 			require.NoError(t, err)
-			require.ElementsMatch(t, testCase.ExpectedGoods, goods)
+
+			goodsIDs := []int{}
+			for _, g := range output.Goods {
+				goodsIDs = append(goodsIDs, g.ID)
+			}
+			require.Equal(t, testCase.ExpectedIDs, goodsIDs)
 		})
 	}
 }
@@ -102,6 +391,7 @@ func TestAddToCart(mainT *testing.T) {
 		Name           string
 		Input          []service.AddToCartInput
 		ExpectedOutput service.AddToCartOutput
+		ExpectedError  error
 	}{
 		{
 			Name: "Successfully add goods to cart from empty cart",
@@ -140,11 +430,70 @@ func TestAddToCart(mainT *testing.T) {
 				TotalAmount: (1 * 2000) + (4 * 1500),
 			},
 		},
+		{
+			Name: "Merge the same goods into one line",
+			Input: []service.AddToCartInput{
+				{
+					UserID:  250,
+					GoodsID: 1,
+					Total:   1,
+				},
+				{
+					CartID:  1,
+					UserID:  250,
+					GoodsID: 1,
+					Total:   2,
+				},
+			},
+			ExpectedOutput: service.AddToCartOutput{
+				TotalGoods:  3,
+				TotalAmount: 3 * 2000,
+			},
+		},
+		{
+			Name: "Use goods price from storage when price not submitted",
+			Input: []service.AddToCartInput{
+				{
+					UserID:  300,
+					GoodsID: 3,
+					Total:   2,
+				},
+			},
+			ExpectedOutput: service.AddToCartOutput{
+				TotalGoods:  2,
+				TotalAmount: 2 * 1500,
+			},
+		},
+		{
+			Name: "Reject submitted price which different from goods price",
+			Input: []service.AddToCartInput{
+				{
+					UserID:     400,
+					GoodsID:    3,
+					GoodsPrice: 1,
+					Total:      2,
+				},
+			},
+			ExpectedError: service.ErrPriceMismatch,
+		},
+		{
+			Name: "Reject goods which not exist",
+			Input: []service.AddToCartInput{
+				{
+					UserID:  500,
+					GoodsID: 99,
+					Total:   1,
+				},
+			},
+			ExpectedError: service.ErrGoodsNotFound,
+		},
 	}
 
 	for _, testCase := range testCases {
 		mainT.Run(testCase.Name, func(t *testing.T) {
-			deps := newMockDependencies(mockDependenciesConfig{})
+			deps := newMockDependencies(mockDependenciesConfig{
+				mockStorageDummyGoods: newCartGoods(),
+			})
 
 			svc, err := service.NewService(service.ServiceConfig(deps))
 			require.NoError(t, err)
@@ -155,6 +504,10 @@ func TestAddToCart(mainT *testing.T) {
 			}
 			for _, input := range testCase.Input {
 				output, err := svc.AddToCart(context.Background(), input)
+				if testCase.ExpectedError != nil {
+					require.ErrorIs(t, err, testCase.ExpectedError)
+					return
+				}
 				require.NoError(t, err)
 				actualOutput.TotalGoods = output.TotalGoods
 				actualOutput.TotalAmount = output.TotalAmount
@@ -164,154 +517,3443 @@ func TestAddToCart(mainT *testing.T) {
 	}
 }
 
-func TestPay(mainT *testing.T) {
+func TestAbandonCart(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: newCartGoods(),
+	})
+
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	output, err := svc.AddToCart(ctx, service.AddToCartInput{
+		UserID:     100,
+		GoodsID:    1,
+		GoodsPrice: 2000,
+		Total:      2,
+	})
+	require.NoError(mainT, err)
+
+	require.NoError(mainT, svc.AbandonCart(ctx, output.CartID))
+
+	// abandoned cart can't be used anymore
+	_, err = svc.AddToCart(ctx, service.AddToCartInput{
+		CartID:     output.CartID,
+		UserID:     100,
+		GoodsID:    1,
+		GoodsPrice: 2000,
+		Total:      1,
+	})
+	require.Error(mainT, err)
+	require.ErrorIs(mainT, svc.AbandonCart(ctx, output.CartID), service.ErrCartNotFound)
+}
+
+func TestManageCartGoods(mainT *testing.T) {
 	testCases := []struct {
-		Name              string
-		ShoppingCartInput service.AddToCartInput
-		Input             service.PayInput
-		ExpectedSuccess   bool
+		Name                string
+		Action              func(svc service.Service, cartID int64) (*entity.ShoppingCart, error)
+		ExpectedTotalGoods  int
+		ExpectedTotalAmount entity.Money
+		ExpectedError       error
 	}{
 		{
-			Name: "Successfully do payment",
-			ShoppingCartInput: service.AddToCartInput{
-				UserID:     200,
-				GoodsID:    1,
-				GoodsPrice: 2000,
-				Total:      2,
+			Name: "Get shopping cart",
+			Action: func(svc service.Service, cartID int64) (*entity.ShoppingCart, error) {
+				return svc.GetCart(context.Background(), cartID)
 			},
-			Input: service.PayInput{
-				CartID: 1,
+			ExpectedTotalGoods:  2 + 4,
+			ExpectedTotalAmount: (2 * 2000) + (4 * 1500),
+		},
+		{
+			Name: "Update quantity of goods",
+			Action: func(svc service.Service, cartID int64) (*entity.ShoppingCart, error) {
+				return svc.UpdateCartGoods(context.Background(), service.UpdateCartGoodsInput{
+					CartID:  cartID,
+					GoodsID: 3,
+					Total:   1,
+				})
+			},
+			ExpectedTotalGoods:  2 + 1,
+			ExpectedTotalAmount: (2 * 2000) + 1500,
+		},
+		{
+			Name: "Update quantity of goods to zero",
+			Action: func(svc service.Service, cartID int64) (*entity.ShoppingCart, error) {
+				return svc.UpdateCartGoods(context.Background(), service.UpdateCartGoodsInput{
+					CartID:  cartID,
+					GoodsID: 3,
+					Total:   0,
+				})
+			},
+			ExpectedError: service.ErrInvalidInput,
+		},
+		{
+			Name: "Update quantity of goods which not in the cart",
+			Action: func(svc service.Service, cartID int64) (*entity.ShoppingCart, error) {
+				return svc.UpdateCartGoods(context.Background(), service.UpdateCartGoodsInput{
+					CartID:  cartID,
+					GoodsID: 2,
+					Total:   1,
+				})
+			},
+			ExpectedError: entity.ErrGoodsNotInCart,
+		},
+		{
+			Name: "Remove goods from cart",
+			Action: func(svc service.Service, cartID int64) (*entity.ShoppingCart, error) {
+				return svc.RemoveGoodsFromCart(context.Background(), cartID, 1, 0)
+			},
+			ExpectedTotalGoods:  4,
+			ExpectedTotalAmount: 4 * 1500,
+		},
+		{
+			Name: "Clear cart",
+			Action: func(svc service.Service, cartID int64) (*entity.ShoppingCart, error) {
+				return svc.ClearCart(context.Background(), cartID)
 			},
-			ExpectedSuccess: true,
+			ExpectedTotalGoods:  0,
+			ExpectedTotalAmount: 0,
+		},
+		{
+			Name: "Get shopping cart which not exist",
+			Action: func(svc service.Service, cartID int64) (*entity.ShoppingCart, error) {
+				return svc.GetCart(context.Background(), cartID+1)
+			},
+			ExpectedError: service.ErrCartNotFound,
 		},
 	}
 
 	for _, testCase := range testCases {
 		mainT.Run(testCase.Name, func(t *testing.T) {
-			deps := newMockDependencies(mockDependenciesConfig{})
+			deps := newMockDependencies(mockDependenciesConfig{
+				mockStorageDummyGoods: newCartGoods(),
+			})
 
 			svc, err := service.NewService(service.ServiceConfig(deps))
 			require.NoError(t, err)
 
 			ctx := context.Background()
-
-			// add to cart first
-			_, err = svc.AddToCart(ctx, testCase.ShoppingCartInput)
+			output, err := svc.AddToCart(ctx, service.AddToCartInput{
+				UserID:  100,
+				GoodsID: 1,
+				Total:   2,
+			})
+			require.NoError(t, err)
+			_, err = svc.AddToCart(ctx, service.AddToCartInput{
+				CartID:  output.CartID,
+				UserID:  100,
+				GoodsID: 3,
+				Total:   4,
+			})
 			require.NoError(t, err)
 
-			trx, err := svc.Pay(ctx, testCase.Input)
+			cart, err := testCase.Action(svc, output.CartID)
+			if testCase.ExpectedError != nil {
+				require.ErrorIs(t, err, testCase.ExpectedError)
+				return
+			}
 			require.NoError(t, err)
-			require.Equal(t, trx.ID != 0, testCase.ExpectedSuccess)
+			require.Equal(t, testCase.ExpectedTotalGoods, cart.GetTotalGoods())
+			require.Equal(t, testCase.ExpectedTotalAmount, cart.GetTotalAmount())
 		})
 	}
 }
 
-type mockDependencies struct {
-	Storage        service.Storage
-	SupportService service.SupportService
-}
-
-type mockDependenciesConfig struct {
-	mockStorageDummyGoods []entity.Goods
-}
-
-func newMockDependencies(config mockDependenciesConfig) mockDependencies {
-	return mockDependencies{
-		Storage: &mockStorage{
-			Goods:        config.mockStorageDummyGoods,
-			ShoppingCart: map[int64]entity.ShoppingCart{},
+func TestPay(mainT *testing.T) {
+	testCases := []struct {
+		Name                 string
+		ShoppingCartInput    service.AddToCartInput
+		PreviousInputs       []service.PayInput
+		Input                service.PayInput
+		ExpectedReturnAmount entity.Money
+		ExpectedError        error
+	}{
+		{
+			Name: "Successfully do payment",
+			ShoppingCartInput: service.AddToCartInput{
+				UserID:     200,
+				GoodsID:    1,
+				GoodsPrice: 2000,
+				Total:      2,
+			},
+			Input: service.PayInput{
+				CartID:        1,
+				PaymentAmount: 5000,
+			},
+			ExpectedReturnAmount: 1000,
 		},
-		SupportService: &mockSupportService{},
-	}
-}
-
-func newDummyGoods(total int) []entity.Goods {
-	f := faker.New()
-	rg := rand.New(rand.NewSource(time.Now().UnixNano()))
-	output := []entity.Goods{}
-	for i := 1; i <= total; i++ {
-		output = append(output, entity.Goods{
-			ID:     i,
-			Name:   f.Food().Fruit(),
-			Stocks: rg.Int(),
-			Price:  rg.Float64(),
-		})
-	}
-
-	return output
-}
-
-type mockStorage struct {
-	Goods        []entity.Goods
-	ShoppingCart map[int64]entity.ShoppingCart
-}
-
-func (m *mockStorage) GetGoods(ctx context.Context, input service.GetGoodsInput) ([]entity.Goods, error) {
-	if len(m.Goods) == 0 || m.Goods == nil {
-		return []entity.Goods{}, nil
-	}
-
-	if input.Limit > len(m.Goods) {
-		return m.Goods, nil
-	}
-
-	return m.Goods[input.Offset*input.Limit : (input.Offset+1)*input.Limit], nil
-}
-
-func (m *mockStorage) GetExistingShoppingCart(ctx context.Context, shoppingCartID int64) (*entity.ShoppingCart, error) {
-	existCart, ok := m.ShoppingCart[shoppingCartID]
-	if !ok {
-		return nil, fmt.Errorf("shopping cart with %d ID not exist yet", shoppingCartID)
-	}
-	return &existCart, nil
-}
-
-func (m *mockStorage) AddGoodToCart(ctx context.Context, cart *entity.ShoppingCart) (*entity.ShoppingCart, error) {
-	var cartOutput entity.ShoppingCart
-	if cart.ID > 0 {
-		existCart, ok := m.ShoppingCart[cart.ID]
-		if !ok {
-			return nil, fmt.Errorf("unexpected error: no existing cart for ID %d", cart.ID)
-		}
-		existCart.Details = cart.Details
-		existCart.TotalAmount = existCart.GetTotalAmount()
-
-		m.ShoppingCart[cart.ID] = existCart
-
-		cartOutput = m.ShoppingCart[cart.ID]
-	} else {
-		newCartID := len(m.ShoppingCart) + 1
-		m.ShoppingCart[int64(newCartID)] = entity.ShoppingCart{
-			ID:          int64(newCartID),
-			UserID:      cart.UserID,
-			TotalAmount: cart.GetTotalAmount(),
+		{
+			Name: "Reject underpayment",
+			ShoppingCartInput: service.AddToCartInput{
+				UserID:     200,
+				GoodsID:    1,
+				GoodsPrice: 2000,
+				Total:      2,
+			},
+			Input: service.PayInput{
+				CartID:        1,
+				PaymentAmount: 3999,
+			},
+			ExpectedError: entity.InsufficientPaymentError{TotalAmount: 4000, PaymentAmount: 3999},
+		},
+		{
+			Name: "Reject shopping cart which already paid",
+			ShoppingCartInput: service.AddToCartInput{
+				UserID:     200,
+				GoodsID:    1,
+				GoodsPrice: 2000,
+				Total:      2,
+			},
+			PreviousInputs: []service.PayInput{
+				{
+					CartID:        1,
+					PaymentAmount: 4000,
+				},
+			},
+			Input: service.PayInput{
+				CartID:        1,
+				PaymentAmount: 4000,
+			},
+			ExpectedError: service.ErrCartAlreadyPaid,
+		},
+		{
+			Name: "Reject shopping cart which not exist",
+			ShoppingCartInput: service.AddToCartInput{
+				UserID:     200,
+				GoodsID:    1,
+				GoodsPrice: 2000,
+				Total:      2,
+			},
+			Input: service.PayInput{
+				CartID:        2,
+				PaymentAmount: 4000,
+			},
+			ExpectedError: service.ErrCartNotFound,
+		},
+		{
+			Name: "Retried payment with the same idempotency key return the original receipt",
+			ShoppingCartInput: service.AddToCartInput{
+				UserID:     200,
+				GoodsID:    1,
+				GoodsPrice: 2000,
+				Total:      2,
+			},
+			PreviousInputs: []service.PayInput{
+				{
+					CartID:         1,
+					PaymentAmount:  5000,
+					IdempotencyKey: "pay-1",
+				},
+			},
+			Input: service.PayInput{
+				CartID:         1,
+				PaymentAmount:  5000,
+				IdempotencyKey: "pay-1",
+			},
+			ExpectedReturnAmount: 1000,
+		},
+		{
+			Name: "Reject idempotency key used for another shopping cart",
+			ShoppingCartInput: service.AddToCartInput{
+				UserID:     200,
+				GoodsID:    1,
+				GoodsPrice: 2000,
+				Total:      2,
+			},
+			PreviousInputs: []service.PayInput{
+				{
+					CartID:         1,
+					PaymentAmount:  5000,
+					IdempotencyKey: "pay-1",
+				},
+			},
+			Input: service.PayInput{
+				CartID:         2,
+				PaymentAmount:  5000,
+				IdempotencyKey: "pay-1",
+			},
+			ExpectedError: service.ErrIdempotencyKeyReused,
+		},
+		{
+			Name: "Change is given from the cash tender",
+			ShoppingCartInput: service.AddToCartInput{
+				UserID:     200,
+				GoodsID:    1,
+				GoodsPrice: 2000,
+				Total:      2,
+			},
+			Input: service.PayInput{
+				CartID: 1,
+				Tenders: []entity.Tender{
+					{Method: entity.PaymentMethodEWallet, Amount: 3000},
+					{Method: "cash", Amount: 2000},
+				},
+			},
+			ExpectedReturnAmount: 1000,
+		},
+		{
+			Name: "Reject non cash tenders which exceed the total amount",
+			ShoppingCartInput: service.AddToCartInput{
+				UserID:     200,
+				GoodsID:    1,
+				GoodsPrice: 2000,
+				Total:      2,
+			},
+			Input: service.PayInput{
+				CartID: 1,
+				Tenders: []entity.Tender{
+					{Method: entity.PaymentMethodTransfer, Amount: 3000},
+					{Method: entity.PaymentMethodEWallet, Amount: 2000},
+				},
+			},
+			ExpectedError: entity.NonCashOverpaymentError{TotalAmount: 4000, NonCashAmount: 5000},
+		},
+		{
+			Name: "Reject unknown payment method",
+			ShoppingCartInput: service.AddToCartInput{
+				UserID:     200,
+				GoodsID:    1,
+				GoodsPrice: 2000,
+				Total:      2,
+			},
+			Input: service.PayInput{
+				CartID:  1,
+				Tenders: []entity.Tender{{Method: "COUPON", Amount: 4000}},
+			},
+			ExpectedError: service.ErrInvalidInput,
+		},
+	}
+
+	for _, testCase := range testCases {
+		mainT.Run(testCase.Name, func(t *testing.T) {
+			deps := newMockDependencies(mockDependenciesConfig{
+				mockStorageDummyGoods: newCartGoods(),
+			})
+
+			svc, err := service.NewService(service.ServiceConfig(deps))
+			require.NoError(t, err)
+
+			ctx := context.Background()
+
+			// add to cart first
+			_, err = svc.AddToCart(ctx, testCase.ShoppingCartInput)
+			require.NoError(t, err)
+
+			for _, input := range testCase.PreviousInputs {
+				_, err = svc.Pay(ctx, input)
+				require.NoError(t, err)
+			}
+
+			trx, err := svc.Pay(ctx, testCase.Input)
+			if testCase.ExpectedError != nil {
+				require.ErrorIs(t, err, testCase.ExpectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.Input.CartID, trx.ID)
+			require.Equal(t, testCase.ExpectedReturnAmount, trx.ReturnAmount)
+		})
+	}
+}
+
+func TestPayWithTenders(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: newCartGoods(),
+	})
+
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	payments := [][]entity.Tender{
+		{{Method: entity.PaymentMethodCash, Amount: 5000}},
+		{
+			{Method: entity.PaymentMethodCash, Amount: 1000},
+			{Method: entity.PaymentMethodEWallet, Amount: 1000},
+			{Method: entity.PaymentMethodEWallet, Amount: 2000},
+		},
+		{{Method: entity.PaymentMethodTransfer, Amount: 4000}},
+	}
+	for i, tenders := range payments {
+		output, err := svc.AddToCart(ctx, service.AddToCartInput{
+			UserID:  100 + i,
+			GoodsID: 1,
+			Total:   2,
+		})
+		require.NoError(mainT, err)
+		_, err = svc.Pay(ctx, service.PayInput{CartID: output.CartID, Tenders: tenders})
+		require.NoError(mainT, err)
+	}
+
+	// tenders of the same method are merged
+	order, err := svc.GetOrder(ctx, 2)
+	require.NoError(mainT, err)
+	require.Equal(mainT, []entity.Tender{
+		{Method: entity.PaymentMethodCash, Amount: 1000},
+		{Method: entity.PaymentMethodEWallet, Amount: 3000},
+	}, order.Tenders)
+
+	// the cash is counted after the change
+	report, err := svc.GetDailySalesReport(ctx, service.GetDailySalesReportInput{Date: time.Now()})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money(12000), report.GrossAmount)
+	require.Equal(mainT, []entity.TenderTotal{
+		{Method: entity.PaymentMethodCash, TotalTransactions: 2, Amount: 5000},
+		{Method: entity.PaymentMethodEWallet, TotalTransactions: 1, Amount: 3000},
+		{Method: entity.PaymentMethodTransfer, TotalTransactions: 1, Amount: 4000},
+	}, report.Tenders)
+}
+
+func TestPayEmptyCart(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{})
+	deps.Storage.(*mockStorage).ShoppingCart[1] = entity.ShoppingCart{
+		ID:     1,
+		UserID: 100,
+	}
+
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	_, err = svc.Pay(context.Background(), service.PayInput{
+		CartID:        1,
+		PaymentAmount: 1000,
+	})
+	require.ErrorIs(mainT, err, service.ErrEmptyCart)
+}
+
+func TestCashlessPayment(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: newCartGoods(),
+	})
+
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	output, err := svc.AddToCart(ctx, service.AddToCartInput{
+		UserID:  100,
+		GoodsID: 1,
+		Total:   2,
+	})
+	require.NoError(mainT, err)
+
+	newCallback := func(payment *entity.Payment, amount entity.Money) []byte {
+		payload, err := json.Marshal(service.PaymentCallback{
+			Reference: payment.Reference,
+			Status:    entity.PaymentStatusPaid,
+			Amount:    amount,
+			PaidAt:    time.Now().Unix(),
+		})
+		require.NoError(mainT, err)
+		return payload
+	}
+
+	_, err = svc.RequestPayment(ctx, service.RequestPaymentInput{
+		CartID: output.CartID,
+		Method: entity.PaymentMethodCash,
+	})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+
+	payment, err := svc.RequestPayment(ctx, service.RequestPaymentInput{CartID: output.CartID})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.PaymentMethodQRIS, payment.Method)
+	require.Equal(mainT, entity.Money(4000), payment.Amount)
+	require.True(mainT, payment.IsPending())
+	require.NotEmpty(mainT, payment.QRPayload)
+
+	mainT.Run("Test shopping cart is frozen while waiting for the payment", func(t *testing.T) {
+		_, err := svc.AddToCart(ctx, service.AddToCartInput{
+			CartID:  output.CartID,
+			UserID:  100,
+			GoodsID: 2,
+			Total:   1,
+		})
+		require.ErrorIs(t, err, service.ErrPaymentPending)
+		_, err = svc.UpdateCartGoods(ctx, service.UpdateCartGoodsInput{CartID: output.CartID, GoodsID: 1, Total: 1})
+		require.ErrorIs(t, err, service.ErrPaymentPending)
+		_, err = svc.RemoveGoodsFromCart(ctx, output.CartID, 1, 0)
+		require.ErrorIs(t, err, service.ErrPaymentPending)
+		_, err = svc.ClearCart(ctx, output.CartID)
+		require.ErrorIs(t, err, service.ErrPaymentPending)
+		err = svc.AbandonCart(ctx, output.CartID)
+		require.ErrorIs(t, err, service.ErrPaymentPending)
+		_, err = svc.RequestPayment(ctx, service.RequestPaymentInput{CartID: output.CartID})
+		require.ErrorIs(t, err, service.ErrPaymentPending)
+
+		// the expiry job leave the shopping cart to its payment
+		totalExpired, err := svc.ExpireAbandonedCarts(ctx, time.Minute)
+		require.NoError(t, err)
+		require.Zero(t, totalExpired)
+
+		cart, err := svc.GetCart(ctx, output.CartID)
+		require.NoError(t, err)
+		require.Equal(t, 2, cart.GetTotalGoods())
+	})
+
+	mainT.Run("Test callback with invalid signature", func(t *testing.T) {
+		_, err := svc.ConfirmPayment(ctx, service.ConfirmPaymentInput{
+			Payload:   newCallback(payment, payment.Amount),
+			Signature: "forged",
+		})
+		require.ErrorIs(t, err, service.ErrInvalidSignature)
+	})
+
+	mainT.Run("Test callback with different amount", func(t *testing.T) {
+		_, err := svc.ConfirmPayment(ctx, service.ConfirmPaymentInput{
+			Payload:   newCallback(payment, payment.Amount-1),
+			Signature: mockPaymentSignature,
+		})
+		require.ErrorIs(t, err, service.ErrInvalidInput)
+
+		stored, err := svc.GetPayment(ctx, payment.Reference)
+		require.NoError(t, err)
+		require.True(t, stored.IsPending())
+	})
+
+	mainT.Run("Test callback of unknown payment", func(t *testing.T) {
+		_, err := svc.ConfirmPayment(ctx, service.ConfirmPaymentInput{
+			Payload:   newCallback(&entity.Payment{Reference: "UNKNOWN"}, payment.Amount),
+			Signature: mockPaymentSignature,
+		})
+		require.ErrorIs(t, err, service.ErrPaymentNotFound)
+	})
+
+	mainT.Run("Test callback complete the transaction", func(t *testing.T) {
+		paidTrx, err := svc.ConfirmPayment(ctx, service.ConfirmPaymentInput{
+			Payload:   newCallback(payment, payment.Amount),
+			Signature: mockPaymentSignature,
+		})
+		require.NoError(t, err)
+		require.Equal(t, entity.TransactionStatusPaid, paidTrx.Status)
+		require.Equal(t, []entity.Tender{{Method: entity.PaymentMethodQRIS, Amount: 4000}}, paidTrx.Tenders)
+		require.Equal(t, entity.Money(0), paidTrx.ReturnAmount)
+
+		stored, err := svc.GetPayment(ctx, payment.Reference)
+		require.NoError(t, err)
+		require.Equal(t, entity.PaymentStatusPaid, stored.Status)
+		require.NotZero(t, stored.PaidAt)
+
+		// the provider retry the callback when it doesn't get the response
+		retriedTrx, err := svc.ConfirmPayment(ctx, service.ConfirmPaymentInput{
+			Payload:   newCallback(payment, payment.Amount),
+			Signature: mockPaymentSignature,
+		})
+		require.NoError(t, err)
+		require.Equal(t, paidTrx.ID, retriedTrx.ID)
+	})
+
+	mainT.Run("Test request payment of paid cart", func(t *testing.T) {
+		_, err := svc.RequestPayment(ctx, service.RequestPaymentInput{CartID: output.CartID})
+		require.ErrorIs(t, err, service.ErrCartAlreadyPaid)
+	})
+
+	mainT.Run("Test request payment without payment provider", func(t *testing.T) {
+		deps := newMockDependencies(mockDependenciesConfig{})
+		deps.PaymentProvider = nil
+		svc, err := service.NewService(service.ServiceConfig(deps))
+		require.NoError(t, err)
+
+		_, err = svc.RequestPayment(ctx, service.RequestPaymentInput{CartID: output.CartID})
+		require.ErrorIs(t, err, service.ErrPaymentUnavailable)
+	})
+}
+
+func TestExpireAbandonedCarts(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: newCartGoods(),
+	})
+	mockStrg := deps.Storage.(*mockStorage)
+
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	output, err := svc.AddToCart(ctx, service.AddToCartInput{
+		UserID:  100,
+		GoodsID: 1,
+		Total:   2,
+	})
+	require.NoError(mainT, err)
+
+	_, err = svc.ExpireAbandonedCarts(ctx, 0)
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+
+	ttl := 30 * time.Minute
+	totalExpired, err := svc.ExpireAbandonedCarts(ctx, ttl)
+	require.NoError(mainT, err)
+	require.Equal(mainT, 1, totalExpired)
+	require.InDelta(mainT, time.Now().Add(-ttl).Unix(), mockStrg.LastExpireInput.LastActivityBefore, 1)
+	require.Positive(mainT, mockStrg.LastExpireInput.Limit)
+
+	// expired shopping cart can't be paid
+	_, err = svc.Pay(ctx, service.PayInput{
+		CartID:        output.CartID,
+		PaymentAmount: 4000,
+	})
+	require.ErrorIs(mainT, err, service.ErrCartExpired)
+}
+
+func TestReqCalculateDeliveryPrice(mainT *testing.T) {
+	testCases := []struct {
+		Name          string
+		Input         service.ReqCalculateDeliveryPriceInput
+		ExpectedQuote *service.DeliveryQuote
+		ExpectedError error
+	}{
+		{
+			Name: "Successfully calculate delivery price from shop location",
+			Input: service.ReqCalculateDeliveryPriceInput{
+				Location:  3404,
+				GoodsSpec: service.GoodsSpecification{Weight: 2},
+			},
+			ExpectedQuote: &service.DeliveryQuote{
+				Origin:           3471,
+				Destination:      3404,
+				Zone:             "MOCK",
+				ChargeableWeight: 2,
+				Price:            2000,
+			},
+		},
+		{
+			Name: "Invalid location",
+			Input: service.ReqCalculateDeliveryPriceInput{
+				Location:  1,
+				GoodsSpec: service.GoodsSpecification{Weight: 2},
+			},
+			ExpectedError: service.ErrInvalidInput,
+		},
+		{
+			Name: "Missing package specification",
+			Input: service.ReqCalculateDeliveryPriceInput{
+				Location: 3404,
+			},
+			ExpectedError: service.ErrInvalidInput,
+		},
+		{
+			Name: "Weight is not a number",
+			Input: service.ReqCalculateDeliveryPriceInput{
+				Location:  3404,
+				GoodsSpec: service.GoodsSpecification{Weight: float32(math.NaN())},
+			},
+			ExpectedError: service.ErrInvalidInput,
+		},
+		{
+			Name: "Infinite volume",
+			Input: service.ReqCalculateDeliveryPriceInput{
+				Location:  3404,
+				GoodsSpec: service.GoodsSpecification{Weight: 1, Volume: float32(math.Inf(1))},
+			},
+			ExpectedError: service.ErrInvalidInput,
+		},
+		{
+			Name: "Package is too heavy",
+			Input: service.ReqCalculateDeliveryPriceInput{
+				Location:  3404,
+				GoodsSpec: service.GoodsSpecification{Weight: service.MaxPackageWeight + 1},
+			},
+			ExpectedError: service.ErrInvalidInput,
+		},
+	}
+
+	for _, testCase := range testCases {
+		mainT.Run(testCase.Name, func(t *testing.T) {
+			deps := newMockDependencies(mockDependenciesConfig{})
+
+			svc, err := service.NewService(service.ServiceConfig(deps))
+			require.NoError(t, err)
+
+			quote, err := svc.ReqCalculateDeliveryPrice(context.Background(), testCase.Input)
+			if testCase.ExpectedError != nil {
+				require.ErrorIs(t, err, testCase.ExpectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.ExpectedQuote, quote)
+		})
+	}
+}
+
+func TestReqPickupDelivery(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: newCartGoods(),
+	})
+
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	output, err := svc.AddToCart(ctx, service.AddToCartInput{
+		UserID:  100,
+		GoodsID: 1,
+		Total:   2,
+	})
+	require.NoError(mainT, err)
+
+	input := service.ReqPickupDeliveryInput{
+		TransactionID: output.CartID,
+		Location:      3404,
+		GoodsSpec:     service.GoodsSpecification{Weight: 2},
+	}
+
+	// shopping cart is not paid yet
+	_, err = svc.ReqPickupDelivery(ctx, input)
+	require.ErrorIs(mainT, err, service.ErrTransactionNotPaid)
+
+	_, err = svc.Pay(ctx, service.PayInput{
+		CartID:        output.CartID,
+		PaymentAmount: 4000,
+	})
+	require.NoError(mainT, err)
+
+	// the failed pickup can be requested again
+	supportSvc := deps.SupportService.(*mockSupportService)
+	errCourierDown := errors.New("courier is down")
+	supportSvc.pickupErr = errCourierDown
+	_, err = svc.ReqPickupDelivery(ctx, input)
+	require.ErrorIs(mainT, err, errCourierDown)
+	supportSvc.pickupErr = nil
+
+	// another request while the courier is being asked is rejected, so the courier only asked once
+	supportSvc.onPickup = func() {
+		_, err := svc.ReqPickupDelivery(ctx, input)
+		require.ErrorIs(mainT, err, service.ErrDeliveryAlreadyRequested)
+	}
+	delivery, err := svc.ReqPickupDelivery(ctx, input)
+	require.NoError(mainT, err)
+	supportSvc.onPickup = nil
+	require.Equal(mainT, output.CartID, delivery.TransactionID)
+	require.Equal(mainT, fmt.Sprintf("MOCK-%d", output.CartID), delivery.TrackingNumber)
+	require.Equal(mainT, entity.DeliveryStatusRequested, delivery.Status)
+	require.Equal(mainT, 3404, delivery.Destination)
+	require.Equal(mainT, entity.Money(2000), delivery.Price)
+
+	// the paid transaction only picked up once
+	_, err = svc.ReqPickupDelivery(ctx, input)
+	require.ErrorIs(mainT, err, service.ErrDeliveryAlreadyRequested)
+
+	_, err = svc.ReqPickupDelivery(ctx, service.ReqPickupDeliveryInput{
+		TransactionID: 999,
+		Location:      3404,
+		GoodsSpec:     service.GoodsSpecification{Weight: 2},
+	})
+	require.ErrorIs(mainT, err, service.ErrCartNotFound)
+
+	_, err = svc.ReqPickupDelivery(ctx, service.ReqPickupDeliveryInput{
+		TransactionID: output.CartID,
+		Location:      1,
+		GoodsSpec:     service.GoodsSpecification{Weight: 2},
+	})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+}
+
+func TestUpdateOrderStatus(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: newCartGoods(),
+	})
+
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	output, err := svc.AddToCart(ctx, service.AddToCartInput{
+		UserID:  100,
+		GoodsID: 1,
+		Total:   2,
+	})
+	require.NoError(mainT, err)
+
+	// shopping cart can't be prepared before it's paid
+	_, err = svc.UpdateOrderStatus(ctx, service.UpdateOrderStatusInput{
+		TransactionID: output.CartID,
+		Status:        entity.TransactionStatusPreparing,
+	})
+	var transitionErr entity.InvalidStatusTransitionError
+	require.ErrorAs(mainT, err, &transitionErr)
+	require.Equal(mainT, entity.TransactionStatusCart, transitionErr.From)
+
+	_, err = svc.Pay(ctx, service.PayInput{
+		CartID:        output.CartID,
+		PaymentAmount: 4000,
+	})
+	require.NoError(mainT, err)
+
+	// payment and cancellation have their own flow
+	_, err = svc.UpdateOrderStatus(ctx, service.UpdateOrderStatusInput{
+		TransactionID: output.CartID,
+		Status:        entity.TransactionStatusCancelled,
+	})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+
+	// skipping status is not allowed
+	_, err = svc.UpdateOrderStatus(ctx, service.UpdateOrderStatusInput{
+		TransactionID: output.CartID,
+		Status:        entity.TransactionStatusShipped,
+	})
+	require.ErrorAs(mainT, err, &transitionErr)
+
+	for _, status := range []entity.TransactionStatus{
+		entity.TransactionStatusPreparing,
+		entity.TransactionStatusReady,
+		entity.TransactionStatusCompleted,
+	} {
+		order, err := svc.UpdateOrderStatus(ctx, service.UpdateOrderStatusInput{
+			TransactionID: output.CartID,
+			Status:        status,
+		})
+		require.NoError(mainT, err)
+		require.Equal(mainT, status, order.Status)
+	}
+
+	// completed order can't go back
+	_, err = svc.UpdateOrderStatus(ctx, service.UpdateOrderStatusInput{
+		TransactionID: output.CartID,
+		Status:        entity.TransactionStatusPreparing,
+	})
+	require.ErrorAs(mainT, err, &transitionErr)
+
+	order, err := svc.GetOrder(ctx, output.CartID)
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.TransactionStatusCompleted, order.Status)
+	require.Len(mainT, order.StatusHistory, 4)
+	require.Equal(mainT, entity.TransactionStatusPaid, order.StatusHistory[0].To)
+	require.Equal(mainT, entity.TransactionStatusReady, *order.StatusHistory[3].From)
+
+	// completed order is no longer active
+	orders, err := svc.ShowListOfOrders(ctx, service.ShowListOfOrdersInput{})
+	require.NoError(mainT, err)
+	require.Empty(mainT, orders)
+
+	orders, err = svc.ShowListOfOrders(ctx, service.ShowListOfOrdersInput{Status: "COMPLETED"})
+	require.NoError(mainT, err)
+	require.Len(mainT, orders, 1)
+
+	_, err = svc.ShowListOfOrders(ctx, service.ShowListOfOrdersInput{Status: "UNKNOWN"})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+}
+
+func TestRefundOrder(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: newCartGoods(),
+	})
+	mockStrg := deps.Storage.(*mockStorage)
+
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	output, err := svc.AddToCart(ctx, service.AddToCartInput{
+		UserID:  100,
+		GoodsID: 1,
+		Total:   3,
+	})
+	require.NoError(mainT, err)
+	_, err = svc.AddToCart(ctx, service.AddToCartInput{
+		CartID:  output.CartID,
+		UserID:  100,
+		GoodsID: 3,
+		Total:   2,
+	})
+	require.NoError(mainT, err)
+
+	// shopping cart can't be refunded
+	_, err = svc.RefundOrder(ctx, service.RefundOrderInput{TransactionID: output.CartID})
+	require.ErrorIs(mainT, err, service.ErrTransactionNotPaid)
+
+	_, err = svc.Pay(ctx, service.PayInput{
+		CartID:        output.CartID,
+		PaymentAmount: 10000,
+	})
+	require.NoError(mainT, err)
+
+	_, err = svc.RefundOrder(ctx, service.RefundOrderInput{
+		TransactionID: output.CartID,
+		Items:         []entity.RefundItem{{GoodsID: 1, TotalGoods: 1}, {GoodsID: 1, TotalGoods: 1}},
+	})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+
+	// partial refund keep the order status
+	refund, err := svc.RefundOrder(ctx, service.RefundOrderInput{
+		TransactionID: output.CartID,
+		Items:         []entity.RefundItem{{GoodsID: 1, TotalGoods: 2}},
+		Reason:        "spilled",
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money(4000), refund.Amount)
+	require.Equal(mainT, 102, mockStrg.Goods[0].Stocks)
+
+	order, err := svc.GetOrder(ctx, output.CartID)
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.TransactionStatusPaid, order.Status)
+	require.Equal(mainT, entity.Money(4000), order.RefundedAmount)
+
+	// refund can't exceed the purchased goods
+	_, err = svc.RefundOrder(ctx, service.RefundOrderInput{
+		TransactionID: output.CartID,
+		Items:         []entity.RefundItem{{GoodsID: 1, TotalGoods: 2}},
+	})
+	var exceedsErr entity.RefundExceedsPurchaseError
+	require.ErrorAs(mainT, err, &exceedsErr)
+	require.Equal(mainT, 1, exceedsErr.Refundable)
+
+	_, err = svc.RefundOrder(ctx, service.RefundOrderInput{
+		TransactionID: output.CartID,
+		Items:         []entity.RefundItem{{GoodsID: 2, TotalGoods: 1}},
+	})
+	require.ErrorAs(mainT, err, &exceedsErr)
+
+	// full refund take the rest of the goods
+	refund, err = svc.RefundOrder(ctx, service.RefundOrderInput{TransactionID: output.CartID})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money(2000+3000), refund.Amount)
+
+	order, err = svc.GetOrder(ctx, output.CartID)
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.TransactionStatusRefunded, order.Status)
+	require.Equal(mainT, order.TotalAmount, order.RefundedAmount)
+
+	// refunded order is final
+	_, err = svc.RefundOrder(ctx, service.RefundOrderInput{TransactionID: output.CartID})
+	require.ErrorAs(mainT, err, &entity.InvalidStatusTransitionError{})
+
+	// refunds reduce the sales of the day
+	report, err := svc.GetDailySalesReport(ctx, service.GetDailySalesReportInput{Date: time.Now()})
+	require.NoError(mainT, err)
+	require.Equal(mainT, 1, report.TotalTransactions)
+	require.Equal(mainT, 2, report.TotalRefunds)
+	require.Equal(mainT, entity.Money(9000), report.GrossAmount)
+	require.Equal(mainT, entity.Money(0), report.NetAmount())
+}
+
+func TestCancelOrder(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: newCartGoods(),
+	})
+
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	output, err := svc.AddToCart(ctx, service.AddToCartInput{
+		UserID:  100,
+		GoodsID: 1,
+		Total:   2,
+	})
+	require.NoError(mainT, err)
+	_, err = svc.Pay(ctx, service.PayInput{
+		CartID:        output.CartID,
+		PaymentAmount: 4000,
+	})
+	require.NoError(mainT, err)
+
+	for _, status := range []entity.TransactionStatus{
+		entity.TransactionStatusPreparing,
+		entity.TransactionStatusReady,
+		entity.TransactionStatusShipped,
+	} {
+		_, err = svc.UpdateOrderStatus(ctx, service.UpdateOrderStatusInput{
+			TransactionID: output.CartID,
+			Status:        status,
+		})
+		require.NoError(mainT, err)
+	}
+
+	// shipped order must be refunded instead
+	_, err = svc.CancelOrder(ctx, output.CartID, "buyer changed mind")
+	require.ErrorAs(mainT, err, &entity.InvalidStatusTransitionError{})
+
+	output, err = svc.AddToCart(ctx, service.AddToCartInput{
+		UserID:  100,
+		GoodsID: 2,
+		Total:   1,
+	})
+	require.NoError(mainT, err)
+	_, err = svc.Pay(ctx, service.PayInput{
+		CartID:        output.CartID,
+		PaymentAmount: 2000,
+	})
+	require.NoError(mainT, err)
+
+	refund, err := svc.CancelOrder(ctx, output.CartID, "out of ingredients")
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money(2000), refund.Amount)
+	require.Equal(mainT, "out of ingredients", refund.Reason)
+
+	order, err := svc.GetOrder(ctx, output.CartID)
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.TransactionStatusCancelled, order.Status)
+}
+
+func TestManageGoods(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: newCartGoods(),
+	})
+
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	goods, err := svc.CreateGoods(ctx, service.CreateGoodsInput{
+		Name:   " Es Jeruk ",
+		Stocks: 20,
+		Price:  3000,
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, 4, goods.ID)
+	require.Equal(mainT, "Es Jeruk", goods.Name)
+
+	// NewGoods validation is applied
+	_, err = svc.CreateGoods(ctx, service.CreateGoodsInput{Name: "Es Teh"})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+	_, err = svc.CreateGoods(ctx, service.CreateGoodsInput{Name: "Es Teh", Price: -1000})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+
+	_, err = svc.CreateGoods(ctx, service.CreateGoodsInput{Name: "kopi", Price: 3000})
+	require.ErrorIs(mainT, err, service.ErrGoodsNameAlreadyExists)
+
+	goods, err = svc.UpdateGoods(ctx, service.UpdateGoodsInput{ID: 4, Name: "Es Jeruk Nipis", Price: 3500})
+	require.NoError(mainT, err)
+	require.Equal(mainT, "Es Jeruk Nipis", goods.Name)
+	require.Equal(mainT, 20, goods.Stocks)
+
+	_, err = svc.UpdateGoods(ctx, service.UpdateGoodsInput{ID: 4, Name: "Bakwan", Price: 3500})
+	require.ErrorIs(mainT, err, service.ErrGoodsNameAlreadyExists)
+
+	// deleted goods can't be sold anymore but it's still found
+	err = svc.DeleteGoods(ctx, 1)
+	require.NoError(mainT, err)
+	err = svc.DeleteGoods(ctx, 1)
+	require.ErrorIs(mainT, err, service.ErrGoodsNotFound)
+
+	goods, err = svc.GetGoodsByID(ctx, 1)
+	require.NoError(mainT, err)
+	require.True(mainT, goods.IsDeleted())
+
+	_, err = svc.AddToCart(ctx, service.AddToCartInput{
+		UserID:  100,
+		GoodsID: 1,
+		Total:   1,
+	})
+	require.ErrorIs(mainT, err, service.ErrGoodsNotFound)
+
+	_, err = svc.UpdateGoods(ctx, service.UpdateGoodsInput{ID: 1, Name: "Kopi Tubruk", Price: 3000})
+	require.ErrorIs(mainT, err, service.ErrGoodsNotFound)
+
+	listOfGoods, err := svc.ShowListOfGoods(ctx, service.ShowListOfGoodsInput{TotalGoods: 10})
+	require.NoError(mainT, err)
+	require.Len(mainT, listOfGoods.Goods, 3)
+	require.NotEqual(mainT, 1, listOfGoods.Goods[0].ID)
+
+	// name of the deleted goods can be used again
+	_, err = svc.CreateGoods(ctx, service.CreateGoodsInput{Name: "Kopi", Price: 4000})
+	require.NoError(mainT, err)
+}
+
+func TestGoodsVariants(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: newCartGoods(),
+	})
+
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	drinks, err := svc.CreateCategory(ctx, service.CreateCategoryInput{Name: "Minuman"})
+	require.NoError(mainT, err)
+	_, err = svc.CreateCategory(ctx, service.CreateCategoryInput{Name: "minuman"})
+	require.ErrorIs(mainT, err, service.ErrCategoryAlreadyExists)
+
+	_, err = svc.UpdateGoods(ctx, service.UpdateGoodsInput{ID: 1, Name: "Kopi", Price: 2000, CategoryID: drinks.ID})
+	require.NoError(mainT, err)
+
+	iced, err := svc.CreateGoodsVariant(ctx, service.CreateGoodsVariantInput{
+		GoodsID:    1,
+		Name:       "Es",
+		PriceDelta: 1000,
+		Stocks:     10,
+	})
+	require.NoError(mainT, err)
+	_, err = svc.CreateGoodsVariant(ctx, service.CreateGoodsVariantInput{GoodsID: 1, Name: "es"})
+	require.ErrorIs(mainT, err, service.ErrVariantAlreadyExists)
+	_, err = svc.CreateGoodsVariant(ctx, service.CreateGoodsVariantInput{GoodsID: 1, Name: "Gratis", PriceDelta: -2000})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+	hot, err := svc.CreateGoodsVariant(ctx, service.CreateGoodsVariantInput{GoodsID: 1, Name: "Panas", PriceDelta: -500})
+	require.NoError(mainT, err)
+
+	// the goods price can't be lowered until one of its variants is free
+	_, err = svc.UpdateGoods(ctx, service.UpdateGoodsInput{ID: 1, Name: "Kopi", Price: 500, CategoryID: drinks.ID})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+	goods, err := svc.GetGoodsByID(ctx, 1)
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money(1500), goods.Variants[1].Price(goods.Price))
+	require.Equal(mainT, hot.ID, goods.Variants[1].ID)
+
+	// goods which has variants hold its stocks per variant
+	_, err = svc.UpdateStock(ctx, service.UpdateStockInput{Action: service.IncreaseStock, GoodsID: 1, Total: 5})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+	goods, err = svc.UpdateStock(ctx, service.UpdateStockInput{
+		Action:    service.DecreaseStock,
+		GoodsID:   1,
+		VariantID: iced.ID,
+		Total:     2,
+	})
+	require.NoError(mainT, err)
+	icedVariant, ok := goods.FindVariant(iced.ID)
+	require.True(mainT, ok)
+	require.Equal(mainT, 8, icedVariant.Stocks)
+
+	// goods which has variants must be sold through one of them
+	_, err = svc.AddToCart(ctx, service.AddToCartInput{UserID: 100, GoodsID: 1, Total: 1})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+	_, err = svc.AddToCart(ctx, service.AddToCartInput{UserID: 100, GoodsID: 1, VariantID: 999, Total: 1})
+	require.ErrorIs(mainT, err, service.ErrVariantNotFound)
+	_, err = svc.AddToCart(ctx, service.AddToCartInput{UserID: 100, GoodsID: 2, VariantID: iced.ID, Total: 1})
+	require.ErrorIs(mainT, err, service.ErrVariantNotFound)
+
+	output, err := svc.AddToCart(ctx, service.AddToCartInput{UserID: 100, GoodsID: 1, VariantID: iced.ID, Total: 2})
+	require.NoError(mainT, err)
+	output, err = svc.AddToCart(ctx, service.AddToCartInput{CartID: output.CartID, UserID: 100, GoodsID: 3, Total: 1})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money((2*3000)+(1*1500)), output.TotalAmount)
+
+	cart, err := svc.UpdateCartGoods(ctx, service.UpdateCartGoodsInput{
+		CartID:    output.CartID,
+		GoodsID:   1,
+		VariantID: iced.ID,
+		Total:     1,
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money(3000+1500), cart.GetTotalAmount())
+
+	_, err = svc.RemoveGoodsFromCart(ctx, output.CartID, 1, 0)
+	require.ErrorIs(mainT, err, entity.ErrGoodsNotInCart)
+
+	menu, err := svc.ShowMenu(ctx)
+	require.NoError(mainT, err)
+	require.Len(mainT, menu, 2)
+	require.Equal(mainT, "Minuman", menu[0].Category.Name)
+	require.Len(mainT, menu[0].Goods, 1)
+	require.Len(mainT, menu[0].Goods[0].Variants, 2)
+	require.Equal(mainT, service.UncategorizedMenuName, menu[1].Category.Name)
+	require.Len(mainT, menu[1].Goods, 2)
+}
+
+func TestRecipes(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: newCartGoods(),
+	})
+
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	rawMaterials := []service.CreateGoodsInput{
+		{Name: "Biji Kopi", Stocks: 100, Price: 200, IsRawMaterial: true, Unit: "gram"},
+		{Name: "Gula Aren", Stocks: 50, Price: 50, IsRawMaterial: true, Unit: "gram"},
+		{Name: "Susu", Stocks: 1000, Price: 20, IsRawMaterial: true, Unit: "ml"},
+	}
+	rawMaterialIDs := []int{}
+	for _, input := range rawMaterials {
+		rawMaterial, err := svc.CreateGoods(ctx, input)
+		require.NoError(mainT, err)
+		rawMaterialIDs = append(rawMaterialIDs, rawMaterial.ID)
+	}
+	kopiGulaAren, err := svc.CreateGoods(ctx, service.CreateGoodsInput{Name: "Kopi Gula Aren", Price: 18000})
+	require.NoError(mainT, err)
+
+	// only raw materials could be the ingredients, each of them listed once
+	_, err = svc.SetRecipe(ctx, service.SetRecipeInput{
+		GoodsID:     kopiGulaAren.ID,
+		Ingredients: []entity.RecipeIngredient{{IngredientID: 3, Quantity: 1}},
+	})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+	_, err = svc.SetRecipe(ctx, service.SetRecipeInput{
+		GoodsID: kopiGulaAren.ID,
+		Ingredients: []entity.RecipeIngredient{
+			{IngredientID: rawMaterialIDs[0], Quantity: 18},
+			{IngredientID: rawMaterialIDs[0], Quantity: 10},
+		},
+	})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+
+	recipe, err := svc.SetRecipe(ctx, service.SetRecipeInput{
+		GoodsID: kopiGulaAren.ID,
+		Ingredients: []entity.RecipeIngredient{
+			{IngredientID: rawMaterialIDs[0], Quantity: 18},
+			{IngredientID: rawMaterialIDs[1], Quantity: 20},
+			{IngredientID: rawMaterialIDs[2], Quantity: 150},
+		},
+	})
+	require.NoError(mainT, err)
+	// palm sugar is the scarcest, 50 gram only enough for 2 servings
+	require.Equal(mainT, 2, recipe.Servings)
+
+	_, err = svc.AddToCart(ctx, service.AddToCartInput{UserID: 100, GoodsID: rawMaterialIDs[0], Total: 1})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+	_, err = svc.AddToCart(ctx, service.AddToCartInput{UserID: 100, GoodsID: kopiGulaAren.ID, Total: 3})
+	require.ErrorAs(mainT, err, &entity.InsufficientStockError{})
+
+	output, err := svc.AddToCart(ctx, service.AddToCartInput{UserID: 100, GoodsID: kopiGulaAren.ID, Total: 1})
+	require.NoError(mainT, err)
+	// the raw materials is reserved by the first cart, another cart can't take them
+	otherOutput, err := svc.AddToCart(ctx, service.AddToCartInput{UserID: 101, GoodsID: kopiGulaAren.ID, Total: 2})
+	require.ErrorAs(mainT, err, &entity.InsufficientStockError{})
+	otherOutput, err = svc.AddToCart(ctx, service.AddToCartInput{UserID: 101, GoodsID: kopiGulaAren.ID, Total: 1})
+	require.NoError(mainT, err)
+	_, err = svc.UpdateCartGoods(ctx, service.UpdateCartGoodsInput{CartID: output.CartID, GoodsID: kopiGulaAren.ID, Total: 2})
+	require.ErrorAs(mainT, err, &entity.InsufficientStockError{})
+	// abandoned cart give back its raw materials
+	require.NoError(mainT, svc.AbandonCart(ctx, otherOutput.CartID))
+	rawMaterial, err := svc.GetGoodsByID(ctx, rawMaterialIDs[1])
+	require.NoError(mainT, err)
+	require.Equal(mainT, 20, rawMaterial.ReservedStocks)
+	_, err = svc.UpdateCartGoods(ctx, service.UpdateCartGoodsInput{CartID: output.CartID, GoodsID: kopiGulaAren.ID, Total: 2})
+	require.NoError(mainT, err)
+
+	_, err = svc.Pay(ctx, service.PayInput{CartID: output.CartID, PaymentAmount: 36000})
+	require.NoError(mainT, err)
+
+	expectedStocks := []int{100 - 2*18, 50 - 2*20, 1000 - 2*150}
+	for i, rawMaterialID := range rawMaterialIDs {
+		rawMaterial, err := svc.GetGoodsByID(ctx, rawMaterialID)
+		require.NoError(mainT, err)
+		require.Equal(mainT, expectedStocks[i], rawMaterial.Stocks)
+		require.Equal(mainT, 0, rawMaterial.ReservedStocks)
+	}
+	goods, err := svc.GetGoodsByID(ctx, kopiGulaAren.ID)
+	require.NoError(mainT, err)
+	require.Equal(mainT, 0, goods.SellableStocks())
+
+	// raw materials is not on the menu
+	menu, err := svc.ShowMenu(ctx)
+	require.NoError(mainT, err)
+	require.Len(mainT, menu, 1)
+	require.Len(mainT, menu[0].Goods, 4)
+}
+
+func TestUpdateStock(mainT *testing.T) {
+	testCases := []struct {
+		Name           string
+		Input          service.UpdateStockInput
+		ExpectedStocks int
+		ExpectedError  error
+	}{
+		{
+			Name: "Successfully increase stock",
+			Input: service.UpdateStockInput{
+				Action:  service.IncreaseStock,
+				GoodsID: 1,
+				Total:   5,
+			},
+			ExpectedStocks: 15,
+		},
+		{
+			Name: "Successfully decrease stock by goods name",
+			Input: service.UpdateStockInput{
+				Action:    service.DecreaseStock,
+				GoodsName: "Kopi",
+				Total:     10,
+			},
+			ExpectedStocks: 0,
+		},
+		{
+			Name: "Decrease stock below zero",
+			Input: service.UpdateStockInput{
+				Action:  service.DecreaseStock,
+				GoodsID: 1,
+				Total:   11,
+			},
+			ExpectedError: entity.InsufficientStockError{GoodsID: 1, Stocks: 10, Requested: 11},
+		},
+		{
+			Name: "Unknown action",
+			Input: service.UpdateStockInput{
+				Action:  "SET",
+				GoodsID: 1,
+				Total:   1,
+			},
+			ExpectedError: service.ErrInvalidInput,
+		},
+		{
+			Name: "Goods not exist",
+			Input: service.UpdateStockInput{
+				Action:  service.IncreaseStock,
+				GoodsID: 99,
+				Total:   1,
+			},
+			ExpectedError: service.ErrGoodsNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		mainT.Run(testCase.Name, func(t *testing.T) {
+			deps := newMockDependencies(mockDependenciesConfig{
+				mockStorageDummyGoods: []entity.Goods{
+					{ID: 1, Name: "Kopi", Stocks: 10, Price: 3000},
+				},
+			})
+
+			svc, err := service.NewService(service.ServiceConfig(deps))
+			require.NoError(t, err)
+
+			goods, err := svc.UpdateStock(context.Background(), testCase.Input)
+			if testCase.ExpectedError != nil {
+				require.ErrorIs(t, err, testCase.ExpectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testCase.ExpectedStocks, goods.Stocks)
+		})
+	}
+}
+
+func TestStockMovements(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: []entity.Goods{
+			{ID: 1, Name: "Kopi", Stocks: 10, Price: 3000},
+		},
+	})
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	_, err = svc.UpdateStock(ctx, service.UpdateStockInput{
+		Action:  service.WasteStock,
+		GoodsID: 1,
+		Total:   3,
+		Actor:   "budi",
+		Reason:  "spoiled",
+	})
+	require.NoError(mainT, err)
+
+	mainT.Run("Show today movements", func(t *testing.T) {
+		movements, err := svc.ShowStockMovements(ctx, service.ShowStockMovementsInput{
+			GoodsID: 1,
+			From:    time.Now(),
+			To:      time.Now(),
+		})
+		require.NoError(t, err)
+		require.Len(t, movements, 1)
+		require.Equal(t, entity.StockMovementWastage, movements[0].Type)
+		require.Equal(t, -3, movements[0].Quantity)
+		require.Equal(t, 10, movements[0].Before)
+		require.Equal(t, 7, movements[0].After)
+		require.Equal(t, "budi", movements[0].Actor)
+		require.Equal(t, "spoiled", movements[0].Reason)
+	})
+
+	mainT.Run("Movements before the date range", func(t *testing.T) {
+		movements, err := svc.ShowStockMovements(ctx, service.ShowStockMovementsInput{
+			GoodsID: 1,
+			From:    time.Now().AddDate(0, 0, 1),
+			To:      time.Now().AddDate(0, 0, 2),
+		})
+		require.NoError(t, err)
+		require.Empty(t, movements)
+	})
+
+	mainT.Run("From date after to date", func(t *testing.T) {
+		_, err := svc.ShowStockMovements(ctx, service.ShowStockMovementsInput{
+			GoodsID: 1,
+			From:    time.Now(),
+			To:      time.Now().AddDate(0, 0, -1),
+		})
+		require.ErrorIs(t, err, service.ErrInvalidInput)
+	})
+
+	mainT.Run("Goods ID is required", func(t *testing.T) {
+		_, err := svc.ShowStockMovements(ctx, service.ShowStockMovementsInput{From: time.Now(), To: time.Now()})
+		require.ErrorIs(t, err, service.ErrInvalidInput)
+	})
+
+	mainT.Run("Reconcile stocks report the drifts", func(t *testing.T) {
+		deps.Storage.(*mockStorage).StockDrifts = []entity.StockDrift{{GoodsID: 1, Stocks: 7, LedgerStocks: 5}}
+		drifts, err := svc.ReconcileStocks(ctx)
+		require.NoError(t, err)
+		require.Len(t, drifts, 1)
+		require.Equal(t, 2, drifts[0].Drift())
+	})
+}
+
+func TestStockCounts(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: newCartGoods(),
+	})
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	stockCount, err := svc.OpenStockCount(ctx, service.OpenStockCountInput{})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.StockCountOpen, stockCount.Status)
+	require.Equal(mainT, entity.StockActorSystem, stockCount.OpenedBy)
+
+	testCases := []struct {
+		Name  string
+		Items []entity.StockCountItem
+	}{
+		{
+			Name: "Counted stocks is negative",
+			Items: []entity.StockCountItem{
+				{GoodsID: 1, CountedStocks: -1},
+			},
+		},
+		{
+			Name: "Goods counted more than once",
+			Items: []entity.StockCountItem{
+				{GoodsID: 1, CountedStocks: 10},
+				{GoodsID: 1, CountedStocks: 12},
+			},
+		},
+		{
+			Name:  "Nothing counted",
+			Items: []entity.StockCountItem{},
+		},
+	}
+	for _, testCase := range testCases {
+		mainT.Run(testCase.Name, func(t *testing.T) {
+			_, err := svc.SubmitStockCount(ctx, service.SubmitStockCountInput{
+				CountID: stockCount.ID,
+				Items:   testCase.Items,
+			})
+			require.ErrorIs(t, err, service.ErrInvalidInput)
+		})
+	}
+
+	mainT.Run("Variance against the system stocks", func(t *testing.T) {
+		stockCount, err := svc.SubmitStockCount(ctx, service.SubmitStockCountInput{
+			CountID: stockCount.ID,
+			Items: []entity.StockCountItem{
+				{GoodsID: 1, CountedStocks: 97},
+				{GoodsID: 3, CountedStocks: 102},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, stockCount.Items, 2)
+		require.Equal(t, -3, stockCount.Items[0].Variance())
+		require.Equal(t, entity.Money(-6000), stockCount.Items[0].VarianceValue())
+		require.Equal(t, entity.Money(-3000), stockCount.TotalVarianceValue())
+	})
+
+	mainT.Run("Approve adjust the stocks", func(t *testing.T) {
+		stockCount, err := svc.ApproveStockCount(ctx, service.ApproveStockCountInput{
+			CountID:    stockCount.ID,
+			ApprovedBy: " siti ",
+		})
+		require.NoError(t, err)
+		require.Equal(t, entity.StockCountApproved, stockCount.Status)
+		require.Equal(t, "siti", stockCount.ApprovedBy)
+		require.Positive(t, stockCount.ApprovedAt)
+
+		goods, err := svc.GetGoodsByID(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, 97, goods.Stocks)
+
+		_, err = svc.ApproveStockCount(ctx, service.ApproveStockCountInput{CountID: stockCount.ID})
+		require.ErrorIs(t, err, service.ErrStockCountApproved)
+	})
+
+	mainT.Run("Stock count not exist", func(t *testing.T) {
+		_, err := svc.GetStockCount(ctx, 99)
+		require.ErrorIs(t, err, service.ErrStockCountNotFound)
+	})
+
+	mainT.Run("Shrinkage below the reserved stocks", func(t *testing.T) {
+		deps := newMockDependencies(mockDependenciesConfig{})
+		svc, err := service.NewService(service.ServiceConfig(deps))
+		require.NoError(t, err)
+		notifier := deps.Notifier.(*mockNotifier)
+
+		kopi, err := svc.CreateGoods(ctx, service.CreateGoodsInput{Name: "Kopi", Stocks: 10, Price: 3000, ReorderPoint: 5})
+		require.NoError(t, err)
+		// open shopping cart reserve more than the counted stocks
+		_, err = svc.AddToCart(ctx, service.AddToCartInput{UserID: 100, GoodsID: kopi.ID, Total: 6})
+		require.NoError(t, err)
+
+		stockCount, err := svc.OpenStockCount(ctx, service.OpenStockCountInput{})
+		require.NoError(t, err)
+		_, err = svc.SubmitStockCount(ctx, service.SubmitStockCountInput{
+			CountID: stockCount.ID,
+			Items:   []entity.StockCountItem{{GoodsID: kopi.ID, CountedStocks: 4}},
+		})
+		require.NoError(t, err)
+		stockCount, err = svc.ApproveStockCount(ctx, service.ApproveStockCountInput{CountID: stockCount.ID})
+		require.NoError(t, err)
+		require.Equal(t, []entity.StockMovement{{
+			GoodsID:   kopi.ID,
+			Type:      entity.StockMovementAdjustment,
+			Quantity:  -6,
+			Before:    10,
+			After:     4,
+			Actor:     entity.StockActorSystem,
+			Reason:    fmt.Sprintf("stock count #%d", stockCount.ID),
+			CreatedAt: stockCount.ApprovedAt,
+		}}, stockCount.Adjustments())
+
+		goods, err := svc.GetGoodsByID(ctx, kopi.ID)
+		require.NoError(t, err)
+		require.Equal(t, 4, goods.Stocks)
+
+		// the shrinkage cross the reorder point
+		deps.AlertDispatcher.(*mockAlertDispatcher).Wait()
+		require.Len(t, notifier.LowStocks(), 1)
+		require.Equal(t, 4, notifier.LowStocks()[0].Stocks)
+	})
+}
+
+func TestPurchaseOrders(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: []entity.Goods{
+			{ID: 1, Name: "Kopi", Stocks: 10, Price: 3000, AverageCost: 500},
+			{ID: 2, Name: "Gula Aren", Stocks: 0, Price: 50, IsRawMaterial: true, Unit: "gram"},
+		},
+	})
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	_, err = svc.CreateSupplier(ctx, service.CreateSupplierInput{Name: " "})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+	supplier, err := svc.CreateSupplier(ctx, service.CreateSupplierInput{Name: "Toko Sembako Jaya", Phone: "08123456789"})
+	require.NoError(mainT, err)
+
+	invalidOrders := map[string]service.CreatePurchaseOrderInput{
+		"Without items": {SupplierID: supplier.ID},
+		"Zero quantity": {SupplierID: supplier.ID, Items: []entity.PurchaseOrderItem{{GoodsID: 1, UnitCost: 1000}}},
+		"Negative cost": {SupplierID: supplier.ID, Items: []entity.PurchaseOrderItem{{GoodsID: 1, Quantity: 1, UnitCost: -1}}},
+		"Duplicate goods": {SupplierID: supplier.ID, Items: []entity.PurchaseOrderItem{
+			{GoodsID: 1, Quantity: 1, UnitCost: 1000},
+			{GoodsID: 1, Quantity: 2, UnitCost: 1000},
+		}},
+	}
+	for name, input := range invalidOrders {
+		mainT.Run(name, func(t *testing.T) {
+			_, err := svc.CreatePurchaseOrder(ctx, input)
+			require.ErrorIs(t, err, service.ErrInvalidInput)
+		})
+	}
+
+	purchaseOrder, err := svc.CreatePurchaseOrder(ctx, service.CreatePurchaseOrderInput{
+		SupplierID: supplier.ID,
+		CreatedBy:  "budi",
+		Items: []entity.PurchaseOrderItem{
+			{GoodsID: 1, Quantity: 10, UnitCost: 1000},
+			{GoodsID: 2, Quantity: 1000, UnitCost: 40},
+		},
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.PurchaseOrderOpen, purchaseOrder.Status)
+	require.Equal(mainT, entity.Money(50000), purchaseOrder.TotalCost())
+
+	mainT.Run("Receive part of the order", func(t *testing.T) {
+		received, err := svc.ReceivePurchaseOrder(ctx, service.ReceivePurchaseOrderInput{
+			OrderID:    purchaseOrder.ID,
+			ReceivedBy: "budi",
+			Items:      []entity.PurchaseOrderReceiptItem{{GoodsID: 1, Quantity: 10}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, entity.PurchaseOrderPartiallyReceived, received.Status)
+		require.Equal(t, entity.Money(10000), received.ReceivedCost())
+
+		// 10 goods at 500 and 10 goods at 1000
+		goods, err := svc.GetGoodsByID(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, 20, goods.Stocks)
+		require.Equal(t, float64(750), goods.AverageCost)
+	})
+
+	mainT.Run("Receive more than ordered", func(t *testing.T) {
+		_, err := svc.ReceivePurchaseOrder(ctx, service.ReceivePurchaseOrderInput{
+			OrderID: purchaseOrder.ID,
+			Items:   []entity.PurchaseOrderReceiptItem{{GoodsID: 1, Quantity: 1}},
+		})
+		require.ErrorIs(t, err, service.ErrInvalidInput)
+	})
+
+	mainT.Run("Receive the rest of the order", func(t *testing.T) {
+		received, err := svc.ReceivePurchaseOrder(ctx, service.ReceivePurchaseOrderInput{
+			OrderID: purchaseOrder.ID,
+			Items:   []entity.PurchaseOrderReceiptItem{{GoodsID: 2, Quantity: 1000}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, entity.PurchaseOrderReceived, received.Status)
+		require.Len(t, received.Receipts, 2)
+
+		rawMaterial, err := svc.GetGoodsByID(ctx, 2)
+		require.NoError(t, err)
+		require.Equal(t, float64(40), rawMaterial.UnitCost())
+	})
+
+	mainT.Run("Closed purchase order", func(t *testing.T) {
+		_, err := svc.ReceivePurchaseOrder(ctx, service.ReceivePurchaseOrderInput{
+			OrderID: purchaseOrder.ID,
+			Items:   []entity.PurchaseOrderReceiptItem{{GoodsID: 2, Quantity: 1}},
+		})
+		require.ErrorIs(t, err, service.ErrPurchaseOrderClosed)
+		_, err = svc.CancelPurchaseOrder(ctx, purchaseOrder.ID)
+		require.ErrorIs(t, err, service.ErrPurchaseOrderClosed)
+	})
+
+	mainT.Run("Unit cost only for increasing stocks", func(t *testing.T) {
+		_, err := svc.UpdateStock(ctx, service.UpdateStockInput{
+			Action:   service.DecreaseStock,
+			GoodsID:  1,
+			Total:    1,
+			UnitCost: 1000,
+		})
+		require.ErrorIs(t, err, service.ErrInvalidInput)
+	})
+
+	purchaseOrders, err := svc.ShowPurchaseOrders(ctx, service.ShowPurchaseOrdersInput{Status: entity.PurchaseOrderReceived})
+	require.NoError(mainT, err)
+	require.Len(mainT, purchaseOrders, 1)
+	_, err = svc.ShowPurchaseOrders(ctx, service.ShowPurchaseOrdersInput{Status: "DONE"})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+}
+
+func TestReorderPoints(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{})
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+	notifier := deps.Notifier.(*mockNotifier)
+	alertDispatcher := deps.AlertDispatcher.(*mockAlertDispatcher)
+
+	ctx := context.Background()
+	kopi, err := svc.CreateGoods(ctx, service.CreateGoodsInput{Name: "Kopi", Stocks: 10, Price: 3000, ReorderPoint: 5})
+	require.NoError(mainT, err)
+	gula, err := svc.CreateGoods(ctx, service.CreateGoodsInput{
+		Name:          "Gula Aren",
+		Stocks:        100,
+		Price:         50,
+		IsRawMaterial: true,
+		Unit:          "gram",
+		ReorderPoint:  60,
+	})
+	require.NoError(mainT, err)
+	esKopi, err := svc.CreateGoods(ctx, service.CreateGoodsInput{Name: "Es Kopi Gula Aren", Price: 18000})
+	require.NoError(mainT, err)
+	_, err = svc.SetRecipe(ctx, service.SetRecipeInput{
+		GoodsID:     esKopi.ID,
+		Ingredients: []entity.RecipeIngredient{{IngredientID: gula.ID, Quantity: 20}},
+	})
+	require.NoError(mainT, err)
+
+	_, err = svc.CreateGoods(ctx, service.CreateGoodsInput{Name: "Teh", Stocks: 10, Price: 2000, ReorderPoint: -1})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+
+	mainT.Run("Decrease above the reorder point", func(t *testing.T) {
+		_, err := svc.UpdateStock(ctx, service.UpdateStockInput{Action: service.DecreaseStock, GoodsID: kopi.ID, Total: 4})
+		require.NoError(t, err)
+		// the check is run in the background, wait it before asserting nothing is notified
+		alertDispatcher.Wait()
+		require.Empty(t, notifier.LowStocks())
+	})
+
+	mainT.Run("Decrease crossing the reorder point", func(t *testing.T) {
+		_, err := svc.UpdateStock(ctx, service.UpdateStockInput{Action: service.DecreaseStock, GoodsID: kopi.ID, Total: 2})
+		require.NoError(t, err)
+		alertDispatcher.Wait()
+		require.Len(t, notifier.LowStocks(), 1)
+
+		lowStock := notifier.LowStocks()[0]
+		require.Equal(t, kopi.ID, lowStock.GoodsID)
+		require.Equal(t, 4, lowStock.Stocks)
+		require.Equal(t, 5, lowStock.ReorderPoint)
+	})
+
+	mainT.Run("Decrease already below the reorder point", func(t *testing.T) {
+		_, err := svc.UpdateStock(ctx, service.UpdateStockInput{Action: service.WasteStock, GoodsID: kopi.ID, Total: 1})
+		require.NoError(t, err)
+		alertDispatcher.Wait()
+		require.Len(t, notifier.LowStocks(), 1)
+	})
+
+	mainT.Run("Sale crossing the raw material reorder point", func(t *testing.T) {
+		output, err := svc.AddToCart(ctx, service.AddToCartInput{UserID: 100, GoodsID: esKopi.ID, Total: 2})
+		require.NoError(t, err)
+		_, err = svc.Pay(ctx, service.PayInput{CartID: output.CartID, PaymentAmount: 36000})
+		require.NoError(t, err)
+		alertDispatcher.Wait()
+		require.Len(t, notifier.LowStocks(), 2)
+
+		lowStock := notifier.LowStocks()[1]
+		require.Equal(t, gula.ID, lowStock.GoodsID)
+		require.Equal(t, 60, lowStock.Stocks)
+		require.Equal(t, "gram", lowStock.Unit)
+	})
+
+	mainT.Run("Show low stocks", func(t *testing.T) {
+		lowStocks, err := svc.ShowLowStocks(ctx)
+		require.NoError(t, err)
+		require.Len(t, lowStocks, 2)
+	})
+}
+
+func TestStockBatches(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{})
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+	storage := deps.Storage.(*mockStorage)
+
+	ctx := context.Background()
+	susu, err := svc.CreateGoods(ctx, service.CreateGoodsInput{Name: "Susu", Price: 8000})
+	require.NoError(mainT, err)
+	bakwan, err := svc.CreateGoods(ctx, service.CreateGoodsInput{Name: "Bakwan", Price: 1500})
+	require.NoError(mainT, err)
+
+	now := time.Now()
+	restocks := []service.UpdateStockInput{
+		{GoodsID: susu.ID, Total: 5, ExpiresAt: now.AddDate(0, 0, -1).Unix()},
+		{GoodsID: susu.ID, Total: 10, ExpiresAt: now.AddDate(0, 0, 5).Unix()},
+		{GoodsID: susu.ID, Total: 10},
+		{GoodsID: bakwan.ID, Total: 20, ExpiresAt: now.Add(time.Hour).Unix()},
+	}
+	for _, restock := range restocks {
+		restock.Action = service.IncreaseStock
+		_, err = svc.UpdateStock(ctx, restock)
+		require.NoError(mainT, err)
+	}
+
+	mainT.Run("Expiry date is only for increased stocks", func(t *testing.T) {
+		_, err := svc.UpdateStock(ctx, service.UpdateStockInput{
+			Action:    service.DecreaseStock,
+			GoodsID:   susu.ID,
+			Total:     1,
+			ExpiresAt: now.Unix(),
+		})
+		require.ErrorIs(t, err, service.ErrInvalidInput)
+	})
+
+	mainT.Run("Show batches expiring today", func(t *testing.T) {
+		batches, err := svc.ShowExpiringBatches(ctx, service.ShowExpiringBatchesInput{Days: 0})
+		require.NoError(t, err)
+		// the expired susu and the bakwan which expires within an hour, unless it's already tomorrow by then
+		require.NotEmpty(t, batches)
+		require.Equal(t, susu.ID, batches[0].GoodsID)
+		require.True(t, batches[0].IsExpired(now.Unix()))
+	})
+
+	mainT.Run("Show batches expiring within a week", func(t *testing.T) {
+		batches, err := svc.ShowExpiringBatches(ctx, service.ShowExpiringBatchesInput{Days: 7})
+		require.NoError(t, err)
+		require.Len(t, batches, 3)
+		require.Equal(t, 10, batches[2].Quantity)
+	})
+
+	mainT.Run("Negative days", func(t *testing.T) {
+		_, err := svc.ShowExpiringBatches(ctx, service.ShowExpiringBatchesInput{Days: -1})
+		require.ErrorIs(t, err, service.ErrInvalidInput)
+	})
+
+	mainT.Run("Write off without reason", func(t *testing.T) {
+		_, err := svc.WriteOffExpiredBatches(ctx, service.WriteOffExpiredBatchesInput{Reason: " "})
+		require.ErrorIs(t, err, service.ErrInvalidInput)
+	})
+
+	mainT.Run("Write off expired batches", func(t *testing.T) {
+		movements, err := svc.WriteOffExpiredBatches(ctx, service.WriteOffExpiredBatchesInput{
+			Actor:  "budi",
+			Reason: "kedaluwarsa",
+		})
+		require.NoError(t, err)
+		require.Len(t, movements, 1)
+		require.Equal(t, susu.ID, movements[0].GoodsID)
+		require.Equal(t, entity.StockMovementWastage, movements[0].Type)
+		require.Equal(t, -5, movements[0].Quantity)
+		require.Equal(t, 20, movements[0].After)
+		require.Equal(t, "kedaluwarsa", movements[0].Reason)
+
+		goods, err := svc.GetGoodsByID(ctx, susu.ID)
+		require.NoError(t, err)
+		require.Equal(t, 20, goods.Stocks)
+		require.Zero(t, storage.StockBatches[0].Quantity)
+		require.Equal(t, 10, storage.StockBatches[1].Quantity)
+	})
+
+	mainT.Run("Nothing expired anymore", func(t *testing.T) {
+		movements, err := svc.WriteOffExpiredBatches(ctx, service.WriteOffExpiredBatchesInput{Reason: "kedaluwarsa"})
+		require.NoError(t, err)
+		require.Empty(t, movements)
+	})
+}
+
+func TestPromotions(mainT *testing.T) {
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: newCartGoods(),
+	})
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+	storage := deps.Storage.(*mockStorage)
+
+	ctx := context.Background()
+	mainT.Run("Invalid promotions", func(t *testing.T) {
+		invalidInputs := []service.CreatePromotionInput{
+			{Name: "Diskon", Type: "PERCENTAGE", Value: 150},
+			{Name: "Diskon", Type: "FIXED"},
+			{Name: "Diskon", Type: "FIXED", Value: 1000},
+			{Name: "Gratis", Type: "BUY_X_GET_Y", BuyQuantity: 2, FreeQuantity: 1},
+			{Name: "Diskon", Type: "CASHBACK", Value: 10},
+			{Name: "Diskon", Type: "PERCENTAGE", Value: 10, HappyHourStart: "25:00"},
+		}
+		for _, input := range invalidInputs {
+			_, err := svc.CreatePromotion(ctx, input)
+			require.ErrorIs(t, err, service.ErrInvalidInput)
+		}
+	})
+
+	_, err = svc.CreatePromotion(ctx, service.CreatePromotionInput{
+		Name:         "Bakwan beli 2 gratis 1",
+		Type:         "BUY_X_GET_Y",
+		GoodsID:      3,
+		BuyQuantity:  2,
+		FreeQuantity: 1,
+	})
+	require.NoError(mainT, err)
+	voucher, err := svc.CreatePromotion(ctx, service.CreatePromotionInput{
+		Name:        "Hemat 10%",
+		Type:        "percentage",
+		Value:       10,
+		MinPurchase: 10000,
+		VoucherCode: " hemat10 ",
+		UsageLimit:  1,
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, "HEMAT10", voucher.VoucherCode)
+
+	mainT.Run("Duplicate voucher", func(t *testing.T) {
+		_, err := svc.CreatePromotion(ctx, service.CreatePromotionInput{
+			Name:        "Hemat",
+			Type:        "FIXED",
+			Amount:      1000,
+			VoucherCode: "HEMAT10",
+		})
+		require.ErrorIs(t, err, service.ErrVoucherAlreadyExists)
+	})
+
+	mainT.Run("Unknown voucher", func(t *testing.T) {
+		_, err := svc.AddToCart(ctx, service.AddToCartInput{
+			UserID:      100,
+			GoodsID:     1,
+			Total:       1,
+			VoucherCode: "GRATIS",
+		})
+		require.ErrorIs(t, err, service.ErrVoucherNotApplicable)
+	})
+
+	output, err := svc.AddToCart(ctx, service.AddToCartInput{
+		UserID:      100,
+		GoodsID:     3,
+		Total:       3,
+		VoucherCode: "hemat10",
+	})
+	require.NoError(mainT, err)
+	// the voucher is kept although the cart doesn't reach its minimum purchase yet
+	require.Equal(mainT, entity.Money(4500), output.SubtotalAmount)
+	require.Equal(mainT, entity.Money(3000), output.TotalAmount)
+	require.Len(mainT, output.Discounts, 1)
+	require.Equal(mainT, "Bakwan beli 2 gratis 1", output.Discounts[0].Name)
+
+	output, err = svc.AddToCart(ctx, service.AddToCartInput{
+		CartID:  output.CartID,
+		UserID:  100,
+		GoodsID: 1,
+		Total:   4,
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money(12500), output.SubtotalAmount)
+	require.Equal(mainT, entity.Money(12500-1500-1250), output.TotalAmount)
+	require.Len(mainT, output.Discounts, 2)
+	require.Equal(mainT, "HEMAT10", output.Discounts[1].VoucherCode)
+
+	mainT.Run("Get cart with discounts", func(t *testing.T) {
+		cart, err := svc.GetCart(ctx, output.CartID)
+		require.NoError(t, err)
+		require.Equal(t, "HEMAT10", cart.VoucherCode)
+		require.Equal(t, entity.Money(9750), cart.TotalAmount)
+		require.Equal(t, entity.Money(2750), cart.GetDiscountAmount())
+	})
+
+	mainT.Run("Payment is checked against the total after discounts", func(t *testing.T) {
+		_, err := svc.Pay(ctx, service.PayInput{CartID: output.CartID, PaymentAmount: 9700})
+		var insufficientErr entity.InsufficientPaymentError
+		require.ErrorAs(t, err, &insufficientErr)
+	})
+
+	trx, err := svc.Pay(ctx, service.PayInput{CartID: output.CartID, PaymentAmount: 10000})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money(9750), trx.TotalAmount)
+	require.Equal(mainT, entity.Money(2750), trx.DiscountAmount)
+	require.Equal(mainT, entity.Money(250), trx.ReturnAmount)
+	require.Len(mainT, trx.Discounts, 2)
+	require.Equal(mainT, 1, storage.Promotions[1].UsedCount)
+
+	mainT.Run("Voucher reach its usage limit", func(t *testing.T) {
+		_, err := svc.AddToCart(ctx, service.AddToCartInput{
+			UserID:      101,
+			GoodsID:     1,
+			Total:       5,
+			VoucherCode: "HEMAT10",
+		})
+		require.ErrorIs(t, err, service.ErrVoucherNotApplicable)
+	})
+
+	mainT.Run("Refund of discounted order", func(t *testing.T) {
+		refund, err := svc.RefundOrder(ctx, service.RefundOrderInput{
+			TransactionID: trx.ID,
+			Items:         []entity.RefundItem{{GoodsID: 1, TotalGoods: 2}},
+		})
+		require.NoError(t, err)
+		// the discount is spread into the goods proportionally to their price
+		require.Equal(t, entity.Money(3120), refund.Amount)
+
+		refund, err = svc.RefundOrder(ctx, service.RefundOrderInput{TransactionID: trx.ID})
+		require.NoError(t, err)
+		require.Equal(t, entity.Money(9750-3120), refund.Amount)
+	})
+}
+
+func TestTaxes(mainT *testing.T) {
+	goods := newCartGoods()
+	goods[1].PriceIncludesTax = true
+	deps := newMockDependencies(mockDependenciesConfig{
+		mockStorageDummyGoods: goods,
+	})
+	deps.TaxRate = 11
+	deps.ServiceChargeRate = 5
+
+	svc, err := service.NewService(service.ServiceConfig(deps))
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	output, err := svc.AddToCart(ctx, service.AddToCartInput{
+		UserID:  100,
+		GoodsID: 1,
+		Total:   2,
+	})
+	require.NoError(mainT, err)
+	output, err = svc.AddToCart(ctx, service.AddToCartInput{
+		CartID:  output.CartID,
+		UserID:  100,
+		GoodsID: 2,
+		Total:   1,
+	})
+	require.NoError(mainT, err)
+	// Kopi 4000 get PPN on top of it, Teh manis 2000 already contains PPN 198
+	expectedBill := entity.Bill{
+		SubtotalAmount:      6000,
+		ServiceChargeAmount: 290,
+		TaxBaseAmount:       6092,
+		TaxAmount:           670,
+		GrandTotalAmount:    6762,
+	}
+	require.Equal(mainT, expectedBill, output.Bill)
+
+	cart, err := svc.GetCart(ctx, output.CartID)
+	require.NoError(mainT, err)
+	require.Equal(mainT, expectedBill, cart.GetBill())
+
+	_, err = svc.Pay(ctx, service.PayInput{CartID: output.CartID, PaymentAmount: 6000})
+	require.ErrorIs(mainT, err, entity.InsufficientPaymentError{TotalAmount: 6762, PaymentAmount: 6000})
+
+	trx, err := svc.Pay(ctx, service.PayInput{CartID: output.CartID, PaymentAmount: 7000})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money(6762), trx.TotalAmount)
+	require.Equal(mainT, entity.Money(238), trx.ReturnAmount)
+	require.Equal(mainT, entity.Money(6000), trx.SubtotalAmount)
+	require.Equal(mainT, entity.Money(290), trx.ServiceChargeAmount)
+	require.Equal(mainT, entity.Money(670), trx.TaxAmount)
+	require.Equal(mainT, float64(11), trx.TaxRate)
+	require.Equal(mainT, float64(5), trx.ServiceChargeRate)
+
+	// the refund get its share of the service charge and PPN
+	refund, err := svc.RefundOrder(ctx, service.RefundOrderInput{
+		TransactionID: trx.ID,
+		Items:         []entity.RefundItem{{GoodsID: 1, TotalGoods: 1}},
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money(2254), refund.Amount)
+
+	recap, err := svc.GetMonthlyTaxRecap(ctx, service.GetMonthlyTaxRecapInput{Month: time.Now()})
+	require.NoError(mainT, err)
+	require.Equal(mainT, 1, recap.TotalTransactions)
+	require.Equal(mainT, entity.Money(670), recap.TaxAmount)
+	require.Equal(mainT, entity.Money(223), recap.RefundedTaxAmount)
+	require.Equal(mainT, entity.Money(447), recap.NetTaxAmount())
+}
+
+type mockDependencies struct {
+	Storage           service.Storage
+	SupportService    service.SupportService
+	Notifier          service.Notifier
+	AlertDispatcher   service.AlertDispatcher
+	PaymentProvider   service.PaymentProvider
+	ShopLocation      int
+	TaxRate           float64
+	ServiceChargeRate float64
+}
+
+type mockDependenciesConfig struct {
+	mockStorageDummyGoods []entity.Goods
+}
+
+func newMockDependencies(config mockDependenciesConfig) mockDependencies {
+	return mockDependencies{
+		Storage: &mockStorage{
+			Goods:        config.mockStorageDummyGoods,
+			ShoppingCart: map[int64]entity.ShoppingCart{},
+			Transactions: map[int64]entity.Transaction{},
+			Deliveries:   map[int64]entity.Delivery{},
+			History:      map[int64][]entity.TransactionStatusChange{},
+			Payments:     map[string]entity.Payment{},
+		},
+		SupportService:  &mockSupportService{},
+		Notifier:        &mockNotifier{},
+		AlertDispatcher: &mockAlertDispatcher{},
+		PaymentProvider: &mockPaymentProvider{},
+		ShopLocation:    3471,
+	}
+}
+
+func newDummyGoods(total int) []entity.Goods {
+	f := faker.New()
+	rg := rand.New(rand.NewSource(time.Now().UnixNano()))
+	output := []entity.Goods{}
+	for i := 1; i <= total; i++ {
+		output = append(output, entity.Goods{
+			ID:     i,
+			Name:   f.Food().Fruit(),
+			Stocks: rg.Int(),
+			Price:  entity.Money(rg.Intn(100000)),
+		})
+	}
+
+	return output
+}
+
+// newCartGoods create goods which used by shopping cart test cases
+func newCartGoods() []entity.Goods {
+	return []entity.Goods{
+		{ID: 1, Name: "Kopi", Stocks: 100, Price: 2000},
+		{ID: 2, Name: "Teh manis", Stocks: 100, Price: 2000},
+		{ID: 3, Name: "Bakwan", Stocks: 100, Price: 1500},
+	}
+}
+
+// mockStorage is accessed by the alert checks in the background, so every method hold the mutex
+type mockStorage struct {
+	mutex sync.Mutex
+
+	Goods        []entity.Goods
+	ShoppingCart map[int64]entity.ShoppingCart
+	Transactions map[int64]entity.Transaction
+	Deliveries   map[int64]entity.Delivery
+	History      map[int64][]entity.TransactionStatusChange
+	Refunds      []entity.Refund
+	Categories   []entity.Category
+	// StockMovements is recorded by UpdateGoodsStock, StockDrifts is returned as is by GetStockDrifts
+	StockMovements []entity.StockMovement
+	StockDrifts    []entity.StockDrift
+	StockCounts    map[int64]entity.StockCount
+	Suppliers      []entity.Supplier
+	PurchaseOrders map[int64]entity.PurchaseOrder
+	// StockBatches is received by UpdateGoodsStock, it's only consumed by WriteOffExpiredStockBatches
+	StockBatches []entity.StockBatch
+	// Promotions used count is increased by CreateTransaction when its voucher is used
+	Promotions []entity.Promotion
+	// LastExpireInput is the latest input of ExpireShoppingCarts
+	LastExpireInput service.ExpireShoppingCartsInput
+	// Payments is keyed by the payment reference, the payment is marked as paid by CreateTransaction
+	Payments map[string]entity.Payment
+}
+
+func (m *mockStorage) GetGoods(ctx context.Context, input service.GetGoodsInput) ([]entity.Goods, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	filteredGoods := m.filterGoods(input)
+
+	sort.SliceStable(filteredGoods, func(i, j int) bool {
+		return goodsLess(input.Sort, input.SortBy, filteredGoods[i], filteredGoods[j])
+	})
+
+	// the cursor only holds the sort key, so compare with the goods it points to
+	cursorGoods := func(cursor *service.GoodsCursor) entity.Goods {
+		return entity.Goods{ID: cursor.ID, Name: cursor.Name, Price: cursor.Price, Stocks: cursor.Stocks}
+	}
+	offset := input.Offset
+	switch {
+	case input.After != nil:
+		after := cursorGoods(input.After)
+		offset = sort.Search(len(filteredGoods), func(i int) bool {
+			return goodsLess(input.Sort, input.SortBy, after, filteredGoods[i])
+		})
+	case input.Before != nil:
+		before := cursorGoods(input.Before)
+		end := sort.Search(len(filteredGoods), func(i int) bool {
+			return !goodsLess(input.Sort, input.SortBy, filteredGoods[i], before)
+		})
+		offset = end - input.Limit
+		if offset < 0 {
+			offset = 0
+		}
+		return filteredGoods[offset:end], nil
+	}
+
+	if offset >= len(filteredGoods) {
+		return []entity.Goods{}, nil
+	}
+	end := offset + input.Limit
+	if end > len(filteredGoods) {
+		end = len(filteredGoods)
+	}
+
+	return filteredGoods[offset:end], nil
+}
+
+func (m *mockStorage) CountGoods(ctx context.Context, input service.GetGoodsInput) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return len(m.filterGoods(input)), nil
+}
+
+func (m *mockStorage) filterGoods(input service.GetGoodsInput) []entity.Goods {
+	filteredGoods := []entity.Goods{}
+	for _, goods := range m.Goods {
+		switch {
+		case goods.IsDeleted(),
+			len(input.Name) > 0 && !strings.Contains(strings.ToLower(goods.Name), strings.ToLower(input.Name)),
+			input.MinPrice > 0 && goods.Price < input.MinPrice,
+			input.MaxPrice > 0 && goods.Price > input.MaxPrice,
+			input.InStockOnly && goods.AvailableStocks() <= 0:
+			continue
+		}
+		filteredGoods = append(filteredGoods, goods)
+	}
+	return filteredGoods
+}
+
+// goodsLess report whether goods a is listed before goods b, the ID is used as tie breaker like the real storage
+func goodsLess(sortDirection service.Sort, sortBy service.GoodsSortField, a, b entity.Goods) bool {
+	if sortDirection == service.SortDesc {
+		a, b = b, a
+	}
+	switch {
+	case sortBy == service.GoodsSortByName && a.Name != b.Name:
+		return a.Name < b.Name
+	case sortBy == service.GoodsSortByPrice && a.Price != b.Price:
+		return a.Price < b.Price
+	case sortBy == service.GoodsSortByStocks && a.Stocks != b.Stocks:
+		return a.Stocks < b.Stocks
+	}
+	return a.ID < b.ID
+}
+
+func (m *mockStorage) GetGoodsByID(ctx context.Context, goodsID int) (*entity.Goods, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.getGoodsByID(ctx, goodsID)
+}
+
+func (m *mockStorage) getGoodsByID(ctx context.Context, goodsID int) (*entity.Goods, error) {
+	for _, goods := range m.Goods {
+		if goods.ID == goodsID {
+			goods.Recipe = m.loadRecipe(goods.Recipe)
+			return &goods, nil
+		}
+	}
+	return nil, service.ErrGoodsNotFound
+}
+
+// loadRecipe copy the recipe along with the current stocks of its raw materials
+func (m *mockStorage) loadRecipe(recipe *entity.Recipe) *entity.Recipe {
+	if recipe == nil {
+		return nil
+	}
+	loaded := &entity.Recipe{GoodsID: recipe.GoodsID}
+	for _, ingredient := range recipe.Ingredients {
+		for _, goods := range m.Goods {
+			if goods.ID == ingredient.IngredientID {
+				ingredient.Name = goods.Name
+				ingredient.Unit = goods.Unit
+				ingredient.AvailableStocks = goods.AvailableStocks()
+			}
+		}
+		loaded.Ingredients = append(loaded.Ingredients, ingredient)
+	}
+	loaded.CalculateServings()
+	return loaded
+}
+
+func (m *mockStorage) GetExistingShoppingCart(ctx context.Context, shoppingCartID int64) (*entity.ShoppingCart, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	existCart, ok := m.ShoppingCart[shoppingCartID]
+	if !ok {
+		return nil, nil
+	}
+	// copy the details, so the stored cart not modified by the caller
+	existCart.Details = append([]entity.ShoppingCartDetail{}, existCart.Details...)
+	return &existCart, nil
+}
+
+func (m *mockStorage) AddGoodToCart(ctx context.Context, cart *entity.ShoppingCart) (*entity.ShoppingCart, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var cartOutput entity.ShoppingCart
+	if cart.ID > 0 {
+		if _, ok := m.ShoppingCart[cart.ID]; !ok {
+			return nil, fmt.Errorf("unexpected error: no existing cart for ID %d", cart.ID)
+		}
+		if m.hasAwaitedPayment(cart.ID) {
+			return nil, service.ErrPaymentPending
+		}
+	}
+	// the raw materials of the goods made to order is reserved, the mock doesn't reserve other goods
+	for _, detail := range cart.Details {
+		if err := m.reserveRawMaterials(detail.GoodsID, detail.TotalGoods); err != nil {
+			return nil, err
+		}
+	}
+	if cart.ID > 0 {
+		existCart := m.ShoppingCart[cart.ID]
+		for _, detail := range cart.Details {
+			existCart.AddGoods(entity.AddGoodsInput{
+				GoodsID:          detail.GoodsID,
+				VariantID:        detail.VariantID,
+				TotalGoods:       detail.TotalGoods,
+				GoodsPrice:       detail.GoodsPrice,
+				PriceIncludesTax: detail.PriceIncludesTax,
+			})
+		}
+		if len(cart.VoucherCode) > 0 {
+			existCart.VoucherCode = cart.VoucherCode
+		}
+
+		m.ShoppingCart[cart.ID] = existCart
+
+		cartOutput = m.ShoppingCart[cart.ID]
+	} else {
+		newCartID := len(m.ShoppingCart) + 1
+		m.ShoppingCart[int64(newCartID)] = entity.ShoppingCart{
+			ID:          int64(newCartID),
+			UserID:      cart.UserID,
+			TotalAmount: cart.GetSubtotalAmount(),
 			Details:     cart.Details,
+			VoucherCode: cart.VoucherCode,
+		}
+
+		cartOutput = m.ShoppingCart[int64(newCartID)]
+	}
+	return &cartOutput, nil
+}
+
+func (m *mockStorage) SetCartGoodsQuantity(ctx context.Context, input service.SetCartGoodsQuantityInput) (*entity.ShoppingCart, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	cart, ok := m.ShoppingCart[input.CartID]
+	if !ok {
+		return nil, service.ErrCartNotFound
+	}
+	if m.hasAwaitedPayment(input.CartID) {
+		return nil, service.ErrPaymentPending
+	}
+	// copy the details, so the stored cart not modified by the caller
+	cart.Details = append([]entity.ShoppingCartDetail{}, cart.Details...)
+	for _, detail := range cart.Details {
+		if detail.GoodsID == input.GoodsID && detail.VariantID == input.VariantID {
+			if err := m.reserveRawMaterials(input.GoodsID, input.Total-detail.TotalGoods); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var err error
+	if input.Total > 0 {
+		err = cart.UpdateGoodsQuantity(input.GoodsID, input.VariantID, input.Total)
+	} else {
+		err = cart.RemoveGoods(input.GoodsID, input.VariantID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.ShoppingCart[input.CartID] = cart
+
+	return &cart, nil
+}
+
+func (m *mockStorage) ClearShoppingCart(ctx context.Context, shoppingCartID int64) (*entity.ShoppingCart, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	cart, ok := m.ShoppingCart[shoppingCartID]
+	if !ok {
+		return nil, service.ErrCartNotFound
+	}
+	if m.hasAwaitedPayment(shoppingCartID) {
+		return nil, service.ErrPaymentPending
+	}
+	m.releaseCartRawMaterials(cart)
+	cart.Clear()
+	m.ShoppingCart[shoppingCartID] = cart
+
+	return &cart, nil
+}
+
+func (m *mockStorage) DeleteShoppingCart(ctx context.Context, shoppingCartID int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	cart, ok := m.ShoppingCart[shoppingCartID]
+	if !ok {
+		return service.ErrCartNotFound
+	}
+	if m.hasAwaitedPayment(shoppingCartID) {
+		return service.ErrPaymentPending
+	}
+	m.releaseCartRawMaterials(cart)
+	delete(m.ShoppingCart, shoppingCartID)
+	return nil
+}
+
+// reserveRawMaterials reserve the raw materials of the goods made to order, negative servings release them
+func (m *mockStorage) reserveRawMaterials(goodsID int, servings int) error {
+	var requirements map[int]int
+	for _, goods := range m.Goods {
+		if goods.ID == goodsID && goods.IsMadeToOrder() {
+			requirements = goods.Recipe.Requirements(servings)
+		}
+	}
+	// the mock isn't transactional, so all raw materials is checked before any of them reserved
+	for i := range m.Goods {
+		if total := requirements[m.Goods[i].ID]; total > m.Goods[i].AvailableStocks() {
+			return entity.InsufficientStockError{GoodsID: m.Goods[i].ID, Stocks: m.Goods[i].AvailableStocks(), Requested: total}
+		}
+	}
+	for i := range m.Goods {
+		switch total := requirements[m.Goods[i].ID]; {
+		case total > 0:
+			m.Goods[i].ReservedStocks += total
+		case total < 0:
+			m.Goods[i].ReleaseStock(-total)
+		}
+	}
+	return nil
+}
+
+func (m *mockStorage) releaseCartRawMaterials(cart entity.ShoppingCart) {
+	for _, detail := range cart.Details {
+		m.reserveRawMaterials(detail.GoodsID, -detail.TotalGoods)
+	}
+}
+
+// hasAwaitedPayment return true when the shopping cart has pending payment which can still be paid
+func (m *mockStorage) hasAwaitedPayment(shoppingCartID int64) bool {
+	now := time.Now().Unix()
+	for _, payment := range m.Payments {
+		if payment.TransactionID == shoppingCartID && payment.IsAwaited(now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *mockStorage) GetTransaction(ctx context.Context, transactionID int64) (*entity.Transaction, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if paidTrx, ok := m.Transactions[transactionID]; ok {
+		return &paidTrx, nil
+	}
+	cart, ok := m.ShoppingCart[transactionID]
+	if !ok {
+		return nil, service.ErrCartNotFound
+	}
+	return &entity.Transaction{
+		ID:          cart.ID,
+		Status:      entity.TransactionStatusCart,
+		TotalGoods:  cart.GetTotalGoods(),
+		TotalAmount: cart.GetTotalAmount(),
+	}, nil
+}
+
+func (m *mockStorage) GetTransactionByIdempotencyKey(ctx context.Context, idempotencyKey string) (*entity.Transaction, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, paidTrx := range m.Transactions {
+		if paidTrx.IdempotencyKey == idempotencyKey {
+			return &paidTrx, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockStorage) ExpireShoppingCarts(ctx context.Context, input service.ExpireShoppingCartsInput) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.LastExpireInput = input
+
+	// all unpaid shopping carts are considered abandoned, except the ones waiting for their payment
+	var totalExpired int
+	for cartID := range m.ShoppingCart {
+		if _, ok := m.Transactions[cartID]; ok || m.hasAwaitedPayment(cartID) {
+			continue
+		}
+		m.releaseCartRawMaterials(m.ShoppingCart[cartID])
+		m.Transactions[cartID] = entity.Transaction{
+			ID:     cartID,
+			Status: entity.TransactionStatusExpired,
+		}
+		delete(m.ShoppingCart, cartID)
+		totalExpired++
+	}
+	return totalExpired, nil
+}
+
+func (m *mockStorage) CreateTransaction(ctx context.Context, input service.CreateTransactionInput) (*entity.Transaction, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if input.CartID <= 0 {
+		return nil, fmt.Errorf("no shopping cart")
+	}
+	cart, ok := m.ShoppingCart[input.CartID]
+	if !ok {
+		return nil, service.ErrCartNotFound
+	}
+	if _, ok := m.Transactions[input.CartID]; ok {
+		return nil, service.ErrCartAlreadyPaid
+	}
+	bill := input.Bill
+	if bill.SubtotalAmount > 0 && cart.GetSubtotalAmount() != bill.SubtotalAmount {
+		return nil, service.ErrCartChanged
+	}
+	var discountAmount entity.Money
+	for _, discount := range input.Discounts {
+		discountAmount += discount.Amount
+	}
+	if bill.SubtotalAmount == 0 {
+		bill = entity.Bill{
+			SubtotalAmount:   cart.GetSubtotalAmount(),
+			DiscountAmount:   discountAmount,
+			TaxBaseAmount:    cart.GetSubtotalAmount() - discountAmount,
+			GrandTotalAmount: cart.GetSubtotalAmount() - discountAmount,
+		}
+	}
+	// the mock isn't transactional, so the payment is checked before anything is changed
+	payment := entity.Transaction{TotalAmount: bill.GrandTotalAmount}
+	if err := payment.SetTenders(input.GetTenders()); err != nil {
+		return nil, err
+	}
+	for _, discount := range input.Discounts {
+		if len(discount.VoucherCode) == 0 {
+			continue
+		}
+		for i := range m.Promotions {
+			if m.Promotions[i].ID == discount.PromotionID {
+				if m.Promotions[i].IsUsedUp() {
+					return nil, service.ErrVoucherNotApplicable
+				}
+				m.Promotions[i].UsedCount++
+			}
+		}
+	}
+	// deduct the reserved raw materials of the goods made to order
+	rawMaterialNeeds := map[int]int{}
+	for _, detail := range cart.Details {
+		for _, goods := range m.Goods {
+			if goods.ID == detail.GoodsID && goods.IsMadeToOrder() {
+				for rawMaterialID, total := range goods.Recipe.Requirements(detail.TotalGoods) {
+					rawMaterialNeeds[rawMaterialID] += total
+				}
+			}
+		}
+	}
+	for i := range m.Goods {
+		if total, ok := rawMaterialNeeds[m.Goods[i].ID]; ok {
+			before := m.Goods[i].Stocks
+			m.Goods[i].DeductReservedStock(total)
+			m.StockMovements = append(m.StockMovements, entity.StockMovement{
+				GoodsID:       m.Goods[i].ID,
+				Type:          entity.StockMovementSale,
+				Quantity:      -total,
+				Before:        before,
+				After:         m.Goods[i].Stocks,
+				TransactionID: input.CartID,
+				CreatedAt:     time.Now().Unix(),
+			})
+		}
+	}
+	paidTrx := entity.Transaction{
+		ID:                  input.CartID,
+		Status:              entity.TransactionStatusPaid,
+		TotalGoods:          cart.GetTotalGoods(),
+		TotalAmount:         bill.GrandTotalAmount,
+		SubtotalAmount:      bill.SubtotalAmount,
+		DiscountAmount:      discountAmount,
+		ServiceChargeAmount: bill.ServiceChargeAmount,
+		TaxBaseAmount:       bill.TaxBaseAmount,
+		TaxAmount:           bill.TaxAmount,
+		TaxRate:             input.TaxRates.TaxRate,
+		ServiceChargeRate:   input.TaxRates.ServiceChargeRate,
+		Discounts:           input.Discounts,
+		Tenders:             payment.Tenders,
+		PaymentAmount:       payment.PaymentAmount,
+		ReturnAmount:        payment.ReturnAmount,
+		IdempotencyKey:      input.IdempotencyKey,
+	}
+	if payment, ok := m.Payments[input.PaymentReference]; ok {
+		payment.Status = entity.PaymentStatusPaid
+		payment.PaidAt = time.Now().Unix()
+		m.Payments[input.PaymentReference] = payment
+	}
+	m.Transactions[input.CartID] = paidTrx
+	m.recordStatusChange(input.CartID, entity.TransactionStatusCart, entity.TransactionStatusPaid)
+
+	return &paidTrx, nil
+}
+
+func (m *mockStorage) CreatePayment(ctx context.Context, payment entity.Payment) (*entity.Payment, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.Payments[payment.Reference]; ok {
+		return nil, fmt.Errorf("duplicate payment reference %s", payment.Reference)
+	}
+	if m.hasAwaitedPayment(payment.TransactionID) {
+		return nil, service.ErrPaymentPending
+	}
+	m.Payments[payment.Reference] = payment
+	return &payment, nil
+}
+
+func (m *mockStorage) GetPayment(ctx context.Context, reference string) (*entity.Payment, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	payment, ok := m.Payments[reference]
+	if !ok {
+		return nil, service.ErrPaymentNotFound
+	}
+	return &payment, nil
+}
+
+func (m *mockStorage) GetTransactions(ctx context.Context, input service.GetTransactionsInput) ([]entity.Transaction, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	orders := []entity.Transaction{}
+	for _, trx := range m.Transactions {
+		for _, status := range input.Statuses {
+			if trx.Status == status {
+				orders = append(orders, trx)
+			}
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].ID < orders[j].ID
+	})
+	return orders, nil
+}
+
+func (m *mockStorage) GetTransactionStatusHistory(ctx context.Context, transactionID int64) ([]entity.TransactionStatusChange, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.History[transactionID], nil
+}
+
+func (m *mockStorage) UpdateTransactionStatus(ctx context.Context, input service.UpdateTransactionStatusInput) (*entity.Transaction, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	trx, ok := m.Transactions[input.TransactionID]
+	if !ok {
+		return nil, service.ErrCartNotFound
+	}
+	from := trx.Status
+	if err := trx.TransitionTo(input.Status); err != nil {
+		return nil, err
+	}
+	m.Transactions[input.TransactionID] = trx
+	m.recordStatusChange(input.TransactionID, from, input.Status)
+
+	return &trx, nil
+}
+
+func (m *mockStorage) CreateRefund(ctx context.Context, input service.CreateRefundInput) (*entity.Refund, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	trx, ok := m.Transactions[input.TransactionID]
+	if !ok {
+		return nil, service.ErrCartNotFound
+	}
+	if !trx.Status.CanTransitionTo(input.Status) {
+		return nil, entity.InvalidStatusTransitionError{From: trx.Status, To: input.Status}
+	}
+
+	purchased := []entity.PurchasedGoods{}
+	for _, detail := range m.ShoppingCart[input.TransactionID].Details {
+		goods := entity.PurchasedGoods{
+			GoodsID:    detail.GoodsID,
+			VariantID:  detail.VariantID,
+			TotalGoods: detail.TotalGoods,
+			GoodsPrice: detail.GoodsPrice,
+		}
+		for _, refund := range m.Refunds {
+			for _, refundDetail := range refund.Details {
+				if refund.TransactionID == input.TransactionID &&
+					refundDetail.GoodsID == detail.GoodsID &&
+					refundDetail.VariantID == detail.VariantID {
+					goods.RefundedGoods += refundDetail.TotalGoods
+				}
+			}
+		}
+		purchased = append(purchased, goods)
+	}
+
+	items := input.Items
+	if input.Status == entity.TransactionStatusCancelled {
+		items = nil
+	}
+	refund, err := entity.NewRefund(input.TransactionID, purchased, items, input.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if err = trx.ApplyRefund(refund); err != nil {
+		return nil, err
+	}
+	for _, detail := range refund.Details {
+		for i := range m.Goods {
+			if m.Goods[i].ID == detail.GoodsID {
+				m.Goods[i].IncreaseStock(detail.TotalGoods)
+			}
+		}
+	}
+	if entity.IsAllGoodsRefunded(purchased) {
+		m.recordStatusChange(input.TransactionID, trx.Status, input.Status)
+		trx.Status = input.Status
+	}
+	m.Transactions[input.TransactionID] = trx
+
+	refund.ID = int64(len(m.Refunds) + 1)
+	refund.CreatedAt = input.CreatedAt
+	m.Refunds = append(m.Refunds, *refund)
+
+	return refund, nil
+}
+
+func (m *mockStorage) GetSalesSummary(ctx context.Context, input service.GetSalesSummaryInput) (*entity.SalesSummary, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	summary := &entity.SalesSummary{From: input.From, To: input.To}
+	tenderTotals := map[entity.PaymentMethod]*entity.TenderTotal{}
+	for _, trx := range m.Transactions {
+		if trx.Status == entity.TransactionStatusExpired {
+			continue
+		}
+		summary.TotalTransactions++
+		summary.GrossAmount += trx.TotalAmount
+		for _, tender := range trx.Tenders {
+			tenderTotal, ok := tenderTotals[tender.Method]
+			if !ok {
+				tenderTotal = &entity.TenderTotal{Method: tender.Method}
+				tenderTotals[tender.Method] = tenderTotal
+			}
+			tenderTotal.TotalTransactions++
+			tenderTotal.Amount += tender.Amount
+			if tender.Method == entity.PaymentMethodCash {
+				tenderTotal.Amount -= trx.ReturnAmount
+			}
 		}
+	}
+	for _, tenderTotal := range tenderTotals {
+		summary.Tenders = append(summary.Tenders, *tenderTotal)
+	}
+	sort.Slice(summary.Tenders, func(i, j int) bool {
+		return summary.Tenders[i].Method < summary.Tenders[j].Method
+	})
+	for _, refund := range m.Refunds {
+		if refund.CreatedAt >= input.From && refund.CreatedAt < input.To {
+			summary.TotalRefunds++
+			summary.RefundAmount += refund.Amount
+		}
+	}
+	return summary, nil
+}
 
-		cartOutput = m.ShoppingCart[int64(newCartID)]
+func (m *mockStorage) GetTaxRecap(ctx context.Context, input service.GetTaxRecapInput) (*entity.TaxRecap, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	recap := &entity.TaxRecap{From: input.From, To: input.To}
+	for _, trx := range m.Transactions {
+		if trx.Status == entity.TransactionStatusExpired {
+			continue
+		}
+		recap.TotalTransactions++
+		recap.SubtotalAmount += trx.SubtotalAmount
+		recap.DiscountAmount += trx.DiscountAmount
+		recap.ServiceChargeAmount += trx.ServiceChargeAmount
+		recap.TaxBaseAmount += trx.TaxBaseAmount
+		recap.TaxAmount += trx.TaxAmount
+		recap.GrandTotalAmount += trx.TotalAmount
 	}
-	return &cartOutput, nil
+	for _, refund := range m.Refunds {
+		if refund.CreatedAt >= input.From && refund.CreatedAt < input.To {
+			trx := m.Transactions[refund.TransactionID]
+			recap.RefundAmount += refund.Amount
+			recap.RefundedTaxAmount += refund.Amount.Prorate(trx.TaxAmount, trx.TotalAmount)
+		}
+	}
+	return recap, nil
 }
 
-func (m *mockStorage) CreateTransaction(ctx context.Context, input service.CreateTransactionInput) (*entity.Transaction, error) {
-	if input.CartID <= 0 {
-		return nil, fmt.Errorf("no shopping cart")
+func (m *mockStorage) recordStatusChange(transactionID int64, from entity.TransactionStatus, to entity.TransactionStatus) {
+	m.History[transactionID] = append(m.History[transactionID], entity.TransactionStatusChange{
+		From:      &from,
+		To:        to,
+		ChangedAt: time.Now().Unix(),
+	})
+}
+
+func (m *mockStorage) UpdateGoodsStock(ctx context.Context, input service.UpdateGoodsStockInput) (*entity.Goods, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.updateGoodsStock(ctx, input)
+}
+
+func (m *mockStorage) updateGoodsStock(ctx context.Context, input service.UpdateGoodsStockInput) (*entity.Goods, error) {
+	for i, goods := range m.Goods {
+		if goods.ID != input.GoodsID && goods.Name != input.GoodsName {
+			continue
+		}
+		if input.VariantID > 0 {
+			return m.updateGoodsVariantStock(i, input)
+		}
+		if goods.HasVariants() {
+			return nil, fmt.Errorf("%w: goods %s holds its stocks per variant", service.ErrInvalidInput, goods.Name)
+		}
+		movement := entity.StockMovement{
+			ID:        int64(len(m.StockMovements) + 1),
+			GoodsID:   goods.ID,
+			Type:      entity.StockMovementRestock,
+			Before:    goods.Stocks,
+			Actor:     input.Actor,
+			Reason:    input.Reason,
+			CreatedAt: time.Now().Unix(),
+		}
+		switch input.Action {
+		case service.IncreaseStock:
+			goods.ReceiveStock(input.Total, input.UnitCost)
+			m.StockBatches = append(m.StockBatches, entity.StockBatch{
+				ID:               int64(len(m.StockBatches) + 1),
+				GoodsID:          goods.ID,
+				Name:             goods.Name,
+				ReceivedQuantity: input.Total,
+				Quantity:         input.Total,
+				UnitCost:         input.UnitCost,
+				ReceivedAt:       movement.CreatedAt,
+				ExpiresAt:        input.ExpiresAt,
+			})
+		case service.DecreaseStock, service.WasteStock:
+			if err := goods.DecreaseStock(input.Total); err != nil {
+				return nil, err
+			}
+			movement.Type = entity.StockMovementAdjustment
+			if input.Action == service.WasteStock {
+				movement.Type = entity.StockMovementWastage
+			}
+		}
+		movement.After = goods.Stocks
+		movement.Quantity = movement.After - movement.Before
+		movement.UnitCost = input.UnitCost
+		if movement.UnitCost == 0 {
+			movement.UnitCost = goods.UnitCost()
+		}
+		m.StockMovements = append(m.StockMovements, movement)
+		m.Goods[i] = goods
+		return &goods, nil
 	}
-	return &entity.Transaction{
-		ID: time.Now().Unix(),
-	}, nil
+	return nil, service.ErrGoodsNotFound
+}
+
+// updateGoodsVariantStock is updateGoodsStock for the variant of the goods at the given index, the batches of
+// the variant is not tracked
+func (m *mockStorage) updateGoodsVariantStock(goodsIndex int, input service.UpdateGoodsStockInput) (*entity.Goods, error) {
+	goods := m.Goods[goodsIndex]
+	goods.Variants = append([]entity.GoodsVariant{}, goods.Variants...)
+	for i := range goods.Variants {
+		variant := &goods.Variants[i]
+		if variant.ID != input.VariantID {
+			continue
+		}
+		movement := entity.StockMovement{
+			ID:        int64(len(m.StockMovements) + 1),
+			GoodsID:   goods.ID,
+			VariantID: variant.ID,
+			Type:      entity.StockMovementRestock,
+			Before:    variant.Stocks,
+			UnitCost:  input.UnitCost,
+			Actor:     input.Actor,
+			Reason:    input.Reason,
+			CreatedAt: time.Now().Unix(),
+		}
+		switch input.Action {
+		case service.IncreaseStock:
+			variant.ReceiveStock(input.Total, input.UnitCost)
+		case service.DecreaseStock, service.WasteStock:
+			if err := variant.DecreaseStock(input.Total); err != nil {
+				return nil, err
+			}
+			movement.Type = entity.StockMovementAdjustment
+			if input.Action == service.WasteStock {
+				movement.Type = entity.StockMovementWastage
+			}
+		}
+		movement.After = variant.Stocks
+		movement.Quantity = movement.After - movement.Before
+		m.StockMovements = append(m.StockMovements, movement)
+		m.Goods[goodsIndex] = goods
+		return &goods, nil
+	}
+	return nil, service.ErrVariantNotFound
+}
+
+func (m *mockStorage) GetStockMovements(ctx context.Context, input service.GetStockMovementsInput) ([]entity.StockMovement, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	movements := []entity.StockMovement{}
+	for _, movement := range m.StockMovements {
+		if input.GoodsID > 0 && movement.GoodsID != input.GoodsID {
+			continue
+		}
+		if input.From > 0 && movement.CreatedAt < input.From {
+			continue
+		}
+		if input.To > 0 && movement.CreatedAt >= input.To {
+			continue
+		}
+		if input.TransactionID > 0 && movement.TransactionID != input.TransactionID {
+			continue
+		}
+		if input.VariantID > 0 && movement.VariantID != input.VariantID {
+			continue
+		}
+		movements = append(movements, movement)
+	}
+	return movements, nil
+}
+
+func (m *mockStorage) GetLowStocks(ctx context.Context) ([]entity.LowStock, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	lowStocks := []entity.LowStock{}
+	for _, goods := range m.Goods {
+		if goods.ReorderPoint > 0 && goods.Stocks <= goods.ReorderPoint {
+			lowStocks = append(lowStocks, entity.LowStock{
+				GoodsID:      goods.ID,
+				Name:         goods.Name,
+				Unit:         goods.Unit,
+				Stocks:       goods.Stocks,
+				ReorderPoint: goods.ReorderPoint,
+			})
+		}
+	}
+	return lowStocks, nil
+}
+
+func (m *mockStorage) GetExpiringStockBatches(ctx context.Context, input service.GetExpiringStockBatchesInput) ([]entity.StockBatch, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	batches := []entity.StockBatch{}
+	for _, batch := range m.StockBatches {
+		if batch.Quantity > 0 && batch.ExpiresAt > 0 && batch.ExpiresAt <= input.ExpiresUntil {
+			batches = append(batches, batch)
+		}
+	}
+	entity.SortStockBatchesFEFO(batches)
+	return batches, nil
+}
+
+func (m *mockStorage) WriteOffExpiredStockBatches(ctx context.Context, input service.WriteOffStockBatchesInput) ([]entity.StockMovement, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	movements := []entity.StockMovement{}
+	for i, goods := range m.Goods {
+		if input.GoodsID > 0 && goods.ID != input.GoodsID {
+			continue
+		}
+		var batches []entity.StockBatch
+		expired := 0
+		for _, batch := range m.StockBatches {
+			if batch.GoodsID != goods.ID {
+				continue
+			}
+			batches = append(batches, batch)
+			if batch.IsExpired(input.ExpiredAt) {
+				expired += batch.Quantity
+			}
+		}
+		if expired > goods.AvailableStocks() {
+			expired = goods.AvailableStocks()
+		}
+		if expired <= 0 {
+			continue
+		}
+
+		movement := entity.StockMovement{
+			ID:        int64(len(m.StockMovements) + 1),
+			GoodsID:   goods.ID,
+			Type:      entity.StockMovementWastage,
+			Quantity:  -expired,
+			UnitCost:  goods.UnitCost(),
+			Before:    goods.Stocks,
+			Actor:     input.Actor,
+			Reason:    input.Reason,
+			CreatedAt: input.ExpiredAt,
+		}
+		if err := goods.DecreaseStock(expired); err != nil {
+			return nil, err
+		}
+		movement.After = goods.Stocks
+		for _, consumed := range entity.ConsumeStockBatches(batches, expired) {
+			for j := range m.StockBatches {
+				if m.StockBatches[j].ID == consumed.ID {
+					m.StockBatches[j] = consumed
+				}
+			}
+		}
+		m.StockMovements = append(m.StockMovements, movement)
+		movements = append(movements, movement)
+		m.Goods[i] = goods
+	}
+	return movements, nil
+}
+
+func (m *mockStorage) CreateSupplier(ctx context.Context, supplier entity.Supplier) (*entity.Supplier, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, existSupplier := range m.Suppliers {
+		if strings.EqualFold(existSupplier.Name, supplier.Name) {
+			return nil, service.ErrSupplierAlreadyExists
+		}
+	}
+	supplier.ID = len(m.Suppliers) + 1
+	m.Suppliers = append(m.Suppliers, supplier)
+	return &supplier, nil
+}
+
+func (m *mockStorage) GetSuppliers(ctx context.Context) ([]entity.Supplier, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.Suppliers, nil
+}
+
+func (m *mockStorage) CreatePromotion(ctx context.Context, promotion entity.Promotion) (*entity.Promotion, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, existPromotion := range m.Promotions {
+		if len(promotion.VoucherCode) > 0 && existPromotion.VoucherCode == promotion.VoucherCode {
+			return nil, service.ErrVoucherAlreadyExists
+		}
+	}
+	promotion.ID = len(m.Promotions) + 1
+	m.Promotions = append(m.Promotions, promotion)
+	return &promotion, nil
+}
+
+func (m *mockStorage) GetPromotions(ctx context.Context, input service.GetPromotionsInput) ([]entity.Promotion, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	promotions := []entity.Promotion{}
+	for _, promotion := range m.Promotions {
+		if input.ActiveAt > 0 {
+			if promotion.StartsAt > input.ActiveAt || (promotion.EndsAt > 0 && promotion.EndsAt <= input.ActiveAt) {
+				continue
+			}
+			if len(promotion.VoucherCode) > 0 && promotion.VoucherCode != input.VoucherCode {
+				continue
+			}
+		}
+		promotions = append(promotions, promotion)
+	}
+	// automatic promotions first, the same as the storage
+	sort.SliceStable(promotions, func(i, j int) bool {
+		return len(promotions[i].VoucherCode) == 0 && len(promotions[j].VoucherCode) > 0
+	})
+	return promotions, nil
+}
+
+func (m *mockStorage) CreatePurchaseOrder(ctx context.Context, purchaseOrder entity.PurchaseOrder) (*entity.PurchaseOrder, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if purchaseOrder.SupplierID > len(m.Suppliers) {
+		return nil, service.ErrSupplierNotFound
+	}
+	if m.PurchaseOrders == nil {
+		m.PurchaseOrders = map[int64]entity.PurchaseOrder{}
+	}
+	purchaseOrder.ID = int64(len(m.PurchaseOrders) + 1)
+	m.PurchaseOrders[purchaseOrder.ID] = purchaseOrder
+	return &purchaseOrder, nil
+}
+
+func (m *mockStorage) GetPurchaseOrders(ctx context.Context, input service.GetPurchaseOrdersInput) ([]entity.PurchaseOrder, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	purchaseOrders := []entity.PurchaseOrder{}
+	for _, purchaseOrder := range m.PurchaseOrders {
+		if input.SupplierID > 0 && purchaseOrder.SupplierID != input.SupplierID {
+			continue
+		}
+		if len(input.Status) > 0 && purchaseOrder.Status != input.Status {
+			continue
+		}
+		purchaseOrders = append(purchaseOrders, purchaseOrder)
+	}
+	sort.Slice(purchaseOrders, func(i, j int) bool {
+		return purchaseOrders[i].ID > purchaseOrders[j].ID
+	})
+	return purchaseOrders, nil
+}
+
+func (m *mockStorage) GetPurchaseOrder(ctx context.Context, orderID int64) (*entity.PurchaseOrder, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	purchaseOrder, ok := m.PurchaseOrders[orderID]
+	if !ok {
+		return nil, service.ErrPurchaseOrderNotFound
+	}
+	return &purchaseOrder, nil
+}
+
+func (m *mockStorage) ReceivePurchaseOrder(ctx context.Context, receipt entity.PurchaseOrderReceipt) (*entity.PurchaseOrder, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	purchaseOrder, ok := m.PurchaseOrders[receipt.OrderID]
+	if !ok {
+		return nil, service.ErrPurchaseOrderNotFound
+	}
+	if purchaseOrder.IsClosed() {
+		return nil, service.ErrPurchaseOrderClosed
+	}
+	received, err := purchaseOrder.Receive(receipt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", service.ErrInvalidInput, err)
+	}
+	for _, item := range received.Items {
+		_, err = m.updateGoodsStock(ctx, service.UpdateGoodsStockInput{
+			Action:   service.IncreaseStock,
+			GoodsID:  item.GoodsID,
+			Total:    item.Quantity,
+			Actor:    received.ReceivedBy,
+			Reason:   fmt.Sprintf("purchase order #%d", received.OrderID),
+			UnitCost: item.UnitCost,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	m.PurchaseOrders[receipt.OrderID] = purchaseOrder
+	return &purchaseOrder, nil
+}
+
+func (m *mockStorage) CancelPurchaseOrder(ctx context.Context, input service.CancelPurchaseOrderInput) (*entity.PurchaseOrder, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	purchaseOrder, ok := m.PurchaseOrders[input.OrderID]
+	if !ok {
+		return nil, service.ErrPurchaseOrderNotFound
+	}
+	if err := purchaseOrder.Cancel(input.CancelledAt); err != nil {
+		return nil, service.ErrPurchaseOrderClosed
+	}
+	m.PurchaseOrders[input.OrderID] = purchaseOrder
+	return &purchaseOrder, nil
+}
+
+func (m *mockStorage) GetStockDrifts(ctx context.Context) ([]entity.StockDrift, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.StockDrifts, nil
+}
+
+func (m *mockStorage) CreateStockCount(ctx context.Context, stockCount entity.StockCount) (*entity.StockCount, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.StockCounts == nil {
+		m.StockCounts = map[int64]entity.StockCount{}
+	}
+	stockCount.ID = int64(len(m.StockCounts) + 1)
+	m.StockCounts[stockCount.ID] = stockCount
+	return &stockCount, nil
+}
+
+func (m *mockStorage) SetStockCountItems(ctx context.Context, input service.SetStockCountItemsInput) (*entity.StockCount, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stockCount, ok := m.StockCounts[input.CountID]
+	if !ok {
+		return nil, service.ErrStockCountNotFound
+	}
+	if !stockCount.IsOpen() {
+		return nil, service.ErrStockCountApproved
+	}
+	for _, item := range input.Items {
+		if _, err := m.getGoodsByID(ctx, item.GoodsID); err != nil {
+			return nil, err
+		}
+		isReplaced := false
+		for i, countedItem := range stockCount.Items {
+			if countedItem.GoodsID == item.GoodsID && countedItem.VariantID == item.VariantID {
+				stockCount.Items[i].CountedStocks = item.CountedStocks
+				isReplaced = true
+			}
+		}
+		if !isReplaced {
+			stockCount.Items = append(stockCount.Items, item)
+		}
+	}
+	m.StockCounts[input.CountID] = stockCount
+	return m.getStockCount(ctx, input.CountID)
+}
+
+func (m *mockStorage) GetStockCount(ctx context.Context, countID int64) (*entity.StockCount, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.getStockCount(ctx, countID)
+}
+
+func (m *mockStorage) getStockCount(ctx context.Context, countID int64) (*entity.StockCount, error) {
+	stockCount, ok := m.StockCounts[countID]
+	if !ok {
+		return nil, service.ErrStockCountNotFound
+	}
+	items := []entity.StockCountItem{}
+	for _, item := range stockCount.Items {
+		if stockCount.IsOpen() {
+			goods, err := m.getGoodsByID(ctx, item.GoodsID)
+			if err != nil {
+				return nil, err
+			}
+			item.Name, item.SystemStocks, item.Price = goods.Name, goods.Stocks, goods.Price
+		}
+		items = append(items, item)
+	}
+	stockCount.Items = items
+	return &stockCount, nil
+}
+
+func (m *mockStorage) ApplyStockCount(ctx context.Context, input service.ApplyStockCountInput) (*entity.StockCount, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stockCount, err := m.getStockCount(ctx, input.CountID)
+	if err != nil {
+		return nil, err
+	}
+	if !stockCount.IsOpen() {
+		return nil, service.ErrStockCountApproved
+	}
+	for _, item := range stockCount.Items {
+		for i := range m.Goods {
+			if m.Goods[i].ID == item.GoodsID {
+				m.Goods[i].Stocks = item.CountedStocks
+			}
+		}
+	}
+	stockCount.Status = entity.StockCountApproved
+	stockCount.ApprovedBy = input.ApprovedBy
+	stockCount.ApprovedAt = input.ApprovedAt
+	m.StockCounts[input.CountID] = *stockCount
+	return stockCount, nil
+}
+
+func (m *mockStorage) CreateDelivery(ctx context.Context, delivery entity.Delivery) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.Deliveries[delivery.TransactionID]; ok {
+		return service.ErrDeliveryAlreadyRequested
+	}
+	m.Deliveries[delivery.TransactionID] = delivery
+	return nil
+}
+
+func (m *mockStorage) UpdateDelivery(ctx context.Context, delivery entity.Delivery) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if pending, ok := m.Deliveries[delivery.TransactionID]; !ok || pending.Status != entity.DeliveryStatusPending {
+		return service.ErrDeliveryAlreadyRequested
+	}
+	m.Deliveries[delivery.TransactionID] = delivery
+	return nil
+}
+
+func (m *mockStorage) DeletePendingDelivery(ctx context.Context, transactionID int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if delivery, ok := m.Deliveries[transactionID]; ok && delivery.Status == entity.DeliveryStatusPending {
+		delete(m.Deliveries, transactionID)
+	}
+	return nil
+}
+
+func (m *mockStorage) CreateGoods(ctx context.Context, goods entity.Goods) (*entity.Goods, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, existGoods := range m.Goods {
+		if !existGoods.IsDeleted() && strings.EqualFold(existGoods.Name, goods.Name) {
+			return nil, service.ErrGoodsNameAlreadyExists
+		}
+	}
+	goods.ID = len(m.Goods) + 1
+	m.Goods = append(m.Goods, goods)
+	return &goods, nil
+}
+
+func (m *mockStorage) UpdateGoods(ctx context.Context, goods entity.Goods) (*entity.Goods, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	idx := -1
+	for i, existGoods := range m.Goods {
+		if existGoods.ID == goods.ID && !existGoods.IsDeleted() {
+			idx = i
+			continue
+		}
+		if !existGoods.IsDeleted() && strings.EqualFold(existGoods.Name, goods.Name) {
+			return nil, service.ErrGoodsNameAlreadyExists
+		}
+	}
+	if idx < 0 {
+		return nil, service.ErrGoodsNotFound
+	}
+	for _, variant := range m.Goods[idx].Variants {
+		if variant.Price(goods.Price) <= 0 {
+			return nil, fmt.Errorf("%w: price of variant %s must be greater than zero", service.ErrInvalidInput, variant.Name)
+		}
+	}
+	m.Goods[idx].Name = goods.Name
+	m.Goods[idx].Price = goods.Price
+	m.Goods[idx].CategoryID = goods.CategoryID
+	updatedGoods := m.Goods[idx]
+	return &updatedGoods, nil
+}
+
+func (m *mockStorage) DeleteGoods(ctx context.Context, goodsID int, deletedAt int64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, goods := range m.Goods {
+		if goods.ID == goodsID && !goods.IsDeleted() {
+			m.Goods[i].DeletedAt = deletedAt
+			return nil
+		}
+	}
+	return service.ErrGoodsNotFound
+}
+
+func (m *mockStorage) CreateCategory(ctx context.Context, category entity.Category) (*entity.Category, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, existCategory := range m.Categories {
+		if strings.EqualFold(existCategory.Name, category.Name) {
+			return nil, service.ErrCategoryAlreadyExists
+		}
+	}
+	category.ID = len(m.Categories) + 1
+	m.Categories = append(m.Categories, category)
+	return &category, nil
+}
+
+func (m *mockStorage) GetCategories(ctx context.Context) ([]entity.Category, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.Categories, nil
+}
+
+func (m *mockStorage) CreateGoodsVariant(ctx context.Context, variant entity.GoodsVariant) (*entity.GoodsVariant, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, goods := range m.Goods {
+		if goods.ID != variant.GoodsID {
+			continue
+		}
+		for _, existVariant := range goods.Variants {
+			if strings.EqualFold(existVariant.Name, variant.Name) {
+				return nil, service.ErrVariantAlreadyExists
+			}
+		}
+		variant.ID = goods.ID*100 + len(goods.Variants) + 1
+		m.Goods[i].Variants = append(m.Goods[i].Variants, variant)
+		return &variant, nil
+	}
+	return nil, service.ErrGoodsNotFound
+}
+
+func (m *mockStorage) GetMenuGoods(ctx context.Context) ([]entity.Goods, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	menuGoods := []entity.Goods{}
+	for _, goods := range m.Goods {
+		if !goods.IsDeleted() && !goods.IsRawMaterial {
+			goods.Recipe = m.loadRecipe(goods.Recipe)
+			menuGoods = append(menuGoods, goods)
+		}
+	}
+	return menuGoods, nil
+}
+
+func (m *mockStorage) SetRecipe(ctx context.Context, recipe entity.Recipe) (*entity.Recipe, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i, goods := range m.Goods {
+		if goods.ID != recipe.GoodsID {
+			continue
+		}
+		m.Goods[i].Recipe = nil
+		if len(recipe.Ingredients) > 0 {
+			m.Goods[i].Recipe = &recipe
+		}
+		return m.loadRecipe(&recipe), nil
+	}
+	return nil, service.ErrGoodsNotFound
 }
 
 func (m *mockStorage) TruncateAllData(ctx context.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return nil
+}
+
+// mockNotifier keep the notified low stocks, it's notified from the background so the access is guarded
+type mockNotifier struct {
+	mutex     sync.Mutex
+	lowStocks []entity.LowStock
+}
+
+func (m *mockNotifier) NotifyLowStock(ctx context.Context, lowStock entity.LowStock) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.lowStocks = append(m.lowStocks, lowStock)
 	return nil
 }
 
-type mockSupportService struct{}
+func (m *mockNotifier) LowStocks() []entity.LowStock {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]entity.LowStock{}, m.lowStocks...)
+}
+
+// mockAlertDispatcher run each check in its own goroutine, Wait is called by the test before asserting the alerts
+type mockAlertDispatcher struct {
+	pending sync.WaitGroup
+}
+
+func (m *mockAlertDispatcher) Dispatch(check func(ctx context.Context)) {
+	m.pending.Add(1)
+	go func() {
+		defer m.pending.Done()
+		check(context.Background())
+	}()
+}
+
+func (m *mockAlertDispatcher) Wait() {
+	m.pending.Wait()
+}
+
+// mockPaymentProvider accept callback signed with mockPaymentSignature, its payload is PaymentCallback in JSON
+type mockPaymentProvider struct {
+	totalPayments int
+}
+
+const mockPaymentSignature = "MOCK-SIGNATURE"
+
+func (m *mockPaymentProvider) CreatePayment(ctx context.Context, input service.CreatePaymentInput) (*service.CreatePaymentOutput, error) {
+	m.totalPayments++
+	return &service.CreatePaymentOutput{
+		Reference: fmt.Sprintf("MOCK-%d-%d", input.TransactionID, m.totalPayments),
+		QRPayload: fmt.Sprintf("MOCK-QR-%s-%d", input.Method, input.Amount),
+		ExpiresAt: time.Now().Add(15 * time.Minute).Unix(),
+	}, nil
+}
+
+func (m *mockPaymentProvider) ParseCallback(payload []byte, signature string) (*service.PaymentCallback, error) {
+	if signature != mockPaymentSignature {
+		return nil, service.ErrInvalidSignature
+	}
+	var callback service.PaymentCallback
+	if err := json.Unmarshal(payload, &callback); err != nil {
+		return nil, err
+	}
+	return &callback, nil
+}
+
+type mockSupportService struct {
+	// pickupErr is returned by PickupDelivery when it's set
+	pickupErr error
+	// onPickup is called while the courier is being asked to pick up the delivery
+	onPickup func()
+}
 
-func (m *mockSupportService) CalculateDeliveryPrice(ctx context.Context) (float64, error) {
-	return 0, nil
+func (m *mockSupportService) CalculateDeliveryPrice(ctx context.Context, input service.CalculateDeliveryPriceInput) (*service.DeliveryQuote, error) {
+	return &service.DeliveryQuote{
+		Origin:           input.Origin,
+		Destination:      input.Destination,
+		Zone:             "MOCK",
+		ChargeableWeight: input.Package.Weight,
+		Price:            entity.NewMoney(float64(input.Package.Weight) * 1000),
+	}, nil
 }
 
-func (m *mockSupportService) PickupDelivery(ctx context.Context) (bool, error) {
-	return true, nil
+func (m *mockSupportService) PickupDelivery(ctx context.Context, input service.PickupDeliveryInput) (*service.PickupDeliveryOutput, error) {
+	if m.onPickup != nil {
+		m.onPickup()
+	}
+	if m.pickupErr != nil {
+		return nil, m.pickupErr
+	}
+	return &service.PickupDeliveryOutput{
+		TrackingNumber: fmt.Sprintf("MOCK-%d", input.TransactionID),
+		Status:         entity.DeliveryStatusRequested,
+		Price:          entity.NewMoney(float64(input.Package.Weight) * 1000),
+	}, nil
 }
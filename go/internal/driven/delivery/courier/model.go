@@ -0,0 +1,34 @@
+package deliverycourier
+
+import "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+
+// DeliveryPriceResponse is response body of `GET /delivery-price` courier API
+type DeliveryPriceResponse struct {
+	Origin           int          `json:"origin"`
+	Destination      int          `json:"destination"`
+	Zone             string       `json:"zone"`
+	ChargeableWeight float32      `json:"chargeable_weight"`
+	Price            entity.Money `json:"price"`
+}
+
+// PickupRequest is request body of `POST /pickups` courier API
+type PickupRequest struct {
+	TransactionID int64   `json:"transaction_id"`
+	Origin        int     `json:"origin"`
+	Destination   int     `json:"destination"`
+	Weight        float32 `json:"weight"`
+	Volume        float32 `json:"volume"`
+}
+
+// PickupResponse is response body of `POST /pickups` and `GET /pickups/:tracking_number` courier API
+type PickupResponse struct {
+	TrackingNumber string       `json:"tracking_number"`
+	TransactionID  int64        `json:"transaction_id"`
+	Status         string       `json:"status"`
+	Price          entity.Money `json:"price"`
+	CreatedAt      int64        `json:"created_at"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,124 @@
+package deliverycourier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/service"
+	"gopkg.in/validator.v2"
+)
+
+// supportService call the courier service through its HTTP API
+type supportService struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+type SupportServiceConfig struct {
+	// BaseURL of the courier service, e.g. http://courier:8081
+	BaseURL    string `validate:"nonzero"`
+	HTTPClient *http.Client
+}
+
+func NewSupportService(config SupportServiceConfig) (*supportService, error) {
+	if err := validator.Validate(config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &supportService{
+		baseURL:    strings.TrimSuffix(config.BaseURL, "/"),
+		httpClient: httpClient,
+	}, nil
+}
+
+func (s *supportService) CalculateDeliveryPrice(ctx context.Context, input service.CalculateDeliveryPriceInput) (*service.DeliveryQuote, error) {
+	query := url.Values{}
+	query.Set("origin", strconv.Itoa(input.Origin))
+	query.Set("destination", strconv.Itoa(input.Destination))
+	query.Set("weight", strconv.FormatFloat(float64(input.Package.Weight), 'f', -1, 32))
+	query.Set("volume", strconv.FormatFloat(float64(input.Package.Volume), 'f', -1, 32))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/delivery-price?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create delivery price request due: %w", err)
+	}
+
+	var respBody DeliveryPriceResponse
+	if err = s.do(req, http.StatusOK, &respBody); err != nil {
+		return nil, fmt.Errorf("unable to get delivery price from courier due: %w", err)
+	}
+
+	return &service.DeliveryQuote{
+		Origin:           respBody.Origin,
+		Destination:      respBody.Destination,
+		Zone:             respBody.Zone,
+		ChargeableWeight: respBody.ChargeableWeight,
+		Price:            respBody.Price,
+	}, nil
+}
+
+func (s *supportService) PickupDelivery(ctx context.Context, input service.PickupDeliveryInput) (*service.PickupDeliveryOutput, error) {
+	reqBody, err := json.Marshal(PickupRequest{
+		TransactionID: input.TransactionID,
+		Origin:        input.Origin,
+		Destination:   input.Destination,
+		Weight:        input.Package.Weight,
+		Volume:        input.Package.Volume,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal pickup request due: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/pickups", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create pickup request due: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var respBody PickupResponse
+	if err = s.do(req, http.StatusCreated, &respBody); err != nil {
+		return nil, fmt.Errorf("unable to request pickup to courier due: %w", err)
+	}
+
+	return &service.PickupDeliveryOutput{
+		TrackingNumber: respBody.TrackingNumber,
+		Status:         entity.DeliveryStatus(respBody.Status),
+		Price:          respBody.Price,
+	}, nil
+}
+
+// do send the request then decode the response body into out when the response status is as expected.
+// Bad request from the courier means the input is invalid.
+func (s *supportService) do(req *http.Request, expectedStatus int, out interface{}) error {
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatus {
+		var errResp ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		if resp.StatusCode == http.StatusBadRequest {
+			return fmt.Errorf("%w: %s", service.ErrInvalidInput, errResp.Error)
+		}
+		return fmt.Errorf("unexpected response status %d: %s", resp.StatusCode, errResp.Error)
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("unable to decode response body due: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package deliverycourier_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/service"
+	deliverycourier "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/driven/delivery/courier"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculateDeliveryPrice(t *testing.T) {
+	courierSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/delivery-price", r.URL.Path)
+		if r.URL.Query().Get("destination") == "1" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(deliverycourier.ErrorResponse{Error: "invalid destination"})
+			return
+		}
+		require.Equal(t, "3471", r.URL.Query().Get("origin"))
+		require.Equal(t, "3404", r.URL.Query().Get("destination"))
+		require.Equal(t, "1.5", r.URL.Query().Get("weight"))
+		json.NewEncoder(w).Encode(deliverycourier.DeliveryPriceResponse{
+			Origin:           3471,
+			Destination:      3404,
+			Zone:             "INTRA_PROVINCE",
+			ChargeableWeight: 2,
+			Price:            14000,
+		})
+	}))
+	defer courierSrv.Close()
+
+	supportSvc, err := deliverycourier.NewSupportService(deliverycourier.SupportServiceConfig{
+		BaseURL: courierSrv.URL,
+	})
+	require.NoError(t, err)
+
+	quote, err := supportSvc.CalculateDeliveryPrice(context.Background(), service.CalculateDeliveryPriceInput{
+		Origin:      3471,
+		Destination: 3404,
+		Package:     service.GoodsSpecification{Weight: 1.5},
+	})
+	require.NoError(t, err)
+	require.Equal(t, &service.DeliveryQuote{
+		Origin:           3471,
+		Destination:      3404,
+		Zone:             "INTRA_PROVINCE",
+		ChargeableWeight: 2,
+		Price:            14000,
+	}, quote)
+
+	_, err = supportSvc.CalculateDeliveryPrice(context.Background(), service.CalculateDeliveryPriceInput{
+		Origin:      3471,
+		Destination: 1,
+	})
+	require.ErrorIs(t, err, service.ErrInvalidInput)
+}
+
+func TestPickupDelivery(t *testing.T) {
+	courierSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/pickups", r.URL.Path)
+
+		var reqBody deliverycourier.PickupRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		require.Equal(t, deliverycourier.PickupRequest{
+			TransactionID: 10,
+			Origin:        3471,
+			Destination:   3171,
+			Weight:        1,
+		}, reqBody)
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(deliverycourier.PickupResponse{
+			TrackingNumber: "NRM000000000001",
+			TransactionID:  10,
+			Status:         "REQUESTED",
+			Price:          15000,
+		})
+	}))
+	defer courierSrv.Close()
+
+	supportSvc, err := deliverycourier.NewSupportService(deliverycourier.SupportServiceConfig{
+		BaseURL: courierSrv.URL + "/",
+	})
+	require.NoError(t, err)
+
+	output, err := supportSvc.PickupDelivery(context.Background(), service.PickupDeliveryInput{
+		TransactionID: 10,
+		Origin:        3471,
+		Destination:   3171,
+		Package:       service.GoodsSpecification{Weight: 1},
+	})
+	require.NoError(t, err)
+	require.Equal(t, &service.PickupDeliveryOutput{
+		TrackingNumber: "NRM000000000001",
+		Status:         entity.DeliveryStatusRequested,
+		Price:          15000,
+	}, output)
+}
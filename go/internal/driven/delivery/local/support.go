@@ -0,0 +1,122 @@
+package deliverylocal
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/service"
+)
+
+const (
+	ZoneIntraCity     = "INTRA_CITY"
+	ZoneIntraProvince = "INTRA_PROVINCE"
+	ZoneIntraIsland   = "INTRA_ISLAND"
+	ZoneNational      = "NATIONAL"
+
+	// volumetricDivisor convert volume in cubic centimeter into volumetric weight in kilogram
+	volumetricDivisor = 6000
+	// heavyWeightThreshold is the weight in kilogram after which the price per kilogram discounted
+	heavyWeightThreshold = 10
+	heavyWeightRate      = 0.8
+)
+
+type zoneTariff struct {
+	// FirstKilogram is price for the first kilogram
+	FirstKilogram float64
+	// NextKilogram is price for each of the next kilogram
+	NextKilogram float64
+}
+
+var tariffs = map[string]zoneTariff{
+	ZoneIntraCity:     {FirstKilogram: 8000, NextKilogram: 2000},
+	ZoneIntraProvince: {FirstKilogram: 10000, NextKilogram: 4000},
+	ZoneIntraIsland:   {FirstKilogram: 15000, NextKilogram: 8000},
+	ZoneNational:      {FirstKilogram: 25000, NextKilogram: 15000},
+}
+
+// supportService calculate the delivery price locally using simple rules, so big UMKM scenario
+// can run without live courier service
+type supportService struct{}
+
+func NewSupportService() *supportService {
+	return &supportService{}
+}
+
+func (s *supportService) CalculateDeliveryPrice(ctx context.Context, input service.CalculateDeliveryPriceInput) (*service.DeliveryQuote, error) {
+	if !service.IsValidLocation(input.Origin) || !service.IsValidLocation(input.Destination) {
+		return nil, fmt.Errorf("%w: origin and destination must be 4 digits regency / city code", service.ErrInvalidInput)
+	}
+
+	zone := GetZone(input.Origin, input.Destination)
+	chargeableWeight := GetChargeableWeight(input.Package)
+
+	return &service.DeliveryQuote{
+		Origin:           input.Origin,
+		Destination:      input.Destination,
+		Zone:             zone,
+		ChargeableWeight: chargeableWeight,
+		Price:            GetPrice(zone, chargeableWeight),
+	}, nil
+}
+
+// PickupDelivery accept the pickup right away, the shipment status never advance since there's no courier
+func (s *supportService) PickupDelivery(ctx context.Context, input service.PickupDeliveryInput) (*service.PickupDeliveryOutput, error) {
+	quote, err := s.CalculateDeliveryPrice(ctx, service.CalculateDeliveryPriceInput{
+		Origin:      input.Origin,
+		Destination: input.Destination,
+		Package:     input.Package,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &service.PickupDeliveryOutput{
+		TrackingNumber: fmt.Sprintf("LOCAL-%d-%d", input.TransactionID, time.Now().UnixNano()),
+		Status:         entity.DeliveryStatusRequested,
+		Price:          quote.Price,
+	}, nil
+}
+
+// GetZone determine delivery zone from regency / city code of origin and destination.
+// The first digit of the code is the island group, the first two digits is the province.
+func GetZone(origin int, destination int) string {
+	switch {
+	case origin == destination:
+		return ZoneIntraCity
+	case origin/100 == destination/100:
+		return ZoneIntraProvince
+	case origin/1000 == destination/1000:
+		return ZoneIntraIsland
+	default:
+		return ZoneNational
+	}
+}
+
+// GetChargeableWeight get the greater one between actual and volumetric weight,
+// rounded up to the next kilogram with minimum 1 kilogram
+func GetChargeableWeight(spec service.GoodsSpecification) float32 {
+	weight := math.Max(float64(spec.Weight), float64(spec.Volume)/volumetricDivisor)
+	return float32(math.Max(1, math.Ceil(weight)))
+}
+
+// GetPrice is the delivery price of the chargeable weight, rounded to whole rupiah. The first kilogram is charged
+// by its own price, the next kilograms up to the heavy weight threshold by the normal price and the rest of them
+// by the discounted price.
+func GetPrice(zone string, chargeableWeight float32) entity.Money {
+	tariff, ok := tariffs[zone]
+	if !ok {
+		tariff = tariffs[ZoneNational]
+	}
+
+	weight := math.Floor(float64(chargeableWeight))
+	normalKilograms := math.Max(0, math.Min(weight, heavyWeightThreshold)-1)
+	heavyKilograms := math.Max(0, weight-heavyWeightThreshold)
+	price := tariff.FirstKilogram +
+		normalKilograms*tariff.NextKilogram +
+		heavyKilograms*tariff.NextKilogram*heavyWeightRate
+
+	return entity.NewMoney(price)
+}
@@ -0,0 +1,114 @@
+package deliverylocal_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/service"
+	deliverylocal "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/driven/delivery/local"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculateDeliveryPrice(mainT *testing.T) {
+	testCases := []struct {
+		Name          string
+		Input         service.CalculateDeliveryPriceInput
+		ExpectedQuote *service.DeliveryQuote
+		IsError       bool
+	}{
+		{
+			Name: "Same city with light package",
+			Input: service.CalculateDeliveryPriceInput{
+				Origin:      3471,
+				Destination: 3471,
+				Package:     service.GoodsSpecification{Weight: 0.3},
+			},
+			ExpectedQuote: &service.DeliveryQuote{
+				Origin:           3471,
+				Destination:      3471,
+				Zone:             deliverylocal.ZoneIntraCity,
+				ChargeableWeight: 1,
+				Price:            8000,
+			},
+		},
+		{
+			Name: "Same province charged by actual weight",
+			Input: service.CalculateDeliveryPriceInput{
+				Origin:      3471,
+				Destination: 3404,
+				Package:     service.GoodsSpecification{Weight: 2.5, Volume: 1000},
+			},
+			ExpectedQuote: &service.DeliveryQuote{
+				Origin:           3471,
+				Destination:      3404,
+				Zone:             deliverylocal.ZoneIntraProvince,
+				ChargeableWeight: 3,
+				Price:            10000 + (2 * 4000),
+			},
+		},
+		{
+			Name: "Same island charged by volumetric weight",
+			Input: service.CalculateDeliveryPriceInput{
+				Origin:      3471,
+				Destination: 3173,
+				Package:     service.GoodsSpecification{Weight: 1, Volume: 24000},
+			},
+			ExpectedQuote: &service.DeliveryQuote{
+				Origin:           3471,
+				Destination:      3173,
+				Zone:             deliverylocal.ZoneIntraIsland,
+				ChargeableWeight: 4,
+				Price:            15000 + (3 * 8000),
+			},
+		},
+		{
+			Name: "Another island with heavy package",
+			Input: service.CalculateDeliveryPriceInput{
+				Origin:      3471,
+				Destination: 7371,
+				Package:     service.GoodsSpecification{Weight: 12},
+			},
+			ExpectedQuote: &service.DeliveryQuote{
+				Origin:           3471,
+				Destination:      7371,
+				Zone:             deliverylocal.ZoneNational,
+				ChargeableWeight: 12,
+				Price:            25000 + (9 * 15000) + (2 * 15000 * 0.8),
+			},
+		},
+		{
+			Name: "Same city with very heavy package",
+			Input: service.CalculateDeliveryPriceInput{
+				Origin:      3471,
+				Destination: 3471,
+				Package:     service.GoodsSpecification{Weight: 1000},
+			},
+			ExpectedQuote: &service.DeliveryQuote{
+				Origin:           3471,
+				Destination:      3471,
+				Zone:             deliverylocal.ZoneIntraCity,
+				ChargeableWeight: 1000,
+				Price:            8000 + (9 * 2000) + (990 * 2000 * 0.8),
+			},
+		},
+		{
+			Name: "Invalid destination",
+			Input: service.CalculateDeliveryPriceInput{
+				Origin:      3471,
+				Destination: 12,
+				Package:     service.GoodsSpecification{Weight: 1},
+			},
+			IsError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		mainT.Run(testCase.Name, func(t *testing.T) {
+			supportSvc := deliverylocal.NewSupportService()
+
+			quote, err := supportSvc.CalculateDeliveryPrice(context.Background(), testCase.Input)
+			require.Equal(t, testCase.IsError, (err != nil), "unexpected error")
+			require.Equal(t, testCase.ExpectedQuote, quote)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package notifierlocal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+)
+
+// Alert is a line written into the alert file
+type Alert struct {
+	Event    string          `json:"event"`
+	LowStock entity.LowStock `json:"low_stock"`
+	SentAt   int64           `json:"sent_at"`
+}
+
+const EventLowStock = "LOW_STOCK"
+
+// notifier write the alerts into local file as JSON lines, or into the log when the file is not set,
+// so the alerts can be checked without any external service
+type notifier struct {
+	filePath string
+	mu       sync.Mutex
+}
+
+type NotifierConfig struct {
+	// FilePath is the file which the alerts appended into, the alerts is written into the log when it's empty
+	FilePath string
+}
+
+func NewNotifier(config NotifierConfig) *notifier {
+	return &notifier{filePath: config.FilePath}
+}
+
+func (n *notifier) NotifyLowStock(ctx context.Context, lowStock entity.LowStock) error {
+	line, err := json.Marshal(Alert{
+		Event:    EventLowStock,
+		LowStock: lowStock,
+		SentAt:   time.Now().Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to marshal low stock alert due: %w", err)
+	}
+
+	if len(n.filePath) == 0 {
+		log.Printf("[ALERT] %s", line)
+		return nil
+	}
+
+	// alerts could be sent concurrently, so only one of them write into the file at a time
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	file, err := os.OpenFile(n.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open alert file due: %w", err)
+	}
+	defer file.Close()
+
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("unable to write low stock alert into file due: %w", err)
+	}
+	return nil
+}
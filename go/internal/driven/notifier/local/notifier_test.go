@@ -0,0 +1,42 @@
+package notifierlocal_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+	notifierlocal "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/driven/notifier/local"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyLowStock(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "alerts.log")
+	notifier := notifierlocal.NewNotifier(notifierlocal.NotifierConfig{FilePath: filePath})
+
+	ctx := context.Background()
+	require.NoError(t, notifier.NotifyLowStock(ctx, entity.LowStock{GoodsID: 1, Name: "Kopi", Stocks: 9, ReorderPoint: 10}))
+	require.NoError(t, notifier.NotifyLowStock(ctx, entity.LowStock{GoodsID: 2, Name: "Pisang Goreng", Stocks: 5, ReorderPoint: 5}))
+
+	file, err := os.Open(filePath)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var alerts []notifierlocal.Alert
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var alert notifierlocal.Alert
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &alert))
+		alerts = append(alerts, alert)
+	}
+	require.Len(t, alerts, 2)
+	require.Equal(t, notifierlocal.EventLowStock, alerts[0].Event)
+	require.Equal(t, "Kopi", alerts[0].LowStock.Name)
+	require.Equal(t, 5, alerts[1].LowStock.Stocks)
+
+	// without file the alert is written into the log
+	require.NoError(t, notifierlocal.NewNotifier(notifierlocal.NotifierConfig{}).NotifyLowStock(ctx, entity.LowStock{GoodsID: 1}))
+}
@@ -0,0 +1,15 @@
+package notifierwebhook
+
+const EventLowStock = "LOW_STOCK"
+
+// LowStockRequest is request body sent into the webhook when the goods stocks dropped to its reorder point
+type LowStockRequest struct {
+	Event        string `json:"event"`
+	GoodsID      int    `json:"goods_id"`
+	VariantID    int    `json:"variant_id,omitempty"`
+	Name         string `json:"name"`
+	Unit         string `json:"unit,omitempty"`
+	Stocks       int    `json:"stocks"`
+	ReorderPoint int    `json:"reorder_point"`
+	CreatedAt    int64  `json:"created_at"`
+}
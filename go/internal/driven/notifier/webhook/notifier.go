@@ -0,0 +1,73 @@
+package notifierwebhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+	"gopkg.in/validator.v2"
+)
+
+// notifier post the alerts as JSON into the webhook URL, e.g. the incoming webhook of the shop owner chat app
+type notifier struct {
+	url        string
+	httpClient *http.Client
+}
+
+type NotifierConfig struct {
+	URL        string `validate:"nonzero"`
+	HTTPClient *http.Client
+}
+
+func NewNotifier(config NotifierConfig) (*notifier, error) {
+	if err := validator.Validate(config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &notifier{
+		url:        config.URL,
+		httpClient: httpClient,
+	}, nil
+}
+
+func (n *notifier) NotifyLowStock(ctx context.Context, lowStock entity.LowStock) error {
+	reqBody, err := json.Marshal(LowStockRequest{
+		Event:        EventLowStock,
+		GoodsID:      lowStock.GoodsID,
+		VariantID:    lowStock.VariantID,
+		Name:         lowStock.Name,
+		Unit:         lowStock.Unit,
+		Stocks:       lowStock.Stocks,
+		ReorderPoint: lowStock.ReorderPoint,
+		CreatedAt:    time.Now().Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to marshal low stock request due: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("unable to create low stock request due: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send low stock into webhook due: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// any successful status is accepted since each chat app respond differently
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unable to send low stock into webhook due: unexpected response status %d", resp.StatusCode)
+	}
+	return nil
+}
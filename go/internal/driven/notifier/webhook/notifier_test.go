@@ -0,0 +1,57 @@
+package notifierwebhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+	notifierwebhook "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/driven/notifier/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyLowStock(t *testing.T) {
+	var received []notifierwebhook.LowStockRequest
+	webhookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var reqBody notifierwebhook.LowStockRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		if reqBody.GoodsID == 99 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		received = append(received, reqBody)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer webhookSrv.Close()
+
+	_, err := notifierwebhook.NewNotifier(notifierwebhook.NotifierConfig{})
+	require.Error(t, err)
+
+	notifier, err := notifierwebhook.NewNotifier(notifierwebhook.NotifierConfig{
+		URL: webhookSrv.URL,
+	})
+	require.NoError(t, err)
+
+	err = notifier.NotifyLowStock(context.Background(), entity.LowStock{
+		GoodsID:      8,
+		Name:         "Biji Kopi",
+		Unit:         "gram",
+		Stocks:       450,
+		ReorderPoint: 500,
+	})
+	require.NoError(t, err)
+	require.Len(t, received, 1)
+	require.Equal(t, notifierwebhook.EventLowStock, received[0].Event)
+	require.Equal(t, "Biji Kopi", received[0].Name)
+	require.Equal(t, 450, received[0].Stocks)
+	require.Equal(t, 500, received[0].ReorderPoint)
+	require.Positive(t, received[0].CreatedAt)
+
+	err = notifier.NotifyLowStock(context.Background(), entity.LowStock{GoodsID: 99})
+	require.Error(t, err)
+}
@@ -0,0 +1,57 @@
+package paymentsimulator
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+)
+
+// SignatureHeader is the header of the callback which holds the signature of its body
+const SignatureHeader = "X-Signature"
+
+// CreatePaymentRequest is request body of `POST /payments` payment simulator API
+type CreatePaymentRequest struct {
+	TransactionID int64        `json:"transaction_id"`
+	Method        string       `json:"method"`
+	Amount        entity.Money `json:"amount"`
+	// CallbackURL is where the simulator send the callback once the payment is paid
+	CallbackURL string `json:"callback_url"`
+}
+
+// PaymentResponse is response body of `POST /payments`, `GET /payments/:reference` and
+// `POST /payments/:reference/pay` payment simulator API
+type PaymentResponse struct {
+	Reference     string       `json:"reference"`
+	TransactionID int64        `json:"transaction_id"`
+	Method        string       `json:"method"`
+	Amount        entity.Money `json:"amount"`
+	Status        string       `json:"status"`
+	QRPayload     string       `json:"qr_payload"`
+	CreatedAt     int64        `json:"created_at"`
+	ExpiresAt     int64        `json:"expires_at"`
+	PaidAt        int64        `json:"paid_at"`
+}
+
+// CallbackRequest is request body of the callback sent by payment simulator
+type CallbackRequest struct {
+	Reference     string       `json:"reference"`
+	TransactionID int64        `json:"transaction_id"`
+	Method        string       `json:"method"`
+	Amount        entity.Money `json:"amount"`
+	Status        string       `json:"status"`
+	PaidAt        int64        `json:"paid_at"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Sign compute the signature of the callback body, it's hex encoded HMAC-SHA256 with the secret shared by
+// the simulator and the app
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
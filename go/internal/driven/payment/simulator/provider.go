@@ -0,0 +1,112 @@
+package paymentsimulator
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/service"
+	"gopkg.in/validator.v2"
+)
+
+// paymentProvider call the payment simulator through its HTTP API
+type paymentProvider struct {
+	baseURL     string
+	callbackURL string
+	secret      string
+	httpClient  *http.Client
+}
+
+type PaymentProviderConfig struct {
+	// BaseURL of the payment simulator, e.g. http://payment-simulator:8082
+	BaseURL string `validate:"nonzero"`
+	// CallbackURL is the callback endpoint of this app which reachable by the simulator
+	CallbackURL string `validate:"nonzero"`
+	// Secret is shared with the simulator to sign the callback
+	Secret     string `validate:"nonzero"`
+	HTTPClient *http.Client
+}
+
+func NewPaymentProvider(config PaymentProviderConfig) (*paymentProvider, error) {
+	if err := validator.Validate(config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &paymentProvider{
+		baseURL:     strings.TrimSuffix(config.BaseURL, "/"),
+		callbackURL: config.CallbackURL,
+		secret:      config.Secret,
+		httpClient:  httpClient,
+	}, nil
+}
+
+func (p *paymentProvider) CreatePayment(ctx context.Context, input service.CreatePaymentInput) (*service.CreatePaymentOutput, error) {
+	reqBody, err := json.Marshal(CreatePaymentRequest{
+		TransactionID: input.TransactionID,
+		Method:        string(input.Method),
+		Amount:        input.Amount,
+		CallbackURL:   p.callbackURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal payment request due: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/payments", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create payment request due: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to request payment to simulator due: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		var errResp ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		if resp.StatusCode == http.StatusBadRequest {
+			return nil, fmt.Errorf("%w: %s", service.ErrInvalidInput, errResp.Error)
+		}
+		return nil, fmt.Errorf("unexpected response status %d: %s", resp.StatusCode, errResp.Error)
+	}
+	var respBody PaymentResponse
+	if err = json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return nil, fmt.Errorf("unable to decode response body due: %w", err)
+	}
+
+	return &service.CreatePaymentOutput{
+		Reference: respBody.Reference,
+		QRPayload: respBody.QRPayload,
+		ExpiresAt: respBody.ExpiresAt,
+	}, nil
+}
+
+func (p *paymentProvider) ParseCallback(payload []byte, signature string) (*service.PaymentCallback, error) {
+	if !hmac.Equal([]byte(Sign(p.secret, payload)), []byte(strings.ToLower(signature))) {
+		return nil, service.ErrInvalidSignature
+	}
+
+	var callback CallbackRequest
+	if err := json.Unmarshal(payload, &callback); err != nil {
+		return nil, fmt.Errorf("%w: unable to decode callback due: %v", service.ErrInvalidInput, err)
+	}
+
+	return &service.PaymentCallback{
+		Reference: callback.Reference,
+		Status:    entity.PaymentStatus(callback.Status),
+		Amount:    callback.Amount,
+		PaidAt:    callback.PaidAt,
+	}, nil
+}
@@ -0,0 +1,106 @@
+package paymentsimulator_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/service"
+	paymentsimulator "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/driven/payment/simulator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatePayment(t *testing.T) {
+	simulatorSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/payments", r.URL.Path)
+
+		var reqBody paymentsimulator.CreatePaymentRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&reqBody))
+		if reqBody.Amount <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(paymentsimulator.ErrorResponse{Error: "amount must be positive"})
+			return
+		}
+		require.Equal(t, paymentsimulator.CreatePaymentRequest{
+			TransactionID: 12,
+			Method:        "QRIS",
+			Amount:        4000,
+			CallbackURL:   "http://umkm:8080/api/payments/callback",
+		}, reqBody)
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(paymentsimulator.PaymentResponse{
+			Reference:     "SIM-1",
+			TransactionID: reqBody.TransactionID,
+			Method:        reqBody.Method,
+			Amount:        reqBody.Amount,
+			Status:        "PENDING",
+			QRPayload:     "000201010212",
+			ExpiresAt:     1689874260,
+		})
+	}))
+	defer simulatorSrv.Close()
+
+	provider, err := paymentsimulator.NewPaymentProvider(paymentsimulator.PaymentProviderConfig{
+		BaseURL:     simulatorSrv.URL,
+		CallbackURL: "http://umkm:8080/api/payments/callback",
+		Secret:      "secret",
+	})
+	require.NoError(t, err)
+
+	output, err := provider.CreatePayment(context.Background(), service.CreatePaymentInput{
+		TransactionID: 12,
+		Method:        entity.PaymentMethodQRIS,
+		Amount:        4000,
+	})
+	require.NoError(t, err)
+	require.Equal(t, &service.CreatePaymentOutput{
+		Reference: "SIM-1",
+		QRPayload: "000201010212",
+		ExpiresAt: 1689874260,
+	}, output)
+
+	_, err = provider.CreatePayment(context.Background(), service.CreatePaymentInput{
+		TransactionID: 12,
+		Method:        entity.PaymentMethodQRIS,
+	})
+	require.ErrorIs(t, err, service.ErrInvalidInput)
+}
+
+func TestParseCallback(t *testing.T) {
+	provider, err := paymentsimulator.NewPaymentProvider(paymentsimulator.PaymentProviderConfig{
+		BaseURL:     "http://payment-simulator:8082",
+		CallbackURL: "http://umkm:8080/api/payments/callback",
+		Secret:      "secret",
+	})
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(paymentsimulator.CallbackRequest{
+		Reference:     "SIM-1",
+		TransactionID: 12,
+		Method:        "QRIS",
+		Amount:        4000,
+		Status:        "PAID",
+		PaidAt:        1689873400,
+	})
+	require.NoError(t, err)
+
+	callback, err := provider.ParseCallback(payload, paymentsimulator.Sign("secret", payload))
+	require.NoError(t, err)
+	require.Equal(t, &service.PaymentCallback{
+		Reference: "SIM-1",
+		Status:    entity.PaymentStatusPaid,
+		Amount:    4000,
+		PaidAt:    1689873400,
+	}, callback)
+
+	_, err = provider.ParseCallback(payload, paymentsimulator.Sign("another secret", payload))
+	require.ErrorIs(t, err, service.ErrInvalidSignature)
+
+	_, err = provider.ParseCallback(payload, "")
+	require.ErrorIs(t, err, service.ErrInvalidSignature)
+}
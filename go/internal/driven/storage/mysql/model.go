@@ -1,16 +1,41 @@
 package storagemysql
 
-import "github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+import (
+	"database/sql"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+)
 
 type GoodsRow struct {
-	ID     int     `db:"id"`
-	Name   string  `db:"name"`
-	Stocks int     `db:"stocks"`
-	Price  float64 `db:"price"`
+	ID               int           `db:"id"`
+	Name             string        `db:"name"`
+	Stocks           int           `db:"stocks"`
+	ReservedStocks   int           `db:"reserved_stocks"`
+	Price            entity.Money  `db:"price"`
+	CategoryID       sql.NullInt64 `db:"id_category"`
+	IsRawMaterial    bool          `db:"is_raw_material"`
+	Unit             string        `db:"unit"`
+	ReorderPoint     int           `db:"reorder_point"`
+	AverageCost      float64       `db:"average_cost"`
+	PriceIncludesTax bool          `db:"price_includes_tax"`
+	DeletedAt        sql.NullInt64 `db:"deleted_at"`
 }
 
 func (r GoodsRow) ToGoodsEntity() entity.Goods {
-	return entity.Goods(r)
+	return entity.Goods{
+		ID:               r.ID,
+		Name:             r.Name,
+		Stocks:           r.Stocks,
+		ReservedStocks:   r.ReservedStocks,
+		Price:            r.Price,
+		CategoryID:       int(r.CategoryID.Int64),
+		IsRawMaterial:    r.IsRawMaterial,
+		Unit:             r.Unit,
+		ReorderPoint:     r.ReorderPoint,
+		AverageCost:      r.AverageCost,
+		PriceIncludesTax: r.PriceIncludesTax,
+		DeletedAt:        r.DeletedAt.Int64,
+	}
 }
 
 type GoodsRowCollection []GoodsRow
@@ -23,22 +48,400 @@ func (c GoodsRowCollection) ToGoodsEntityCollection() []entity.Goods {
 	return goodsEntityCollection
 }
 
+type CategoryRow struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+type GoodsVariantRow struct {
+	ID             int          `db:"id"`
+	GoodsID        int          `db:"id_goods"`
+	Name           string       `db:"name"`
+	PriceDelta     entity.Money `db:"price_delta"`
+	Stocks         int          `db:"stocks"`
+	ReservedStocks int          `db:"reserved_stocks"`
+	AverageCost    float64      `db:"average_cost"`
+}
+
+func (r GoodsVariantRow) ToGoodsVariantEntity() entity.GoodsVariant {
+	return entity.GoodsVariant(r)
+}
+
+type RecipeIngredientRow struct {
+	GoodsID         int    `db:"id_goods"`
+	IngredientID    int    `db:"id_ingredient"`
+	Name            string `db:"name"`
+	Unit            string `db:"unit"`
+	Quantity        int    `db:"quantity"`
+	AvailableStocks int    `db:"available_stocks"`
+}
+
+type RecipeIngredientRowCollection []RecipeIngredientRow
+
+// ToRecipeEntities group the ingredients by their goods and calculate the servings of each recipe
+func (c RecipeIngredientRowCollection) ToRecipeEntities() map[int]*entity.Recipe {
+	recipes := map[int]*entity.Recipe{}
+	for _, ingredientRow := range c {
+		recipe, ok := recipes[ingredientRow.GoodsID]
+		if !ok {
+			recipe = &entity.Recipe{GoodsID: ingredientRow.GoodsID}
+			recipes[ingredientRow.GoodsID] = recipe
+		}
+		recipe.Ingredients = append(recipe.Ingredients, entity.RecipeIngredient{
+			IngredientID:    ingredientRow.IngredientID,
+			Name:            ingredientRow.Name,
+			Unit:            ingredientRow.Unit,
+			Quantity:        ingredientRow.Quantity,
+			AvailableStocks: ingredientRow.AvailableStocks,
+		})
+	}
+	for _, recipe := range recipes {
+		recipe.CalculateServings()
+	}
+	return recipes
+}
+
 type ShoppingCartRow struct {
-	ID          int64   `db:"id"`
-	UserID      int     `db:"id_user"`
-	TotalAmount float64 `db:"total_amount"`
-	Status      int     `db:"status"`
+	ID          int64        `db:"id"`
+	UserID      int          `db:"id_user"`
+	TotalAmount entity.Money `db:"total_amount"`
+	Status      int          `db:"status"`
+}
+
+type TransactionSummaryRow struct {
+	ID                  int64          `db:"id"`
+	Status              int            `db:"status"`
+	TotalGoods          int            `db:"total_goods"`
+	TotalAmount         entity.Money   `db:"total_amount"`
+	SubtotalAmount      entity.Money   `db:"subtotal_amount"`
+	DiscountAmount      entity.Money   `db:"discount_amount"`
+	ServiceChargeAmount entity.Money   `db:"service_charge_amount"`
+	TaxBaseAmount       entity.Money   `db:"tax_base_amount"`
+	TaxAmount           entity.Money   `db:"tax_amount"`
+	TaxRate             float64        `db:"tax_rate"`
+	ServiceChargeRate   float64        `db:"service_charge_rate"`
+	PaymentAmount       sql.NullInt64  `db:"payment_amount"`
+	RefundedAmount      entity.Money   `db:"refunded_amount"`
+	IdempotencyKey      sql.NullString `db:"idempotency_key"`
+}
+
+func (r TransactionSummaryRow) ToTransactionEntity() *entity.Transaction {
+	trx := &entity.Transaction{
+		ID:                  r.ID,
+		Status:              entity.TransactionStatus(r.Status),
+		TotalGoods:          r.TotalGoods,
+		TotalAmount:         r.TotalAmount,
+		SubtotalAmount:      r.SubtotalAmount,
+		DiscountAmount:      r.DiscountAmount,
+		ServiceChargeAmount: r.ServiceChargeAmount,
+		TaxBaseAmount:       r.TaxBaseAmount,
+		TaxAmount:           r.TaxAmount,
+		TaxRate:             r.TaxRate,
+		ServiceChargeRate:   r.ServiceChargeRate,
+		PaymentAmount:       entity.Money(r.PaymentAmount.Int64),
+		RefundedAmount:      r.RefundedAmount,
+		IdempotencyKey:      r.IdempotencyKey.String,
+	}
+	// payment amount only recorded when the shopping cart is paid
+	if r.PaymentAmount.Valid {
+		trx.ReturnAmount = trx.PaymentAmount - trx.TotalAmount
+	}
+
+	return trx
+}
+
+type TransactionStatusHistoryRow struct {
+	FromStatus sql.NullInt16 `db:"from_status"`
+	ToStatus   int           `db:"to_status"`
+	CreatedAt  int64         `db:"created_at"`
+}
+
+type TransactionStatusHistoryRowCollection []TransactionStatusHistoryRow
+
+func (c TransactionStatusHistoryRowCollection) ToTransactionStatusChanges() []entity.TransactionStatusChange {
+	changes := []entity.TransactionStatusChange{}
+	for _, historyRow := range c {
+		change := entity.TransactionStatusChange{
+			To:        entity.TransactionStatus(historyRow.ToStatus),
+			ChangedAt: historyRow.CreatedAt,
+		}
+		if historyRow.FromStatus.Valid {
+			from := entity.TransactionStatus(historyRow.FromStatus.Int16)
+			change.From = &from
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+type StockMovementRow struct {
+	ID            int64         `db:"id"`
+	GoodsID       int           `db:"id_goods"`
+	VariantID     int           `db:"id_variant"`
+	Type          string        `db:"type"`
+	Quantity      int           `db:"quantity"`
+	UnitCost      float64       `db:"unit_cost"`
+	StocksBefore  int           `db:"stocks_before"`
+	StocksAfter   int           `db:"stocks_after"`
+	Actor         string        `db:"actor"`
+	Reason        string        `db:"reason"`
+	TransactionID sql.NullInt64 `db:"id_transaction"`
+	ExpiresAt     sql.NullInt64 `db:"expires_at"`
+	CreatedAt     int64         `db:"created_at"`
+}
+
+type StockMovementRowCollection []StockMovementRow
+
+func (c StockMovementRowCollection) ToStockMovements() []entity.StockMovement {
+	movements := []entity.StockMovement{}
+	for _, movementRow := range c {
+		movements = append(movements, entity.StockMovement{
+			ID:            movementRow.ID,
+			GoodsID:       movementRow.GoodsID,
+			VariantID:     movementRow.VariantID,
+			Type:          entity.StockMovementType(movementRow.Type),
+			Quantity:      movementRow.Quantity,
+			UnitCost:      movementRow.UnitCost,
+			Before:        movementRow.StocksBefore,
+			After:         movementRow.StocksAfter,
+			Actor:         movementRow.Actor,
+			Reason:        movementRow.Reason,
+			TransactionID: movementRow.TransactionID.Int64,
+			ExpiresAt:     movementRow.ExpiresAt.Int64,
+			CreatedAt:     movementRow.CreatedAt,
+		})
+	}
+	return movements
+}
+
+type StockBatchRow struct {
+	ID               int64         `db:"id"`
+	GoodsID          int           `db:"id_goods"`
+	VariantID        int           `db:"id_variant"`
+	Name             string        `db:"name"`
+	Unit             string        `db:"unit"`
+	ReceivedQuantity int           `db:"received_quantity"`
+	Quantity         int           `db:"quantity"`
+	UnitCost         float64       `db:"unit_cost"`
+	ReceivedAt       int64         `db:"received_at"`
+	ExpiresAt        sql.NullInt64 `db:"expires_at"`
+}
+
+type StockBatchRowCollection []StockBatchRow
+
+func (c StockBatchRowCollection) ToStockBatches() []entity.StockBatch {
+	batches := []entity.StockBatch{}
+	for _, batchRow := range c {
+		batches = append(batches, entity.StockBatch{
+			ID:               batchRow.ID,
+			GoodsID:          batchRow.GoodsID,
+			VariantID:        batchRow.VariantID,
+			Name:             batchRow.Name,
+			Unit:             batchRow.Unit,
+			ReceivedQuantity: batchRow.ReceivedQuantity,
+			Quantity:         batchRow.Quantity,
+			UnitCost:         batchRow.UnitCost,
+			ReceivedAt:       batchRow.ReceivedAt,
+			ExpiresAt:        batchRow.ExpiresAt.Int64,
+		})
+	}
+	return batches
+}
+
+type StockDriftRow struct {
+	GoodsID      int `db:"id_goods"`
+	VariantID    int `db:"id_variant"`
+	Stocks       int `db:"stocks"`
+	LedgerStocks int `db:"ledger_stocks"`
+}
+
+type StockCountRow struct {
+	ID         int64         `db:"id"`
+	Status     string        `db:"status"`
+	OpenedBy   string        `db:"opened_by"`
+	ApprovedBy string        `db:"approved_by"`
+	CreatedAt  int64         `db:"created_at"`
+	ApprovedAt sql.NullInt64 `db:"approved_at"`
+}
+
+func (r StockCountRow) ToStockCountEntity() *entity.StockCount {
+	return &entity.StockCount{
+		ID:         r.ID,
+		Status:     entity.StockCountStatus(r.Status),
+		OpenedBy:   r.OpenedBy,
+		ApprovedBy: r.ApprovedBy,
+		Items:      []entity.StockCountItem{},
+		CreatedAt:  r.CreatedAt,
+		ApprovedAt: r.ApprovedAt.Int64,
+	}
+}
+
+type StockCountItemRow struct {
+	GoodsID       int          `db:"id_goods"`
+	VariantID     int          `db:"id_variant"`
+	Name          string       `db:"name"`
+	CountedStocks int          `db:"counted_stocks"`
+	SystemStocks  int          `db:"system_stocks"`
+	Price         entity.Money `db:"price"`
+}
+
+type StockCountItemRowCollection []StockCountItemRow
+
+func (c StockCountItemRowCollection) ToStockCountItems() []entity.StockCountItem {
+	items := []entity.StockCountItem{}
+	for _, itemRow := range c {
+		items = append(items, entity.StockCountItem{
+			GoodsID:       itemRow.GoodsID,
+			VariantID:     itemRow.VariantID,
+			Name:          itemRow.Name,
+			CountedStocks: itemRow.CountedStocks,
+			SystemStocks:  itemRow.SystemStocks,
+			Price:         itemRow.Price,
+		})
+	}
+	return items
+}
+
+type LowStockRow struct {
+	GoodsID      int    `db:"id_goods"`
+	VariantID    int    `db:"id_variant"`
+	Name         string `db:"name"`
+	Unit         string `db:"unit"`
+	Stocks       int    `db:"stocks"`
+	ReorderPoint int    `db:"reorder_point"`
+}
+
+type RefundableTransactionRow struct {
+	ID             int64        `db:"id"`
+	Status         int          `db:"status"`
+	TotalAmount    entity.Money `db:"total_amount"`
+	SubtotalAmount entity.Money `db:"subtotal_amount"`
+	RefundedAmount entity.Money `db:"refunded_amount"`
+}
+
+func (r RefundableTransactionRow) ToTransactionEntity() *entity.Transaction {
+	return &entity.Transaction{
+		ID:             r.ID,
+		Status:         entity.TransactionStatus(r.Status),
+		TotalAmount:    r.TotalAmount,
+		SubtotalAmount: r.SubtotalAmount,
+		RefundedAmount: r.RefundedAmount,
+	}
+}
+
+type TransactionDiscountRow struct {
+	PromotionID int          `db:"id_promotion"`
+	Name        string       `db:"name"`
+	VoucherCode string       `db:"voucher_code"`
+	Amount      entity.Money `db:"amount"`
+}
+
+type TransactionTenderRow struct {
+	Method string       `db:"method"`
+	Amount entity.Money `db:"amount"`
+}
+
+func (r TransactionTenderRow) ToTenderEntity() entity.Tender {
+	return entity.Tender{
+		Method: entity.PaymentMethod(r.Method),
+		Amount: r.Amount,
+	}
+}
+
+type PurchasedGoodsRow struct {
+	GoodsID       int          `db:"id_goods"`
+	VariantID     int          `db:"id_variant"`
+	TotalGoods    int          `db:"total_goods"`
+	RefundedGoods int          `db:"refunded_goods"`
+	GoodsPrice    entity.Money `db:"price"`
+}
+
+type PurchasedGoodsRowCollection []PurchasedGoodsRow
+
+func (c PurchasedGoodsRowCollection) ToPurchasedGoodsEntities() []entity.PurchasedGoods {
+	purchased := []entity.PurchasedGoods{}
+	for _, goodsRow := range c {
+		purchased = append(purchased, entity.PurchasedGoods(goodsRow))
+	}
+	return purchased
+}
+
+type SalesSummaryRow struct {
+	TotalTransactions int          `db:"total_transactions"`
+	GrossAmount       entity.Money `db:"gross_amount"`
+	TotalRefunds      int          `db:"total_refunds"`
+	RefundAmount      entity.Money `db:"refund_amount"`
+	CostOfGoodsSold   entity.Money `db:"cost_of_goods_sold"`
+}
+
+func (r SalesSummaryRow) ToSalesSummaryEntity() *entity.SalesSummary {
+	return &entity.SalesSummary{
+		TotalTransactions: r.TotalTransactions,
+		GrossAmount:       r.GrossAmount,
+		TotalRefunds:      r.TotalRefunds,
+		RefundAmount:      r.RefundAmount,
+		CostOfGoodsSold:   r.CostOfGoodsSold,
+	}
+}
+
+type TenderTotalRow struct {
+	Method            string       `db:"method"`
+	TotalTransactions int          `db:"total_transactions"`
+	Amount            entity.Money `db:"amount"`
+}
+
+func (r TenderTotalRow) ToTenderTotalEntity() entity.TenderTotal {
+	return entity.TenderTotal{
+		Method:            entity.PaymentMethod(r.Method),
+		TotalTransactions: r.TotalTransactions,
+		Amount:            r.Amount,
+	}
+}
+
+type TaxRecapRow struct {
+	TotalTransactions   int          `db:"total_transactions"`
+	SubtotalAmount      entity.Money `db:"subtotal_amount"`
+	DiscountAmount      entity.Money `db:"discount_amount"`
+	ServiceChargeAmount entity.Money `db:"service_charge_amount"`
+	TaxBaseAmount       entity.Money `db:"tax_base_amount"`
+	TaxAmount           entity.Money `db:"tax_amount"`
+	GrandTotalAmount    entity.Money `db:"grand_total_amount"`
+	RefundAmount        entity.Money `db:"refund_amount"`
+	RefundedTaxAmount   entity.Money `db:"refunded_tax_amount"`
+}
+
+func (r TaxRecapRow) ToTaxRecapEntity() *entity.TaxRecap {
+	return &entity.TaxRecap{
+		TotalTransactions:   r.TotalTransactions,
+		SubtotalAmount:      r.SubtotalAmount,
+		DiscountAmount:      r.DiscountAmount,
+		ServiceChargeAmount: r.ServiceChargeAmount,
+		TaxBaseAmount:       r.TaxBaseAmount,
+		TaxAmount:           r.TaxAmount,
+		GrandTotalAmount:    r.GrandTotalAmount,
+		RefundAmount:        r.RefundAmount,
+		RefundedTaxAmount:   r.RefundedTaxAmount,
+	}
+}
+
+type CartGoodsQuantityRow struct {
+	GoodsID    int `db:"id_goods"`
+	VariantID  int `db:"id_variant"`
+	TotalGoods int `db:"total_goods"`
 }
 
 type TransactionRow struct {
-	ID          int64   `db:"id"`
-	UserID      int     `db:"id_user"`
-	TotalAmount float64 `db:"total_amount"`
-	Status      int     `db:"status"`
-	GoodsID     int     `db:"id_goods"`
-	GoodsPrice  float64 `db:"price"`
-	TotalGoods  int     `db:"total_goods"`
-	CreatedAt   int64   `db:"created_at"`
+	ID               int64        `db:"id"`
+	UserID           int          `db:"id_user"`
+	TotalAmount      entity.Money `db:"total_amount"`
+	Status           int          `db:"status"`
+	VoucherCode      string       `db:"voucher_code"`
+	GoodsID          int          `db:"id_goods"`
+	VariantID        int          `db:"id_variant"`
+	GoodsPrice       entity.Money `db:"price"`
+	PriceIncludesTax bool         `db:"price_includes_tax"`
+	TotalGoods       int          `db:"total_goods"`
+	CreatedAt        int64        `db:"created_at"`
 }
 
 type TransactionRowCollection []TransactionRow
@@ -48,16 +451,185 @@ func (r TransactionRowCollection) ToShoppingCartEntity() *entity.ShoppingCart {
 		ID:          r[0].ID,
 		UserID:      r[0].UserID,
 		TotalAmount: r[0].TotalAmount,
+		VoucherCode: r[0].VoucherCode,
 	}
 
 	for _, trxRow := range r {
+		// empty shopping cart has no goods
+		if trxRow.GoodsID == 0 {
+			continue
+		}
 		cart.Details = append(cart.Details, entity.ShoppingCartDetail{
-			GoodsID:    trxRow.GoodsID,
-			GoodsPrice: trxRow.GoodsPrice,
-			TotalGoods: trxRow.TotalGoods,
-			CreatedAt:  trxRow.CreatedAt,
+			GoodsID:          trxRow.GoodsID,
+			VariantID:        trxRow.VariantID,
+			GoodsPrice:       trxRow.GoodsPrice,
+			TotalGoods:       trxRow.TotalGoods,
+			CreatedAt:        trxRow.CreatedAt,
+			PriceIncludesTax: trxRow.PriceIncludesTax,
 		})
 	}
 
 	return cart
 }
+
+type PaymentRow struct {
+	Reference     string       `db:"reference"`
+	TransactionID int64        `db:"id_transaction"`
+	Method        string       `db:"method"`
+	Amount        entity.Money `db:"amount"`
+	Status        string       `db:"status"`
+	QRPayload     string       `db:"qr_payload"`
+	VoucherCode   string       `db:"voucher_code"`
+	CreatedAt     int64        `db:"created_at"`
+	ExpiresAt     int64        `db:"expires_at"`
+	PaidAt        int64        `db:"paid_at"`
+}
+
+func (r PaymentRow) ToPaymentEntity() *entity.Payment {
+	return &entity.Payment{
+		Reference:     r.Reference,
+		TransactionID: r.TransactionID,
+		Method:        entity.PaymentMethod(r.Method),
+		Amount:        r.Amount,
+		Status:        entity.PaymentStatus(r.Status),
+		QRPayload:     r.QRPayload,
+		VoucherCode:   r.VoucherCode,
+		CreatedAt:     r.CreatedAt,
+		ExpiresAt:     r.ExpiresAt,
+		PaidAt:        r.PaidAt,
+	}
+}
+
+type PromotionRow struct {
+	ID             int          `db:"id"`
+	Name           string       `db:"name"`
+	Type           string       `db:"type"`
+	GoodsID        int          `db:"id_goods"`
+	Value          float64      `db:"value"`
+	Amount         entity.Money `db:"amount"`
+	BuyQuantity    int          `db:"buy_quantity"`
+	FreeQuantity   int          `db:"free_quantity"`
+	MinPurchase    entity.Money `db:"min_purchase"`
+	HappyHourStart int          `db:"happy_hour_start"`
+	HappyHourEnd   int          `db:"happy_hour_end"`
+	StartsAt       int64        `db:"starts_at"`
+	EndsAt         int64        `db:"ends_at"`
+	VoucherCode    string       `db:"voucher_code"`
+	UsageLimit     int          `db:"usage_limit"`
+	UsedCount      int          `db:"used_count"`
+	CreatedAt      int64        `db:"created_at"`
+}
+
+func (r PromotionRow) ToPromotionEntity() entity.Promotion {
+	return entity.Promotion{
+		ID:             r.ID,
+		Name:           r.Name,
+		Type:           entity.PromotionType(r.Type),
+		GoodsID:        r.GoodsID,
+		Value:          r.Value,
+		Amount:         r.Amount,
+		BuyQuantity:    r.BuyQuantity,
+		FreeQuantity:   r.FreeQuantity,
+		MinPurchase:    r.MinPurchase,
+		HappyHourStart: r.HappyHourStart,
+		HappyHourEnd:   r.HappyHourEnd,
+		StartsAt:       r.StartsAt,
+		EndsAt:         r.EndsAt,
+		VoucherCode:    r.VoucherCode,
+		UsageLimit:     r.UsageLimit,
+		UsedCount:      r.UsedCount,
+		CreatedAt:      r.CreatedAt,
+	}
+}
+
+type SupplierRow struct {
+	ID        int    `db:"id"`
+	Name      string `db:"name"`
+	Phone     string `db:"phone"`
+	Address   string `db:"address"`
+	CreatedAt int64  `db:"created_at"`
+}
+
+func (r SupplierRow) ToSupplierEntity() entity.Supplier {
+	return entity.Supplier(r)
+}
+
+type PurchaseOrderRow struct {
+	ID         int64  `db:"id"`
+	SupplierID int    `db:"id_supplier"`
+	Status     string `db:"status"`
+	Note       string `db:"note"`
+	CreatedBy  string `db:"created_by"`
+	CreatedAt  int64  `db:"created_at"`
+	UpdatedAt  int64  `db:"updated_at"`
+}
+
+func (r PurchaseOrderRow) ToPurchaseOrderEntity() entity.PurchaseOrder {
+	return entity.PurchaseOrder{
+		ID:         r.ID,
+		SupplierID: r.SupplierID,
+		Status:     entity.PurchaseOrderStatus(r.Status),
+		Note:       r.Note,
+		CreatedBy:  r.CreatedBy,
+		Items:      []entity.PurchaseOrderItem{},
+		Receipts:   []entity.PurchaseOrderReceipt{},
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+	}
+}
+
+type PurchaseOrderItemRow struct {
+	OrderID          int64   `db:"id_purchase_order"`
+	GoodsID          int     `db:"id_goods"`
+	VariantID        int     `db:"id_variant"`
+	Name             string  `db:"name"`
+	Quantity         int     `db:"quantity"`
+	ReceivedQuantity int     `db:"received_quantity"`
+	UnitCost         float64 `db:"unit_cost"`
+}
+
+func (r PurchaseOrderItemRow) ToPurchaseOrderItemEntity() entity.PurchaseOrderItem {
+	return entity.PurchaseOrderItem{
+		GoodsID:          r.GoodsID,
+		VariantID:        r.VariantID,
+		Name:             r.Name,
+		Quantity:         r.Quantity,
+		ReceivedQuantity: r.ReceivedQuantity,
+		UnitCost:         r.UnitCost,
+	}
+}
+
+type PurchaseOrderReceiptRow struct {
+	ID         int64  `db:"id"`
+	OrderID    int64  `db:"id_purchase_order"`
+	ReceivedBy string `db:"received_by"`
+	ReceivedAt int64  `db:"received_at"`
+}
+
+func (r PurchaseOrderReceiptRow) ToPurchaseOrderReceiptEntity() entity.PurchaseOrderReceipt {
+	return entity.PurchaseOrderReceipt{
+		ID:         r.ID,
+		OrderID:    r.OrderID,
+		ReceivedBy: r.ReceivedBy,
+		ReceivedAt: r.ReceivedAt,
+	}
+}
+
+type PurchaseOrderReceiptItemRow struct {
+	ReceiptID int64         `db:"id_receipt"`
+	GoodsID   int           `db:"id_goods"`
+	VariantID int           `db:"id_variant"`
+	Quantity  int           `db:"quantity"`
+	UnitCost  float64       `db:"unit_cost"`
+	ExpiresAt sql.NullInt64 `db:"expires_at"`
+}
+
+func (r PurchaseOrderReceiptItemRow) ToPurchaseOrderReceiptItemEntity() entity.PurchaseOrderReceiptItem {
+	return entity.PurchaseOrderReceiptItem{
+		GoodsID:   r.GoodsID,
+		VariantID: r.VariantID,
+		Quantity:  r.Quantity,
+		UnitCost:  r.UnitCost,
+		ExpiresAt: r.ExpiresAt.Int64,
+	}
+}
@@ -2,10 +2,15 @@ package storagemysql
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
 	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/service"
 	"github.com/jmoiron/sqlx"
@@ -30,69 +35,348 @@ func NewStorage(config StorageConfig) (*storage, error) {
 	}, nil
 }
 
+// goodsColumns is the columns of goods table which mapped into GoodsRow
+const goodsColumns = "id, name, stocks, reserved_stocks, price, id_category, is_raw_material, unit, reorder_point, price_includes_tax, average_cost, deleted_at"
+
+// goodsVariantColumns is the columns of goods_variants table which mapped into GoodsVariantRow
+const goodsVariantColumns = "id, id_goods, name, price_delta, stocks, reserved_stocks, average_cost"
+
+// goodsSortColumns whitelist the columns which list of goods can be ordered by, the column name
+// can't be bound as query parameter so it must never come from the user input directly
+var goodsSortColumns = map[service.GoodsSortField]string{
+	service.GoodsSortByID:     "id",
+	service.GoodsSortByName:   "name",
+	service.GoodsSortByPrice:  "price",
+	service.GoodsSortByStocks: "stocks",
+}
+
+// likeEscaper escape the wildcard characters of LIKE pattern, so they're matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// goodsFilterConditions construct the WHERE conditions of list of goods with its bound arguments
+func goodsFilterConditions(input service.GetGoodsInput) ([]string, []interface{}) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	if len(input.Name) > 0 {
+		conditions = append(conditions, "name LIKE ?")
+		args = append(args, "%"+likeEscaper.Replace(input.Name)+"%")
+	}
+	if input.MinPrice > 0 {
+		conditions = append(conditions, "price >= ?")
+		args = append(args, input.MinPrice)
+	}
+	if input.MaxPrice > 0 {
+		conditions = append(conditions, "price <= ?")
+		args = append(args, input.MaxPrice)
+	}
+	if input.InStockOnly {
+		conditions = append(conditions, "stocks - reserved_stocks > 0")
+	}
+	return conditions, args
+}
+
 func (s *storage) GetGoods(ctx context.Context, input service.GetGoodsInput) ([]entity.Goods, error) {
-	query := fmt.Sprintf(`
+	sortColumn, ok := goodsSortColumns[input.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: unable to sort goods by %s", service.ErrInvalidInput, input.SortBy)
+	}
+	sortDirection := "DESC"
+	if input.Sort == service.SortAsc {
+		sortDirection = "ASC"
+	}
+
+	conditions, args := goodsFilterConditions(input)
+	offset := input.Offset
+	// the goods before the cursor is seek in the reversed order, then put back into the requested order
+	seekDirection := sortDirection
+	cursor := input.After
+	if input.Before != nil {
+		cursor = input.Before
+		seekDirection = "ASC"
+		if sortDirection == "ASC" {
+			seekDirection = "DESC"
+		}
+	}
+	if cursor != nil {
+		// seek right after the cursor, the comparison is expanded from (column, id) > (?, ?) since MySQL
+		// doesn't use the (column, id) index for row comparison
+		comparator := "<"
+		if seekDirection == "ASC" {
+			comparator = ">"
+		}
+		if sortColumn == "id" {
+			conditions = append(conditions, "id "+comparator+" ?")
+			args = append(args, cursor.ID)
+		} else {
+			conditions = append(
+				conditions,
+				"("+sortColumn+" "+comparator+" ? OR ("+sortColumn+" = ? AND id "+comparator+" ?))",
+			)
+			args = append(args, cursor.SortValue(), cursor.SortValue(), cursor.ID)
+		}
+		offset = 0
+	}
+	args = append(args, input.Limit, offset)
+
+	// id is used as tie breaker so goods with the same value keep the same order across pages
+	orderBy := sortColumn + " " + seekDirection
+	if sortColumn != "id" {
+		orderBy += ", id " + seekDirection
+	}
+	query := `
 			SELECT 
-				* 
+				` + goodsColumns + ` 
 			FROM goods
-			ORDER BY '%s' %s
-			LIMIT %d OFFSET %d
-		`,
-		input.SortBy,
-		input.Sort,
-		input.Limit,
-		input.Offset,
-	)
+			WHERE ` + strings.Join(conditions, " AND ") + `
+			ORDER BY ` + orderBy + `
+			LIMIT ? OFFSET ?
+		`
 
 	var goodsCollection GoodsRowCollection
 	err := s.client.SelectContext(
 		ctx,
 		&goodsCollection,
 		query,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for goods due: %w", err)
+	}
+
+	goods := goodsCollection.ToGoodsEntityCollection()
+	if input.Before != nil {
+		for i, j := 0, len(goods)-1; i < j; i, j = i+1, j-1 {
+			goods[i], goods[j] = goods[j], goods[i]
+		}
+	}
+	if err = s.attachRecipes(ctx, s.client, goods); err != nil {
+		return nil, err
+	}
+
+	return goods, nil
+}
+
+func (s *storage) CountGoods(ctx context.Context, input service.GetGoodsInput) (int, error) {
+	conditions, args := goodsFilterConditions(input)
+
+	var total int
+	err := s.client.GetContext(
+		ctx,
+		&total,
+		"SELECT COUNT(*) FROM goods WHERE "+strings.Join(conditions, " AND "),
+		args...,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("unable to execute count query for goods due: %w", err)
+	}
+
+	return total, nil
+}
+
+func (s *storage) GetGoodsByID(ctx context.Context, goodsID int) (*entity.Goods, error) {
+	var goodsRow GoodsRow
+	err := s.client.GetContext(
+		ctx,
+		&goodsRow,
+		"SELECT "+goodsColumns+" FROM goods WHERE id = ?",
+		goodsID,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrGoodsNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("unable to execute select query for goods due: %w", err)
 	}
 
-	return goodsCollection.ToGoodsEntityCollection(), nil
+	goods := goodsRow.ToGoodsEntity()
+	variants, err := s.getGoodsVariants(ctx, s.client, []int{goods.ID})
+	if err != nil {
+		return nil, err
+	}
+	goods.Variants = variants[goods.ID]
+	recipes, err := s.getRecipes(ctx, s.client, []int{goods.ID})
+	if err != nil {
+		return nil, err
+	}
+	goods.Recipe = recipes[goods.ID]
+
+	return &goods, nil
+}
+
+// GetMenuGoods get all goods which still sold along with their variants and recipes, raw materials is excluded
+func (s *storage) GetMenuGoods(ctx context.Context) ([]entity.Goods, error) {
+	var goodsCollection GoodsRowCollection
+	err := s.client.SelectContext(
+		ctx,
+		&goodsCollection,
+		"SELECT "+goodsColumns+" FROM goods WHERE deleted_at IS NULL AND is_raw_material = 0 ORDER BY name, id",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for menu goods due: %w", err)
+	}
+
+	goods := []entity.Goods{}
+	goodsIDs := []int{}
+	for _, goodsRow := range goodsCollection {
+		goods = append(goods, goodsRow.ToGoodsEntity())
+		goodsIDs = append(goodsIDs, goodsRow.ID)
+	}
+	variants, err := s.getGoodsVariants(ctx, s.client, goodsIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range goods {
+		goods[i].Variants = variants[goods[i].ID]
+	}
+	if err = s.attachRecipes(ctx, s.client, goods); err != nil {
+		return nil, err
+	}
+
+	return goods, nil
+}
+
+// getGoodsVariants get the variants of the goods grouped by the goods ID
+func (s storage) getGoodsVariants(ctx context.Context, queryer sqlx.QueryerContext, goodsIDs []int) (map[int][]entity.GoodsVariant, error) {
+	variants := map[int][]entity.GoodsVariant{}
+	if len(goodsIDs) == 0 {
+		return variants, nil
+	}
+	query, args, err := sqlx.In(
+		"SELECT "+goodsVariantColumns+" FROM goods_variants WHERE id_goods IN (?) ORDER BY id_goods, id",
+		goodsIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to construct select query for goods variants due: %w", err)
+	}
+
+	var variantRows []GoodsVariantRow
+	if err = sqlx.SelectContext(ctx, queryer, &variantRows, s.client.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("unable to execute select query for goods variants due: %w", err)
+	}
+	for _, variantRow := range variantRows {
+		variants[variantRow.GoodsID] = append(variants[variantRow.GoodsID], variantRow.ToGoodsVariantEntity())
+	}
+	return variants, nil
+}
+
+// getRecipes get the recipes of the goods made to order along with the available stocks of their raw materials,
+// goods which hold its own stocks has no recipe in the result
+func (s storage) getRecipes(ctx context.Context, queryer sqlx.QueryerContext, goodsIDs []int) (map[int]*entity.Recipe, error) {
+	if len(goodsIDs) == 0 {
+		return map[int]*entity.Recipe{}, nil
+	}
+	query, args, err := sqlx.In(
+		`SELECT
+			r.id_goods,
+			r.id_ingredient,
+			g.name,
+			g.unit,
+			r.quantity,
+			g.stocks - g.reserved_stocks AS available_stocks
+		FROM recipes r
+		JOIN goods g ON g.id = r.id_ingredient
+		WHERE r.id_goods IN (?)
+		ORDER BY r.id_goods, r.id_ingredient`,
+		goodsIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to construct select query for recipes due: %w", err)
+	}
+
+	var ingredientRows RecipeIngredientRowCollection
+	if err = sqlx.SelectContext(ctx, queryer, &ingredientRows, s.client.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("unable to execute select query for recipes due: %w", err)
+	}
+	return ingredientRows.ToRecipeEntities(), nil
+}
+
+func (s storage) attachRecipes(ctx context.Context, queryer sqlx.QueryerContext, goods []entity.Goods) error {
+	goodsIDs := []int{}
+	for _, g := range goods {
+		goodsIDs = append(goodsIDs, g.ID)
+	}
+	recipes, err := s.getRecipes(ctx, queryer, goodsIDs)
+	if err != nil {
+		return err
+	}
+	for i := range goods {
+		goods[i].Recipe = recipes[goods[i].ID]
+	}
+	return nil
+}
+
+// getMadeToOrderGoods tell which of the goods is made from its recipe, so it doesn't hold its own stocks
+func (s storage) getMadeToOrderGoods(ctx context.Context, queryer sqlx.QueryerContext, goodsIDs []int) (map[int]bool, error) {
+	madeToOrder := map[int]bool{}
+	if len(goodsIDs) == 0 {
+		return madeToOrder, nil
+	}
+	query, args, err := sqlx.In("SELECT DISTINCT id_goods FROM recipes WHERE id_goods IN (?)", goodsIDs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to construct select query for made to order goods due: %w", err)
+	}
+
+	var madeToOrderIDs []int
+	if err = sqlx.SelectContext(ctx, queryer, &madeToOrderIDs, s.client.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("unable to execute select query for made to order goods due: %w", err)
+	}
+	for _, goodsID := range madeToOrderIDs {
+		madeToOrder[goodsID] = true
+	}
+	return madeToOrder, nil
 }
 
+// GetExistingShoppingCart return nil when there's no unpaid shopping cart with the given ID
 func (s *storage) GetExistingShoppingCart(ctx context.Context, shoppingCartID int64) (*entity.ShoppingCart, error) {
+	return s.getExistingShoppingCart(ctx, s.client, shoppingCartID)
+}
+
+func (s storage) getExistingShoppingCart(ctx context.Context, queryer sqlx.QueryerContext, shoppingCartID int64) (*entity.ShoppingCart, error) {
+	// shopping cart could be empty, so the details are optional
 	query := `
 		SELECT 
-			trx.*,
-			trx_details.id_goods,
-			trx_details.total_goods,
-			trx_details.created_at,
-			g.price
+			trx.id,
+			trx.id_user,
+			trx.total_amount,
+			trx.status,
+			COALESCE(trx.voucher_code, '') AS voucher_code,
+			COALESCE(trx_details.id_goods, 0) AS id_goods,
+			COALESCE(trx_details.id_variant, 0) AS id_variant,
+			COALESCE(trx_details.total_goods, 0) AS total_goods,
+			COALESCE(trx_details.created_at, 0) AS created_at,
+			COALESCE(trx_details.price, 0) AS price,
+			COALESCE(trx_details.price_includes_tax, 0) AS price_includes_tax
 		FROM transactions trx 
-		JOIN transaction_details trx_details
+		LEFT JOIN transaction_details trx_details
 			ON trx.id = trx_details.id_transaction
-		JOIN goods g
-			ON trx_details.id_goods = g.id
-		WHERE trx.id = ? AND trx.status = 0
+		WHERE trx.id = ? AND trx.status = ?
+		ORDER BY trx_details.created_at, trx_details.id_goods, trx_details.id_variant
 	`
 
 	var existingCart TransactionRowCollection
-	err := s.client.SelectContext(
+	err := sqlx.SelectContext(
 		ctx,
+		queryer,
 		&existingCart,
 		query,
 		shoppingCartID,
+		entity.TransactionStatusCart,
 	)
-	if len(existingCart) == 0 {
-		return nil, nil
-	}
 	if err != nil {
 		return nil, fmt.Errorf("unable to execute select query for get existing cart due: %w", err)
 	}
+	if len(existingCart) == 0 {
+		return nil, nil
+	}
 	log.Printf("[DEBUG] EXISTING CART: %+v", existingCart)
 
 	return existingCart.ToShoppingCartEntity(), nil
 }
 
+// AddGoodToCart create new shopping cart or add goods into existing one and reserve the stocks of the goods.
+// For existing shopping cart, the details only contain the newly added goods.
 func (s *storage) AddGoodToCart(ctx context.Context, shoppingCart *entity.ShoppingCart) (*entity.ShoppingCart, error) {
-	dbTx, err := s.client.BeginTx(ctx, nil)
+	dbTx, err := s.client.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("unable to begin transaction for add goods to cart query: %w", err)
 	}
@@ -102,6 +386,7 @@ func (s *storage) AddGoodToCart(ctx context.Context, shoppingCart *entity.Shoppi
 	defer dbTx.Rollback()
 
 	simpleCart := &entity.ShoppingCart{
+		ID:     shoppingCart.ID,
 		UserID: shoppingCart.UserID,
 	}
 
@@ -110,55 +395,115 @@ func (s *storage) AddGoodToCart(ctx context.Context, shoppingCart *entity.Shoppi
 		// new cart, then insert into transactions table
 		queryTrx := `
 			INSERT INTO transactions 
-				(id_user, total_amount, status) 
+				(id_user, total_amount, voucher_code, status, created_at, updated_at) 
 			VALUES
-				(?, ?, ?)
+				(?, ?, NULLIF(?, ''), ?, ?, ?)
 		`
-		// for this query, it should be execute immediately
-		_, err := s.client.ExecContext(ctx, queryTrx, shoppingCart.UserID, shoppingCart.GetTotalAmount(), 0)
+		now := time.Now().Unix()
+		result, err := dbTx.ExecContext(
+			ctx,
+			queryTrx,
+			shoppingCart.UserID,
+			0,
+			shoppingCart.VoucherCode,
+			entity.TransactionStatusCart,
+			now,
+			now,
+		)
 		if err != nil {
 			return nil, fmt.Errorf("unable to create new shopping cart in database due: %w", err)
 		}
 
 		// get new transaction / shopping cart ID
-		queryTrx = "SELECT id FROM transactions WHERE id_user = ? AND status = 0 LIMIT 1"
-		cartRow := dbTx.QueryRowContext(ctx, queryTrx, shoppingCart.UserID)
-		var newShoppingCartID int64
-		if err = cartRow.Scan(&newShoppingCartID); err != nil {
+		simpleCart.ID, err = result.LastInsertId()
+		if err != nil {
 			return nil, fmt.Errorf("unable to get new shopping cart ID from database due: %w", err)
 		}
-
-		// construct query for insert into transaction details table
-		completeQueryTrxDetails := s.constructTransactionDetailsQuery(newShoppingCartID, shoppingCart.Details)
-
-		// insert into transaction details table
-		_, err = dbTx.ExecContext(ctx, completeQueryTrxDetails)
+		err = s.insertTransactionStatusChange(ctx, dbTx, simpleCart.ID, nil, entity.TransactionStatusCart, now)
 		if err != nil {
-			return nil, fmt.Errorf("unable to insert goods into shopping cart details into database due: %w", err)
+			return nil, err
 		}
 	default:
-		// construct query for insert into transaction details table
-		completeQueryTrxDetails := s.constructTransactionDetailsQuery(shoppingCart.ID, shoppingCart.Details)
+		// lock the existing cart so it can't be paid while new goods is being added
+		if err = s.lockEditableShoppingCart(ctx, dbTx, shoppingCart.ID); err != nil {
+			return nil, err
+		}
+		// voucher is only sent when the buyer use new one
+		if len(shoppingCart.VoucherCode) > 0 {
+			_, err = dbTx.ExecContext(
+				ctx,
+				"UPDATE transactions SET voucher_code = ? WHERE id = ?",
+				shoppingCart.VoucherCode,
+				shoppingCart.ID,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("unable to update voucher of shopping cart due: %w", err)
+			}
+		}
+	}
 
-		// insert into transaction details first
-		_, err = dbTx.ExecContext(ctx, completeQueryTrxDetails)
+	// reserve the stocks of added goods, lock the goods in the same order to avoid deadlock
+	details := make([]entity.ShoppingCartDetail, len(shoppingCart.Details))
+	copy(details, shoppingCart.Details)
+	sort.Slice(details, func(i, j int) bool {
+		if details[i].GoodsID != details[j].GoodsID {
+			return details[i].GoodsID < details[j].GoodsID
+		}
+		return details[i].VariantID < details[j].VariantID
+	})
+	goodsIDs := []int{}
+	for _, detail := range details {
+		goodsIDs = append(goodsIDs, detail.GoodsID)
+	}
+	recipes, err := s.getRecipes(ctx, dbTx, goodsIDs)
+	if err != nil {
+		return nil, err
+	}
+	rawMaterialNeeds := map[int]int{}
+	for _, detail := range details {
+		goods, err := s.lockGoods(ctx, dbTx, detail.GoodsID)
 		if err != nil {
-			return nil, fmt.Errorf("unable to insert goods into shopping cart details into database due: %w", err)
+			return nil, err
+		}
+		// the goods could be deleted after its price checked
+		if goods.IsDeleted() {
+			return nil, service.ErrGoodsNotFound
+		}
+		// goods made to order has no stocks to reserve, its raw materials is reserved instead
+		if recipe, ok := recipes[goods.ID]; ok {
+			for rawMaterialID, total := range recipe.Requirements(detail.TotalGoods) {
+				rawMaterialNeeds[rawMaterialID] += total
+			}
+			continue
+		}
+		var holder stockHolder = goods
+		if detail.VariantID > 0 {
+			if holder, err = s.lockGoodsVariant(ctx, dbTx, detail.GoodsID, detail.VariantID); err != nil {
+				return nil, err
+			}
 		}
+		if err = s.reserveSellableStock(ctx, dbTx, holder, detail.TotalGoods); err != nil {
+			return nil, err
+		}
+		if err = s.updateStockHolder(ctx, dbTx, holder); err != nil {
+			return nil, err
+		}
+	}
+	if err = s.reserveRawMaterials(ctx, dbTx, rawMaterialNeeds); err != nil {
+		return nil, err
+	}
 
-		// update total_amount for existing cart in transactions table
-		queryTrx := `
-			UPDATE transactions
-			SET total_amount = (
-				SELECT
-					SUM(td.total_goods * g.price) AS total_goods_price
-				FROM transaction_details td 
-				JOIN goods g ON td.id_goods = g.id
-			)
-			WHERE id = ?
-		`
-		// update transaction details table
-		dbTx.ExecContext(ctx, queryTrx, shoppingCart.ID)
+	// construct query for insert into transaction details table
+	queryTrxDetails, trxDetailsArgs := s.constructTransactionDetailsQuery(simpleCart.ID, shoppingCart.Details)
+
+	// insert into transaction details table
+	_, err = dbTx.ExecContext(ctx, queryTrxDetails, trxDetailsArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to insert goods into shopping cart details into database due: %w", err)
+	}
+
+	if err = s.updateCartTotalAmount(ctx, dbTx, simpleCart.ID); err != nil {
+		return nil, err
 	}
 
 	// commit changes
@@ -167,126 +512,3408 @@ func (s *storage) AddGoodToCart(ctx context.Context, shoppingCart *entity.Shoppi
 		return nil, fmt.Errorf("unable to commit add to cart operations in database due: %w", err)
 	}
 
-	// fill shopping cart output details
-	var latestCart []ShoppingCartRow
-	err = s.client.SelectContext(
+	// fill shopping cart output details
+	var latestCart ShoppingCartRow
+	err = s.client.GetContext(
 		ctx,
 		&latestCart,
-		"SELECT id, id_user, total_amount, status FROM transactions WHERE id_user = ? AND status = 0 LIMIT 1",
-		shoppingCart.UserID,
+		"SELECT id, id_user, total_amount, status FROM transactions WHERE id = ?",
+		simpleCart.ID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get latest cart details from database due: %w", err)
 	}
-	simpleCart.ID = latestCart[0].ID
-	simpleCart.TotalAmount = latestCart[0].TotalAmount
+	simpleCart.TotalAmount = latestCart.TotalAmount
 
 	log.Printf("[DEBUG] SIMPLE CART: %+v", simpleCart)
 
 	return simpleCart, nil
 }
 
-func (s storage) constructTransactionDetailsQuery(cartID int64, cartDetails []entity.ShoppingCartDetail) string {
-	// prefix query for transaction details
-	queryTrxDetails := `
-		INSERT INTO transaction_details
-			(id_transaction, id_goods, total_goods, created_at)
-		VALUES
-	`
+// SetCartGoodsQuantity set total of goods in the shopping cart and adjust the reserved stocks based on the difference
+func (s *storage) SetCartGoodsQuantity(ctx context.Context, input service.SetCartGoodsQuantityInput) (*entity.ShoppingCart, error) {
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for set cart goods quantity query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
 
-	trxDetailsValueQueries := []string{}
-	for _, goodsDetail := range cartDetails {
-		// add multiple values into transaction details query
-		vq := fmt.Sprintf(
-			`(%d, %d, %d, %d)`,
-			cartID,
-			goodsDetail.GoodsID,
-			goodsDetail.TotalGoods,
-			goodsDetail.CreatedAt,
+	if err = s.lockEditableShoppingCart(ctx, dbTx, input.CartID); err != nil {
+		return nil, err
+	}
+
+	var currTotalGoods int
+	err = dbTx.GetContext(
+		ctx,
+		&currTotalGoods,
+		"SELECT total_goods FROM transaction_details WHERE id_transaction = ? AND id_goods = ? AND id_variant = ? FOR UPDATE",
+		input.CartID,
+		input.GoodsID,
+		input.VariantID,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, entity.ErrGoodsNotInCart
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get goods in shopping cart due: %w", err)
+	}
+
+	recipes, err := s.getRecipes(ctx, dbTx, []int{input.GoodsID})
+	if err != nil {
+		return nil, err
+	}
+	if recipe, ok := recipes[input.GoodsID]; ok {
+		if err = s.reserveRawMaterials(ctx, dbTx, recipe.Requirements(input.Total-currTotalGoods)); err != nil {
+			return nil, err
+		}
+	} else {
+		holder, err := s.lockStockHolder(ctx, dbTx, input.GoodsID, input.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		switch diff := input.Total - currTotalGoods; {
+		case diff > 0:
+			if err = s.reserveSellableStock(ctx, dbTx, holder, diff); err != nil {
+				return nil, err
+			}
+		case diff < 0:
+			holder.ReleaseStock(-diff)
+		}
+		if err = s.updateStockHolder(ctx, dbTx, holder); err != nil {
+			return nil, err
+		}
+	}
+
+	if input.Total > 0 {
+		_, err = dbTx.ExecContext(
+			ctx,
+			"UPDATE transaction_details SET total_goods = ? WHERE id_transaction = ? AND id_goods = ? AND id_variant = ?",
+			input.Total,
+			input.CartID,
+			input.GoodsID,
+			input.VariantID,
+		)
+	} else {
+		_, err = dbTx.ExecContext(
+			ctx,
+			"DELETE FROM transaction_details WHERE id_transaction = ? AND id_goods = ? AND id_variant = ?",
+			input.CartID,
+			input.GoodsID,
+			input.VariantID,
 		)
-		trxDetailsValueQueries = append(trxDetailsValueQueries, vq)
 	}
-	completeQueryTrxDetails := []string{
-		queryTrxDetails,
-		strings.Join(trxDetailsValueQueries, ","),
+	if err != nil {
+		return nil, fmt.Errorf("unable to update goods in shopping cart details due: %w", err)
+	}
+
+	if err = s.updateCartTotalAmount(ctx, dbTx, input.CartID); err != nil {
+		return nil, err
+	}
+
+	cart, err := s.getExistingShoppingCart(ctx, dbTx, input.CartID)
+	if err != nil {
+		return nil, err
+	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit set cart goods quantity query in database due: %w", err)
 	}
 
-	return strings.Join(completeQueryTrxDetails, "\n")
+	return cart, nil
 }
 
-// CreateTransaction simply update transaction status from `0` to `1`
-func (s *storage) CreateTransaction(ctx context.Context, input service.CreateTransactionInput) (*entity.Transaction, error) {
-	dbTx, err := s.client.BeginTx(ctx, nil)
+// ClearShoppingCart remove all goods from unpaid shopping cart and release their reserved stocks
+func (s *storage) ClearShoppingCart(ctx context.Context, shoppingCartID int64) (*entity.ShoppingCart, error) {
+	dbTx, err := s.client.BeginTxx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("unable to begin transaction for add goods to cart query: %w", err)
+		return nil, fmt.Errorf("unable to begin transaction for clear shopping cart query: %w", err)
 	}
 	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
 	// making the deferred rollback call a no-op.
 	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
 	defer dbTx.Rollback()
 
-	// update transaction status
-	queryTrx := `
-		UPDATE 
-			transactions 
-		SET 
-			status = 1,
-			payment_amount = ?
-		WHERE id = ?`
-	_, err = dbTx.ExecContext(ctx, queryTrx, input.PaymentAmount, input.CartID)
-	if err != nil {
-		return nil, fmt.Errorf("unable to create new transactions into datbaase due: %w", err)
+	if err = s.lockEditableShoppingCart(ctx, dbTx, shoppingCartID); err != nil {
+		return nil, err
+	}
+	if err = s.releaseCartStocks(ctx, dbTx, shoppingCartID); err != nil {
+		return nil, err
 	}
 
-	// get the transaction record to returned it
-	var transactionRow struct {
-		ID          int64   `db:"id"`
-		TotalAmount float64 `db:"total_amount"`
-		Status      int     `db:"status"`
+	_, err = dbTx.ExecContext(ctx, "DELETE FROM transaction_details WHERE id_transaction = ?", shoppingCartID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to delete shopping cart details from database due: %w", err)
+	}
+	if err = s.updateCartTotalAmount(ctx, dbTx, shoppingCartID); err != nil {
+		return nil, err
 	}
-	queryTrx = `
-		SELECT
-			id,
-			total_amount,
-			status 
-		FROM transactions
-		WHERE id = ? AND status = 1
-		LIMIT 1
-	`
 
-	trxRow := dbTx.QueryRowContext(ctx, queryTrx, input.CartID)
-	err = trxRow.Scan(
-		&transactionRow.ID,
-		&transactionRow.TotalAmount,
-		&transactionRow.Status,
-	)
+	cart, err := s.getExistingShoppingCart(ctx, dbTx, shoppingCartID)
 	if err != nil {
-		return nil, fmt.Errorf("unable to get transaction details due: %w", err)
+		return nil, err
 	}
 
 	// commit changes
 	if err = dbTx.Commit(); err != nil {
-		return nil, fmt.Errorf("unable to commit creat transaction query in database due: %w", err)
+		return nil, fmt.Errorf("unable to commit clear shopping cart query in database due: %w", err)
 	}
 
-	if transactionRow.Status != 1 {
-		return nil, fmt.Errorf("unable to create transaction, shopping cart status not updated properly")
+	return cart, nil
+}
+
+// DeleteShoppingCart remove unpaid shopping cart and release all stocks reserved by it
+func (s *storage) DeleteShoppingCart(ctx context.Context, shoppingCartID int64) error {
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction for delete shopping cart query: %w", err)
 	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
 
-	return &entity.Transaction{
-		ID:          transactionRow.ID,
-		TotalAmount: transactionRow.TotalAmount,
-	}, nil
-}
+	if err = s.lockEditableShoppingCart(ctx, dbTx, shoppingCartID); err != nil {
+		return err
+	}
+	if err = s.releaseCartStocks(ctx, dbTx, shoppingCartID); err != nil {
+		return err
+	}
 
-func (s *storage) TruncateAllData(ctx context.Context) error {
-	_, err := s.client.ExecContext(ctx, "TRUNCATE transactions")
+	_, err = dbTx.ExecContext(ctx, "DELETE FROM transaction_details WHERE id_transaction = ?", shoppingCartID)
 	if err != nil {
-		return fmt.Errorf("unable to truncate shopping cart / transaction table due: %w", err)
+		return fmt.Errorf("unable to delete shopping cart details from database due: %w", err)
 	}
-	_, err = s.client.ExecContext(ctx, "TRUNCATE transaction_details")
+	_, err = dbTx.ExecContext(ctx, "DELETE FROM transaction_status_histories WHERE id_transaction = ?", shoppingCartID)
 	if err != nil {
-		return fmt.Errorf("unable to truncate shopping cart / transaction details table due: %w", err)
+		return fmt.Errorf("unable to delete shopping cart status history from database due: %w", err)
+	}
+	_, err = dbTx.ExecContext(ctx, "DELETE FROM transactions WHERE id = ?", shoppingCartID)
+	if err != nil {
+		return fmt.Errorf("unable to delete shopping cart from database due: %w", err)
 	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return fmt.Errorf("unable to commit delete shopping cart query in database due: %w", err)
+	}
+
 	return nil
 }
+
+// expireCartsLockName is name of the lock which make sure only one app instance sweep the shopping carts at a time
+const expireCartsLockName = "umkm_expire_shopping_carts"
+
+// ExpireShoppingCarts set status of unpaid shopping carts which not updated for a while into expired
+// and release their reserved stocks, shopping carts which still wait for their cashless payment are skipped. It's safe to be called by many app instances at the same time,
+// when another instance is sweeping then this one just skip it.
+func (s *storage) ExpireShoppingCarts(ctx context.Context, input service.ExpireShoppingCartsInput) (int, error) {
+	// named lock is bound to the database connection, so hold one connection until the sweep finished
+	conn, err := s.client.Connx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get database connection for expire shopping carts due: %w", err)
+	}
+	defer conn.Close()
+
+	var isLocked sql.NullInt64
+	if err = conn.GetContext(ctx, &isLocked, "SELECT GET_LOCK(?, 0)", expireCartsLockName); err != nil {
+		return 0, fmt.Errorf("unable to acquire expire shopping carts lock due: %w", err)
+	}
+	if isLocked.Int64 != 1 {
+		return 0, nil
+	}
+	defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", expireCartsLockName)
+
+	var cartIDs []int64
+	err = conn.SelectContext(
+		ctx,
+		&cartIDs,
+		"SELECT id FROM transactions WHERE status = ? AND updated_at < ? AND "+noAwaitedPaymentCondition+
+			" ORDER BY updated_at LIMIT ?",
+		entity.TransactionStatusCart,
+		input.LastActivityBefore,
+		entity.PaymentStatusPending,
+		time.Now().Unix(),
+		input.Limit,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get abandoned shopping carts due: %w", err)
+	}
+
+	var totalExpired int
+	for _, cartID := range cartIDs {
+		isExpired, err := s.expireShoppingCart(ctx, conn, cartID, input.LastActivityBefore)
+		if err != nil {
+			return totalExpired, err
+		}
+		if isExpired {
+			totalExpired++
+		}
+	}
+
+	return totalExpired, nil
+}
+
+// expireShoppingCart expire single shopping cart in its own database transaction, the shopping cart is skipped
+// when it's paid, updated or its payment requested after it's selected to be expired
+func (s storage) expireShoppingCart(ctx context.Context, conn *sqlx.Conn, shoppingCartID int64, lastActivityBefore int64) (bool, error) {
+	dbTx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("unable to begin transaction for expire shopping cart query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
+
+	var cartID int64
+	err = dbTx.GetContext(
+		ctx,
+		&cartID,
+		"SELECT id FROM transactions WHERE id = ? AND status = ? AND updated_at < ? AND "+noAwaitedPaymentCondition+
+			" FOR UPDATE",
+		shoppingCartID,
+		entity.TransactionStatusCart,
+		lastActivityBefore,
+		entity.PaymentStatusPending,
+		time.Now().Unix(),
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to lock shopping cart due: %w", err)
+	}
+
+	if err = s.releaseCartStocks(ctx, dbTx, shoppingCartID); err != nil {
+		return false, err
+	}
+	err = s.setTransactionStatus(ctx, dbTx, shoppingCartID, entity.TransactionStatusCart, entity.TransactionStatusExpired, time.Now().Unix())
+	if err != nil {
+		return false, err
+	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return false, fmt.Errorf("unable to commit expire shopping cart query in database due: %w", err)
+	}
+
+	return true, nil
+}
+
+// releaseCartStocks release all stocks reserved by the shopping cart
+func (s storage) releaseCartStocks(ctx context.Context, dbTx *sqlx.Tx, shoppingCartID int64) error {
+	cartGoods, err := s.getCartGoodsQuantity(ctx, dbTx, shoppingCartID)
+	if err != nil {
+		return err
+	}
+	recipes, err := s.getRecipes(ctx, dbTx, cartGoodsIDs(cartGoods))
+	if err != nil {
+		return err
+	}
+	rawMaterialNeeds := map[int]int{}
+	for _, cartGoodsRow := range cartGoods {
+		if recipe, ok := recipes[cartGoodsRow.GoodsID]; ok {
+			for rawMaterialID, total := range recipe.Requirements(-cartGoodsRow.TotalGoods) {
+				rawMaterialNeeds[rawMaterialID] += total
+			}
+			continue
+		}
+		holder, err := s.lockStockHolder(ctx, dbTx, cartGoodsRow.GoodsID, cartGoodsRow.VariantID)
+		if err != nil {
+			return err
+		}
+		holder.ReleaseStock(cartGoodsRow.TotalGoods)
+		if err = s.updateStockHolder(ctx, dbTx, holder); err != nil {
+			return err
+		}
+	}
+	return s.reserveRawMaterials(ctx, dbTx, rawMaterialNeeds)
+}
+
+// reserveRawMaterials reserve the raw materials of the goods made to order, negative total release the reserved
+// raw materials instead. Raw materials is never sold directly so they're always locked after the sold goods,
+// in the same order to avoid deadlock.
+func (s storage) reserveRawMaterials(ctx context.Context, dbTx *sqlx.Tx, rawMaterialNeeds map[int]int) error {
+	for _, rawMaterialID := range sortedRawMaterialIDs(rawMaterialNeeds) {
+		total := rawMaterialNeeds[rawMaterialID]
+		if total == 0 {
+			continue
+		}
+		rawMaterial, err := s.lockGoods(ctx, dbTx, rawMaterialID)
+		if err != nil {
+			return err
+		}
+		if total > 0 {
+			if err = s.reserveSellableStock(ctx, dbTx, rawMaterial, total); err != nil {
+				return err
+			}
+		} else {
+			rawMaterial.ReleaseStock(-total)
+		}
+		if err = s.updateGoodsStocks(ctx, dbTx, rawMaterial); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedRawMaterialIDs(rawMaterialNeeds map[int]int) []int {
+	rawMaterialIDs := []int{}
+	for rawMaterialID := range rawMaterialNeeds {
+		rawMaterialIDs = append(rawMaterialIDs, rawMaterialID)
+	}
+	sort.Ints(rawMaterialIDs)
+	return rawMaterialIDs
+}
+
+// lockShoppingCart lock unpaid shopping cart row until the database transaction finished
+func (s storage) lockShoppingCart(ctx context.Context, dbTx *sqlx.Tx, shoppingCartID int64) error {
+	var cartID int64
+	err := dbTx.GetContext(
+		ctx,
+		&cartID,
+		"SELECT id FROM transactions WHERE id = ? AND status = ? FOR UPDATE",
+		shoppingCartID,
+		entity.TransactionStatusCart,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return service.ErrCartNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("unable to lock shopping cart due: %w", err)
+	}
+	return nil
+}
+
+// noAwaitedPaymentCondition filter out shopping carts which have pending payment not expired yet,
+// the arguments are the pending status and the current time
+const noAwaitedPaymentCondition = `NOT EXISTS (
+	SELECT 1 FROM payments WHERE payments.id_transaction = transactions.id AND payments.status = ? AND payments.expires_at > ?
+)`
+
+// lockEditableShoppingCart lock unpaid shopping cart row like lockShoppingCart, but return ErrPaymentPending
+// when the buyer can still pay its cashless payment, so the paid amount always match the shopping cart
+func (s storage) lockEditableShoppingCart(ctx context.Context, dbTx *sqlx.Tx, shoppingCartID int64) error {
+	if err := s.lockShoppingCart(ctx, dbTx, shoppingCartID); err != nil {
+		return err
+	}
+
+	var totalAwaited int
+	err := dbTx.GetContext(
+		ctx,
+		&totalAwaited,
+		"SELECT COUNT(*) FROM payments WHERE id_transaction = ? AND status = ? AND expires_at > ?",
+		shoppingCartID,
+		entity.PaymentStatusPending,
+		time.Now().Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to get pending payments of shopping cart due: %w", err)
+	}
+	if totalAwaited > 0 {
+		return service.ErrPaymentPending
+	}
+	return nil
+}
+
+// lockGoods get the goods and lock its row until the database transaction finished
+func (s storage) lockGoods(ctx context.Context, dbTx *sqlx.Tx, goodsID int) (*entity.Goods, error) {
+	var goodsRow GoodsRow
+	err := dbTx.GetContext(
+		ctx,
+		&goodsRow,
+		"SELECT "+goodsColumns+" FROM goods WHERE id = ? FOR UPDATE",
+		goodsID,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrGoodsNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock goods due: %w", err)
+	}
+
+	goods := goodsRow.ToGoodsEntity()
+	return &goods, nil
+}
+
+// lockGoodsVariant get the variant of the goods and lock its row until the database transaction finished
+func (s storage) lockGoodsVariant(ctx context.Context, dbTx *sqlx.Tx, goodsID int, variantID int) (*entity.GoodsVariant, error) {
+	var variantRow GoodsVariantRow
+	err := dbTx.GetContext(
+		ctx,
+		&variantRow,
+		"SELECT "+goodsVariantColumns+" FROM goods_variants WHERE id = ? AND id_goods = ? FOR UPDATE",
+		variantID,
+		goodsID,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrVariantNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock goods variant due: %w", err)
+	}
+
+	variant := variantRow.ToGoodsVariantEntity()
+	return &variant, nil
+}
+
+// lockGoodsVariants get all variants of the goods and lock their rows until the database transaction finished
+func (s storage) lockGoodsVariants(ctx context.Context, dbTx *sqlx.Tx, goodsID int) ([]entity.GoodsVariant, error) {
+	var variantRows []GoodsVariantRow
+	err := dbTx.SelectContext(
+		ctx,
+		&variantRows,
+		"SELECT "+goodsVariantColumns+" FROM goods_variants WHERE id_goods = ? ORDER BY id FOR UPDATE",
+		goodsID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock goods variants due: %w", err)
+	}
+
+	variants := []entity.GoodsVariant{}
+	for _, variantRow := range variantRows {
+		variants = append(variants, variantRow.ToGoodsVariantEntity())
+	}
+	return variants, nil
+}
+
+// stockHolder is the goods or its variant, whichever holds the stocks of the sold goods
+type stockHolder interface {
+	AvailableStocks() int
+	DecreaseStock(total int) error
+	IncreaseStock(total int)
+	ReceiveStock(total int, unitCost float64)
+	SetCountedStock(counted int)
+	ReserveStock(total int) error
+	ReleaseStock(total int)
+	DeductReservedStock(total int)
+}
+
+// lockStockHolder lock the variant when the goods sold through its variant, otherwise the goods itself
+func (s storage) lockStockHolder(ctx context.Context, dbTx *sqlx.Tx, goodsID int, variantID int) (stockHolder, error) {
+	if variantID > 0 {
+		return s.lockGoodsVariant(ctx, dbTx, goodsID, variantID)
+	}
+	return s.lockGoods(ctx, dbTx, goodsID)
+}
+
+// stockHolderState get the identity and the current stocks of the stock holder
+func stockHolderState(holder stockHolder) (goodsID int, variantID int, stocks int) {
+	switch h := holder.(type) {
+	case *entity.Goods:
+		return h.ID, 0, h.Stocks
+	case *entity.GoodsVariant:
+		return h.GoodsID, h.ID, h.Stocks
+	}
+	return 0, 0, 0
+}
+
+// stockHolderUnitCost get the current cost of one goods held by the stock holder
+func stockHolderUnitCost(holder stockHolder) float64 {
+	switch h := holder.(type) {
+	case *entity.Goods:
+		return h.UnitCost()
+	case *entity.GoodsVariant:
+		return h.AverageCost
+	}
+	return 0
+}
+
+// recordStockMovement append the stocks change of the stock holder into the stock ledger, the goods, quantity
+// and before/after stocks is taken from the stock holder. The unit cost is the current cost of the stock holder
+// unless it's given. Nothing recorded when the stocks is not changed.
+func (s storage) recordStockMovement(ctx context.Context, dbTx *sqlx.Tx, holder stockHolder, stocksBefore int, movement entity.StockMovement) error {
+	movement.GoodsID, movement.VariantID, movement.After = stockHolderState(holder)
+	movement.Before = stocksBefore
+	movement.Quantity = movement.After - movement.Before
+	if movement.Quantity == 0 {
+		return nil
+	}
+	if len(movement.Actor) == 0 {
+		movement.Actor = entity.StockActorSystem
+	}
+	if movement.UnitCost == 0 {
+		movement.UnitCost = stockHolderUnitCost(holder)
+	}
+	if movement.CreatedAt == 0 {
+		movement.CreatedAt = time.Now().Unix()
+	}
+
+	_, err := dbTx.ExecContext(
+		ctx,
+		`INSERT INTO stock_movements
+			(id_goods, id_variant, type, quantity, unit_cost, stocks_before, stocks_after, actor, reason, id_transaction, expires_at, created_at)
+		VALUES
+			(?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0), NULLIF(?, 0), ?)`,
+		movement.GoodsID,
+		movement.VariantID,
+		movement.Type,
+		movement.Quantity,
+		movement.UnitCost,
+		movement.Before,
+		movement.After,
+		movement.Actor,
+		movement.Reason,
+		movement.TransactionID,
+		movement.ExpiresAt,
+		movement.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to insert stock movement into database due: %w", err)
+	}
+
+	return s.moveStockBatches(ctx, dbTx, movement)
+}
+
+// moveStockBatches apply the stock movement into the stock batches, stocks in is received as new batch while
+// stocks out is taken from the batches in FEFO order. Sale never takes the expired batches.
+func (s storage) moveStockBatches(ctx context.Context, dbTx *sqlx.Tx, movement entity.StockMovement) error {
+	if movement.Quantity > 0 {
+		_, err := dbTx.ExecContext(
+			ctx,
+			`INSERT INTO stock_batches (id_goods, id_variant, received_quantity, quantity, unit_cost, received_at, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, 0))`,
+			movement.GoodsID,
+			movement.VariantID,
+			movement.Quantity,
+			movement.Quantity,
+			movement.UnitCost,
+			movement.CreatedAt,
+			movement.ExpiresAt,
+		)
+		if err != nil {
+			return fmt.Errorf("unable to insert stock batch into database due: %w", err)
+		}
+		return nil
+	}
+
+	// the stock holder is already locked, so the batches is locked only to be safe from direct changes
+	var batchRows StockBatchRowCollection
+	err := dbTx.SelectContext(
+		ctx,
+		&batchRows,
+		`SELECT id, id_goods, id_variant, '' AS name, '' AS unit, received_quantity, quantity, unit_cost, received_at, expires_at
+		FROM stock_batches
+		WHERE id_goods = ? AND id_variant = ? AND quantity > 0
+		FOR UPDATE`,
+		movement.GoodsID,
+		movement.VariantID,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to lock stock batches due: %w", err)
+	}
+	batches := batchRows.ToStockBatches()
+	var consumed []entity.StockBatch
+	if movement.Type == entity.StockMovementSale {
+		// the expired batches is never sold, they're left to be written off
+		consumed, err = entity.ConsumeSellableStockBatches(batches, movement.Before, -movement.Quantity, movement.CreatedAt)
+		if err != nil {
+			return err
+		}
+	} else {
+		consumed = entity.ConsumeStockBatches(batches, -movement.Quantity)
+	}
+	for _, batch := range consumed {
+		_, err = dbTx.ExecContext(ctx, "UPDATE stock_batches SET quantity = ? WHERE id = ?", batch.Quantity, batch.ID)
+		if err != nil {
+			return fmt.Errorf("unable to update stock batch quantity due: %w", err)
+		}
+	}
+	return nil
+}
+
+// reserveSellableStock reserve the stocks for shopping cart, the expired batches which not written off yet can't be
+// sold so they're not reservable either
+func (s storage) reserveSellableStock(ctx context.Context, dbTx *sqlx.Tx, holder stockHolder, total int) error {
+	goodsID, variantID, _ := stockHolderState(holder)
+	expired, err := s.getExpiredStocks(ctx, dbTx, goodsID, variantID, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	if sellable := holder.AvailableStocks() - expired; total > sellable {
+		if sellable < 0 {
+			sellable = 0
+		}
+		return entity.InsufficientStockError{
+			GoodsID:   goodsID,
+			VariantID: variantID,
+			Stocks:    sellable,
+			Requested: total,
+		}
+	}
+	return holder.ReserveStock(total)
+}
+
+// getExpiredStocks get total stocks of the batches which expired at the given time, the stock holder must be locked
+func (s storage) getExpiredStocks(ctx context.Context, dbTx *sqlx.Tx, goodsID int, variantID int, expiredAt int64) (int, error) {
+	var expired int
+	err := dbTx.GetContext(
+		ctx,
+		&expired,
+		`SELECT COALESCE(SUM(quantity), 0) FROM stock_batches
+		WHERE id_goods = ? AND id_variant = ? AND quantity > 0 AND expires_at IS NOT NULL AND expires_at <= ?`,
+		goodsID,
+		variantID,
+		expiredAt,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get expired stocks of goods %d due: %w", goodsID, err)
+	}
+	return expired, nil
+}
+
+func (s storage) updateStockHolder(ctx context.Context, dbTx *sqlx.Tx, holder stockHolder) error {
+	switch h := holder.(type) {
+	case *entity.Goods:
+		return s.updateGoodsStocks(ctx, dbTx, h)
+	case *entity.GoodsVariant:
+		return s.updateGoodsVariantStocks(ctx, dbTx, h)
+	}
+	return fmt.Errorf("unknown stock holder %T", holder)
+}
+
+func (s storage) updateGoodsVariantStocks(ctx context.Context, dbTx *sqlx.Tx, variant *entity.GoodsVariant) error {
+	_, err := dbTx.ExecContext(
+		ctx,
+		"UPDATE goods_variants SET stocks = ?, reserved_stocks = ?, average_cost = ? WHERE id = ?",
+		variant.Stocks,
+		variant.ReservedStocks,
+		variant.AverageCost,
+		variant.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to update goods variant stocks in database due: %w", err)
+	}
+	return nil
+}
+
+func (s storage) updateGoodsStocks(ctx context.Context, dbTx *sqlx.Tx, goods *entity.Goods) error {
+	_, err := dbTx.ExecContext(
+		ctx,
+		"UPDATE goods SET stocks = ?, reserved_stocks = ?, average_cost = ? WHERE id = ?",
+		goods.Stocks,
+		goods.ReservedStocks,
+		goods.AverageCost,
+		goods.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to update goods stocks in database due: %w", err)
+	}
+	return nil
+}
+
+// getCartGoodsQuantity get total of each goods variant in the shopping cart, ordered by goods ID and variant ID
+// so the goods rows always locked in the same order
+func (s storage) getCartGoodsQuantity(ctx context.Context, dbTx *sqlx.Tx, shoppingCartID int64) ([]CartGoodsQuantityRow, error) {
+	query := `
+		SELECT
+			id_goods,
+			id_variant,
+			SUM(total_goods) AS total_goods
+		FROM transaction_details
+		WHERE id_transaction = ?
+		GROUP BY id_goods, id_variant
+		ORDER BY id_goods, id_variant
+	`
+
+	var cartGoods []CartGoodsQuantityRow
+	if err := dbTx.SelectContext(ctx, &cartGoods, query, shoppingCartID); err != nil {
+		return nil, fmt.Errorf("unable to get goods quantity of shopping cart due: %w", err)
+	}
+	return cartGoods, nil
+}
+
+func cartGoodsIDs(cartGoods []CartGoodsQuantityRow) []int {
+	goodsIDs := []int{}
+	for _, cartGoodsRow := range cartGoods {
+		goodsIDs = append(goodsIDs, cartGoodsRow.GoodsID)
+	}
+	return goodsIDs
+}
+
+func (s storage) constructTransactionDetailsQuery(cartID int64, cartDetails []entity.ShoppingCartDetail) (string, []interface{}) {
+	// prefix query for transaction details
+	queryTrxDetails := `
+		INSERT INTO transaction_details
+			(id_transaction, id_goods, id_variant, total_goods, price, price_includes_tax, created_at)
+		VALUES
+	`
+
+	trxDetailsValueQueries := []string{}
+	trxDetailsArgs := []interface{}{}
+	for _, goodsDetail := range cartDetails {
+		// add multiple values into transaction details query
+		trxDetailsValueQueries = append(trxDetailsValueQueries, "(?, ?, ?, ?, ?, ?, ?)")
+		trxDetailsArgs = append(
+			trxDetailsArgs,
+			cartID,
+			goodsDetail.GoodsID,
+			goodsDetail.VariantID,
+			goodsDetail.TotalGoods,
+			goodsDetail.GoodsPrice,
+			goodsDetail.PriceIncludesTax,
+			goodsDetail.CreatedAt,
+		)
+	}
+	// the same goods variant in a shopping cart merged into one line
+	completeQueryTrxDetails := []string{
+		queryTrxDetails,
+		strings.Join(trxDetailsValueQueries, ","),
+		`ON DUPLICATE KEY UPDATE
+			total_goods = total_goods + VALUES(total_goods),
+			price = VALUES(price),
+			price_includes_tax = VALUES(price_includes_tax)`,
+	}
+
+	return strings.Join(completeQueryTrxDetails, "\n"), trxDetailsArgs
+}
+
+// updateCartTotalAmount recalculate total amount of shopping cart from the goods price snapshot in its details,
+// it also mark the last activity of the shopping cart so it won't be expired
+func (s storage) updateCartTotalAmount(ctx context.Context, dbTx *sqlx.Tx, shoppingCartID int64) error {
+	queryTrx := `
+		UPDATE transactions
+		SET 
+			total_amount = (
+				SELECT
+					COALESCE(SUM(td.total_goods * td.price), 0) AS total_goods_price
+				FROM transaction_details td
+				WHERE td.id_transaction = ?
+			),
+			updated_at = ?
+		WHERE id = ?
+	`
+	_, err := dbTx.ExecContext(ctx, queryTrx, shoppingCartID, time.Now().Unix(), shoppingCartID)
+	if err != nil {
+		return fmt.Errorf("unable to update total amount of shopping cart due: %w", err)
+	}
+	return nil
+}
+
+func (s *storage) GetTransaction(ctx context.Context, transactionID int64) (*entity.Transaction, error) {
+	trx, err := s.getTransaction(ctx, s.client, "trx.id = ?", transactionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrCartNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return trx, nil
+}
+
+// GetTransactionByIdempotencyKey return nil when there's no transaction paid with the idempotency key
+func (s *storage) GetTransactionByIdempotencyKey(ctx context.Context, idempotencyKey string) (*entity.Transaction, error) {
+	trx, err := s.getTransaction(ctx, s.client, "trx.idempotency_key = ?", idempotencyKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return trx, nil
+}
+
+func (s storage) getTransaction(ctx context.Context, queryer sqlx.QueryerContext, condition string, args ...interface{}) (*entity.Transaction, error) {
+	queryTrx := `
+		SELECT
+			trx.id,
+			trx.status,
+			trx.total_amount,
+			trx.subtotal_amount,
+			trx.discount_amount,
+			trx.service_charge_amount,
+			trx.tax_base_amount,
+			trx.tax_amount,
+			trx.tax_rate,
+			trx.service_charge_rate,
+			trx.payment_amount,
+			trx.refunded_amount,
+			trx.idempotency_key,
+			(
+				SELECT COALESCE(SUM(td.total_goods), 0)
+				FROM transaction_details td
+				WHERE td.id_transaction = trx.id
+			) AS total_goods
+		FROM transactions trx
+		WHERE ` + condition + `
+		LIMIT 1
+	`
+
+	var trxRow TransactionSummaryRow
+	err := sqlx.GetContext(ctx, queryer, &trxRow, queryTrx, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for transaction due: %w", err)
+	}
+	trx := trxRow.ToTransactionEntity()
+
+	// only paid transaction has discount lines
+	if trx.DiscountAmount > 0 {
+		var discountRows []TransactionDiscountRow
+		err = sqlx.SelectContext(
+			ctx,
+			queryer,
+			&discountRows,
+			"SELECT id_promotion, name, voucher_code, amount FROM transaction_discounts WHERE id_transaction = ? ORDER BY id",
+			trx.ID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to execute select query for transaction discounts due: %w", err)
+		}
+		for _, discountRow := range discountRows {
+			trx.Discounts = append(trx.Discounts, entity.Discount(discountRow))
+		}
+	}
+
+	// only paid transaction has tenders
+	if trx.PaymentAmount > 0 {
+		var tenderRows []TransactionTenderRow
+		err = sqlx.SelectContext(
+			ctx,
+			queryer,
+			&tenderRows,
+			"SELECT method, amount FROM transaction_tenders WHERE id_transaction = ? ORDER BY id",
+			trx.ID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to execute select query for transaction tenders due: %w", err)
+		}
+		for _, tenderRow := range tenderRows {
+			trx.Tenders = append(trx.Tenders, tenderRow.ToTenderEntity())
+		}
+	}
+
+	return trx, nil
+}
+
+// CreateTransaction update transaction status from cart to paid and turn the stocks reserved by the shopping cart
+// into permanent deduction
+func (s *storage) CreateTransaction(ctx context.Context, input service.CreateTransactionInput) (*entity.Transaction, error) {
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for add goods to cart query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
+
+	// lock the shopping cart, so concurrent payment for the same cart wait for this one
+	var currCart ShoppingCartRow
+	err = dbTx.GetContext(
+		ctx,
+		&currCart,
+		"SELECT id, id_user, total_amount, status FROM transactions WHERE id = ? FOR UPDATE",
+		input.CartID,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrCartNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock shopping cart due: %w", err)
+	}
+	switch entity.TransactionStatus(currCart.Status) {
+	case entity.TransactionStatusCart:
+	case entity.TransactionStatusExpired:
+		return nil, service.ErrCartExpired
+	default:
+		return nil, service.ErrCartAlreadyPaid
+	}
+	// the bill is computed from the goods in the cart, so it must not change in the meantime
+	bill := input.Bill
+	if bill.SubtotalAmount > 0 && currCart.TotalAmount != bill.SubtotalAmount {
+		return nil, service.ErrCartChanged
+	}
+
+	var discountAmount entity.Money
+	for _, discount := range input.Discounts {
+		discountAmount += discount.Amount
+		// voucher usage counted here, so concurrent payments can't use it beyond its limit
+		if len(discount.VoucherCode) > 0 {
+			if err = s.useVoucher(ctx, dbTx, discount.PromotionID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(input.Discounts) > 0 {
+		queryDiscounts := "INSERT INTO transaction_discounts (id_transaction, id_promotion, name, voucher_code, amount) VALUES "
+		var discountsArgs []interface{}
+		for i, discount := range input.Discounts {
+			if i > 0 {
+				queryDiscounts += ", "
+			}
+			queryDiscounts += "(?, ?, ?, ?, ?)"
+			discountsArgs = append(
+				discountsArgs,
+				input.CartID,
+				discount.PromotionID,
+				discount.Name,
+				discount.VoucherCode,
+				discount.Amount,
+			)
+		}
+		if _, err = dbTx.ExecContext(ctx, queryDiscounts, discountsArgs...); err != nil {
+			return nil, fmt.Errorf("unable to insert transaction discounts into database due: %w", err)
+		}
+	}
+
+	// without the bill, the cart is paid as is i.e. no service charge nor PPN
+	if bill.SubtotalAmount == 0 {
+		bill = entity.Bill{
+			SubtotalAmount:   currCart.TotalAmount,
+			DiscountAmount:   discountAmount,
+			TaxBaseAmount:    currCart.TotalAmount - discountAmount,
+			GrandTotalAmount: currCart.TotalAmount - discountAmount,
+		}
+	}
+	// the payment is checked while the cart is locked, so the total amount can't change after it's checked
+	paidTrx := entity.Transaction{TotalAmount: bill.GrandTotalAmount}
+	if err = paidTrx.SetTenders(input.GetTenders()); err != nil {
+		return nil, err
+	}
+
+	// record the payment then update transaction status, the total amount become the grand total of the bill
+	queryTrx := `
+		UPDATE 
+			transactions 
+		SET 
+			total_amount = ?,
+			subtotal_amount = ?,
+			discount_amount = ?,
+			service_charge_amount = ?,
+			tax_base_amount = ?,
+			tax_amount = ?,
+			tax_rate = ?,
+			service_charge_rate = ?,
+			voucher_code = NULLIF(?, ''),
+			payment_amount = ?,
+			idempotency_key = NULLIF(?, ''),
+			paid_at = ?
+		WHERE id = ?`
+	now := time.Now().Unix()
+	_, err = dbTx.ExecContext(
+		ctx,
+		queryTrx,
+		bill.GrandTotalAmount,
+		bill.SubtotalAmount,
+		discountAmount,
+		bill.ServiceChargeAmount,
+		bill.TaxBaseAmount,
+		bill.TaxAmount,
+		input.TaxRates.TaxRate,
+		input.TaxRates.ServiceChargeRate,
+		input.VoucherCode,
+		paidTrx.PaymentAmount,
+		input.IdempotencyKey,
+		now,
+		input.CartID,
+	)
+	if isDuplicateEntryError(err) {
+		return nil, service.ErrIdempotencyKeyReused
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new transactions into datbaase due: %w", err)
+	}
+	if err = s.insertTransactionTenders(ctx, dbTx, input); err != nil {
+		return nil, err
+	}
+	if len(input.PaymentReference) > 0 {
+		if err = s.setPaymentPaid(ctx, dbTx, input.PaymentReference, now); err != nil {
+			return nil, err
+		}
+	}
+	err = s.setTransactionStatus(ctx, dbTx, input.CartID, entity.TransactionStatusCart, entity.TransactionStatusPaid, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// deduct the reserved stocks
+	cartGoods, err := s.getCartGoodsQuantity(ctx, dbTx, input.CartID)
+	if err != nil {
+		return nil, err
+	}
+	recipes, err := s.getRecipes(ctx, dbTx, cartGoodsIDs(cartGoods))
+	if err != nil {
+		return nil, err
+	}
+	rawMaterialNeeds := map[int]int{}
+	for _, cartGoodsRow := range cartGoods {
+		if recipe, ok := recipes[cartGoodsRow.GoodsID]; ok {
+			for rawMaterialID, total := range recipe.Requirements(cartGoodsRow.TotalGoods) {
+				rawMaterialNeeds[rawMaterialID] += total
+			}
+			continue
+		}
+		holder, err := s.lockStockHolder(ctx, dbTx, cartGoodsRow.GoodsID, cartGoodsRow.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		_, _, stocksBefore := stockHolderState(holder)
+		holder.DeductReservedStock(cartGoodsRow.TotalGoods)
+		if err = s.updateStockHolder(ctx, dbTx, holder); err != nil {
+			return nil, err
+		}
+		err = s.recordStockMovement(ctx, dbTx, holder, stocksBefore, entity.StockMovement{
+			Type:          entity.StockMovementSale,
+			TransactionID: input.CartID,
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// deduct the reserved raw materials of the goods made to order, raw materials is never sold directly
+	// so they're always locked after the sold goods, in the same order to avoid deadlock
+	for _, rawMaterialID := range sortedRawMaterialIDs(rawMaterialNeeds) {
+		rawMaterial, err := s.lockGoods(ctx, dbTx, rawMaterialID)
+		if err != nil {
+			return nil, err
+		}
+		stocksBefore := rawMaterial.Stocks
+		rawMaterial.DeductReservedStock(rawMaterialNeeds[rawMaterialID])
+		if err = s.updateGoodsStocks(ctx, dbTx, rawMaterial); err != nil {
+			return nil, err
+		}
+		err = s.recordStockMovement(ctx, dbTx, rawMaterial, stocksBefore, entity.StockMovement{
+			Type:          entity.StockMovementSale,
+			Reason:        "raw material of made to order goods",
+			TransactionID: input.CartID,
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// get the transaction record to returned it
+	trx, err := s.getTransaction(ctx, dbTx, "trx.id = ?", input.CartID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get transaction details due: %w", err)
+	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit creat transaction query in database due: %w", err)
+	}
+
+	if trx.Status != entity.TransactionStatusPaid {
+		return nil, fmt.Errorf("unable to create transaction, shopping cart status not updated properly")
+	}
+
+	return trx, nil
+}
+
+// insertTransactionTenders record the tenders of the payment, payment without tenders is paid in cash
+func (s storage) insertTransactionTenders(ctx context.Context, dbTx *sqlx.Tx, input service.CreateTransactionInput) error {
+	tenders := input.GetTenders()
+	queryTenders := "INSERT INTO transaction_tenders (id_transaction, method, amount) VALUES "
+	var tendersArgs []interface{}
+	for i, tender := range tenders {
+		if i > 0 {
+			queryTenders += ", "
+		}
+		queryTenders += "(?, ?, ?)"
+		tendersArgs = append(tendersArgs, input.CartID, tender.Method, tender.Amount)
+	}
+	if _, err := dbTx.ExecContext(ctx, queryTenders, tendersArgs...); err != nil {
+		return fmt.Errorf("unable to insert transaction tenders into database due: %w", err)
+	}
+	return nil
+}
+
+// setPaymentPaid mark the pending payment as paid along with its transaction
+func (s storage) setPaymentPaid(ctx context.Context, dbTx *sqlx.Tx, reference string, paidAt int64) error {
+	result, err := dbTx.ExecContext(
+		ctx,
+		"UPDATE payments SET status = ?, paid_at = ? WHERE reference = ? AND status = ?",
+		entity.PaymentStatusPaid,
+		paidAt,
+		reference,
+		entity.PaymentStatusPending,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to update payment status due: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unable to get affected rows of payment status due: %w", err)
+	}
+	if affected == 0 {
+		return service.ErrPaymentNotFound
+	}
+	return nil
+}
+
+// useVoucher count the usage of the voucher, return ErrVoucherNotApplicable when it already reach its usage limit
+func (s storage) useVoucher(ctx context.Context, dbTx *sqlx.Tx, promotionID int) error {
+	result, err := dbTx.ExecContext(
+		ctx,
+		"UPDATE promotions SET used_count = used_count + 1 WHERE id = ? AND (usage_limit = 0 OR used_count < usage_limit)",
+		promotionID,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to update voucher usage due: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unable to get affected rows of voucher usage due: %w", err)
+	}
+	if affected == 0 {
+		return service.ErrVoucherNotApplicable
+	}
+	return nil
+}
+
+// GetTransactions get the orders with given statuses, the oldest one first
+func (s *storage) GetTransactions(ctx context.Context, input service.GetTransactionsInput) ([]entity.Transaction, error) {
+	if len(input.Statuses) == 0 {
+		return []entity.Transaction{}, nil
+	}
+	query, args, err := sqlx.In(`
+		SELECT
+			trx.id,
+			trx.status,
+			trx.total_amount,
+			trx.subtotal_amount,
+			trx.discount_amount,
+			trx.service_charge_amount,
+			trx.tax_base_amount,
+			trx.tax_amount,
+			trx.tax_rate,
+			trx.service_charge_rate,
+			trx.payment_amount,
+			trx.refunded_amount,
+			trx.idempotency_key,
+			(
+				SELECT COALESCE(SUM(td.total_goods), 0)
+				FROM transaction_details td
+				WHERE td.id_transaction = trx.id
+			) AS total_goods
+		FROM transactions trx
+		WHERE trx.status IN (?)
+		ORDER BY trx.id
+		LIMIT ? OFFSET ?
+	`, input.Statuses, input.Limit, input.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("unable to construct select query for transactions due: %w", err)
+	}
+
+	var trxRows []TransactionSummaryRow
+	if err = s.client.SelectContext(ctx, &trxRows, s.client.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("unable to execute select query for transactions due: %w", err)
+	}
+
+	transactions := []entity.Transaction{}
+	for _, trxRow := range trxRows {
+		transactions = append(transactions, *trxRow.ToTransactionEntity())
+	}
+	return transactions, nil
+}
+
+func (s *storage) GetTransactionStatusHistory(ctx context.Context, transactionID int64) ([]entity.TransactionStatusChange, error) {
+	var historyRows TransactionStatusHistoryRowCollection
+	err := s.client.SelectContext(
+		ctx,
+		&historyRows,
+		`SELECT from_status, to_status, created_at
+		FROM transaction_status_histories
+		WHERE id_transaction = ?
+		ORDER BY id`,
+		transactionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for transaction status history due: %w", err)
+	}
+
+	return historyRows.ToTransactionStatusChanges(), nil
+}
+
+// UpdateTransactionStatus lock the order then move it into the next status when the transition is allowed
+// from its latest status
+func (s *storage) UpdateTransactionStatus(ctx context.Context, input service.UpdateTransactionStatusInput) (*entity.Transaction, error) {
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for update transaction status query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
+
+	var currStatus entity.TransactionStatus
+	err = dbTx.GetContext(ctx, &currStatus, "SELECT status FROM transactions WHERE id = ? FOR UPDATE", input.TransactionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrCartNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock transaction due: %w", err)
+	}
+
+	trx := &entity.Transaction{ID: input.TransactionID, Status: currStatus}
+	if err = trx.TransitionTo(input.Status); err != nil {
+		return nil, err
+	}
+	if err = s.setTransactionStatus(ctx, dbTx, input.TransactionID, currStatus, input.Status, input.ChangedAt); err != nil {
+		return nil, err
+	}
+
+	trx, err = s.getTransaction(ctx, dbTx, "trx.id = ?", input.TransactionID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get transaction details due: %w", err)
+	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit update transaction status query in database due: %w", err)
+	}
+
+	return trx, nil
+}
+
+// CreateRefund lock the paid transaction and its goods, then record the refund of the returned goods and put them
+// back into the stocks. The transaction status is changed when all of its goods refunded.
+func (s *storage) CreateRefund(ctx context.Context, input service.CreateRefundInput) (*entity.Refund, error) {
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for create refund query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
+
+	var trxRow RefundableTransactionRow
+	err = dbTx.GetContext(
+		ctx,
+		&trxRow,
+		"SELECT id, status, total_amount, subtotal_amount, refunded_amount FROM transactions WHERE id = ? FOR UPDATE",
+		input.TransactionID,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrCartNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock transaction due: %w", err)
+	}
+	trx := trxRow.ToTransactionEntity()
+	if trx.Status == entity.TransactionStatusCart || trx.Status == entity.TransactionStatusExpired {
+		return nil, service.ErrTransactionNotPaid
+	}
+	currStatus := trx.Status
+	if !currStatus.CanTransitionTo(input.Status) {
+		return nil, entity.InvalidStatusTransitionError{
+			From: currStatus,
+			To:   input.Status,
+		}
+	}
+
+	var purchasedRows PurchasedGoodsRowCollection
+	err = dbTx.SelectContext(
+		ctx,
+		&purchasedRows,
+		`SELECT id_goods, id_variant, total_goods, refunded_goods, price
+		FROM transaction_details
+		WHERE id_transaction = ?
+		ORDER BY id_goods, id_variant
+		FOR UPDATE`,
+		input.TransactionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock transaction details due: %w", err)
+	}
+	purchased := purchasedRows.ToPurchasedGoodsEntities()
+
+	// cancellation always refund all of the remaining goods
+	items := input.Items
+	if input.Status == entity.TransactionStatusCancelled {
+		items = nil
+	}
+	refund, err := entity.NewRefund(input.TransactionID, purchased, items, input.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if err = trx.ApplyRefund(refund); err != nil {
+		return nil, err
+	}
+	refund.CreatedAt = input.CreatedAt
+
+	// put the returned goods back into the stocks, lock the goods in the same order to avoid deadlock
+	details := make([]entity.RefundDetail, len(refund.Details))
+	copy(details, refund.Details)
+	sort.Slice(details, func(i, j int) bool {
+		if details[i].GoodsID != details[j].GoodsID {
+			return details[i].GoodsID < details[j].GoodsID
+		}
+		return details[i].VariantID < details[j].VariantID
+	})
+	refundedGoodsIDs := []int{}
+	for _, detail := range details {
+		refundedGoodsIDs = append(refundedGoodsIDs, detail.GoodsID)
+	}
+	madeToOrder, err := s.getMadeToOrderGoods(ctx, dbTx, refundedGoodsIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, detail := range details {
+		// raw materials of the goods made to order is already consumed, there's nothing to put back
+		if !madeToOrder[detail.GoodsID] {
+			holder, err := s.lockStockHolder(ctx, dbTx, detail.GoodsID, detail.VariantID)
+			if err != nil {
+				return nil, err
+			}
+			_, _, stocksBefore := stockHolderState(holder)
+			holder.IncreaseStock(detail.TotalGoods)
+			if err = s.updateStockHolder(ctx, dbTx, holder); err != nil {
+				return nil, err
+			}
+			err = s.recordStockMovement(ctx, dbTx, holder, stocksBefore, entity.StockMovement{
+				Type:          entity.StockMovementRefund,
+				Reason:        refund.Reason,
+				TransactionID: input.TransactionID,
+				CreatedAt:     input.CreatedAt,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		_, err = dbTx.ExecContext(
+			ctx,
+			`UPDATE transaction_details SET refunded_goods = refunded_goods + ?
+			WHERE id_transaction = ? AND id_goods = ? AND id_variant = ?`,
+			detail.TotalGoods,
+			input.TransactionID,
+			detail.GoodsID,
+			detail.VariantID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to update refunded goods of transaction details due: %w", err)
+		}
+	}
+
+	// record the refund
+	result, err := dbTx.ExecContext(
+		ctx,
+		"INSERT INTO refunds (id_transaction, amount, reason, created_at) VALUES (?, ?, ?, ?)",
+		refund.TransactionID,
+		refund.Amount,
+		refund.Reason,
+		refund.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to insert refund into database due: %w", err)
+	}
+	refund.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get new refund ID from database due: %w", err)
+	}
+	queryRefundDetails, refundDetailsArgs := s.constructRefundDetailsQuery(refund)
+	if _, err = dbTx.ExecContext(ctx, queryRefundDetails, refundDetailsArgs...); err != nil {
+		return nil, fmt.Errorf("unable to insert refund details into database due: %w", err)
+	}
+
+	_, err = dbTx.ExecContext(
+		ctx,
+		"UPDATE transactions SET refunded_amount = ?, updated_at = ? WHERE id = ?",
+		trx.RefundedAmount,
+		input.CreatedAt,
+		input.TransactionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to update refunded amount of transaction due: %w", err)
+	}
+	if entity.IsAllGoodsRefunded(purchased) {
+		if err = s.setTransactionStatus(ctx, dbTx, input.TransactionID, currStatus, input.Status, input.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit create refund query in database due: %w", err)
+	}
+
+	return refund, nil
+}
+
+func (s storage) constructRefundDetailsQuery(refund *entity.Refund) (string, []interface{}) {
+	var placeholders []string
+	var args []interface{}
+	for _, detail := range refund.Details {
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?)")
+		args = append(args, refund.ID, detail.GoodsID, detail.VariantID, detail.TotalGoods, detail.GoodsPrice)
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO refund_details (id_refund, id_goods, id_variant, total_goods, price) VALUES %s",
+		strings.Join(placeholders, ", "),
+	)
+	return query, args
+}
+
+// GetSalesSummary sum the payments and the refunds made within the period
+func (s *storage) GetSalesSummary(ctx context.Context, input service.GetSalesSummaryInput) (*entity.SalesSummary, error) {
+	var summaryRow SalesSummaryRow
+	err := s.client.GetContext(
+		ctx,
+		&summaryRow,
+		`SELECT
+			(
+				SELECT COUNT(*) FROM transactions
+				WHERE paid_at >= ? AND paid_at < ?
+			) AS total_transactions,
+			(
+				SELECT COALESCE(SUM(total_amount), 0) FROM transactions
+				WHERE paid_at >= ? AND paid_at < ?
+			) AS gross_amount,
+			(
+				SELECT COUNT(*) FROM refunds
+				WHERE created_at >= ? AND created_at < ?
+			) AS total_refunds,
+			(
+				SELECT COALESCE(SUM(amount), 0) FROM refunds
+				WHERE created_at >= ? AND created_at < ?
+			) AS refund_amount,
+			(
+				SELECT CAST(ROUND(COALESCE(SUM(-quantity * unit_cost), 0)) AS SIGNED) FROM stock_movements
+				WHERE type IN (?, ?) AND created_at >= ? AND created_at < ?
+			) AS cost_of_goods_sold`,
+		input.From, input.To,
+		input.From, input.To,
+		input.From, input.To,
+		input.From, input.To,
+		entity.StockMovementSale, entity.StockMovementRefund, input.From, input.To,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for sales summary due: %w", err)
+	}
+
+	// the change is always given from the cash tender, a transaction has at most one tender of each method
+	var tenderRows []TenderTotalRow
+	err = s.client.SelectContext(
+		ctx,
+		&tenderRows,
+		`SELECT
+			tt.method,
+			COUNT(*) AS total_transactions,
+			SUM(IF(tt.method = ?, tt.amount - (t.payment_amount - t.total_amount), tt.amount)) AS amount
+		FROM transaction_tenders tt
+		JOIN transactions t ON t.id = tt.id_transaction
+		WHERE t.paid_at >= ? AND t.paid_at < ?
+		GROUP BY tt.method
+		ORDER BY tt.method`,
+		entity.PaymentMethodCash, input.From, input.To,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for tender totals due: %w", err)
+	}
+
+	summary := summaryRow.ToSalesSummaryEntity()
+	summary.From = input.From
+	summary.To = input.To
+	for _, tenderRow := range tenderRows {
+		summary.Tenders = append(summary.Tenders, tenderRow.ToTenderTotalEntity())
+	}
+	return summary, nil
+}
+
+// GetTaxRecap sum up the bill of the transactions paid within the period, the PPN of the refunds made within
+// the period is taken proportionally from the PPN of their transactions
+func (s *storage) GetTaxRecap(ctx context.Context, input service.GetTaxRecapInput) (*entity.TaxRecap, error) {
+	var recapRow TaxRecapRow
+	err := s.client.GetContext(
+		ctx,
+		&recapRow,
+		`SELECT
+			trx.total_transactions,
+			trx.subtotal_amount,
+			trx.discount_amount,
+			trx.service_charge_amount,
+			trx.tax_base_amount,
+			trx.tax_amount,
+			trx.grand_total_amount,
+			refund.refund_amount,
+			refund.refunded_tax_amount
+		FROM
+			(
+				SELECT
+					COUNT(*) AS total_transactions,
+					COALESCE(SUM(subtotal_amount), 0) AS subtotal_amount,
+					COALESCE(SUM(discount_amount), 0) AS discount_amount,
+					COALESCE(SUM(service_charge_amount), 0) AS service_charge_amount,
+					COALESCE(SUM(tax_base_amount), 0) AS tax_base_amount,
+					COALESCE(SUM(tax_amount), 0) AS tax_amount,
+					COALESCE(SUM(total_amount), 0) AS grand_total_amount
+				FROM transactions
+				WHERE paid_at >= ? AND paid_at < ?
+			) trx,
+			(
+				SELECT
+					COALESCE(SUM(r.amount), 0) AS refund_amount,
+					COALESCE(SUM(ROUND(r.amount * t.tax_amount / t.total_amount)), 0) AS refunded_tax_amount
+				FROM refunds r
+				JOIN transactions t ON t.id = r.id_transaction
+				WHERE r.created_at >= ? AND r.created_at < ? AND t.total_amount > 0
+			) refund`,
+		input.From, input.To,
+		input.From, input.To,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for tax recap due: %w", err)
+	}
+
+	recap := recapRow.ToTaxRecapEntity()
+	recap.From = input.From
+	recap.To = input.To
+	return recap, nil
+}
+
+// setTransactionStatus update status of the locked transaction and record the change into its history
+func (s storage) setTransactionStatus(ctx context.Context, dbTx *sqlx.Tx, transactionID int64, from entity.TransactionStatus, to entity.TransactionStatus, changedAt int64) error {
+	_, err := dbTx.ExecContext(
+		ctx,
+		"UPDATE transactions SET status = ?, updated_at = ? WHERE id = ? AND status = ?",
+		to,
+		changedAt,
+		transactionID,
+		from,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to update transaction status into %s due: %w", to, err)
+	}
+
+	return s.insertTransactionStatusChange(ctx, dbTx, transactionID, &from, to, changedAt)
+}
+
+func (s storage) insertTransactionStatusChange(ctx context.Context, dbTx *sqlx.Tx, transactionID int64, from *entity.TransactionStatus, to entity.TransactionStatus, changedAt int64) error {
+	_, err := dbTx.ExecContext(
+		ctx,
+		"INSERT INTO transaction_status_histories (id_transaction, from_status, to_status, created_at) VALUES (?, ?, ?, ?)",
+		transactionID,
+		from,
+		to,
+		changedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to insert transaction status history into database due: %w", err)
+	}
+	return nil
+}
+
+func (s *storage) CreateGoods(ctx context.Context, goods entity.Goods) (*entity.Goods, error) {
+	if err := s.checkCategoryExists(ctx, goods.CategoryID); err != nil {
+		return nil, err
+	}
+
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for create goods query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
+
+	result, err := dbTx.ExecContext(
+		ctx,
+		`INSERT INTO goods
+			(name, stocks, reserved_stocks, price, id_category, is_raw_material, unit, reorder_point, price_includes_tax)
+		VALUES (?, ?, 0, ?, NULLIF(?, 0), ?, ?, ?, ?)`,
+		goods.Name,
+		goods.Stocks,
+		goods.Price,
+		goods.CategoryID,
+		goods.IsRawMaterial,
+		goods.Unit,
+		goods.ReorderPoint,
+		goods.PriceIncludesTax,
+	)
+	if isDuplicateEntryError(err) {
+		return nil, service.ErrGoodsNameAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute insert query for goods due: %w", err)
+	}
+
+	goodsID, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get new goods ID from database due: %w", err)
+	}
+	goods.ID = int(goodsID)
+	goods.ReservedStocks = 0
+	err = s.recordStockMovement(ctx, dbTx, &goods, 0, entity.StockMovement{
+		Type:   entity.StockMovementRestock,
+		Reason: "initial stocks",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit create goods query in database due: %w", err)
+	}
+
+	return &goods, nil
+}
+
+// UpdateGoods change name, price and category of the goods which still sold, the stocks is kept as is
+func (s *storage) UpdateGoods(ctx context.Context, goods entity.Goods) (*entity.Goods, error) {
+	if err := s.checkCategoryExists(ctx, goods.CategoryID); err != nil {
+		return nil, err
+	}
+
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for update goods query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
+
+	currGoods, err := s.lockGoods(ctx, dbTx, goods.ID)
+	if err != nil {
+		return nil, err
+	}
+	if currGoods.IsDeleted() {
+		return nil, service.ErrGoodsNotFound
+	}
+	// the variants price follow the goods price, so none of them may drop to zero or below
+	variants, err := s.lockGoodsVariants(ctx, dbTx, goods.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, variant := range variants {
+		if variant.Price(goods.Price) <= 0 {
+			return nil, fmt.Errorf(
+				"%w: price of variant %s must be greater than zero, its price delta is %v",
+				service.ErrInvalidInput,
+				variant.Name,
+				variant.PriceDelta,
+			)
+		}
+	}
+
+	_, err = dbTx.ExecContext(
+		ctx,
+		`UPDATE goods
+		SET name = ?, price = ?, id_category = NULLIF(?, 0), reorder_point = ?, price_includes_tax = ?
+		WHERE id = ?`,
+		goods.Name,
+		goods.Price,
+		goods.CategoryID,
+		goods.ReorderPoint,
+		goods.PriceIncludesTax,
+		goods.ID,
+	)
+	if isDuplicateEntryError(err) {
+		return nil, service.ErrGoodsNameAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute update query for goods due: %w", err)
+	}
+	currGoods.Name = goods.Name
+	currGoods.Price = goods.Price
+	currGoods.CategoryID = goods.CategoryID
+	currGoods.ReorderPoint = goods.ReorderPoint
+	currGoods.PriceIncludesTax = goods.PriceIncludesTax
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit update goods query in database due: %w", err)
+	}
+
+	return currGoods, nil
+}
+
+// DeleteGoods soft delete the goods, so it's hidden from the catalog but still referred by the past transactions
+func (s *storage) DeleteGoods(ctx context.Context, goodsID int, deletedAt int64) error {
+	result, err := s.client.ExecContext(
+		ctx,
+		"UPDATE goods SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL",
+		deletedAt,
+		goodsID,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to execute delete query for goods due: %w", err)
+	}
+	totalDeleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unable to get total deleted goods due: %w", err)
+	}
+	if totalDeleted == 0 {
+		return service.ErrGoodsNotFound
+	}
+
+	return nil
+}
+
+// checkCategoryExists make sure the goods is put into existing category, zero category ID means uncategorized
+func (s storage) checkCategoryExists(ctx context.Context, categoryID int) error {
+	if categoryID <= 0 {
+		return nil
+	}
+	var id int
+	err := s.client.GetContext(ctx, &id, "SELECT id FROM categories WHERE id = ?", categoryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return service.ErrCategoryNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get category due: %w", err)
+	}
+	return nil
+}
+
+func (s *storage) CreateCategory(ctx context.Context, category entity.Category) (*entity.Category, error) {
+	result, err := s.client.ExecContext(ctx, "INSERT INTO categories (name) VALUES (?)", category.Name)
+	if isDuplicateEntryError(err) {
+		return nil, service.ErrCategoryAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute insert query for category due: %w", err)
+	}
+
+	categoryID, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get new category ID from database due: %w", err)
+	}
+	category.ID = int(categoryID)
+
+	return &category, nil
+}
+
+func (s *storage) GetCategories(ctx context.Context) ([]entity.Category, error) {
+	var categoryRows []CategoryRow
+	if err := s.client.SelectContext(ctx, &categoryRows, "SELECT id, name FROM categories ORDER BY name, id"); err != nil {
+		return nil, fmt.Errorf("unable to execute select query for categories due: %w", err)
+	}
+
+	categories := []entity.Category{}
+	for _, categoryRow := range categoryRows {
+		categories = append(categories, entity.Category(categoryRow))
+	}
+	return categories, nil
+}
+
+// CreateGoodsVariant add variant to the goods which still sold, the goods is locked so it can't be deleted meanwhile
+func (s *storage) CreateGoodsVariant(ctx context.Context, variant entity.GoodsVariant) (*entity.GoodsVariant, error) {
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for create goods variant query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
+
+	goods, err := s.lockGoods(ctx, dbTx, variant.GoodsID)
+	if err != nil {
+		return nil, err
+	}
+	if goods.IsDeleted() {
+		return nil, service.ErrGoodsNotFound
+	}
+	// the goods price could be changed after it's checked by the service
+	if variant.Price(goods.Price) <= 0 {
+		return nil, fmt.Errorf("%w: variant price must be greater than zero", service.ErrInvalidInput)
+	}
+
+	result, err := dbTx.ExecContext(
+		ctx,
+		"INSERT INTO goods_variants (id_goods, name, price_delta, stocks, reserved_stocks) VALUES (?, ?, ?, ?, 0)",
+		variant.GoodsID,
+		variant.Name,
+		variant.PriceDelta,
+		variant.Stocks,
+	)
+	if isDuplicateEntryError(err) {
+		return nil, service.ErrVariantAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute insert query for goods variant due: %w", err)
+	}
+	variantID, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get new goods variant ID from database due: %w", err)
+	}
+	variant.ID = int(variantID)
+	variant.ReservedStocks = 0
+	err = s.recordStockMovement(ctx, dbTx, &variant, 0, entity.StockMovement{
+		Type:   entity.StockMovementRestock,
+		Reason: "initial stocks",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit create goods variant query in database due: %w", err)
+	}
+
+	return &variant, nil
+}
+
+// SetRecipe replace the ingredients of the goods recipe, empty ingredients remove the recipe
+func (s *storage) SetRecipe(ctx context.Context, recipe entity.Recipe) (*entity.Recipe, error) {
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for set recipe query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
+
+	// lock the goods so it can't be added to shopping cart while its recipe is changed
+	goods, err := s.lockGoods(ctx, dbTx, recipe.GoodsID)
+	if err != nil {
+		return nil, err
+	}
+	if goods.IsDeleted() {
+		return nil, service.ErrGoodsNotFound
+	}
+
+	// unpaid shopping carts reserve the stocks of goods which is not made to order, so the goods
+	// can't switch between its own stocks and its raw materials stocks until they're paid or released
+	madeToOrder, err := s.getMadeToOrderGoods(ctx, dbTx, []int{goods.ID})
+	if err != nil {
+		return nil, err
+	}
+	if madeToOrder[goods.ID] != (len(recipe.Ingredients) > 0) {
+		var totalCarts int
+		err = dbTx.GetContext(
+			ctx,
+			&totalCarts,
+			`SELECT COUNT(DISTINCT td.id_transaction)
+			FROM transaction_details td
+			JOIN transactions trx ON trx.id = td.id_transaction
+			WHERE td.id_goods = ? AND trx.status = ?`,
+			goods.ID,
+			entity.TransactionStatusCart,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to count shopping carts of goods due: %w", err)
+		}
+		if totalCarts > 0 {
+			return nil, fmt.Errorf("%w: goods %s is still in %d unpaid shopping carts", service.ErrInvalidInput, goods.Name, totalCarts)
+		}
+	}
+	// unpaid shopping carts reserve the raw materials of the goods made to order, so their reservation
+	// follow the new recipe
+	if madeToOrder[goods.ID] && len(recipe.Ingredients) > 0 {
+		if err = s.moveRecipeReservations(ctx, dbTx, recipe); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err = dbTx.ExecContext(ctx, "DELETE FROM recipes WHERE id_goods = ?", goods.ID); err != nil {
+		return nil, fmt.Errorf("unable to delete old recipe due: %w", err)
+	}
+	if len(recipe.Ingredients) > 0 {
+		var placeholders []string
+		var args []interface{}
+		for _, ingredient := range recipe.Ingredients {
+			placeholders = append(placeholders, "(?, ?, ?)")
+			args = append(args, goods.ID, ingredient.IngredientID, ingredient.Quantity)
+		}
+		_, err = dbTx.ExecContext(
+			ctx,
+			"INSERT INTO recipes (id_goods, id_ingredient, quantity) VALUES "+strings.Join(placeholders, ","),
+			args...,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to insert recipe into database due: %w", err)
+		}
+	}
+
+	recipes, err := s.getRecipes(ctx, dbTx, []int{goods.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit set recipe query in database due: %w", err)
+	}
+
+	if newRecipe, ok := recipes[goods.ID]; ok {
+		return newRecipe, nil
+	}
+	return &entity.Recipe{GoodsID: goods.ID, Ingredients: []entity.RecipeIngredient{}}, nil
+}
+
+// moveRecipeReservations move the raw materials reserved by unpaid shopping carts from the current recipe
+// of the goods into the new one
+func (s storage) moveRecipeReservations(ctx context.Context, dbTx *sqlx.Tx, newRecipe entity.Recipe) error {
+	var totalGoods int
+	err := dbTx.GetContext(
+		ctx,
+		&totalGoods,
+		`SELECT COALESCE(SUM(td.total_goods), 0)
+		FROM transaction_details td
+		JOIN transactions trx ON trx.id = td.id_transaction
+		WHERE td.id_goods = ? AND trx.status = ?`,
+		newRecipe.GoodsID,
+		entity.TransactionStatusCart,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to get total goods in shopping carts due: %w", err)
+	}
+	if totalGoods == 0 {
+		return nil
+	}
+	currRecipes, err := s.getRecipes(ctx, dbTx, []int{newRecipe.GoodsID})
+	if err != nil {
+		return err
+	}
+	rawMaterialNeeds := newRecipe.Requirements(totalGoods)
+	for rawMaterialID, total := range currRecipes[newRecipe.GoodsID].Requirements(totalGoods) {
+		rawMaterialNeeds[rawMaterialID] -= total
+	}
+	return s.reserveRawMaterials(ctx, dbTx, rawMaterialNeeds)
+}
+
+// UpdateGoodsStock lock the goods row and apply the stock changes inside single database transaction
+// so concurrent updates for the same goods never overwrite each other
+func (s *storage) UpdateGoodsStock(ctx context.Context, input service.UpdateGoodsStockInput) (*entity.Goods, error) {
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for update goods stock query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
+
+	var movementType entity.StockMovementType
+	switch input.Action {
+	case service.IncreaseStock:
+		movementType = entity.StockMovementRestock
+	case service.DecreaseStock:
+		movementType = entity.StockMovementAdjustment
+	case service.WasteStock:
+		movementType = entity.StockMovementWastage
+	}
+	goods, err := s.updateGoodsStock(ctx, dbTx, input, movementType)
+	if err != nil {
+		return nil, err
+	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit update goods stock query in database due: %w", err)
+	}
+
+	return goods, nil
+}
+
+// updateGoodsStock lock the goods or its variant then apply the stock changes within the given database transaction,
+// the changes is recorded in the stock ledger as the given movement type
+func (s storage) updateGoodsStock(ctx context.Context, dbTx *sqlx.Tx, input service.UpdateGoodsStockInput, movementType entity.StockMovementType) (*entity.Goods, error) {
+	// find the goods ID by its name first when the ID is not given
+	goodsID := input.GoodsID
+	if goodsID <= 0 {
+		err := dbTx.GetContext(ctx, &goodsID, "SELECT id FROM goods WHERE name = ? AND deleted_at IS NULL LIMIT 1", input.GoodsName)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, service.ErrGoodsNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to get goods for update stock due: %w", err)
+		}
+	}
+
+	goods, err := s.lockGoods(ctx, dbTx, goodsID)
+	if err != nil {
+		return nil, err
+	}
+	if goods.IsDeleted() {
+		return nil, service.ErrGoodsNotFound
+	}
+	madeToOrder, err := s.getMadeToOrderGoods(ctx, dbTx, []int{goodsID})
+	if err != nil {
+		return nil, err
+	}
+	if madeToOrder[goodsID] {
+		return nil, fmt.Errorf(
+			"%w: goods %s is made from its recipe, update the stocks of its raw materials instead",
+			service.ErrInvalidInput,
+			goods.Name,
+		)
+	}
+
+	// stocks of goods variant is updated instead when the variant is given, goods which has variants only hold
+	// its stocks per variant
+	var variant *entity.GoodsVariant
+	if input.VariantID > 0 {
+		if variant, err = s.lockGoodsVariant(ctx, dbTx, goodsID, input.VariantID); err != nil {
+			return nil, err
+		}
+	} else {
+		variants, err := s.lockGoodsVariants(ctx, dbTx, goodsID)
+		if err != nil {
+			return nil, err
+		}
+		if len(variants) > 0 {
+			return nil, fmt.Errorf(
+				"%w: goods %s holds its stocks per variant, the variant must be given",
+				service.ErrInvalidInput,
+				goods.Name,
+			)
+		}
+	}
+	var holder stockHolder = goods
+	if variant != nil {
+		holder = variant
+	}
+	_, _, stocksBefore := stockHolderState(holder)
+	switch input.Action {
+	case service.IncreaseStock:
+		holder.ReceiveStock(input.Total, input.UnitCost)
+	case service.DecreaseStock, service.WasteStock:
+		if variant != nil {
+			err = variant.DecreaseStock(input.Total)
+		} else {
+			err = goods.DecreaseStock(input.Total)
+		}
+	default:
+		return nil, fmt.Errorf("unknown update stock action: %s", input.Action)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.updateStockHolder(ctx, dbTx, holder); err != nil {
+		return nil, err
+	}
+	if variant != nil {
+		goods.Variants = []entity.GoodsVariant{*variant}
+	}
+	err = s.recordStockMovement(ctx, dbTx, holder, stocksBefore, entity.StockMovement{
+		Type:      movementType,
+		UnitCost:  input.UnitCost,
+		Actor:     input.Actor,
+		Reason:    input.Reason,
+		ExpiresAt: input.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return goods, nil
+}
+
+// GetStockMovements get the stock ledger filtered by the goods, the transaction or the time range,
+// ordered from the oldest movement
+func (s *storage) GetStockMovements(ctx context.Context, input service.GetStockMovementsInput) ([]entity.StockMovement, error) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+	if input.GoodsID > 0 {
+		conditions = append(conditions, "id_goods = ?")
+		args = append(args, input.GoodsID)
+	}
+	if input.VariantID > 0 {
+		conditions = append(conditions, "id_variant = ?")
+		args = append(args, input.VariantID)
+	}
+	if input.TransactionID > 0 {
+		conditions = append(conditions, "id_transaction = ?")
+		args = append(args, input.TransactionID)
+	}
+	if input.From > 0 {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, input.From)
+	}
+	if input.To > 0 {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, input.To)
+	}
+
+	var movementRows StockMovementRowCollection
+	err := s.client.SelectContext(
+		ctx,
+		&movementRows,
+		fmt.Sprintf(
+			`SELECT id, id_goods, id_variant, type, quantity, unit_cost, stocks_before, stocks_after, actor, reason, id_transaction, expires_at, created_at
+			FROM stock_movements
+			WHERE %s
+			ORDER BY created_at, id`,
+			strings.Join(conditions, " AND "),
+		),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for stock movements due: %w", err)
+	}
+
+	return movementRows.ToStockMovements(), nil
+}
+
+// GetStockDrifts compare the stocks of every goods and variant with the sum of their stock movements,
+// only the ones which doesn't match is returned
+func (s *storage) GetStockDrifts(ctx context.Context) ([]entity.StockDrift, error) {
+	var driftRows []StockDriftRow
+	err := s.client.SelectContext(
+		ctx,
+		&driftRows,
+		`SELECT id_goods, id_variant, stocks, ledger_stocks
+		FROM (
+			SELECT g.id AS id_goods, 0 AS id_variant, g.stocks, COALESCE(m.ledger_stocks, 0) AS ledger_stocks
+			FROM goods g
+			LEFT JOIN (
+				SELECT id_goods, SUM(quantity) AS ledger_stocks
+				FROM stock_movements
+				WHERE id_variant = 0
+				GROUP BY id_goods
+			) m ON m.id_goods = g.id
+			UNION ALL
+			SELECT v.id_goods, v.id AS id_variant, v.stocks, COALESCE(m.ledger_stocks, 0) AS ledger_stocks
+			FROM goods_variants v
+			LEFT JOIN (
+				SELECT id_variant, SUM(quantity) AS ledger_stocks
+				FROM stock_movements
+				WHERE id_variant > 0
+				GROUP BY id_variant
+			) m ON m.id_variant = v.id
+		) AS reconciliation
+		WHERE stocks <> ledger_stocks
+		ORDER BY id_goods, id_variant`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute reconciliation query for stock movements due: %w", err)
+	}
+
+	drifts := []entity.StockDrift{}
+	for _, driftRow := range driftRows {
+		drifts = append(drifts, entity.StockDrift{
+			GoodsID:      driftRow.GoodsID,
+			VariantID:    driftRow.VariantID,
+			Stocks:       driftRow.Stocks,
+			LedgerStocks: driftRow.LedgerStocks,
+		})
+	}
+	return drifts, nil
+}
+
+// GetLowStocks get the goods and variants which stocks at their reorder point or below it, goods made to order
+// is not included since the stocks of its raw materials is monitored instead
+func (s *storage) GetLowStocks(ctx context.Context) ([]entity.LowStock, error) {
+	var lowStockRows []LowStockRow
+	err := s.client.SelectContext(
+		ctx,
+		&lowStockRows,
+		`SELECT g.id AS id_goods, 0 AS id_variant, g.name, g.unit, g.stocks, g.reorder_point
+		FROM goods g
+		WHERE g.deleted_at IS NULL
+			AND g.reorder_point > 0
+			AND g.stocks <= g.reorder_point
+			AND NOT EXISTS (SELECT 1 FROM goods_variants v WHERE v.id_goods = g.id)
+			AND NOT EXISTS (SELECT 1 FROM recipes r WHERE r.id_goods = g.id)
+		UNION ALL
+		SELECT g.id AS id_goods, v.id AS id_variant, CONCAT(g.name, ' - ', v.name) AS name, g.unit, v.stocks, g.reorder_point
+		FROM goods_variants v
+		JOIN goods g ON g.id = v.id_goods
+		WHERE g.deleted_at IS NULL
+			AND g.reorder_point > 0
+			AND v.stocks <= g.reorder_point
+		ORDER BY id_goods, id_variant`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for low stocks due: %w", err)
+	}
+
+	lowStocks := []entity.LowStock{}
+	for _, lowStockRow := range lowStockRows {
+		lowStocks = append(lowStocks, entity.LowStock{
+			GoodsID:      lowStockRow.GoodsID,
+			VariantID:    lowStockRow.VariantID,
+			Name:         lowStockRow.Name,
+			Unit:         lowStockRow.Unit,
+			Stocks:       lowStockRow.Stocks,
+			ReorderPoint: lowStockRow.ReorderPoint,
+		})
+	}
+	return lowStocks, nil
+}
+
+// GetExpiringStockBatches get the remaining batches which expire until the given time including the expired one,
+// ordered from the earliest expiry
+func (s *storage) GetExpiringStockBatches(ctx context.Context, input service.GetExpiringStockBatchesInput) ([]entity.StockBatch, error) {
+	var batchRows StockBatchRowCollection
+	err := s.client.SelectContext(
+		ctx,
+		&batchRows,
+		`SELECT
+			b.id, b.id_goods, b.id_variant, COALESCE(CONCAT(g.name, ' - ', v.name), g.name) AS name, g.unit,
+			b.received_quantity, b.quantity, b.unit_cost, b.received_at, b.expires_at
+		FROM stock_batches b
+		JOIN goods g ON g.id = b.id_goods
+		LEFT JOIN goods_variants v ON v.id = b.id_variant
+		WHERE b.quantity > 0 AND b.expires_at IS NOT NULL AND b.expires_at <= ?
+		ORDER BY b.expires_at, b.id`,
+		input.ExpiresUntil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for expiring stock batches due: %w", err)
+	}
+
+	return batchRows.ToStockBatches(), nil
+}
+
+// WriteOffExpiredStockBatches decrease the stocks of every goods and variant by their expired batches, each of them
+// is recorded as single wastage movement. Expired stocks which still reserved by shopping carts is kept until the
+// carts released it. All of them is done within single database transaction.
+func (s *storage) WriteOffExpiredStockBatches(ctx context.Context, input service.WriteOffStockBatchesInput) ([]entity.StockMovement, error) {
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for write off stock batches query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
+
+	conditions := []string{"quantity > 0", "expires_at IS NOT NULL", "expires_at <= ?"}
+	args := []interface{}{input.ExpiredAt}
+	if input.GoodsID > 0 {
+		conditions = append(conditions, "id_goods = ?")
+		args = append(args, input.GoodsID)
+	}
+	var holders []stockHolderKey
+	err = dbTx.SelectContext(
+		ctx,
+		&holders,
+		fmt.Sprintf(
+			`SELECT DISTINCT id_goods, id_variant FROM stock_batches WHERE %s ORDER BY id_goods, id_variant`,
+			strings.Join(conditions, " AND "),
+		),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for expired stock batches due: %w", err)
+	}
+
+	movements := []entity.StockMovement{}
+	for _, key := range holders {
+		// the batches is only changed while its stock holder locked, so the expired stocks is counted after the lock
+		holder, err := s.lockStockHolder(ctx, dbTx, key.GoodsID, key.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		expired, err := s.getExpiredStocks(ctx, dbTx, key.GoodsID, key.VariantID, input.ExpiredAt)
+		if err != nil {
+			return nil, err
+		}
+		if expired > holder.AvailableStocks() {
+			expired = holder.AvailableStocks()
+		}
+		if expired <= 0 {
+			continue
+		}
+
+		_, _, stocksBefore := stockHolderState(holder)
+		if err = holder.DecreaseStock(expired); err != nil {
+			return nil, err
+		}
+		if err = s.updateStockHolder(ctx, dbTx, holder); err != nil {
+			return nil, err
+		}
+		// the expired batches always consumed first since they have the earliest expiry
+		movement := entity.StockMovement{
+			Type:      entity.StockMovementWastage,
+			UnitCost:  stockHolderUnitCost(holder),
+			Actor:     input.Actor,
+			Reason:    input.Reason,
+			CreatedAt: input.ExpiredAt,
+		}
+		if err = s.recordStockMovement(ctx, dbTx, holder, stocksBefore, movement); err != nil {
+			return nil, err
+		}
+		movement.GoodsID, movement.VariantID, movement.After = stockHolderState(holder)
+		movement.Before = stocksBefore
+		movement.Quantity = movement.After - movement.Before
+		movements = append(movements, movement)
+	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit write off stock batches query in database due: %w", err)
+	}
+
+	return movements, nil
+}
+
+func (s *storage) CreateStockCount(ctx context.Context, stockCount entity.StockCount) (*entity.StockCount, error) {
+	result, err := s.client.ExecContext(
+		ctx,
+		"INSERT INTO stock_counts (status, opened_by, approved_by, created_at) VALUES (?, ?, '', ?)",
+		stockCount.Status,
+		stockCount.OpenedBy,
+		stockCount.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute insert query for stock count due: %w", err)
+	}
+
+	countID, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get new stock count ID from database due: %w", err)
+	}
+	stockCount.ID = countID
+
+	return &stockCount, nil
+}
+
+// SetStockCountItems save the counted goods of the open stock count, counted quantity of the goods which
+// already counted before is replaced
+func (s *storage) SetStockCountItems(ctx context.Context, input service.SetStockCountItemsInput) (*entity.StockCount, error) {
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for set stock count items query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
+
+	stockCount, err := s.lockStockCount(ctx, dbTx, input.CountID)
+	if err != nil {
+		return nil, err
+	}
+	if !stockCount.IsOpen() {
+		return nil, service.ErrStockCountApproved
+	}
+	var holders []stockHolderKey
+	for _, item := range input.Items {
+		holders = append(holders, stockHolderKey{GoodsID: item.GoodsID, VariantID: item.VariantID})
+	}
+	if err = s.checkStockHolders(ctx, dbTx, holders); err != nil {
+		return nil, err
+	}
+
+	countedAt := time.Now().Unix()
+	var placeholders []string
+	var args []interface{}
+	for _, item := range input.Items {
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?)")
+		args = append(args, input.CountID, item.GoodsID, item.VariantID, item.CountedStocks, countedAt)
+	}
+	_, err = dbTx.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			`INSERT INTO stock_count_items (id_stock_count, id_goods, id_variant, counted_stocks, counted_at)
+			VALUES %s
+			ON DUPLICATE KEY UPDATE counted_stocks = VALUES(counted_stocks), counted_at = VALUES(counted_at)`,
+			strings.Join(placeholders, ", "),
+		),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute insert query for stock count items due: %w", err)
+	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit set stock count items query in database due: %w", err)
+	}
+
+	return s.GetStockCount(ctx, input.CountID)
+}
+
+// stockHolderKey identify the goods or its variant which holds the stocks
+type stockHolderKey struct {
+	GoodsID   int `db:"id_goods"`
+	VariantID int `db:"id_variant"`
+}
+
+// checkStockHolders make sure the goods still sold and holds its own stocks, goods which has variants
+// hold the stocks per variant
+func (s storage) checkStockHolders(ctx context.Context, queryer sqlx.QueryerContext, holders []stockHolderKey) error {
+	var goodsIDs []int
+	for _, holder := range holders {
+		goodsIDs = append(goodsIDs, holder.GoodsID)
+	}
+	query, args, err := sqlx.In("SELECT id FROM goods WHERE id IN (?) AND deleted_at IS NULL", goodsIDs)
+	if err != nil {
+		return fmt.Errorf("unable to construct select query for stock holder goods due: %w", err)
+	}
+	var existingIDs []int
+	if err = sqlx.SelectContext(ctx, queryer, &existingIDs, s.client.Rebind(query), args...); err != nil {
+		return fmt.Errorf("unable to execute select query for stock holder goods due: %w", err)
+	}
+	isExist := map[int]bool{}
+	for _, goodsID := range existingIDs {
+		isExist[goodsID] = true
+	}
+	variants, err := s.getGoodsVariants(ctx, queryer, existingIDs)
+	if err != nil {
+		return err
+	}
+	madeToOrder, err := s.getMadeToOrderGoods(ctx, queryer, existingIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, holder := range holders {
+		if !isExist[holder.GoodsID] {
+			return fmt.Errorf("%w: goods %d", service.ErrGoodsNotFound, holder.GoodsID)
+		}
+		if madeToOrder[holder.GoodsID] {
+			return fmt.Errorf(
+				"%w: goods %d is made from its recipe, use its raw materials instead",
+				service.ErrInvalidInput,
+				holder.GoodsID,
+			)
+		}
+		goods := entity.Goods{ID: holder.GoodsID, Variants: variants[holder.GoodsID]}
+		if holder.VariantID == 0 && goods.HasVariants() {
+			return fmt.Errorf("%w: stocks of goods %d is held per variant", service.ErrInvalidInput, holder.GoodsID)
+		}
+		if _, ok := goods.FindVariant(holder.VariantID); holder.VariantID > 0 && !ok {
+			return fmt.Errorf("%w: variant %d of goods %d", service.ErrVariantNotFound, holder.VariantID, holder.GoodsID)
+		}
+	}
+	return nil
+}
+
+// GetStockCount get the stock count along with its counted goods, ordered by the goods
+func (s *storage) GetStockCount(ctx context.Context, countID int64) (*entity.StockCount, error) {
+	var stockCountRow StockCountRow
+	err := s.client.GetContext(
+		ctx,
+		&stockCountRow,
+		"SELECT id, status, opened_by, approved_by, created_at, approved_at FROM stock_counts WHERE id = ?",
+		countID,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrStockCountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get stock count due: %w", err)
+	}
+	stockCount := stockCountRow.ToStockCountEntity()
+
+	stockCount.Items, err = s.getStockCountItems(ctx, s.client, countID)
+	if err != nil {
+		return nil, err
+	}
+
+	return stockCount, nil
+}
+
+// getStockCountItems get the counted goods of the stock count, the system stocks & price is taken from
+// the current goods until the stock count approved
+func (s storage) getStockCountItems(ctx context.Context, queryer sqlx.QueryerContext, countID int64) ([]entity.StockCountItem, error) {
+	var itemRows StockCountItemRowCollection
+	err := sqlx.SelectContext(
+		ctx,
+		queryer,
+		&itemRows,
+		`SELECT
+			i.id_goods,
+			i.id_variant,
+			CASE WHEN v.id IS NULL THEN g.name ELSE CONCAT(g.name, ' - ', v.name) END AS name,
+			i.counted_stocks,
+			COALESCE(i.system_stocks, v.stocks, g.stocks) AS system_stocks,
+			COALESCE(i.price, g.price + COALESCE(v.price_delta, 0)) AS price
+		FROM stock_count_items i
+		JOIN goods g ON g.id = i.id_goods
+		LEFT JOIN goods_variants v ON v.id = i.id_variant
+		WHERE i.id_stock_count = ?
+		ORDER BY i.id_goods, i.id_variant`,
+		countID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for stock count items due: %w", err)
+	}
+
+	return itemRows.ToStockCountItems(), nil
+}
+
+// ApplyStockCount adjust the stocks of every counted goods to its counted quantity then approve the stock count,
+// all of them is done within single database transaction so the stocks is adjusted all at once or not at all
+func (s *storage) ApplyStockCount(ctx context.Context, input service.ApplyStockCountInput) (*entity.StockCount, error) {
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for apply stock count query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
+
+	stockCount, err := s.lockStockCount(ctx, dbTx, input.CountID)
+	if err != nil {
+		return nil, err
+	}
+	if !stockCount.IsOpen() {
+		return nil, service.ErrStockCountApproved
+	}
+	items, err := s.getStockCountItems(ctx, dbTx, input.CountID)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: stock count %d has no counted goods", service.ErrInvalidInput, input.CountID)
+	}
+
+	// items is ordered by the goods, so the goods is locked in the same order as other stock changes
+	for _, item := range items {
+		goods, err := s.lockGoods(ctx, dbTx, item.GoodsID)
+		if err != nil {
+			return nil, err
+		}
+		var holder stockHolder = goods
+		systemStocks, price := goods.Stocks, goods.Price
+		if item.VariantID > 0 {
+			variant, err := s.lockGoodsVariant(ctx, dbTx, item.GoodsID, item.VariantID)
+			if err != nil {
+				return nil, err
+			}
+			holder = variant
+			systemStocks, price = variant.Stocks, variant.Price(goods.Price)
+		}
+
+		// the stocks is set into the counted quantity as is, shrinkage must be recorded even when the open
+		// shopping carts reserve more than the counted quantity
+		holder.SetCountedStock(item.CountedStocks)
+		if err = s.updateStockHolder(ctx, dbTx, holder); err != nil {
+			return nil, err
+		}
+		err = s.recordStockMovement(ctx, dbTx, holder, systemStocks, entity.StockMovement{
+			Type:      entity.StockMovementAdjustment,
+			Actor:     input.ApprovedBy,
+			Reason:    fmt.Sprintf("stock count #%d", input.CountID),
+			CreatedAt: input.ApprovedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// keep the system stocks & price when the goods counted, so the variance is not changed afterwards
+		_, err = dbTx.ExecContext(
+			ctx,
+			`UPDATE stock_count_items SET system_stocks = ?, price = ?
+			WHERE id_stock_count = ? AND id_goods = ? AND id_variant = ?`,
+			systemStocks,
+			price,
+			input.CountID,
+			item.GoodsID,
+			item.VariantID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to update stock count item due: %w", err)
+		}
+	}
+
+	_, err = dbTx.ExecContext(
+		ctx,
+		"UPDATE stock_counts SET status = ?, approved_by = ?, approved_at = ? WHERE id = ?",
+		entity.StockCountApproved,
+		input.ApprovedBy,
+		input.ApprovedAt,
+		input.CountID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to approve stock count due: %w", err)
+	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit apply stock count query in database due: %w", err)
+	}
+
+	return s.GetStockCount(ctx, input.CountID)
+}
+
+// lockStockCount get the stock count without its items and lock its row until the database transaction finished
+func (s storage) lockStockCount(ctx context.Context, dbTx *sqlx.Tx, countID int64) (*entity.StockCount, error) {
+	var stockCountRow StockCountRow
+	err := dbTx.GetContext(
+		ctx,
+		&stockCountRow,
+		"SELECT id, status, opened_by, approved_by, created_at, approved_at FROM stock_counts WHERE id = ? FOR UPDATE",
+		countID,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrStockCountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock stock count due: %w", err)
+	}
+
+	return stockCountRow.ToStockCountEntity(), nil
+}
+
+func (s *storage) CreateSupplier(ctx context.Context, supplier entity.Supplier) (*entity.Supplier, error) {
+	result, err := s.client.ExecContext(
+		ctx,
+		"INSERT INTO suppliers (name, phone, address, created_at) VALUES (?, ?, ?, ?)",
+		supplier.Name,
+		supplier.Phone,
+		supplier.Address,
+		supplier.CreatedAt,
+	)
+	if isDuplicateEntryError(err) {
+		return nil, service.ErrSupplierAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute insert query for supplier due: %w", err)
+	}
+
+	supplierID, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get new supplier ID from database due: %w", err)
+	}
+	supplier.ID = int(supplierID)
+
+	return &supplier, nil
+}
+
+func (s *storage) GetSuppliers(ctx context.Context) ([]entity.Supplier, error) {
+	var supplierRows []SupplierRow
+	err := s.client.SelectContext(
+		ctx,
+		&supplierRows,
+		"SELECT id, name, phone, address, created_at FROM suppliers ORDER BY name, id",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for suppliers due: %w", err)
+	}
+
+	suppliers := []entity.Supplier{}
+	for _, supplierRow := range supplierRows {
+		suppliers = append(suppliers, supplierRow.ToSupplierEntity())
+	}
+	return suppliers, nil
+}
+
+func (s *storage) CreatePromotion(ctx context.Context, promotion entity.Promotion) (*entity.Promotion, error) {
+	query := `
+		INSERT INTO promotions
+			(name, type, id_goods, value, amount, buy_quantity, free_quantity, min_purchase, happy_hour_start,
+			happy_hour_end, starts_at, ends_at, voucher_code, usage_limit, created_at)
+		VALUES
+			(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?)
+	`
+	result, err := s.client.ExecContext(
+		ctx,
+		query,
+		promotion.Name,
+		promotion.Type,
+		promotion.GoodsID,
+		promotion.Value,
+		promotion.Amount,
+		promotion.BuyQuantity,
+		promotion.FreeQuantity,
+		promotion.MinPurchase,
+		promotion.HappyHourStart,
+		promotion.HappyHourEnd,
+		promotion.StartsAt,
+		promotion.EndsAt,
+		promotion.VoucherCode,
+		promotion.UsageLimit,
+		promotion.CreatedAt,
+	)
+	if isDuplicateEntryError(err) {
+		return nil, service.ErrVoucherAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute insert query for promotion due: %w", err)
+	}
+
+	promotionID, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get new promotion ID from database due: %w", err)
+	}
+	promotion.ID = int(promotionID)
+
+	return &promotion, nil
+}
+
+// GetPromotions return the automatic promotions first then the vouchers, each ordered by their ID
+func (s *storage) GetPromotions(ctx context.Context, input service.GetPromotionsInput) ([]entity.Promotion, error) {
+	query := `
+		SELECT
+			id, name, type, id_goods, value, amount, buy_quantity, free_quantity, min_purchase, happy_hour_start,
+			happy_hour_end, starts_at, ends_at, COALESCE(voucher_code, '') AS voucher_code, usage_limit, used_count,
+			created_at
+		FROM promotions
+	`
+	var args []interface{}
+	if input.ActiveAt > 0 {
+		query += `
+			WHERE starts_at <= ? AND (ends_at = 0 OR ends_at > ?) AND (voucher_code IS NULL OR voucher_code = ?)
+		`
+		args = append(args, input.ActiveAt, input.ActiveAt, input.VoucherCode)
+	}
+	query += " ORDER BY voucher_code IS NOT NULL, id"
+
+	var promotionRows []PromotionRow
+	if err := s.client.SelectContext(ctx, &promotionRows, query, args...); err != nil {
+		return nil, fmt.Errorf("unable to execute select query for promotions due: %w", err)
+	}
+
+	promotions := []entity.Promotion{}
+	for _, promotionRow := range promotionRows {
+		promotions = append(promotions, promotionRow.ToPromotionEntity())
+	}
+	return promotions, nil
+}
+
+// CreatePurchaseOrder save the purchase order along with its items, the ordered goods must hold its own stocks
+func (s *storage) CreatePurchaseOrder(ctx context.Context, purchaseOrder entity.PurchaseOrder) (*entity.PurchaseOrder, error) {
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for create purchase order query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
+
+	var supplierID int
+	err = dbTx.GetContext(ctx, &supplierID, "SELECT id FROM suppliers WHERE id = ?", purchaseOrder.SupplierID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrSupplierNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get supplier of purchase order due: %w", err)
+	}
+	var holders []stockHolderKey
+	for _, item := range purchaseOrder.Items {
+		holders = append(holders, stockHolderKey{GoodsID: item.GoodsID, VariantID: item.VariantID})
+	}
+	if err = s.checkStockHolders(ctx, dbTx, holders); err != nil {
+		return nil, err
+	}
+
+	result, err := dbTx.ExecContext(
+		ctx,
+		`INSERT INTO purchase_orders (id_supplier, status, note, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		purchaseOrder.SupplierID,
+		purchaseOrder.Status,
+		purchaseOrder.Note,
+		purchaseOrder.CreatedBy,
+		purchaseOrder.CreatedAt,
+		purchaseOrder.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute insert query for purchase order due: %w", err)
+	}
+	orderID, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get new purchase order ID from database due: %w", err)
+	}
+
+	var placeholders []string
+	var args []interface{}
+	for _, item := range purchaseOrder.Items {
+		placeholders = append(placeholders, "(?, ?, ?, ?, 0, ?)")
+		args = append(args, orderID, item.GoodsID, item.VariantID, item.Quantity, item.UnitCost)
+	}
+	_, err = dbTx.ExecContext(
+		ctx,
+		`INSERT INTO purchase_order_items (id_purchase_order, id_goods, id_variant, quantity, received_quantity, unit_cost)
+		VALUES `+strings.Join(placeholders, ", "),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute insert query for purchase order items due: %w", err)
+	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit create purchase order query in database due: %w", err)
+	}
+
+	return s.GetPurchaseOrder(ctx, orderID)
+}
+
+// GetPurchaseOrders get the purchase orders along with their items, the latest order first
+func (s *storage) GetPurchaseOrders(ctx context.Context, input service.GetPurchaseOrdersInput) ([]entity.PurchaseOrder, error) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+	if input.SupplierID > 0 {
+		conditions = append(conditions, "id_supplier = ?")
+		args = append(args, input.SupplierID)
+	}
+	if len(input.Status) > 0 {
+		conditions = append(conditions, "status = ?")
+		args = append(args, input.Status)
+	}
+
+	var orderRows []PurchaseOrderRow
+	err := s.client.SelectContext(
+		ctx,
+		&orderRows,
+		`SELECT id, id_supplier, status, note, created_by, created_at, updated_at
+		FROM purchase_orders
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY id DESC`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for purchase orders due: %w", err)
+	}
+	purchaseOrders := []entity.PurchaseOrder{}
+	if len(orderRows) == 0 {
+		return purchaseOrders, nil
+	}
+
+	var orderIDs []int64
+	for _, orderRow := range orderRows {
+		orderIDs = append(orderIDs, orderRow.ID)
+	}
+	items, err := s.getPurchaseOrderItems(ctx, s.client, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, orderRow := range orderRows {
+		purchaseOrder := orderRow.ToPurchaseOrderEntity()
+		purchaseOrder.Items = items[purchaseOrder.ID]
+		purchaseOrders = append(purchaseOrders, purchaseOrder)
+	}
+	return purchaseOrders, nil
+}
+
+// GetPurchaseOrder get the purchase order along with its items and its receipts
+func (s *storage) GetPurchaseOrder(ctx context.Context, orderID int64) (*entity.PurchaseOrder, error) {
+	var orderRow PurchaseOrderRow
+	err := s.client.GetContext(
+		ctx,
+		&orderRow,
+		"SELECT id, id_supplier, status, note, created_by, created_at, updated_at FROM purchase_orders WHERE id = ?",
+		orderID,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get purchase order due: %w", err)
+	}
+	purchaseOrder := orderRow.ToPurchaseOrderEntity()
+
+	items, err := s.getPurchaseOrderItems(ctx, s.client, []int64{orderID})
+	if err != nil {
+		return nil, err
+	}
+	purchaseOrder.Items = items[orderID]
+
+	var receiptRows []PurchaseOrderReceiptRow
+	err = s.client.SelectContext(
+		ctx,
+		&receiptRows,
+		"SELECT id, id_purchase_order, received_by, received_at FROM purchase_order_receipts WHERE id_purchase_order = ? ORDER BY id",
+		orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for purchase order receipts due: %w", err)
+	}
+	var receiptItemRows []PurchaseOrderReceiptItemRow
+	err = s.client.SelectContext(
+		ctx,
+		&receiptItemRows,
+		`SELECT i.id_receipt, i.id_goods, i.id_variant, i.quantity, i.unit_cost, i.expires_at
+		FROM purchase_order_receipt_items i
+		JOIN purchase_order_receipts r ON r.id = i.id_receipt
+		WHERE r.id_purchase_order = ?
+		ORDER BY i.id_receipt, i.id_goods, i.id_variant`,
+		orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for purchase order receipt items due: %w", err)
+	}
+	receiptItems := map[int64][]entity.PurchaseOrderReceiptItem{}
+	for _, itemRow := range receiptItemRows {
+		receiptItems[itemRow.ReceiptID] = append(receiptItems[itemRow.ReceiptID], itemRow.ToPurchaseOrderReceiptItemEntity())
+	}
+	for _, receiptRow := range receiptRows {
+		receipt := receiptRow.ToPurchaseOrderReceiptEntity()
+		receipt.Items = receiptItems[receipt.ID]
+		purchaseOrder.Receipts = append(purchaseOrder.Receipts, receipt)
+	}
+
+	return &purchaseOrder, nil
+}
+
+// getPurchaseOrderItems get the items of the purchase orders grouped by the purchase order, ordered by the goods
+func (s storage) getPurchaseOrderItems(ctx context.Context, queryer sqlx.QueryerContext, orderIDs []int64) (map[int64][]entity.PurchaseOrderItem, error) {
+	query, args, err := sqlx.In(
+		`SELECT
+			i.id_purchase_order,
+			i.id_goods,
+			i.id_variant,
+			CASE WHEN v.id IS NULL THEN g.name ELSE CONCAT(g.name, ' - ', v.name) END AS name,
+			i.quantity,
+			i.received_quantity,
+			i.unit_cost
+		FROM purchase_order_items i
+		JOIN goods g ON g.id = i.id_goods
+		LEFT JOIN goods_variants v ON v.id = i.id_variant
+		WHERE i.id_purchase_order IN (?)
+		ORDER BY i.id_purchase_order, i.id_goods, i.id_variant`,
+		orderIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to construct select query for purchase order items due: %w", err)
+	}
+	var itemRows []PurchaseOrderItemRow
+	if err = sqlx.SelectContext(ctx, queryer, &itemRows, s.client.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("unable to execute select query for purchase order items due: %w", err)
+	}
+
+	items := map[int64][]entity.PurchaseOrderItem{}
+	for _, itemRow := range itemRows {
+		items[itemRow.OrderID] = append(items[itemRow.OrderID], itemRow.ToPurchaseOrderItemEntity())
+	}
+	return items, nil
+}
+
+// ReceivePurchaseOrder increase the stocks of the received goods at the unit cost of their order items then
+// record the receipt, all of them is done within single database transaction
+func (s *storage) ReceivePurchaseOrder(ctx context.Context, receipt entity.PurchaseOrderReceipt) (*entity.PurchaseOrder, error) {
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for receive purchase order query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
+
+	purchaseOrder, err := s.lockPurchaseOrder(ctx, dbTx, receipt.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if purchaseOrder.IsClosed() {
+		return nil, service.ErrPurchaseOrderClosed
+	}
+	items, err := s.getPurchaseOrderItems(ctx, dbTx, []int64{receipt.OrderID})
+	if err != nil {
+		return nil, err
+	}
+	purchaseOrder.Items = items[receipt.OrderID]
+	received, err := purchaseOrder.Receive(receipt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", service.ErrInvalidInput, err)
+	}
+
+	// lock the goods in the same order as other stock changes
+	receivedItems := received.Items
+	sort.Slice(receivedItems, func(i, j int) bool {
+		if receivedItems[i].GoodsID != receivedItems[j].GoodsID {
+			return receivedItems[i].GoodsID < receivedItems[j].GoodsID
+		}
+		return receivedItems[i].VariantID < receivedItems[j].VariantID
+	})
+	for _, item := range receivedItems {
+		_, err = s.updateGoodsStock(ctx, dbTx, service.UpdateGoodsStockInput{
+			Action:    service.IncreaseStock,
+			GoodsID:   item.GoodsID,
+			VariantID: item.VariantID,
+			Total:     item.Quantity,
+			Actor:     received.ReceivedBy,
+			Reason:    fmt.Sprintf("purchase order #%d", received.OrderID),
+			UnitCost:  item.UnitCost,
+			ExpiresAt: item.ExpiresAt,
+		}, entity.StockMovementRestock)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = dbTx.ExecContext(
+			ctx,
+			`UPDATE purchase_order_items SET received_quantity = received_quantity + ?
+			WHERE id_purchase_order = ? AND id_goods = ? AND id_variant = ?`,
+			item.Quantity,
+			received.OrderID,
+			item.GoodsID,
+			item.VariantID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to update received quantity of purchase order item due: %w", err)
+		}
+	}
+
+	result, err := dbTx.ExecContext(
+		ctx,
+		"INSERT INTO purchase_order_receipts (id_purchase_order, received_by, received_at) VALUES (?, ?, ?)",
+		received.OrderID,
+		received.ReceivedBy,
+		received.ReceivedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute insert query for purchase order receipt due: %w", err)
+	}
+	receiptID, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get new purchase order receipt ID from database due: %w", err)
+	}
+	var placeholders []string
+	var args []interface{}
+	for _, item := range receivedItems {
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?, NULLIF(?, 0))")
+		args = append(args, receiptID, item.GoodsID, item.VariantID, item.Quantity, item.UnitCost, item.ExpiresAt)
+	}
+	_, err = dbTx.ExecContext(
+		ctx,
+		`INSERT INTO purchase_order_receipt_items (id_receipt, id_goods, id_variant, quantity, unit_cost, expires_at)
+		VALUES `+strings.Join(placeholders, ", "),
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute insert query for purchase order receipt items due: %w", err)
+	}
+
+	if err = s.updatePurchaseOrderStatus(ctx, dbTx, *purchaseOrder); err != nil {
+		return nil, err
+	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit receive purchase order query in database due: %w", err)
+	}
+
+	return s.GetPurchaseOrder(ctx, receipt.OrderID)
+}
+
+// CancelPurchaseOrder close the purchase order, the goods which already received is kept in the stocks
+func (s *storage) CancelPurchaseOrder(ctx context.Context, input service.CancelPurchaseOrderInput) (*entity.PurchaseOrder, error) {
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for cancel purchase order query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
+
+	purchaseOrder, err := s.lockPurchaseOrder(ctx, dbTx, input.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if err = purchaseOrder.Cancel(input.CancelledAt); err != nil {
+		return nil, service.ErrPurchaseOrderClosed
+	}
+	if err = s.updatePurchaseOrderStatus(ctx, dbTx, *purchaseOrder); err != nil {
+		return nil, err
+	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit cancel purchase order query in database due: %w", err)
+	}
+
+	return s.GetPurchaseOrder(ctx, input.OrderID)
+}
+
+// lockPurchaseOrder get the purchase order without its items and lock its row until the database transaction finished
+func (s storage) lockPurchaseOrder(ctx context.Context, dbTx *sqlx.Tx, orderID int64) (*entity.PurchaseOrder, error) {
+	var orderRow PurchaseOrderRow
+	err := dbTx.GetContext(
+		ctx,
+		&orderRow,
+		`SELECT id, id_supplier, status, note, created_by, created_at, updated_at
+		FROM purchase_orders WHERE id = ? FOR UPDATE`,
+		orderID,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to lock purchase order due: %w", err)
+	}
+
+	purchaseOrder := orderRow.ToPurchaseOrderEntity()
+	return &purchaseOrder, nil
+}
+
+func (s storage) updatePurchaseOrderStatus(ctx context.Context, dbTx *sqlx.Tx, purchaseOrder entity.PurchaseOrder) error {
+	_, err := dbTx.ExecContext(
+		ctx,
+		"UPDATE purchase_orders SET status = ?, updated_at = ? WHERE id = ?",
+		purchaseOrder.Status,
+		purchaseOrder.UpdatedAt,
+		purchaseOrder.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to update purchase order status due: %w", err)
+	}
+	return nil
+}
+
+func (s *storage) CreateDelivery(ctx context.Context, delivery entity.Delivery) error {
+	// pending delivery has no tracking number yet, it's stored as NULL so it doesn't collide with other ones
+	_, err := s.client.ExecContext(
+		ctx,
+		`INSERT INTO deliveries (id_transaction, tracking_number, destination, price, status, created_at)
+		VALUES (?, NULLIF(?, ''), ?, ?, ?, ?)`,
+		delivery.TransactionID,
+		delivery.TrackingNumber,
+		delivery.Destination,
+		delivery.Price,
+		string(delivery.Status),
+		delivery.CreatedAt,
+	)
+	// concurrent pickup request for same transaction
+	if isDuplicateEntryError(err) {
+		return service.ErrDeliveryAlreadyRequested
+	}
+	if err != nil {
+		return fmt.Errorf("unable to execute insert query for delivery due: %w", err)
+	}
+
+	return nil
+}
+
+func (s *storage) UpdateDelivery(ctx context.Context, delivery entity.Delivery) error {
+	result, err := s.client.ExecContext(
+		ctx,
+		"UPDATE deliveries SET tracking_number = ?, price = ?, status = ? WHERE id_transaction = ? AND status = ?",
+		delivery.TrackingNumber,
+		delivery.Price,
+		string(delivery.Status),
+		delivery.TransactionID,
+		string(entity.DeliveryStatusPending),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to execute update query for delivery due: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("unable to get affected rows of delivery due: %w", err)
+	}
+	if affected == 0 {
+		return service.ErrDeliveryAlreadyRequested
+	}
+
+	return nil
+}
+
+func (s *storage) DeletePendingDelivery(ctx context.Context, transactionID int64) error {
+	_, err := s.client.ExecContext(
+		ctx,
+		"DELETE FROM deliveries WHERE id_transaction = ? AND status = ?",
+		transactionID,
+		string(entity.DeliveryStatusPending),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to execute delete query for pending delivery due: %w", err)
+	}
+
+	return nil
+}
+
+// CreatePayment store the pending payment of the shopping cart, it return ErrPaymentPending when the shopping cart
+// still has another payment which can be paid
+func (s *storage) CreatePayment(ctx context.Context, payment entity.Payment) (*entity.Payment, error) {
+	dbTx, err := s.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for create payment query: %w", err)
+	}
+	// Defer the transaction’s rollback. If the transaction succeeds, it will be committed before the function exits,
+	// making the deferred rollback call a no-op.
+	// If the transaction fails it won’t be committed, meaning that the rollback will be called as the function exits.
+	defer dbTx.Rollback()
+
+	if err = s.lockEditableShoppingCart(ctx, dbTx, payment.TransactionID); err != nil {
+		return nil, err
+	}
+
+	_, err = dbTx.ExecContext(
+		ctx,
+		`INSERT INTO payments
+			(reference, id_transaction, method, amount, status, qr_payload, voucher_code, created_at, expires_at, paid_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		payment.Reference,
+		payment.TransactionID,
+		string(payment.Method),
+		payment.Amount,
+		string(payment.Status),
+		payment.QRPayload,
+		payment.VoucherCode,
+		payment.CreatedAt,
+		payment.ExpiresAt,
+		payment.PaidAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute insert query for payment due: %w", err)
+	}
+
+	// commit changes
+	if err = dbTx.Commit(); err != nil {
+		return nil, fmt.Errorf("unable to commit create payment query in database due: %w", err)
+	}
+
+	return &payment, nil
+}
+
+func (s *storage) GetPayment(ctx context.Context, reference string) (*entity.Payment, error) {
+	var paymentRow PaymentRow
+	err := s.client.GetContext(
+		ctx,
+		&paymentRow,
+		`SELECT reference, id_transaction, method, amount, status, qr_payload, voucher_code, created_at, expires_at, paid_at
+		FROM payments WHERE reference = ?`,
+		reference,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, service.ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute select query for payment due: %w", err)
+	}
+
+	return paymentRow.ToPaymentEntity(), nil
+}
+
+func (s *storage) TruncateAllData(ctx context.Context) error {
+	// the transaction IDs is reused after truncated, so the stocks moved by the transactions is restored first
+	if err := s.restoreTransactionStocks(ctx); err != nil {
+		return err
+	}
+	_, err := s.client.ExecContext(ctx, "TRUNCATE transactions")
+	if err != nil {
+		return fmt.Errorf("unable to truncate shopping cart / transaction table due: %w", err)
+	}
+	_, err = s.client.ExecContext(ctx, "TRUNCATE transaction_details")
+	if err != nil {
+		return fmt.Errorf("unable to truncate shopping cart / transaction details table due: %w", err)
+	}
+	_, err = s.client.ExecContext(ctx, "TRUNCATE transaction_status_histories")
+	if err != nil {
+		return fmt.Errorf("unable to truncate transaction status histories table due: %w", err)
+	}
+	_, err = s.client.ExecContext(ctx, "TRUNCATE transaction_discounts")
+	if err != nil {
+		return fmt.Errorf("unable to truncate transaction discounts table due: %w", err)
+	}
+	_, err = s.client.ExecContext(ctx, "TRUNCATE refunds")
+	if err != nil {
+		return fmt.Errorf("unable to truncate refunds table due: %w", err)
+	}
+	_, err = s.client.ExecContext(ctx, "TRUNCATE refund_details")
+	if err != nil {
+		return fmt.Errorf("unable to truncate refund details table due: %w", err)
+	}
+	_, err = s.client.ExecContext(ctx, "TRUNCATE deliveries")
+	if err != nil {
+		return fmt.Errorf("unable to truncate deliveries table due: %w", err)
+	}
+	_, err = s.client.ExecContext(ctx, "TRUNCATE transaction_tenders")
+	if err != nil {
+		return fmt.Errorf("unable to truncate transaction tenders table due: %w", err)
+	}
+	_, err = s.client.ExecContext(ctx, "TRUNCATE payments")
+	if err != nil {
+		return fmt.Errorf("unable to truncate payments table due: %w", err)
+	}
+	// all shopping carts are gone, so nothing reserve the stocks anymore
+	_, err = s.client.ExecContext(ctx, "UPDATE goods SET reserved_stocks = 0")
+	if err != nil {
+		return fmt.Errorf("unable to reset reserved stocks of goods due: %w", err)
+	}
+	_, err = s.client.ExecContext(ctx, "UPDATE goods_variants SET reserved_stocks = 0")
+	if err != nil {
+		return fmt.Errorf("unable to reset reserved stocks of goods variants due: %w", err)
+	}
+	return s.resetStockLedger(ctx)
+}
+
+// restoreTransactionStocks undo the stock movements of the transactions i.e. sales and refunds on the goods and
+// variants stocks
+func (s storage) restoreTransactionStocks(ctx context.Context) error {
+	_, err := s.client.ExecContext(
+		ctx,
+		`UPDATE goods g
+		JOIN (
+			SELECT id_goods, SUM(quantity) AS quantity
+			FROM stock_movements
+			WHERE id_transaction IS NOT NULL AND id_variant = 0
+			GROUP BY id_goods
+		) m ON m.id_goods = g.id
+		SET g.stocks = g.stocks - m.quantity`,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to restore stocks of goods due: %w", err)
+	}
+	_, err = s.client.ExecContext(
+		ctx,
+		`UPDATE goods_variants v
+		JOIN (
+			SELECT id_variant, SUM(quantity) AS quantity
+			FROM stock_movements
+			WHERE id_transaction IS NOT NULL AND id_variant > 0
+			GROUP BY id_variant
+		) m ON m.id_variant = v.id
+		SET v.stocks = v.stocks - m.quantity`,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to restore stocks of goods variants due: %w", err)
+	}
+	return nil
+}
+
+// resetStockLedger start the stock ledger and batches over from the current stocks as opening balance, the same
+// way the existing stocks is recorded when the ledger and batches were introduced. Truncate doesn't fire the
+// append-only triggers of the ledger.
+func (s storage) resetStockLedger(ctx context.Context) error {
+	_, err := s.client.ExecContext(ctx, "TRUNCATE stock_movements")
+	if err != nil {
+		return fmt.Errorf("unable to truncate stock movements table due: %w", err)
+	}
+	_, err = s.client.ExecContext(ctx, "TRUNCATE stock_batches")
+	if err != nil {
+		return fmt.Errorf("unable to truncate stock batches table due: %w", err)
+	}
+
+	now := time.Now().Unix()
+	_, err = s.client.ExecContext(
+		ctx,
+		`INSERT INTO stock_movements (id_goods, id_variant, type, quantity, unit_cost, stocks_before, stocks_after, actor, reason, created_at)
+		SELECT id, 0, ?, stocks, average_cost, 0, stocks, ?, 'opening balance', ? FROM goods WHERE stocks <> 0
+		UNION ALL
+		SELECT id_goods, id, ?, stocks, average_cost, 0, stocks, ?, 'opening balance', ? FROM goods_variants WHERE stocks <> 0`,
+		entity.StockMovementAdjustment,
+		entity.StockActorSystem,
+		now,
+		entity.StockMovementAdjustment,
+		entity.StockActorSystem,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to record opening balance of stocks due: %w", err)
+	}
+	// the expiry of the stocks is unknown, so it's kept as single batch which doesn't expire
+	_, err = s.client.ExecContext(
+		ctx,
+		`INSERT INTO stock_batches (id_goods, id_variant, received_quantity, quantity, unit_cost, received_at)
+		SELECT g.id, 0, g.stocks, g.stocks, g.average_cost, ? FROM goods g
+		WHERE g.stocks > 0 AND NOT EXISTS (SELECT 1 FROM goods_variants v WHERE v.id_goods = g.id)
+		UNION ALL
+		SELECT id_goods, id, stocks, stocks, average_cost, ? FROM goods_variants WHERE stocks > 0`,
+		now,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to create opening stock batches due: %w", err)
+	}
+	return nil
+}
+
+// isDuplicateEntryError check whether the error caused by violation of unique key
+func isDuplicateEntryError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
@@ -2,8 +2,13 @@ package storagemysql_test
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
@@ -26,18 +31,93 @@ func TestGetGoods(mainT *testing.T) {
 		Offset: 0,
 		Limit:  5,
 		Sort:   service.SortDesc,
-		SortBy: "id",
+		SortBy: service.GoodsSortByID,
 	})
 	require.NoError(mainT, err)
 	require.NotZero(mainT, listOfGoods)
+	require.Equal(mainT, 7, listOfGoods[0].ID)
+
+	listOfGoods, err = strg.GetGoods(context.Background(), service.GetGoodsInput{
+		Limit:       10,
+		Sort:        service.SortAsc,
+		SortBy:      service.GoodsSortByPrice,
+		Name:        "pisang",
+		MaxPrice:    2000,
+		InStockOnly: true,
+	})
+	require.NoError(mainT, err)
+	require.Len(mainT, listOfGoods, 1)
+	require.Equal(mainT, "Pisang Goreng", listOfGoods[0].Name)
+
+	// wildcard in the name filter is matched literally
+	listOfGoods, err = strg.GetGoods(context.Background(), service.GetGoodsInput{
+		Limit:  10,
+		Sort:   service.SortAsc,
+		SortBy: service.GoodsSortByID,
+		Name:   "%",
+	})
+	require.NoError(mainT, err)
+	require.Empty(mainT, listOfGoods)
+
+	_, err = strg.GetGoods(context.Background(), service.GetGoodsInput{
+		Limit:  10,
+		SortBy: "price; DROP TABLE goods",
+	})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+
+	totalGoods, err := strg.CountGoods(context.Background(), service.GetGoodsInput{Name: "pisang"})
+	require.NoError(mainT, err)
+	require.Equal(mainT, 2, totalGoods)
+
+	// second page skip the whole first page
+	listOfGoods, err = strg.GetGoods(context.Background(), service.GetGoodsInput{
+		Offset: 3,
+		Limit:  3,
+		Sort:   service.SortAsc,
+		SortBy: service.GoodsSortByID,
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, 4, listOfGoods[0].ID)
+
+	// Bakwan & Pisang Goreng have the same price, the ID keep them in stable order
+	listOfGoods, err = strg.GetGoods(context.Background(), service.GetGoodsInput{
+		Limit:  10,
+		Sort:   service.SortAsc,
+		SortBy: service.GoodsSortByPrice,
+		After: &service.GoodsCursor{
+			Sort:   service.SortAsc,
+			SortBy: service.GoodsSortByPrice,
+			ID:     2,
+			Price:  1500,
+		},
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, 3, listOfGoods[0].ID)
+	require.Len(mainT, listOfGoods, 5)
+
+	// previous page is the goods right before the cursor, still in the requested order
+	listOfGoods, err = strg.GetGoods(context.Background(), service.GetGoodsInput{
+		Limit:  2,
+		Sort:   service.SortAsc,
+		SortBy: service.GoodsSortByPrice,
+		Before: &service.GoodsCursor{
+			Sort:     service.SortAsc,
+			SortBy:   service.GoodsSortByPrice,
+			ID:       6,
+			Price:    2500,
+			Backward: true,
+		},
+	})
+	require.NoError(mainT, err)
+	require.Len(mainT, listOfGoods, 2)
+	require.Equal(mainT, 3, listOfGoods[0].ID)
+	require.Equal(mainT, 4, listOfGoods[1].ID)
 }
 
 func TestGetExistingShoppingCart(mainT *testing.T) {
 	dbConn := initDB(mainT)
 	defer func() {
-		// clean transactions and transaction details table
-		dbConn.ExecContext(context.Background(), "TRUNCATE transactions")
-		dbConn.ExecContext(context.Background(), "TRUNCATE transaction_details")
+		cleanUpDatabase(dbConn)
 		dbConn.Close()
 	}()
 
@@ -286,9 +366,7 @@ func TestAddGoodToCart(mainT *testing.T) {
 	for _, testCase := range testCases {
 		mainT.Run(testCase.Name, func(t *testing.T) {
 			defer func() {
-				// clean transactions and transaction details table
-				dbConn.ExecContext(context.Background(), "TRUNCATE transactions")
-				dbConn.ExecContext(context.Background(), "TRUNCATE transaction_details")
+				cleanUpDatabase(dbConn)
 			}()
 
 			for _, input := range testCase.Input {
@@ -309,9 +387,7 @@ func TestAddGoodToCart(mainT *testing.T) {
 func TestCreateTransaction(mainT *testing.T) {
 	dbConn := initDB(mainT)
 	defer func() {
-		// clean transactions and transaction details table
-		dbConn.ExecContext(context.Background(), "TRUNCATE transactions")
-		dbConn.ExecContext(context.Background(), "TRUNCATE transaction_details")
+		cleanUpDatabase(dbConn)
 		dbConn.Close()
 	}()
 
@@ -342,25 +418,1821 @@ func TestCreateTransaction(mainT *testing.T) {
 	require.NoError(mainT, err)
 	require.NotNil(mainT, cartOutput)
 
+	// the payment is checked against the locked shopping cart, nothing is paid when it's not enough
+	_, err = strg.CreateTransaction(context.Background(), service.CreateTransactionInput{
+		CartID:        1,
+		PaymentAmount: cartOutput.TotalAmount - 1,
+	})
+	var insufficientPaymentErr entity.InsufficientPaymentError
+	require.ErrorAs(mainT, err, &insufficientPaymentErr)
+	require.Equal(mainT, cartOutput.TotalAmount, insufficientPaymentErr.TotalAmount)
+	_, err = strg.CreateTransaction(context.Background(), service.CreateTransactionInput{
+		CartID:  1,
+		Tenders: []entity.Tender{{Method: entity.PaymentMethodEWallet, Amount: cartOutput.TotalAmount + 500}},
+	})
+	var nonCashOverpaymentErr entity.NonCashOverpaymentError
+	require.ErrorAs(mainT, err, &nonCashOverpaymentErr)
+
 	// create new transaction
 	newTrx, err := strg.CreateTransaction(context.Background(), service.CreateTransactionInput{
+		CartID:         1,
+		PaymentAmount:  cartOutput.TotalAmount + 500,
+		IdempotencyKey: "pay-1",
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, int64(1), newTrx.ID)
+	require.Equal(mainT, entity.TransactionStatusPaid, newTrx.Status)
+	require.Equal(mainT, entity.Money(500), newTrx.ReturnAmount)
+	// payment without tenders is paid in cash
+	require.Equal(mainT, []entity.Tender{
+		{Method: entity.PaymentMethodCash, Amount: cartOutput.TotalAmount + 500},
+	}, newTrx.Tenders)
+
+	// the same shopping cart can't be paid twice
+	_, err = strg.CreateTransaction(context.Background(), service.CreateTransactionInput{
 		CartID:        1,
 		PaymentAmount: cartOutput.TotalAmount,
 	})
+	require.ErrorIs(mainT, err, service.ErrCartAlreadyPaid)
+
+	// paid transaction can be found by its idempotency key
+	paidTrx, err := strg.GetTransactionByIdempotencyKey(context.Background(), "pay-1")
 	require.NoError(mainT, err)
-	require.Equal(mainT, int64(1), newTrx.ID)
+	require.Equal(mainT, newTrx, paidTrx)
+
+	noTrx, err := strg.GetTransactionByIdempotencyKey(context.Background(), "pay-2")
+	require.NoError(mainT, err)
+	require.Nil(mainT, noTrx)
 }
 
-func initDB(mainT *testing.T) *sqlx.DB {
+func TestCreateTransactionWithTenders(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
 	ctx := context.Background()
-	sqlDSN := os.Getenv("DB_SQLDSN")
-	if len(sqlDSN) == 0 {
-		sqlDSN = "root:test1234@tcp(localhost:23306)/umkm?timeout=5s"
+	payments := [][]entity.Tender{
+		{
+			{Method: entity.PaymentMethodCash, Amount: 2000},
+			{Method: entity.PaymentMethodEWallet, Amount: 2000},
+		},
+		{{Method: entity.PaymentMethodTransfer, Amount: 3000}},
 	}
+	for i, tenders := range payments {
+		cart, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+			UserID:  100 + i,
+			Details: []entity.ShoppingCartDetail{{GoodsID: 1, TotalGoods: 1, GoodsPrice: 3000, CreatedAt: 1689873350}},
+		})
+		require.NoError(mainT, err)
 
-	dbConn, err := sqlx.ConnectContext(ctx, "mysql", sqlDSN)
+		var paymentAmount entity.Money
+		for _, tender := range tenders {
+			paymentAmount += tender.Amount
+		}
+		trx, err := strg.CreateTransaction(ctx, service.CreateTransactionInput{
+			CartID:        cart.ID,
+			PaymentAmount: paymentAmount,
+			Tenders:       tenders,
+		})
+		require.NoError(mainT, err)
+		require.Equal(mainT, tenders, trx.Tenders)
+	}
+
+	// the change of the first transaction is taken from its cash tender
+	summary, err := strg.GetSalesSummary(ctx, service.GetSalesSummaryInput{
+		From: time.Now().Add(-time.Hour).Unix(),
+		To:   time.Now().Add(time.Hour).Unix(),
+	})
 	require.NoError(mainT, err)
-	require.NotNil(mainT, dbConn)
+	require.Equal(mainT, entity.Money(6000), summary.GrossAmount)
+	require.Equal(mainT, []entity.TenderTotal{
+		{Method: entity.PaymentMethodCash, TotalTransactions: 1, Amount: 1000},
+		{Method: entity.PaymentMethodEWallet, TotalTransactions: 1, Amount: 2000},
+		{Method: entity.PaymentMethodTransfer, TotalTransactions: 1, Amount: 3000},
+	}, summary.Tenders)
+}
 
-	return dbConn
+func TestPayments(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	cart, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+		UserID:  100,
+		Details: []entity.ShoppingCartDetail{{GoodsID: 1, TotalGoods: 1, GoodsPrice: 3000, CreatedAt: 1689873350}},
+	})
+	require.NoError(mainT, err)
+
+	_, err = strg.GetPayment(ctx, "SIM-1")
+	require.ErrorIs(mainT, err, service.ErrPaymentNotFound)
+
+	payment := entity.Payment{
+		Reference:     "SIM-1",
+		TransactionID: cart.ID,
+		Method:        entity.PaymentMethodQRIS,
+		Amount:        3000,
+		Status:        entity.PaymentStatusPending,
+		QRPayload:     "000201010212",
+		CreatedAt:     1689873360,
+		ExpiresAt:     1689874260,
+	}
+	_, err = strg.CreatePayment(ctx, payment)
+	require.NoError(mainT, err)
+
+	storedPayment, err := strg.GetPayment(ctx, payment.Reference)
+	require.NoError(mainT, err)
+	require.Equal(mainT, payment, *storedPayment)
+
+	// the payment above already expired, so the shopping cart is only frozen by the new one
+	awaitedPayment := payment
+	awaitedPayment.Reference = "SIM-2"
+	awaitedPayment.ExpiresAt = time.Now().Add(15 * time.Minute).Unix()
+	_, err = strg.CreatePayment(ctx, awaitedPayment)
+	require.NoError(mainT, err)
+	awaitedPayment.Reference = "SIM-3"
+	_, err = strg.CreatePayment(ctx, awaitedPayment)
+	require.ErrorIs(mainT, err, service.ErrPaymentPending)
+
+	_, err = strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+		ID:      cart.ID,
+		UserID:  100,
+		Details: []entity.ShoppingCartDetail{{GoodsID: 2, TotalGoods: 1, GoodsPrice: 1500, CreatedAt: 1689873370}},
+	})
+	require.ErrorIs(mainT, err, service.ErrPaymentPending)
+	_, err = strg.SetCartGoodsQuantity(ctx, service.SetCartGoodsQuantityInput{CartID: cart.ID, GoodsID: 1, Total: 2})
+	require.ErrorIs(mainT, err, service.ErrPaymentPending)
+	_, err = strg.ClearShoppingCart(ctx, cart.ID)
+	require.ErrorIs(mainT, err, service.ErrPaymentPending)
+	err = strg.DeleteShoppingCart(ctx, cart.ID)
+	require.ErrorIs(mainT, err, service.ErrPaymentPending)
+
+	totalExpired, err := strg.ExpireShoppingCarts(ctx, service.ExpireShoppingCartsInput{
+		LastActivityBefore: time.Now().Add(time.Hour).Unix(),
+		Limit:              10,
+	})
+	require.NoError(mainT, err)
+	require.Zero(mainT, totalExpired)
+
+	// the payment is paid along with its transaction
+	trx, err := strg.CreateTransaction(ctx, service.CreateTransactionInput{
+		CartID:           cart.ID,
+		PaymentAmount:    payment.Amount,
+		Tenders:          []entity.Tender{{Method: payment.Method, Amount: payment.Amount}},
+		IdempotencyKey:   payment.Reference,
+		PaymentReference: payment.Reference,
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.TransactionStatusPaid, trx.Status)
+
+	storedPayment, err = strg.GetPayment(ctx, payment.Reference)
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.PaymentStatusPaid, storedPayment.Status)
+	require.NotZero(mainT, storedPayment.PaidAt)
+}
+
+func TestUpdateTransactionStatus(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	cart, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+		UserID: 100,
+		Details: []entity.ShoppingCartDetail{
+			{GoodsID: 1, TotalGoods: 1, GoodsPrice: 3000, CreatedAt: 1689873350},
+		},
+	})
+	require.NoError(mainT, err)
+
+	// shopping cart must be paid first
+	_, err = strg.UpdateTransactionStatus(ctx, service.UpdateTransactionStatusInput{
+		TransactionID: cart.ID,
+		Status:        entity.TransactionStatusPreparing,
+		ChangedAt:     1689873400,
+	})
+	require.ErrorAs(mainT, err, &entity.InvalidStatusTransitionError{})
+
+	_, err = strg.CreateTransaction(ctx, service.CreateTransactionInput{
+		CartID:        cart.ID,
+		PaymentAmount: 3000,
+	})
+	require.NoError(mainT, err)
+
+	order, err := strg.UpdateTransactionStatus(ctx, service.UpdateTransactionStatusInput{
+		TransactionID: cart.ID,
+		Status:        entity.TransactionStatusPreparing,
+		ChangedAt:     1689873500,
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.TransactionStatusPreparing, order.Status)
+	require.Equal(mainT, entity.Money(3000), order.PaymentAmount)
+
+	orders, err := strg.GetTransactions(ctx, service.GetTransactionsInput{
+		Statuses: []entity.TransactionStatus{entity.TransactionStatusPreparing},
+		Limit:    10,
+	})
+	require.NoError(mainT, err)
+	require.Len(mainT, orders, 1)
+	require.Equal(mainT, cart.ID, orders[0].ID)
+
+	// every status change is recorded, started from the shopping cart creation
+	history, err := strg.GetTransactionStatusHistory(ctx, cart.ID)
+	require.NoError(mainT, err)
+	require.Len(mainT, history, 3)
+	require.Nil(mainT, history[0].From)
+	require.Equal(mainT, entity.TransactionStatusCart, history[0].To)
+	require.Equal(mainT, entity.TransactionStatusCart, *history[1].From)
+	require.Equal(mainT, entity.TransactionStatusPaid, history[1].To)
+	require.Equal(mainT, entity.TransactionStatusPaid, *history[2].From)
+	require.Equal(mainT, entity.TransactionStatusPreparing, history[2].To)
+	require.Equal(mainT, int64(1689873500), history[2].ChangedAt)
+}
+
+func TestCreateRefund(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	cart, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+		UserID: 100,
+		Details: []entity.ShoppingCartDetail{
+			{GoodsID: 1, TotalGoods: 3, GoodsPrice: 3000, CreatedAt: 1689873350},
+			{GoodsID: 2, TotalGoods: 2, GoodsPrice: 1500, CreatedAt: 1689873350},
+		},
+	})
+	require.NoError(mainT, err)
+	paidTrx, err := strg.CreateTransaction(ctx, service.CreateTransactionInput{
+		CartID:        cart.ID,
+		PaymentAmount: 20000,
+	})
+	require.NoError(mainT, err)
+
+	// partial refund put the returned goods back into the stocks
+	refund, err := strg.CreateRefund(ctx, service.CreateRefundInput{
+		TransactionID: cart.ID,
+		Items:         []entity.RefundItem{{GoodsID: 2, TotalGoods: 1}, {GoodsID: 1, TotalGoods: 1}},
+		Reason:        "spilled",
+		Status:        entity.TransactionStatusRefunded,
+		CreatedAt:     time.Now().Unix(),
+	})
+	require.NoError(mainT, err)
+	require.Positive(mainT, refund.ID)
+	require.Equal(mainT, entity.Money(4500), refund.Amount)
+
+	goods, err := strg.GetGoodsByID(ctx, 1)
+	require.NoError(mainT, err)
+	require.Equal(mainT, 98, goods.Stocks)
+
+	trx, err := strg.GetTransaction(ctx, cart.ID)
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.TransactionStatusPaid, trx.Status)
+	require.Equal(mainT, entity.Money(4500), trx.RefundedAmount)
+	require.Equal(mainT, paidTrx.PaymentAmount, trx.PaymentAmount)
+
+	// returned goods can't be refunded twice
+	_, err = strg.CreateRefund(ctx, service.CreateRefundInput{
+		TransactionID: cart.ID,
+		Items:         []entity.RefundItem{{GoodsID: 2, TotalGoods: 2}},
+		Status:        entity.TransactionStatusRefunded,
+		CreatedAt:     time.Now().Unix(),
+	})
+	require.ErrorAs(mainT, err, &entity.RefundExceedsPurchaseError{})
+
+	// cancellation refund the rest of the goods
+	refund, err = strg.CreateRefund(ctx, service.CreateRefundInput{
+		TransactionID: cart.ID,
+		Status:        entity.TransactionStatusCancelled,
+		CreatedAt:     time.Now().Unix(),
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money(7500), refund.Amount)
+
+	trx, err = strg.GetTransaction(ctx, cart.ID)
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.TransactionStatusCancelled, trx.Status)
+	require.Equal(mainT, trx.TotalAmount, trx.RefundedAmount)
+
+	summary, err := strg.GetSalesSummary(ctx, service.GetSalesSummaryInput{
+		From: time.Now().Add(-time.Hour).Unix(),
+		To:   time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, 1, summary.TotalTransactions)
+	require.Equal(mainT, entity.Money(12000), summary.GrossAmount)
+	require.Equal(mainT, 2, summary.TotalRefunds)
+	require.Equal(mainT, entity.Money(0), summary.NetAmount())
+	require.Equal(mainT, []entity.TenderTotal{
+		{Method: entity.PaymentMethodCash, TotalTransactions: 1, Amount: 12000},
+	}, summary.Tenders)
+}
+
+func TestCreateDelivery(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	getDelivery := func(transactionID int64) *entity.Delivery {
+		var delivery entity.Delivery
+		var trackingNumber sql.NullString
+		err := dbConn.QueryRowContext(
+			ctx,
+			"SELECT id_transaction, tracking_number, destination, price, status, created_at FROM deliveries WHERE id_transaction = ?",
+			transactionID,
+		).Scan(&delivery.TransactionID, &trackingNumber, &delivery.Destination, &delivery.Price, &delivery.Status, &delivery.CreatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		require.NoError(mainT, err)
+		delivery.TrackingNumber = trackingNumber.String
+		return &delivery
+	}
+	require.Nil(mainT, getDelivery(1))
+
+	// pending deliveries have no tracking number yet
+	delivery := entity.Delivery{
+		TransactionID: 1,
+		Destination:   3171,
+		Status:        entity.DeliveryStatusPending,
+		CreatedAt:     1689873350,
+	}
+	err = strg.CreateDelivery(ctx, delivery)
+	require.NoError(mainT, err)
+	err = strg.CreateDelivery(ctx, entity.Delivery{
+		TransactionID: 2,
+		Destination:   3171,
+		Status:        entity.DeliveryStatusPending,
+		CreatedAt:     1689873350,
+	})
+	require.NoError(mainT, err)
+
+	// the pending delivery hold the transaction
+	err = strg.CreateDelivery(ctx, delivery)
+	require.ErrorIs(mainT, err, service.ErrDeliveryAlreadyRequested)
+
+	require.Equal(mainT, &delivery, getDelivery(1))
+
+	delivery.TrackingNumber = "NRM000000000001"
+	delivery.Price = 15000
+	delivery.Status = entity.DeliveryStatusRequested
+	err = strg.UpdateDelivery(ctx, delivery)
+	require.NoError(mainT, err)
+
+	require.Equal(mainT, &delivery, getDelivery(1))
+
+	// the transaction only delivered once
+	err = strg.UpdateDelivery(ctx, delivery)
+	require.ErrorIs(mainT, err, service.ErrDeliveryAlreadyRequested)
+	err = strg.DeletePendingDelivery(ctx, 1)
+	require.NoError(mainT, err)
+	require.Equal(mainT, &delivery, getDelivery(1))
+
+	// failed pickup release the transaction
+	err = strg.DeletePendingDelivery(ctx, 2)
+	require.NoError(mainT, err)
+	require.Nil(mainT, getDelivery(2))
+}
+
+func TestUpdateGoodsStock(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	goods, err := strg.UpdateGoodsStock(context.Background(), service.UpdateGoodsStockInput{
+		Action:  service.IncreaseStock,
+		GoodsID: 1,
+		Total:   10,
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, 110, goods.Stocks)
+
+	goods, err = strg.UpdateGoodsStock(context.Background(), service.UpdateGoodsStockInput{
+		Action:    service.DecreaseStock,
+		GoodsName: "Kopi",
+		Total:     110,
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, 0, goods.Stocks)
+
+	_, err = strg.UpdateGoodsStock(context.Background(), service.UpdateGoodsStockInput{
+		Action:  service.DecreaseStock,
+		GoodsID: 1,
+		Total:   1,
+	})
+	require.ErrorAs(mainT, err, &entity.InsufficientStockError{})
+
+	_, err = strg.UpdateGoodsStock(context.Background(), service.UpdateGoodsStockInput{
+		Action:  service.IncreaseStock,
+		GoodsID: 999,
+		Total:   1,
+	})
+	require.ErrorIs(mainT, err, service.ErrGoodsNotFound)
+}
+
+func TestManageGoods(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	goods, err := strg.CreateGoods(context.Background(), entity.Goods{
+		Name:   "Tahu Isi",
+		Stocks: 20,
+		Price:  1500,
+	})
+	require.NoError(mainT, err)
+	require.NotZero(mainT, goods.ID)
+
+	_, err = strg.CreateGoods(context.Background(), entity.Goods{Name: "Kopi", Price: 3000})
+	require.ErrorIs(mainT, err, service.ErrGoodsNameAlreadyExists)
+
+	goods.Price = 2000
+	updatedGoods, err := strg.UpdateGoods(context.Background(), *goods)
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money(2000), updatedGoods.Price)
+	require.Equal(mainT, 20, updatedGoods.Stocks)
+
+	err = strg.DeleteGoods(context.Background(), goods.ID, time.Now().Unix())
+	require.NoError(mainT, err)
+
+	err = strg.DeleteGoods(context.Background(), goods.ID, time.Now().Unix())
+	require.ErrorIs(mainT, err, service.ErrGoodsNotFound)
+
+	deletedGoods, err := strg.GetGoodsByID(context.Background(), goods.ID)
+	require.NoError(mainT, err)
+	require.True(mainT, deletedGoods.IsDeleted())
+
+	// name of deleted goods can be used again
+	_, err = strg.CreateGoods(context.Background(), entity.Goods{Name: "Tahu Isi", Price: 1500})
+	require.NoError(mainT, err)
+}
+
+func TestGoodsVariants(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	category, err := strg.CreateCategory(ctx, entity.Category{Name: "Minuman"})
+	require.NoError(mainT, err)
+	require.NotZero(mainT, category.ID)
+
+	_, err = strg.CreateCategory(ctx, entity.Category{Name: "Minuman"})
+	require.ErrorIs(mainT, err, service.ErrCategoryAlreadyExists)
+
+	_, err = strg.UpdateGoods(ctx, entity.Goods{ID: 1, Name: "Kopi", Price: 2000, CategoryID: category.ID})
+	require.NoError(mainT, err)
+	_, err = strg.UpdateGoods(ctx, entity.Goods{ID: 1, Name: "Kopi", Price: 2000, CategoryID: 999})
+	require.ErrorIs(mainT, err, service.ErrCategoryNotFound)
+
+	variant, err := strg.CreateGoodsVariant(ctx, entity.GoodsVariant{
+		GoodsID:    1,
+		Name:       "Es",
+		PriceDelta: 1000,
+		Stocks:     5,
+	})
+	require.NoError(mainT, err)
+	require.NotZero(mainT, variant.ID)
+
+	_, err = strg.CreateGoodsVariant(ctx, entity.GoodsVariant{GoodsID: 1, Name: "Es"})
+	require.ErrorIs(mainT, err, service.ErrVariantAlreadyExists)
+	_, err = strg.CreateGoodsVariant(ctx, entity.GoodsVariant{GoodsID: 1, Name: "Gratis", PriceDelta: -2000})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+
+	// the goods price must keep every variant price above zero
+	_, err = strg.UpdateGoods(ctx, entity.Goods{ID: 1, Name: "Kopi", Price: 1000, CategoryID: category.ID})
+	require.NoError(mainT, err)
+	_, err = strg.CreateGoodsVariant(ctx, entity.GoodsVariant{GoodsID: 1, Name: "Kecil", PriceDelta: -500})
+	require.NoError(mainT, err)
+	_, err = strg.UpdateGoods(ctx, entity.Goods{ID: 1, Name: "Kopi", Price: 500, CategoryID: category.ID})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+	_, err = strg.UpdateGoods(ctx, entity.Goods{ID: 1, Name: "Kopi", Price: 2000, CategoryID: category.ID})
+	require.NoError(mainT, err)
+
+	goods, err := strg.GetGoodsByID(ctx, 1)
+	require.NoError(mainT, err)
+	require.Equal(mainT, category.ID, goods.CategoryID)
+	require.Len(mainT, goods.Variants, 2)
+
+	// the variant stocks is reserved instead of the goods stocks
+	cart, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+		UserID: 100,
+		Details: []entity.ShoppingCartDetail{
+			{
+				GoodsID:    1,
+				VariantID:  variant.ID,
+				TotalGoods: 3,
+				GoodsPrice: 3000,
+				CreatedAt:  1689873350,
+			},
+		},
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money(9000), cart.TotalAmount)
+
+	goods, err = strg.GetGoodsByID(ctx, 1)
+	require.NoError(mainT, err)
+	require.Equal(mainT, 0, goods.ReservedStocks)
+	require.Equal(mainT, 3, goods.Variants[0].ReservedStocks)
+
+	_, err = strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+		ID:     cart.ID,
+		UserID: 100,
+		Details: []entity.ShoppingCartDetail{
+			{GoodsID: 1, VariantID: variant.ID, TotalGoods: 3, GoodsPrice: 3000, CreatedAt: 1689873350},
+		},
+	})
+	require.ErrorAs(mainT, err, &entity.InsufficientStockError{})
+
+	_, err = strg.CreateTransaction(ctx, service.CreateTransactionInput{
+		CartID:        cart.ID,
+		PaymentAmount: 10000,
+	})
+	require.NoError(mainT, err)
+
+	goods, err = strg.GetGoodsByID(ctx, 1)
+	require.NoError(mainT, err)
+	require.Equal(mainT, 100, goods.Stocks)
+	require.Equal(mainT, 2, goods.Variants[0].Stocks)
+	require.Equal(mainT, 0, goods.Variants[0].ReservedStocks)
+
+	// goods which has variants hold its stocks per variant
+	_, err = strg.UpdateGoodsStock(ctx, service.UpdateGoodsStockInput{Action: service.IncreaseStock, GoodsID: 1, Total: 5})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+	goods, err = strg.UpdateGoodsStock(ctx, service.UpdateGoodsStockInput{
+		Action:    service.IncreaseStock,
+		GoodsID:   1,
+		VariantID: variant.ID,
+		Total:     5,
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, 100, goods.Stocks)
+	updatedVariant, ok := goods.FindVariant(variant.ID)
+	require.True(mainT, ok)
+	require.Equal(mainT, 7, updatedVariant.Stocks)
+
+	menuGoods, err := strg.GetMenuGoods(ctx)
+	require.NoError(mainT, err)
+	require.Len(mainT, menuGoods, 7)
+}
+
+func TestRecipes(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	beans, err := strg.CreateGoods(ctx, entity.Goods{Name: "Biji Kopi", Stocks: 100, Price: 200, IsRawMaterial: true, Unit: "gram"})
+	require.NoError(mainT, err)
+	sugar, err := strg.CreateGoods(ctx, entity.Goods{Name: "Gula Aren", Stocks: 50, Price: 50, IsRawMaterial: true, Unit: "gram"})
+	require.NoError(mainT, err)
+	kopiGulaAren, err := strg.CreateGoods(ctx, entity.Goods{Name: "Kopi Gula Aren", Price: 18000})
+	require.NoError(mainT, err)
+
+	recipe, err := strg.SetRecipe(ctx, entity.Recipe{
+		GoodsID: kopiGulaAren.ID,
+		Ingredients: []entity.RecipeIngredient{
+			{IngredientID: beans.ID, Quantity: 18},
+			{IngredientID: sugar.ID, Quantity: 20},
+		},
+	})
+	require.NoError(mainT, err)
+	require.Len(mainT, recipe.Ingredients, 2)
+	require.Equal(mainT, "gram", recipe.Ingredients[0].Unit)
+	require.Equal(mainT, 2, recipe.Servings)
+
+	// goods made to order doesn't reserve its own stocks, its raw materials is reserved instead
+	cart, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+		UserID: 100,
+		Details: []entity.ShoppingCartDetail{
+			{GoodsID: kopiGulaAren.ID, TotalGoods: 2, GoodsPrice: 18000, CreatedAt: 1689873350},
+		},
+	})
+	require.NoError(mainT, err)
+	requireReservedStocks := func(t *testing.T, goodsID int, expected int) {
+		goods, err := strg.GetGoodsByID(ctx, goodsID)
+		require.NoError(t, err)
+		require.Equal(t, expected, goods.ReservedStocks)
+	}
+	requireReservedStocks(mainT, beans.ID, 36)
+	requireReservedStocks(mainT, sugar.ID, 40)
+
+	otherCart := &entity.ShoppingCart{
+		UserID: 200,
+		Details: []entity.ShoppingCartDetail{
+			{GoodsID: kopiGulaAren.ID, TotalGoods: 1, GoodsPrice: 18000, CreatedAt: 1689873350},
+		},
+	}
+	_, err = strg.AddGoodToCart(ctx, otherCart)
+	require.ErrorAs(mainT, err, &entity.InsufficientStockError{})
+
+	// less servings release the raw materials for another cart
+	_, err = strg.SetCartGoodsQuantity(ctx, service.SetCartGoodsQuantityInput{CartID: cart.ID, GoodsID: kopiGulaAren.ID, Total: 1})
+	require.NoError(mainT, err)
+	requireReservedStocks(mainT, sugar.ID, 20)
+	otherCart, err = strg.AddGoodToCart(ctx, otherCart)
+	require.NoError(mainT, err)
+	requireReservedStocks(mainT, sugar.ID, 40)
+	_, err = strg.ClearShoppingCart(ctx, otherCart.ID)
+	require.NoError(mainT, err)
+	requireReservedStocks(mainT, sugar.ID, 20)
+	_, err = strg.SetCartGoodsQuantity(ctx, service.SetCartGoodsQuantityInput{CartID: cart.ID, GoodsID: kopiGulaAren.ID, Total: 2})
+	require.NoError(mainT, err)
+
+	// goods in unpaid cart can't go back holding its own stocks
+	_, err = strg.SetRecipe(ctx, entity.Recipe{GoodsID: kopiGulaAren.ID})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+
+	// the reserved raw materials follow the new recipe
+	_, err = strg.SetRecipe(ctx, entity.Recipe{
+		GoodsID:     kopiGulaAren.ID,
+		Ingredients: []entity.RecipeIngredient{{IngredientID: beans.ID, Quantity: 18}, {IngredientID: sugar.ID, Quantity: 15}},
+	})
+	require.NoError(mainT, err)
+	requireReservedStocks(mainT, sugar.ID, 30)
+	_, err = strg.SetRecipe(ctx, entity.Recipe{
+		GoodsID:     kopiGulaAren.ID,
+		Ingredients: []entity.RecipeIngredient{{IngredientID: beans.ID, Quantity: 18}, {IngredientID: sugar.ID, Quantity: 20}},
+	})
+	require.NoError(mainT, err)
+	requireReservedStocks(mainT, sugar.ID, 40)
+
+	_, err = strg.UpdateGoodsStock(ctx, service.UpdateGoodsStockInput{
+		GoodsID: kopiGulaAren.ID,
+		Action:  service.IncreaseStock,
+		Total:   10,
+	})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+
+	_, err = strg.CreateTransaction(ctx, service.CreateTransactionInput{
+		CartID:        cart.ID,
+		PaymentAmount: 36000,
+	})
+	require.NoError(mainT, err)
+
+	goods, err := strg.GetGoodsByID(ctx, beans.ID)
+	require.NoError(mainT, err)
+	require.Equal(mainT, 64, goods.Stocks)
+	require.Equal(mainT, 0, goods.ReservedStocks)
+	goods, err = strg.GetGoodsByID(ctx, sugar.ID)
+	require.NoError(mainT, err)
+	require.Equal(mainT, 10, goods.Stocks)
+	require.Equal(mainT, 0, goods.ReservedStocks)
+	goods, err = strg.GetGoodsByID(ctx, kopiGulaAren.ID)
+	require.NoError(mainT, err)
+	require.Equal(mainT, 0, goods.Stocks)
+	require.Equal(mainT, 0, goods.Recipe.Servings)
+
+	menuGoods, err := strg.GetMenuGoods(ctx)
+	require.NoError(mainT, err)
+	require.Len(mainT, menuGoods, 8)
+}
+
+func TestStockMovements(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	_, err = strg.UpdateGoodsStock(ctx, service.UpdateGoodsStockInput{
+		GoodsID: 1,
+		Action:  service.IncreaseStock,
+		Total:   20,
+		Actor:   "budi",
+		Reason:  "weekly restock",
+	})
+	require.NoError(mainT, err)
+	_, err = strg.UpdateGoodsStock(ctx, service.UpdateGoodsStockInput{
+		GoodsID: 1,
+		Action:  service.WasteStock,
+		Total:   5,
+		Actor:   "budi",
+		Reason:  "expired",
+	})
+	require.NoError(mainT, err)
+
+	cart, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+		UserID: 100,
+		Details: []entity.ShoppingCartDetail{
+			{GoodsID: 1, TotalGoods: 3, GoodsPrice: 3000, CreatedAt: 1689873350},
+		},
+	})
+	require.NoError(mainT, err)
+	_, err = strg.CreateTransaction(ctx, service.CreateTransactionInput{
+		CartID:        cart.ID,
+		PaymentAmount: 10000,
+	})
+	require.NoError(mainT, err)
+	_, err = strg.CreateRefund(ctx, service.CreateRefundInput{
+		TransactionID: cart.ID,
+		Items:         []entity.RefundItem{{GoodsID: 1, TotalGoods: 1}},
+		Reason:        "spilled",
+		Status:        entity.TransactionStatusRefunded,
+		CreatedAt:     time.Now().Unix(),
+	})
+	require.NoError(mainT, err)
+
+	movements, err := strg.GetStockMovements(ctx, service.GetStockMovementsInput{
+		GoodsID: 1,
+		From:    0,
+		To:      time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(mainT, err)
+	// opening balance, restock, wastage, sale and refund
+	require.Len(mainT, movements, 5)
+	require.Equal(mainT, entity.StockMovementRestock, movements[1].Type)
+	require.Equal(mainT, "budi", movements[1].Actor)
+	require.Equal(mainT, 100, movements[1].Before)
+	require.Equal(mainT, 120, movements[1].After)
+	require.Equal(mainT, entity.StockMovementWastage, movements[2].Type)
+	require.Equal(mainT, -5, movements[2].Quantity)
+	require.Equal(mainT, entity.StockMovementSale, movements[3].Type)
+	require.Equal(mainT, -3, movements[3].Quantity)
+	require.Equal(mainT, cart.ID, movements[3].TransactionID)
+	require.Equal(mainT, entity.StockActorSystem, movements[3].Actor)
+	require.Equal(mainT, entity.StockMovementRefund, movements[4].Type)
+	require.Equal(mainT, "spilled", movements[4].Reason)
+	require.Equal(mainT, 113, movements[4].After)
+
+	drifts, err := strg.GetStockDrifts(ctx)
+	require.NoError(mainT, err)
+	require.Empty(mainT, drifts)
+
+	// stocks changed outside of the storage is reported as drift
+	_, err = dbConn.ExecContext(ctx, "UPDATE goods SET stocks = stocks + 2 WHERE id = 2")
+	require.NoError(mainT, err)
+	drifts, err = strg.GetStockDrifts(ctx)
+	require.NoError(mainT, err)
+	require.Len(mainT, drifts, 1)
+	require.Equal(mainT, 2, drifts[0].GoodsID)
+	require.Equal(mainT, 2, drifts[0].Drift())
+
+	// the ledger can't be rewritten
+	_, err = dbConn.ExecContext(ctx, "DELETE FROM stock_movements WHERE id_goods = 1")
+	require.Error(mainT, err)
+}
+
+func TestTruncateAllData(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	now := time.Now()
+	_, err = strg.UpdateGoodsStock(ctx, service.UpdateGoodsStockInput{
+		GoodsID:   1,
+		Action:    service.IncreaseStock,
+		Total:     20,
+		Actor:     "budi",
+		ExpiresAt: now.AddDate(0, 0, 1).Unix(),
+	})
+	require.NoError(mainT, err)
+	cart, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+		UserID: 100,
+		Details: []entity.ShoppingCartDetail{
+			{GoodsID: 1, TotalGoods: 3, GoodsPrice: 3000, CreatedAt: 1689873350},
+		},
+	})
+	require.NoError(mainT, err)
+	_, err = strg.CreateTransaction(ctx, service.CreateTransactionInput{
+		CartID:        cart.ID,
+		PaymentAmount: 10000,
+	})
+	require.NoError(mainT, err)
+
+	err = strg.TruncateAllData(ctx)
+	require.NoError(mainT, err)
+
+	// the sold stocks is restored, the restock is kept
+	goods, err := strg.GetGoodsByID(ctx, 1)
+	require.NoError(mainT, err)
+	require.Equal(mainT, 120, goods.Stocks)
+	require.Equal(mainT, 0, goods.ReservedStocks)
+
+	movements, err := strg.GetStockMovements(ctx, service.GetStockMovementsInput{
+		GoodsID: 1,
+		From:    0,
+		To:      now.Add(time.Hour).Unix(),
+	})
+	require.NoError(mainT, err)
+	require.Len(mainT, movements, 1)
+	require.Equal(mainT, entity.StockMovementAdjustment, movements[0].Type)
+	require.Equal(mainT, "opening balance", movements[0].Reason)
+	require.Equal(mainT, 120, movements[0].After)
+
+	// the current stocks is kept as batches which doesn't expire
+	batches, err := strg.GetExpiringStockBatches(ctx, service.GetExpiringStockBatchesInput{
+		ExpiresUntil: now.AddDate(0, 0, 3).Unix(),
+	})
+	require.NoError(mainT, err)
+	require.Empty(mainT, batches)
+
+	drifts, err := strg.GetStockDrifts(ctx)
+	require.NoError(mainT, err)
+	require.Empty(mainT, drifts)
+}
+
+func TestGetLowStocks(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	goods, err := strg.CreateGoods(ctx, entity.Goods{Name: "Tahu Isi", Stocks: 20, Price: 1500, ReorderPoint: 10})
+	require.NoError(mainT, err)
+	require.Equal(mainT, 10, goods.ReorderPoint)
+
+	lowStocks, err := strg.GetLowStocks(ctx)
+	require.NoError(mainT, err)
+	require.Empty(mainT, lowStocks)
+
+	_, err = strg.UpdateGoodsStock(ctx, service.UpdateGoodsStockInput{
+		GoodsID: goods.ID,
+		Action:  service.DecreaseStock,
+		Total:   10,
+	})
+	require.NoError(mainT, err)
+
+	lowStocks, err = strg.GetLowStocks(ctx)
+	require.NoError(mainT, err)
+	require.Len(mainT, lowStocks, 1)
+	require.Equal(mainT, goods.ID, lowStocks[0].GoodsID)
+	require.Equal(mainT, 10, lowStocks[0].Stocks)
+	require.Equal(mainT, 10, lowStocks[0].ReorderPoint)
+
+	// lowering the reorder point clear the low stock
+	goods.ReorderPoint = 5
+	updatedGoods, err := strg.UpdateGoods(ctx, *goods)
+	require.NoError(mainT, err)
+	require.Equal(mainT, 5, updatedGoods.ReorderPoint)
+	lowStocks, err = strg.GetLowStocks(ctx)
+	require.NoError(mainT, err)
+	require.Empty(mainT, lowStocks)
+}
+
+func TestStockCounts(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	stockCount, err := strg.CreateStockCount(ctx, entity.StockCount{
+		Status:    entity.StockCountOpen,
+		OpenedBy:  "budi",
+		CreatedAt: time.Now().Unix(),
+	})
+	require.NoError(mainT, err)
+	require.Positive(mainT, stockCount.ID)
+
+	_, err = strg.SetStockCountItems(ctx, service.SetStockCountItemsInput{
+		CountID: stockCount.ID,
+		Items:   []entity.StockCountItem{{GoodsID: 99, CountedStocks: 1}},
+	})
+	require.ErrorIs(mainT, err, service.ErrGoodsNotFound)
+
+	_, err = strg.SetStockCountItems(ctx, service.SetStockCountItemsInput{
+		CountID: stockCount.ID,
+		Items: []entity.StockCountItem{
+			{GoodsID: 2, CountedStocks: 40},
+			{GoodsID: 1, CountedStocks: 100},
+		},
+	})
+	require.NoError(mainT, err)
+	// counting the same goods again replace its counted quantity
+	stockCount, err = strg.SetStockCountItems(ctx, service.SetStockCountItemsInput{
+		CountID: stockCount.ID,
+		Items:   []entity.StockCountItem{{GoodsID: 3, CountedStocks: 52}, {GoodsID: 2, CountedStocks: 42}},
+	})
+	require.NoError(mainT, err)
+	require.Len(mainT, stockCount.Items, 3)
+	require.Equal(mainT, "Pisang Goreng", stockCount.Items[1].Name)
+	require.Equal(mainT, 45, stockCount.Items[1].SystemStocks)
+	require.Equal(mainT, -3, stockCount.Items[1].Variance())
+	require.Equal(mainT, entity.Money(-4500), stockCount.Items[1].VarianceValue())
+	require.Equal(mainT, entity.Money(-1500), stockCount.TotalVarianceValue())
+
+	stockCount, err = strg.ApplyStockCount(ctx, service.ApplyStockCountInput{
+		CountID:    stockCount.ID,
+		ApprovedBy: "siti",
+		ApprovedAt: time.Now().Unix(),
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.StockCountApproved, stockCount.Status)
+	require.Equal(mainT, "siti", stockCount.ApprovedBy)
+
+	goods, err := strg.GetGoodsByID(ctx, 2)
+	require.NoError(mainT, err)
+	require.Equal(mainT, 42, goods.Stocks)
+	goods, err = strg.GetGoodsByID(ctx, 3)
+	require.NoError(mainT, err)
+	require.Equal(mainT, 52, goods.Stocks)
+
+	// variance of the approved stock count is kept as is
+	stockCount, err = strg.GetStockCount(ctx, stockCount.ID)
+	require.NoError(mainT, err)
+	require.Equal(mainT, -3, stockCount.Items[1].Variance())
+
+	movements, err := strg.GetStockMovements(ctx, service.GetStockMovementsInput{
+		GoodsID: 2,
+		From:    0,
+		To:      time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.StockMovementAdjustment, movements[len(movements)-1].Type)
+	require.Equal(mainT, "siti", movements[len(movements)-1].Actor)
+
+	_, err = strg.ApplyStockCount(ctx, service.ApplyStockCountInput{CountID: stockCount.ID, ApprovedBy: "siti"})
+	require.ErrorIs(mainT, err, service.ErrStockCountApproved)
+	_, err = strg.SetStockCountItems(ctx, service.SetStockCountItemsInput{
+		CountID: stockCount.ID,
+		Items:   []entity.StockCountItem{{GoodsID: 1, CountedStocks: 1}},
+	})
+	require.ErrorIs(mainT, err, service.ErrStockCountApproved)
+
+	// shrinkage below the reserved stocks is still posted as counted
+	stockCount, err = strg.CreateStockCount(ctx, entity.StockCount{
+		Status:    entity.StockCountOpen,
+		OpenedBy:  "budi",
+		CreatedAt: time.Now().Unix(),
+	})
+	require.NoError(mainT, err)
+	cart, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+		UserID:  100,
+		Details: []entity.ShoppingCartDetail{{GoodsID: 5, TotalGoods: 10, GoodsPrice: 1000, CreatedAt: 1689873350}},
+	})
+	require.NoError(mainT, err)
+	require.Positive(mainT, cart.ID)
+	_, err = strg.SetStockCountItems(ctx, service.SetStockCountItemsInput{
+		CountID: stockCount.ID,
+		Items:   []entity.StockCountItem{{GoodsID: 4, CountedStocks: 90}, {GoodsID: 5, CountedStocks: 5}},
+	})
+	require.NoError(mainT, err)
+	_, err = strg.ApplyStockCount(ctx, service.ApplyStockCountInput{
+		CountID:    stockCount.ID,
+		ApprovedBy: "siti",
+		ApprovedAt: time.Now().Unix(),
+	})
+	require.NoError(mainT, err)
+	goods, err = strg.GetGoodsByID(ctx, 4)
+	require.NoError(mainT, err)
+	require.Equal(mainT, 90, goods.Stocks)
+	goods, err = strg.GetGoodsByID(ctx, 5)
+	require.NoError(mainT, err)
+	require.Equal(mainT, 5, goods.Stocks)
+	require.Equal(mainT, 10, goods.ReservedStocks)
+
+	movements, err = strg.GetStockMovements(ctx, service.GetStockMovementsInput{
+		GoodsID: 5,
+		From:    0,
+		To:      time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.StockMovementAdjustment, movements[len(movements)-1].Type)
+	require.Equal(mainT, 5, movements[len(movements)-1].After)
+}
+
+func TestPurchaseOrders(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	supplier, err := strg.CreateSupplier(ctx, entity.Supplier{Name: "Toko Sembako Jaya", CreatedAt: time.Now().Unix()})
+	require.NoError(mainT, err)
+	_, err = strg.CreateSupplier(ctx, entity.Supplier{Name: "Toko Sembako Jaya", CreatedAt: time.Now().Unix()})
+	require.ErrorIs(mainT, err, service.ErrSupplierAlreadyExists)
+
+	_, err = strg.CreatePurchaseOrder(ctx, entity.PurchaseOrder{
+		SupplierID: 99,
+		Status:     entity.PurchaseOrderOpen,
+		Items:      []entity.PurchaseOrderItem{{GoodsID: 1, Quantity: 10, UnitCost: 1000}},
+		CreatedAt:  time.Now().Unix(),
+	})
+	require.ErrorIs(mainT, err, service.ErrSupplierNotFound)
+
+	purchaseOrder, err := strg.CreatePurchaseOrder(ctx, entity.PurchaseOrder{
+		SupplierID: supplier.ID,
+		Status:     entity.PurchaseOrderOpen,
+		CreatedBy:  "budi",
+		Items: []entity.PurchaseOrderItem{
+			{GoodsID: 1, Quantity: 10, UnitCost: 1000},
+			{GoodsID: 2, Quantity: 5, UnitCost: 800},
+		},
+		CreatedAt: time.Now().Unix(),
+		UpdatedAt: time.Now().Unix(),
+	})
+	require.NoError(mainT, err)
+	require.Len(mainT, purchaseOrder.Items, 2)
+	require.Equal(mainT, "Kopi", purchaseOrder.Items[0].Name)
+
+	purchaseOrder, err = strg.ReceivePurchaseOrder(ctx, entity.PurchaseOrderReceipt{
+		OrderID:    purchaseOrder.ID,
+		ReceivedBy: "budi",
+		Items:      []entity.PurchaseOrderReceiptItem{{GoodsID: 1, Quantity: 4}},
+		ReceivedAt: time.Now().Unix(),
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.PurchaseOrderPartiallyReceived, purchaseOrder.Status)
+	require.Equal(mainT, 4, purchaseOrder.Items[0].ReceivedQuantity)
+	goods, err := strg.GetGoodsByID(ctx, 1)
+	require.NoError(mainT, err)
+	require.Equal(mainT, 104, goods.Stocks)
+	require.Equal(mainT, float64(1000), goods.AverageCost)
+
+	// nothing is received when one of the goods is more than ordered
+	_, err = strg.ReceivePurchaseOrder(ctx, entity.PurchaseOrderReceipt{
+		OrderID: purchaseOrder.ID,
+		Items: []entity.PurchaseOrderReceiptItem{
+			{GoodsID: 1, Quantity: 6},
+			{GoodsID: 2, Quantity: 6},
+		},
+		ReceivedAt: time.Now().Unix(),
+	})
+	require.ErrorIs(mainT, err, service.ErrInvalidInput)
+	goods, err = strg.GetGoodsByID(ctx, 1)
+	require.NoError(mainT, err)
+	require.Equal(mainT, 104, goods.Stocks)
+
+	purchaseOrder, err = strg.ReceivePurchaseOrder(ctx, entity.PurchaseOrderReceipt{
+		OrderID:    purchaseOrder.ID,
+		ReceivedBy: "budi",
+		Items: []entity.PurchaseOrderReceiptItem{
+			{GoodsID: 1, Quantity: 6},
+			{GoodsID: 2, Quantity: 5},
+		},
+		ReceivedAt: time.Now().Unix(),
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.PurchaseOrderReceived, purchaseOrder.Status)
+	require.Len(mainT, purchaseOrder.Receipts, 2)
+	require.Len(mainT, purchaseOrder.Receipts[1].Items, 2)
+
+	_, err = strg.ReceivePurchaseOrder(ctx, entity.PurchaseOrderReceipt{
+		OrderID:    purchaseOrder.ID,
+		Items:      []entity.PurchaseOrderReceiptItem{{GoodsID: 1, Quantity: 1}},
+		ReceivedAt: time.Now().Unix(),
+	})
+	require.ErrorIs(mainT, err, service.ErrPurchaseOrderClosed)
+	_, err = strg.CancelPurchaseOrder(ctx, service.CancelPurchaseOrderInput{OrderID: purchaseOrder.ID, CancelledAt: time.Now().Unix()})
+	require.ErrorIs(mainT, err, service.ErrPurchaseOrderClosed)
+
+	movements, err := strg.GetStockMovements(ctx, service.GetStockMovementsInput{GoodsID: 2})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.StockMovementRestock, movements[len(movements)-1].Type)
+	require.Equal(mainT, float64(800), movements[len(movements)-1].UnitCost)
+	require.Equal(mainT, fmt.Sprintf("purchase order #%d", purchaseOrder.ID), movements[len(movements)-1].Reason)
+
+	purchaseOrders, err := strg.GetPurchaseOrders(ctx, service.GetPurchaseOrdersInput{Status: entity.PurchaseOrderReceived})
+	require.NoError(mainT, err)
+	require.Len(mainT, purchaseOrders, 1)
+
+	// the sold goods is valued at its average cost
+	cart, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+		UserID: 100,
+		Details: []entity.ShoppingCartDetail{
+			{GoodsID: 1, TotalGoods: 3, GoodsPrice: 3000, CreatedAt: 1689873350},
+		},
+	})
+	require.NoError(mainT, err)
+	_, err = strg.CreateTransaction(ctx, service.CreateTransactionInput{CartID: cart.ID, PaymentAmount: 10000})
+	require.NoError(mainT, err)
+	summary, err := strg.GetSalesSummary(ctx, service.GetSalesSummaryInput{
+		From: time.Now().Add(-time.Hour).Unix(),
+		To:   time.Now().Add(time.Hour).Unix(),
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money(3000), summary.CostOfGoodsSold)
+}
+
+func TestStockBatches(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	now := time.Now()
+	restocks := []service.UpdateGoodsStockInput{
+		{Action: service.IncreaseStock, GoodsID: 1, Total: 10, ExpiresAt: now.Add(-time.Hour).Unix()},
+		{Action: service.IncreaseStock, GoodsID: 1, Total: 5, ExpiresAt: now.AddDate(0, 0, 2).Unix()},
+	}
+	for _, restock := range restocks {
+		_, err = strg.UpdateGoodsStock(ctx, restock)
+		require.NoError(mainT, err)
+	}
+
+	mainT.Run("Stocks out take the earliest expiry first", func(t *testing.T) {
+		_, err := strg.UpdateGoodsStock(ctx, service.UpdateGoodsStockInput{Action: service.DecreaseStock, GoodsID: 1, Total: 3})
+		require.NoError(t, err)
+
+		batches, err := strg.GetExpiringStockBatches(ctx, service.GetExpiringStockBatchesInput{
+			ExpiresUntil: now.AddDate(0, 0, 3).Unix(),
+		})
+		require.NoError(t, err)
+		require.Len(t, batches, 2)
+		require.Equal(t, "Kopi", batches[0].Name)
+		require.Equal(t, 10, batches[0].ReceivedQuantity)
+		require.Equal(t, 7, batches[0].Quantity)
+		require.Equal(t, 5, batches[1].Quantity)
+	})
+
+	mainT.Run("Sales skip the expired batches", func(t *testing.T) {
+		cart, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+			UserID:  1,
+			Details: []entity.ShoppingCartDetail{{GoodsID: 1, TotalGoods: 2, GoodsPrice: 3000, CreatedAt: now.Unix()}},
+		})
+		require.NoError(t, err)
+		_, err = strg.CreateTransaction(ctx, service.CreateTransactionInput{CartID: cart.ID, PaymentAmount: 6000})
+		require.NoError(t, err)
+
+		batches, err := strg.GetExpiringStockBatches(ctx, service.GetExpiringStockBatchesInput{
+			ExpiresUntil: now.AddDate(0, 0, 3).Unix(),
+		})
+		require.NoError(t, err)
+		require.Len(t, batches, 2)
+		require.Equal(t, 7, batches[0].Quantity)
+		require.Equal(t, 3, batches[1].Quantity)
+
+		// 110 stocks is left but 7 of them already expired, so they can't be reserved either
+		_, err = strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+			UserID:  2,
+			Details: []entity.ShoppingCartDetail{{GoodsID: 1, TotalGoods: 104, GoodsPrice: 3000, CreatedAt: now.Unix()}},
+		})
+		var insufficientStockErr entity.InsufficientStockError
+		require.ErrorAs(t, err, &insufficientStockErr)
+		require.Equal(t, 103, insufficientStockErr.Stocks)
+	})
+
+	mainT.Run("Write off the expired batches", func(t *testing.T) {
+		movements, err := strg.WriteOffExpiredStockBatches(ctx, service.WriteOffStockBatchesInput{
+			ExpiredAt: now.Unix(),
+			Actor:     "budi",
+			Reason:    "kedaluwarsa",
+		})
+		require.NoError(t, err)
+		require.Len(t, movements, 1)
+		require.Equal(t, -7, movements[0].Quantity)
+		require.Equal(t, 103, movements[0].After)
+
+		goods, err := strg.GetGoodsByID(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, 103, goods.Stocks)
+		batches, err := strg.GetExpiringStockBatches(ctx, service.GetExpiringStockBatchesInput{
+			ExpiresUntil: now.AddDate(0, 0, 3).Unix(),
+		})
+		require.NoError(t, err)
+		require.Len(t, batches, 1)
+		require.Equal(t, 3, batches[0].Quantity)
+
+		ledger, err := strg.GetStockMovements(ctx, service.GetStockMovementsInput{GoodsID: 1})
+		require.NoError(t, err)
+		last := ledger[len(ledger)-1]
+		require.Equal(t, entity.StockMovementWastage, last.Type)
+		require.Equal(t, "kedaluwarsa", last.Reason)
+		require.Equal(t, restocks[1].ExpiresAt, ledger[len(ledger)-4].ExpiresAt)
+	})
+
+	mainT.Run("Nothing expired anymore", func(t *testing.T) {
+		movements, err := strg.WriteOffExpiredStockBatches(ctx, service.WriteOffStockBatchesInput{
+			ExpiredAt: now.Unix(),
+			Reason:    "kedaluwarsa",
+		})
+		require.NoError(t, err)
+		require.Empty(t, movements)
+	})
+
+	mainT.Run("Expired batches is not reserved by another cart", func(t *testing.T) {
+		stockUpdates := []service.UpdateGoodsStockInput{
+			{Action: service.DecreaseStock, GoodsID: 2, Total: 45},
+			{Action: service.IncreaseStock, GoodsID: 2, Total: 5, ExpiresAt: now.Add(-time.Hour).Unix()},
+			{Action: service.IncreaseStock, GoodsID: 2, Total: 5, ExpiresAt: now.AddDate(0, 0, 2).Unix()},
+		}
+		for _, update := range stockUpdates {
+			_, err := strg.UpdateGoodsStock(ctx, update)
+			require.NoError(t, err)
+		}
+
+		// 10 stocks on hand but only 5 of them sellable
+		cartA, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+			UserID:  3,
+			Details: []entity.ShoppingCartDetail{{GoodsID: 2, TotalGoods: 5, GoodsPrice: 5000, CreatedAt: now.Unix()}},
+		})
+		require.NoError(t, err)
+		_, err = strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+			UserID:  4,
+			Details: []entity.ShoppingCartDetail{{GoodsID: 2, TotalGoods: 5, GoodsPrice: 5000, CreatedAt: now.Unix()}},
+		})
+		var insufficientStockErr entity.InsufficientStockError
+		require.ErrorAs(t, err, &insufficientStockErr)
+		require.Equal(t, 0, insufficientStockErr.Stocks)
+
+		_, err = strg.CreateTransaction(ctx, service.CreateTransactionInput{CartID: cartA.ID, PaymentAmount: 25000})
+		require.NoError(t, err)
+		goods, err := strg.GetGoodsByID(ctx, 2)
+		require.NoError(t, err)
+		require.Equal(t, 5, goods.Stocks)
+		require.Equal(t, 0, goods.ReservedStocks)
+	})
+}
+
+func TestReserveStocks(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	getGoods := func(goodsID int) entity.Goods {
+		var goods entity.Goods
+		err := dbConn.QueryRowContext(
+			ctx,
+			"SELECT id, name, stocks, reserved_stocks, price FROM goods WHERE id = ?",
+			goodsID,
+		).Scan(&goods.ID, &goods.Name, &goods.Stocks, &goods.ReservedStocks, &goods.Price)
+		require.NoError(mainT, err)
+		return goods
+	}
+
+	// Pisang Keju only have 25 stocks, so only two carts which able to reserve 10 of them
+	var wg sync.WaitGroup
+	results := make(chan error, 10)
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func(userID int) {
+			defer wg.Done()
+			_, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+				UserID: userID,
+				Details: []entity.ShoppingCartDetail{
+					{
+						GoodsID:    6,
+						TotalGoods: 10,
+						GoodsPrice: 2500,
+						CreatedAt:  1689873350,
+					},
+				},
+			})
+			results <- err
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	var totalSuccess int
+	for err := range results {
+		if err == nil {
+			totalSuccess++
+			continue
+		}
+		require.ErrorAs(mainT, err, &entity.InsufficientStockError{})
+	}
+	require.Equal(mainT, 2, totalSuccess)
+	require.Equal(mainT, 20, getGoods(6).ReservedStocks)
+
+	var cartIDs []int64
+	err = dbConn.SelectContext(ctx, &cartIDs, "SELECT id FROM transactions ORDER BY id")
+	require.NoError(mainT, err)
+	require.Len(mainT, cartIDs, 2)
+
+	// paid cart turn the reservation into deduction
+	_, err = strg.CreateTransaction(ctx, service.CreateTransactionInput{
+		CartID:        cartIDs[0],
+		PaymentAmount: 25000,
+	})
+	require.NoError(mainT, err)
+	goods := getGoods(6)
+	require.Equal(mainT, 15, goods.Stocks)
+	require.Equal(mainT, 10, goods.ReservedStocks)
+
+	// abandoned cart release the reservation
+	require.NoError(mainT, strg.DeleteShoppingCart(ctx, cartIDs[1]))
+	goods = getGoods(6)
+	require.Equal(mainT, 15, goods.Stocks)
+	require.Equal(mainT, 0, goods.ReservedStocks)
+
+	// paid cart can't be abandoned
+	require.ErrorIs(mainT, strg.DeleteShoppingCart(ctx, cartIDs[0]), service.ErrCartNotFound)
+}
+
+func TestManageCartGoods(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	getReservedStocks := func(goodsID int) int {
+		var reservedStocks int
+		err := dbConn.GetContext(ctx, &reservedStocks, "SELECT reserved_stocks FROM goods WHERE id = ?", goodsID)
+		require.NoError(mainT, err)
+		return reservedStocks
+	}
+
+	cart, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+		UserID: 100,
+		Details: []entity.ShoppingCartDetail{
+			{GoodsID: 1, TotalGoods: 1, GoodsPrice: 3000, CreatedAt: 1689873350},
+			{GoodsID: 2, TotalGoods: 3, GoodsPrice: 1500, CreatedAt: 1689873350},
+		},
+	})
+	require.NoError(mainT, err)
+
+	// the same goods merged into existing line
+	cart, err = strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+		ID:     cart.ID,
+		UserID: 100,
+		Details: []entity.ShoppingCartDetail{
+			{GoodsID: 1, TotalGoods: 2, GoodsPrice: 3000, CreatedAt: 1689873500},
+		},
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money((3*3000)+(3*1500)), cart.TotalAmount)
+	require.Equal(mainT, 3, getReservedStocks(1))
+
+	// decrease quantity release the reserved stocks
+	cart, err = strg.SetCartGoodsQuantity(ctx, service.SetCartGoodsQuantityInput{
+		CartID:  cart.ID,
+		GoodsID: 1,
+		Total:   1,
+	})
+	require.NoError(mainT, err)
+	require.Len(mainT, cart.Details, 2)
+	require.Equal(mainT, entity.Money(3000+(3*1500)), cart.TotalAmount)
+	require.Equal(mainT, 1, getReservedStocks(1))
+
+	// zero quantity remove the goods
+	cart, err = strg.SetCartGoodsQuantity(ctx, service.SetCartGoodsQuantityInput{
+		CartID:  cart.ID,
+		GoodsID: 2,
+		Total:   0,
+	})
+	require.NoError(mainT, err)
+	require.Len(mainT, cart.Details, 1)
+	require.Equal(mainT, 0, getReservedStocks(2))
+
+	_, err = strg.SetCartGoodsQuantity(ctx, service.SetCartGoodsQuantityInput{
+		CartID:  cart.ID,
+		GoodsID: 2,
+		Total:   1,
+	})
+	require.ErrorIs(mainT, err, entity.ErrGoodsNotInCart)
+
+	// cleared cart still exist but empty
+	cart, err = strg.ClearShoppingCart(ctx, cart.ID)
+	require.NoError(mainT, err)
+	require.Empty(mainT, cart.Details)
+	require.Zero(mainT, cart.TotalAmount)
+	require.Equal(mainT, 0, getReservedStocks(1))
+}
+
+func TestExpireShoppingCarts(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	for _, userID := range []int{100, 200} {
+		_, err = strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+			UserID: userID,
+			Details: []entity.ShoppingCartDetail{
+				{GoodsID: 1, TotalGoods: 2, GoodsPrice: 3000, CreatedAt: 1689873350},
+			},
+		})
+		require.NoError(mainT, err)
+	}
+	// only the first shopping cart is abandoned
+	_, err = dbConn.ExecContext(ctx, "UPDATE transactions SET updated_at = 1689873350 WHERE id = 1")
+	require.NoError(mainT, err)
+
+	totalExpired, err := strg.ExpireShoppingCarts(ctx, service.ExpireShoppingCartsInput{
+		LastActivityBefore: 1689873351,
+		Limit:              10,
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, 1, totalExpired)
+
+	expiredTrx, err := strg.GetTransaction(ctx, 1)
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.TransactionStatusExpired, expiredTrx.Status)
+
+	var reservedStocks int
+	err = dbConn.GetContext(ctx, &reservedStocks, "SELECT reserved_stocks FROM goods WHERE id = 1")
+	require.NoError(mainT, err)
+	require.Equal(mainT, 2, reservedStocks)
+
+	// expired shopping cart can't be paid and won't be expired twice
+	_, err = strg.CreateTransaction(ctx, service.CreateTransactionInput{
+		CartID:        1,
+		PaymentAmount: 6000,
+	})
+	require.ErrorIs(mainT, err, service.ErrCartExpired)
+
+	totalExpired, err = strg.ExpireShoppingCarts(ctx, service.ExpireShoppingCartsInput{
+		LastActivityBefore: 1689873351,
+		Limit:              10,
+	})
+	require.NoError(mainT, err)
+	require.Zero(mainT, totalExpired)
+}
+
+func TestPromotions(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	now := time.Now().Unix()
+	automatic, err := strg.CreatePromotion(ctx, entity.Promotion{
+		Name:         "Bakwan beli 2 gratis 1",
+		Type:         entity.PromotionBuyXGetY,
+		GoodsID:      3,
+		BuyQuantity:  2,
+		FreeQuantity: 1,
+		CreatedAt:    now,
+	})
+	require.NoError(mainT, err)
+	voucher, err := strg.CreatePromotion(ctx, entity.Promotion{
+		Name:        "Hemat 1000",
+		Type:        entity.PromotionFixed,
+		Amount:      1000,
+		VoucherCode: "HEMAT",
+		UsageLimit:  1,
+		CreatedAt:   now,
+	})
+	require.NoError(mainT, err)
+	_, err = strg.CreatePromotion(ctx, entity.Promotion{
+		Name:      "Promo kemarin",
+		Type:      entity.PromotionPercentage,
+		Value:     50,
+		EndsAt:    now - 60,
+		CreatedAt: now - 86400,
+	})
+	require.NoError(mainT, err)
+
+	mainT.Run("Duplicate voucher", func(t *testing.T) {
+		_, err := strg.CreatePromotion(ctx, entity.Promotion{
+			Name:        "Hemat lagi",
+			Type:        entity.PromotionFixed,
+			Amount:      500,
+			VoucherCode: "HEMAT",
+			CreatedAt:   now,
+		})
+		require.ErrorIs(t, err, service.ErrVoucherAlreadyExists)
+	})
+
+	mainT.Run("Get active promotions", func(t *testing.T) {
+		promotions, err := strg.GetPromotions(ctx, service.GetPromotionsInput{ActiveAt: now})
+		require.NoError(t, err)
+		require.Len(t, promotions, 1)
+		require.Equal(t, automatic.ID, promotions[0].ID)
+
+		promotions, err = strg.GetPromotions(ctx, service.GetPromotionsInput{ActiveAt: now, VoucherCode: "HEMAT"})
+		require.NoError(t, err)
+		require.Len(t, promotions, 2)
+		require.Equal(t, voucher.ID, promotions[1].ID)
+		require.Equal(t, entity.Money(1000), promotions[1].Amount)
+
+		promotions, err = strg.GetPromotions(ctx, service.GetPromotionsInput{})
+		require.NoError(t, err)
+		require.Len(t, promotions, 3)
+	})
+
+	details := []entity.ShoppingCartDetail{
+		{GoodsID: 1, TotalGoods: 1, GoodsPrice: 3000},
+		{GoodsID: 3, TotalGoods: 3, GoodsPrice: 1500},
+	}
+	cart, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+		UserID:      100,
+		VoucherCode: "HEMAT",
+		Details:     details,
+	})
+	require.NoError(mainT, err)
+	existingCart, err := strg.GetExistingShoppingCart(ctx, cart.ID)
+	require.NoError(mainT, err)
+	require.Equal(mainT, "HEMAT", existingCart.VoucherCode)
+
+	discounts := []entity.Discount{
+		{PromotionID: automatic.ID, Name: automatic.Name, Amount: 1500},
+		{PromotionID: voucher.ID, Name: voucher.Name, VoucherCode: "HEMAT", Amount: 1000},
+	}
+	mainT.Run("Shopping cart changed", func(t *testing.T) {
+		_, err := strg.CreateTransaction(ctx, service.CreateTransactionInput{
+			CartID:        cart.ID,
+			PaymentAmount: 10000,
+			Bill:          entity.NewBill(details[:1], 1000, entity.TaxRates{}),
+			VoucherCode:   "HEMAT",
+			Discounts:     discounts,
+		})
+		require.ErrorIs(t, err, service.ErrCartChanged)
+	})
+
+	trx, err := strg.CreateTransaction(ctx, service.CreateTransactionInput{
+		CartID:        cart.ID,
+		PaymentAmount: 10000,
+		Bill:          entity.NewBill(details, 2500, entity.TaxRates{}),
+		VoucherCode:   "HEMAT",
+		Discounts:     discounts,
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money(5000), trx.TotalAmount)
+	require.Equal(mainT, entity.Money(2500), trx.DiscountAmount)
+	require.Equal(mainT, entity.Money(5000), trx.ReturnAmount)
+	require.Equal(mainT, discounts, trx.Discounts)
+
+	mainT.Run("Voucher reach its usage limit", func(t *testing.T) {
+		cart, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{
+			UserID:  101,
+			Details: []entity.ShoppingCartDetail{{GoodsID: 1, TotalGoods: 1, GoodsPrice: 3000}},
+		})
+		require.NoError(t, err)
+		_, err = strg.CreateTransaction(ctx, service.CreateTransactionInput{
+			CartID:        cart.ID,
+			PaymentAmount: 3000,
+			Bill:          entity.NewBill(details[:1], 1000, entity.TaxRates{}),
+			VoucherCode:   "HEMAT",
+			Discounts:     discounts[1:],
+		})
+		require.ErrorIs(t, err, service.ErrVoucherNotApplicable)
+	})
+
+	mainT.Run("Refund is reduced by the discount", func(t *testing.T) {
+		refund, err := strg.CreateRefund(ctx, service.CreateRefundInput{
+			TransactionID: trx.ID,
+			Status:        entity.TransactionStatusRefunded,
+			Items:         []entity.RefundItem{{GoodsID: 1, TotalGoods: 1}},
+			CreatedAt:     now,
+		})
+		require.NoError(t, err)
+		require.Equal(t, entity.Money(2000), refund.Amount)
+	})
+}
+
+func TestTaxes(mainT *testing.T) {
+	dbConn := initDB(mainT)
+	defer func() {
+		cleanUpDatabase(dbConn)
+		dbConn.Close()
+	}()
+
+	strg, err := storagemysql.NewStorage(storagemysql.StorageConfig{
+		DBClient: dbConn,
+	})
+	require.NoError(mainT, err)
+
+	ctx := context.Background()
+	mainT.Run("Tax inclusive goods", func(t *testing.T) {
+		goods, err := strg.CreateGoods(ctx, entity.Goods{Name: "Es Jeruk", Stocks: 10, Price: 5550, PriceIncludesTax: true})
+		require.NoError(t, err)
+		storedGoods, err := strg.GetGoodsByID(ctx, goods.ID)
+		require.NoError(t, err)
+		require.True(t, storedGoods.PriceIncludesTax)
+
+		goods.PriceIncludesTax = false
+		_, err = strg.UpdateGoods(ctx, *goods)
+		require.NoError(t, err)
+		storedGoods, err = strg.GetGoodsByID(ctx, goods.ID)
+		require.NoError(t, err)
+		require.False(t, storedGoods.PriceIncludesTax)
+	})
+
+	details := []entity.ShoppingCartDetail{
+		{GoodsID: 1, TotalGoods: 2, GoodsPrice: 3000},
+		{GoodsID: 3, TotalGoods: 1, GoodsPrice: 1500, PriceIncludesTax: true},
+	}
+	cart, err := strg.AddGoodToCart(ctx, &entity.ShoppingCart{UserID: 100, Details: details})
+	require.NoError(mainT, err)
+	existingCart, err := strg.GetExistingShoppingCart(ctx, cart.ID)
+	require.NoError(mainT, err)
+	require.Equal(mainT, []bool{false, true}, []bool{
+		existingCart.Details[0].PriceIncludesTax,
+		existingCart.Details[1].PriceIncludesTax,
+	})
+
+	rates := entity.TaxRates{TaxRate: 11, ServiceChargeRate: 5}
+	bill := entity.NewBill(details, 0, rates)
+	trx, err := strg.CreateTransaction(ctx, service.CreateTransactionInput{
+		CartID:        cart.ID,
+		PaymentAmount: 10000,
+		Bill:          bill,
+		TaxRates:      rates,
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, bill.GrandTotalAmount, trx.TotalAmount)
+
+	paidTrx, err := strg.GetTransaction(ctx, cart.ID)
+	require.NoError(mainT, err)
+	require.Equal(mainT, bill.SubtotalAmount, paidTrx.SubtotalAmount)
+	require.Equal(mainT, bill.ServiceChargeAmount, paidTrx.ServiceChargeAmount)
+	require.Equal(mainT, bill.TaxBaseAmount, paidTrx.TaxBaseAmount)
+	require.Equal(mainT, bill.TaxAmount, paidTrx.TaxAmount)
+	require.Equal(mainT, rates.TaxRate, paidTrx.TaxRate)
+	require.Equal(mainT, rates.ServiceChargeRate, paidTrx.ServiceChargeRate)
+
+	now := time.Now()
+	refund, err := strg.CreateRefund(ctx, service.CreateRefundInput{
+		TransactionID: trx.ID,
+		Status:        entity.TransactionStatusRefunded,
+		Items:         []entity.RefundItem{{GoodsID: 1, TotalGoods: 1}},
+		CreatedAt:     now.Unix(),
+	})
+	require.NoError(mainT, err)
+	require.Equal(mainT, entity.Money(3000).Prorate(bill.GrandTotalAmount, bill.SubtotalAmount), refund.Amount)
+
+	recap, err := strg.GetTaxRecap(ctx, service.GetTaxRecapInput{From: now.Unix() - 60, To: now.Unix() + 60})
+	require.NoError(mainT, err)
+	require.Equal(mainT, 1, recap.TotalTransactions)
+	require.Equal(mainT, bill.TaxBaseAmount, recap.TaxBaseAmount)
+	require.Equal(mainT, bill.TaxAmount, recap.TaxAmount)
+	require.Equal(mainT, bill.GrandTotalAmount, recap.GrandTotalAmount)
+	require.Equal(mainT, refund.Amount, recap.RefundAmount)
+	require.Equal(mainT, refund.Amount.Prorate(bill.TaxAmount, bill.GrandTotalAmount), recap.RefundedTaxAmount)
+}
+
+func initDB(mainT *testing.T) *sqlx.DB {
+	ctx := context.Background()
+	sqlDSN := os.Getenv("DB_SQLDSN")
+	if len(sqlDSN) == 0 {
+		sqlDSN = "root:test1234@tcp(localhost:23306)/umkm?timeout=5s"
+	}
+
+	dbConn, err := sqlx.ConnectContext(ctx, "mysql", sqlDSN)
+	require.NoError(mainT, err)
+	require.NotNil(mainT, dbConn)
+
+	return dbConn
+}
+
+// cleanUpDatabase clean transactions and transaction details table, then restore the goods stocks
+func cleanUpDatabase(dbConn *sqlx.DB) {
+	ctx := context.Background()
+	dbConn.ExecContext(ctx, "TRUNCATE transactions")
+	dbConn.ExecContext(ctx, "TRUNCATE transaction_details")
+	dbConn.ExecContext(ctx, "TRUNCATE transaction_status_histories")
+	dbConn.ExecContext(ctx, "TRUNCATE refunds")
+	dbConn.ExecContext(ctx, "TRUNCATE refund_details")
+	dbConn.ExecContext(ctx, "TRUNCATE deliveries")
+	dbConn.ExecContext(ctx, "TRUNCATE transaction_discounts")
+	dbConn.ExecContext(ctx, "TRUNCATE transaction_tenders")
+	dbConn.ExecContext(ctx, "TRUNCATE payments")
+	dbConn.ExecContext(ctx, "TRUNCATE promotions")
+
+	seedStocks := map[int]int{1: 100, 2: 45, 3: 50, 4: 100, 5: 100, 6: 25, 7: 30}
+	for goodsID, stocks := range seedStocks {
+		dbConn.ExecContext(ctx, "UPDATE goods SET stocks = ?, reserved_stocks = 0 WHERE id = ?", stocks, goodsID)
+	}
+	dbConn.ExecContext(ctx, "DELETE FROM goods WHERE id > 7")
+	dbConn.ExecContext(ctx, "UPDATE goods SET deleted_at = NULL, id_category = NULL, reorder_point = 0, price_includes_tax = 0, average_cost = 0")
+	dbConn.ExecContext(ctx, "TRUNCATE goods_variants")
+	dbConn.ExecContext(ctx, "TRUNCATE categories")
+	dbConn.ExecContext(ctx, "TRUNCATE recipes")
+	dbConn.ExecContext(ctx, "TRUNCATE stock_counts")
+	dbConn.ExecContext(ctx, "TRUNCATE stock_count_items")
+	dbConn.ExecContext(ctx, "TRUNCATE suppliers")
+	dbConn.ExecContext(ctx, "TRUNCATE purchase_orders")
+	dbConn.ExecContext(ctx, "TRUNCATE purchase_order_items")
+	dbConn.ExecContext(ctx, "TRUNCATE purchase_order_receipts")
+	dbConn.ExecContext(ctx, "TRUNCATE purchase_order_receipt_items")
+	// truncate doesn't fire the append-only triggers, then the seed stocks is recorded again as opening balance
+	dbConn.ExecContext(ctx, "TRUNCATE stock_movements")
+	dbConn.ExecContext(
+		ctx,
+		`INSERT INTO stock_movements (id_goods, type, quantity, stocks_before, stocks_after, actor, reason, created_at)
+		SELECT id, 'ADJUSTMENT', stocks, 0, stocks, 'system', 'opening balance', UNIX_TIMESTAMP() FROM goods`,
+	)
+	dbConn.ExecContext(ctx, "TRUNCATE stock_batches")
+	dbConn.ExecContext(
+		ctx,
+		`INSERT INTO stock_batches (id_goods, received_quantity, quantity, received_at)
+		SELECT id, stocks, stocks, UNIX_TIMESTAMP() FROM goods WHERE stocks > 0`,
+	)
 }
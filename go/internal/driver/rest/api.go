@@ -1,12 +1,17 @@
 package rest
 
 import (
+	"errors"
+	"expvar"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
 	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/service"
 	"gopkg.in/validator.v2"
 )
@@ -38,11 +43,62 @@ func (a *api) Handler() *gin.Engine {
 	smallRouter := r.Group("/api/small")
 	{
 		smallRouter.GET("/stocks", a.HandleShowListOfGoods)
+		smallRouter.GET("/menu", a.HandleShowMenu)
 		smallRouter.POST("/cart", a.HandleAddGoodsToCart)
+		smallRouter.GET("/cart/:cart_id", a.HandleGetCart)
+		smallRouter.DELETE("/cart/:cart_id", a.HandleAbandonCart)
+		smallRouter.DELETE("/cart/:cart_id/goods", a.HandleClearCart)
+		smallRouter.PATCH("/cart/:cart_id/goods/:goods_id", a.HandleUpdateCartGoods)
+		smallRouter.DELETE("/cart/:cart_id/goods/:goods_id", a.HandleRemoveGoodsFromCart)
 		smallRouter.POST("/pay", a.HandlePay)
+		smallRouter.POST("/payments", a.HandleRequestPayment)
+		smallRouter.GET("/payments/:reference", a.HandleGetPayment)
+		smallRouter.GET("/orders", a.HandleShowListOfOrders)
+		smallRouter.GET("/orders/:order_id", a.HandleGetOrder)
+		smallRouter.PATCH("/orders/:order_id/status", a.HandleUpdateOrderStatus)
+		smallRouter.POST("/orders/:order_id/refunds", a.HandleRefundOrder)
+		smallRouter.POST("/orders/:order_id/cancel", a.HandleCancelOrder)
+		smallRouter.GET("/reports/daily", a.HandleGetDailySalesReport)
+		smallRouter.GET("/reports/tax", a.HandleGetMonthlyTaxRecap)
 	}
+	// big umkm API
+	bigRouter := r.Group("/api/big")
+	{
+		bigRouter.GET("/delivery-price", a.HandleCalculateDeliveryPrice)
+		bigRouter.POST("/pickup", a.HandlePickupDelivery)
+		bigRouter.POST("/:stuff_name/stocks", a.HandleUpdateStock)
+		bigRouter.POST("/goods", a.HandleCreateGoods)
+		bigRouter.GET("/goods/:goods_id", a.HandleGetGoods)
+		bigRouter.PUT("/goods/:goods_id", a.HandleUpdateGoods)
+		bigRouter.DELETE("/goods/:goods_id", a.HandleDeleteGoods)
+		bigRouter.POST("/goods/:goods_id/variants", a.HandleCreateGoodsVariant)
+		bigRouter.PUT("/goods/:goods_id/recipe", a.HandleSetRecipe)
+		bigRouter.GET("/goods/:goods_id/stock-movements", a.HandleShowStockMovements)
+		bigRouter.GET("/stock-reconciliation", a.HandleReconcileStocks)
+		bigRouter.GET("/low-stocks", a.HandleShowLowStocks)
+		bigRouter.GET("/stock-batches/expiring", a.HandleShowExpiringBatches)
+		bigRouter.POST("/stock-batches/write-off", a.HandleWriteOffExpiredBatches)
+		bigRouter.POST("/stock-counts", a.HandleOpenStockCount)
+		bigRouter.GET("/stock-counts/:count_id", a.HandleGetStockCount)
+		bigRouter.POST("/stock-counts/:count_id/items", a.HandleSubmitStockCount)
+		bigRouter.POST("/stock-counts/:count_id/approve", a.HandleApproveStockCount)
+		bigRouter.POST("/categories", a.HandleCreateCategory)
+		bigRouter.POST("/suppliers", a.HandleCreateSupplier)
+		bigRouter.GET("/suppliers", a.HandleShowSuppliers)
+		bigRouter.POST("/promotions", a.HandleCreatePromotion)
+		bigRouter.GET("/promotions", a.HandleShowPromotions)
+		bigRouter.POST("/purchase-orders", a.HandleCreatePurchaseOrder)
+		bigRouter.GET("/purchase-orders", a.HandleShowPurchaseOrders)
+		bigRouter.GET("/purchase-orders/:order_id", a.HandleGetPurchaseOrder)
+		bigRouter.POST("/purchase-orders/:order_id/receive", a.HandleReceivePurchaseOrder)
+		bigRouter.POST("/purchase-orders/:order_id/cancel", a.HandleCancelPurchaseOrder)
+	}
+	// callback of the payment provider, it's not called by the shop staff
+	r.POST("/api/payments/callback", a.HandlePaymentCallback)
 	// for testing API
 	r.POST("/clear-db", a.HandleClearDB)
+	// runtime metrics
+	r.GET("/debug/vars", gin.WrapH(expvar.Handler()))
 
 	return r
 }
@@ -57,6 +113,18 @@ func (a *api) HandleShowListOfGoods(c *gin.Context) {
 	if err != nil {
 		qpErrors = append(qpErrors, err.Error())
 	}
+	qpMinPrice, err := strconv.ParseInt(c.DefaultQuery("min_price", "0"), 10, 64)
+	if err != nil {
+		qpErrors = append(qpErrors, err.Error())
+	}
+	qpMaxPrice, err := strconv.ParseInt(c.DefaultQuery("max_price", "0"), 10, 64)
+	if err != nil {
+		qpErrors = append(qpErrors, err.Error())
+	}
+	qpInStock, err := strconv.ParseBool(c.DefaultQuery("in_stock", "false"))
+	if err != nil {
+		qpErrors = append(qpErrors, err.Error())
+	}
 	if len(qpErrors) > 0 {
 		c.JSON(
 			http.StatusBadRequest,
@@ -65,34 +133,62 @@ func (a *api) HandleShowListOfGoods(c *gin.Context) {
 		return
 	}
 
+	// cursor paging is used once the cursor query parameter exists, even if it's still empty
+	qpCursor, qpCursorPaging := c.GetQuery("cursor")
+
 	// call main service
 	listOfGoods, err := a.servce.ShowListOfGoods(c.Request.Context(), service.ShowListOfGoodsInput{
-		Page:       qpPage,
-		TotalGoods: qpTotalGoods,
-		Sort:       service.Sort(c.DefaultQuery("sort", "DESC")),
-		SortBy:     c.DefaultQuery("sort_by", "id"),
+		Page:         qpPage,
+		TotalGoods:   qpTotalGoods,
+		Sort:         service.Sort(c.DefaultQuery("sort", "DESC")),
+		SortBy:       service.GoodsSortField(c.DefaultQuery("sort_by", "id")),
+		Name:         c.Query("name"),
+		MinPrice:     entity.Money(qpMinPrice),
+		MaxPrice:     entity.Money(qpMaxPrice),
+		InStockOnly:  qpInStock,
+		CursorPaging: qpCursorPaging,
+		Cursor:       qpCursor,
 	})
 	if err != nil {
-		c.JSON(
-			http.StatusInternalServerError,
-			NewInternalServerErrorResponse(err.Error()),
-		)
+		c.JSON(NewErrorResponse(err))
 		return
 	}
 
 	c.JSON(
 		http.StatusOK,
-		NewSuccessResponse(listOfGoods, a.id),
+		NewPaginatedSuccessResponse(
+			listOfGoods.Goods,
+			NewPaginationResponse(listOfGoods.Pagination, c.Request.URL),
+			a.id,
+		),
 	)
 }
 
+func (a *api) HandleShowMenu(c *gin.Context) {
+	menu, err := a.servce.ShowMenu(c.Request.Context())
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	respBody := []MenuSectionResponse{}
+	for _, section := range menu {
+		respBody = append(respBody, NewMenuSectionResponse(section))
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
+}
+
 func (a *api) HandleAddGoodsToCart(c *gin.Context) {
 	var reqBody struct {
-		CartID     int     `json:"cart_id"`
-		UserID     int     `json:"user_id" binding:"required"`
-		GoodsID    int     `json:"goods_id" binding:"required"`
-		GoodsPrice float64 `json:"goods_price" binding:"required"`
-		TotalGoods int     `json:"total_goods" binding:"required"`
+		CartID     int          `json:"cart_id"`
+		UserID     int          `json:"user_id" binding:"required"`
+		GoodsID    int          `json:"goods_id" binding:"required"`
+		VariantID  int          `json:"variant_id"`
+		GoodsPrice entity.Money `json:"goods_price"`
+		TotalGoods int          `json:"total_goods" binding:"required"`
+		// VoucherCode is optional, it's kept by the shopping cart until it's paid
+		VoucherCode string `json:"voucher_code"`
 	}
 
 	err := c.ShouldBindJSON(&reqBody)
@@ -105,36 +201,186 @@ func (a *api) HandleAddGoodsToCart(c *gin.Context) {
 	}
 
 	output, err := a.servce.AddToCart(c.Request.Context(), service.AddToCartInput{
-		CartID:     int64(reqBody.CartID),
-		UserID:     reqBody.UserID,
-		GoodsID:    reqBody.GoodsID,
-		GoodsPrice: reqBody.GoodsPrice,
-		Total:      reqBody.TotalGoods,
+		CartID:      int64(reqBody.CartID),
+		UserID:      reqBody.UserID,
+		GoodsID:     reqBody.GoodsID,
+		VariantID:   reqBody.VariantID,
+		GoodsPrice:  reqBody.GoodsPrice,
+		Total:       reqBody.TotalGoods,
+		VoucherCode: reqBody.VoucherCode,
 	})
 	if err != nil {
-		c.JSON(
-			http.StatusInternalServerError,
-			NewInternalServerErrorResponse(err.Error()),
-		)
+		c.JSON(NewErrorResponse(err))
 		return
 	}
 
 	var respBody struct {
-		CartID      int64   `json:"cart_id"`
-		TotalGoods  int     `json:"total_goods"`
-		TotalAmount float64 `json:"total_amount"`
+		CartID              int64              `json:"cart_id"`
+		TotalGoods          int                `json:"total_goods"`
+		SubtotalAmount      entity.Money       `json:"subtotal_amount"`
+		DiscountAmount      entity.Money       `json:"discount_amount"`
+		TotalAmount         entity.Money       `json:"total_amount"`
+		ServiceChargeAmount entity.Money       `json:"service_charge_amount"`
+		TaxAmount           entity.Money       `json:"tax_amount"`
+		GrandTotalAmount    entity.Money       `json:"grand_total_amount"`
+		Discounts           []DiscountResponse `json:"discounts"`
 	}
 	respBody.CartID = output.CartID
 	respBody.TotalGoods = output.TotalGoods
+	respBody.SubtotalAmount = output.SubtotalAmount
+	respBody.DiscountAmount = output.SubtotalAmount - output.TotalAmount
 	respBody.TotalAmount = output.TotalAmount
+	respBody.ServiceChargeAmount = output.Bill.ServiceChargeAmount
+	respBody.TaxAmount = output.Bill.TaxAmount
+	respBody.GrandTotalAmount = output.Bill.GrandTotalAmount
+	respBody.Discounts = NewDiscountResponses(output.Discounts)
 
 	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
 }
 
+func (a *api) HandleGetCart(c *gin.Context) {
+	cartID, err := strconv.ParseInt(c.Param("cart_id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	cart, err := a.servce.GetCart(c.Request.Context(), cartID)
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewCartResponse(cart), a.id))
+}
+
+func (a *api) HandleUpdateCartGoods(c *gin.Context) {
+	var reqBody struct {
+		TotalGoods int `json:"total_goods" binding:"required"`
+	}
+
+	var reqErrors []string
+	cartID, err := strconv.ParseInt(c.Param("cart_id"), 10, 64)
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	goodsID, err := strconv.Atoi(c.Param("goods_id"))
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	// variant is optional, only for goods which has variants
+	variantID, err := strconv.Atoi(c.DefaultQuery("variant_id", "0"))
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if err = c.ShouldBindJSON(&reqBody); err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if len(reqErrors) > 0 {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(reqErrors),
+		)
+		return
+	}
+
+	cart, err := a.servce.UpdateCartGoods(c.Request.Context(), service.UpdateCartGoodsInput{
+		CartID:    cartID,
+		GoodsID:   goodsID,
+		VariantID: variantID,
+		Total:     reqBody.TotalGoods,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewCartResponse(cart), a.id))
+}
+
+func (a *api) HandleRemoveGoodsFromCart(c *gin.Context) {
+	var reqErrors []string
+	cartID, err := strconv.ParseInt(c.Param("cart_id"), 10, 64)
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	goodsID, err := strconv.Atoi(c.Param("goods_id"))
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	// variant is optional, only for goods which has variants
+	variantID, err := strconv.Atoi(c.DefaultQuery("variant_id", "0"))
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if len(reqErrors) > 0 {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(reqErrors),
+		)
+		return
+	}
+
+	cart, err := a.servce.RemoveGoodsFromCart(c.Request.Context(), cartID, goodsID, variantID)
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewCartResponse(cart), a.id))
+}
+
+func (a *api) HandleClearCart(c *gin.Context) {
+	cartID, err := strconv.ParseInt(c.Param("cart_id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	cart, err := a.servce.ClearCart(c.Request.Context(), cartID)
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewCartResponse(cart), a.id))
+}
+
+func (a *api) HandleAbandonCart(c *gin.Context) {
+	cartID, err := strconv.ParseInt(c.Param("cart_id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	if err = a.servce.AbandonCart(c.Request.Context(), cartID); err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Shopping cart abandoned", a.id))
+}
+
 func (a *api) HandlePay(c *gin.Context) {
 	var reqBody struct {
-		CartID        int64   `json:"cart_id" binding:"required"`
-		PaymentAmount float64 `json:"payment_amount" binding:"required"`
+		CartID int64 `json:"cart_id" binding:"required"`
+		// PaymentAmount is paid in cash when there's no tenders
+		PaymentAmount entity.Money `json:"payment_amount"`
+		Tenders       []struct {
+			Method string       `json:"method" binding:"required"`
+			Amount entity.Money `json:"amount" binding:"required"`
+		} `json:"tenders" binding:"dive"`
+		// VoucherCode replace the voucher kept by the shopping cart when it's sent
+		VoucherCode string `json:"voucher_code"`
 	}
 
 	err := c.ShouldBindJSON(&reqBody)
@@ -146,32 +392,1328 @@ func (a *api) HandlePay(c *gin.Context) {
 		return
 	}
 
+	tenders := []entity.Tender{}
+	for _, tender := range reqBody.Tenders {
+		tenders = append(tenders, entity.Tender{
+			Method: entity.PaymentMethod(tender.Method),
+			Amount: tender.Amount,
+		})
+	}
+
 	trx, err := a.servce.Pay(c.Request.Context(), service.PayInput{
-		CartID:        reqBody.CartID,
-		PaymentAmount: reqBody.PaymentAmount,
+		CartID:         reqBody.CartID,
+		Tenders:        tenders,
+		PaymentAmount:  reqBody.PaymentAmount,
+		IdempotencyKey: c.GetHeader("Idempotency-Key"),
+		VoucherCode:    reqBody.VoucherCode,
 	})
 	if err != nil {
-		c.JSON(
-			http.StatusInternalServerError,
-			NewInternalServerErrorResponse(err.Error()),
-		)
+		c.JSON(NewErrorResponse(err))
 		return
 	}
 
+	// total amount is the grand total of the bill
 	var respBody struct {
-		TransactionID int64   `json:"transaction_id"`
-		TotalAmount   float64 `json:"total_amount"`
-		PaymentAmount float64 `json:"payment_amount"`
-		ReturnAmount  float64 `json:"return_amount"`
+		TransactionID       int64              `json:"transaction_id"`
+		SubtotalAmount      entity.Money       `json:"subtotal_amount"`
+		DiscountAmount      entity.Money       `json:"discount_amount"`
+		ServiceChargeAmount entity.Money       `json:"service_charge_amount"`
+		TaxBaseAmount       entity.Money       `json:"tax_base_amount"`
+		TaxAmount           entity.Money       `json:"tax_amount"`
+		TotalAmount         entity.Money       `json:"total_amount"`
+		PaymentAmount       entity.Money       `json:"payment_amount"`
+		ReturnAmount        entity.Money       `json:"return_amount"`
+		Discounts           []DiscountResponse `json:"discounts"`
+		Tenders             []TenderResponse   `json:"tenders"`
 	}
 	respBody.TransactionID = trx.ID
+	respBody.SubtotalAmount = trx.SubtotalAmount
+	respBody.DiscountAmount = trx.DiscountAmount
+	respBody.ServiceChargeAmount = trx.ServiceChargeAmount
+	respBody.TaxBaseAmount = trx.TaxBaseAmount
+	respBody.TaxAmount = trx.TaxAmount
+	respBody.Discounts = NewDiscountResponses(trx.Discounts)
 	respBody.TotalAmount = trx.TotalAmount
 	respBody.PaymentAmount = trx.PaymentAmount
 	respBody.ReturnAmount = trx.ReturnAmount
+	respBody.Tenders = NewTenderResponses(trx.Tenders)
+
+	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
+}
+
+// HandleRequestPayment issue cashless payment of the shopping cart, the shopping cart is paid once the payment
+// provider confirm it through the callback
+func (a *api) HandleRequestPayment(c *gin.Context) {
+	var reqBody struct {
+		CartID int64 `json:"cart_id" binding:"required"`
+		// Method is QRIS when it's empty
+		Method      string `json:"method"`
+		VoucherCode string `json:"voucher_code"`
+	}
+
+	err := c.ShouldBindJSON(&reqBody)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	payment, err := a.servce.RequestPayment(c.Request.Context(), service.RequestPaymentInput{
+		CartID:      reqBody.CartID,
+		Method:      entity.PaymentMethod(reqBody.Method),
+		VoucherCode: reqBody.VoucherCode,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewPaymentResponse(payment), a.id))
+}
+
+// HandleGetPayment is polled by the cashier until the payment is paid
+func (a *api) HandleGetPayment(c *gin.Context) {
+	payment, err := a.servce.GetPayment(c.Request.Context(), c.Param("reference"))
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewPaymentResponse(payment), a.id))
+}
+
+// HandlePaymentCallback complete the transaction once the payment provider confirm the payment, the signature
+// is computed from the raw body so it's passed as is into the service
+func (a *api) HandlePaymentCallback(c *gin.Context) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	trx, err := a.servce.ConfirmPayment(c.Request.Context(), service.ConfirmPaymentInput{
+		Payload:   payload,
+		Signature: c.GetHeader("X-Signature"),
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	var respBody struct {
+		TransactionID int64            `json:"transaction_id"`
+		TotalAmount   entity.Money     `json:"total_amount"`
+		Tenders       []TenderResponse `json:"tenders"`
+	}
+	respBody.TransactionID = trx.ID
+	respBody.TotalAmount = trx.TotalAmount
+	respBody.Tenders = NewTenderResponses(trx.Tenders)
+
+	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
+}
+
+func (a *api) HandleShowListOfOrders(c *gin.Context) {
+	var qpErrors []string
+	qpPage, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		qpErrors = append(qpErrors, err.Error())
+	}
+	qpTotalOrders, err := strconv.Atoi(c.DefaultQuery("total_orders", "10"))
+	if err != nil {
+		qpErrors = append(qpErrors, err.Error())
+	}
+	if len(qpErrors) > 0 {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(qpErrors),
+		)
+		return
+	}
+
+	orders, err := a.servce.ShowListOfOrders(c.Request.Context(), service.ShowListOfOrdersInput{
+		Status:      c.Query("status"),
+		Page:        qpPage,
+		TotalOrders: qpTotalOrders,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	respBody := []OrderResponse{}
+	for _, order := range orders {
+		respBody = append(respBody, NewOrderResponse(&order))
+	}
 
 	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
 }
 
+func (a *api) HandleGetOrder(c *gin.Context) {
+	orderID, err := strconv.ParseInt(c.Param("order_id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	order, err := a.servce.GetOrder(c.Request.Context(), orderID)
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewOrderResponse(order), a.id))
+}
+
+func (a *api) HandleUpdateOrderStatus(c *gin.Context) {
+	var reqBody struct {
+		Status string `json:"status" binding:"required"`
+	}
+
+	var reqErrors []string
+	orderID, err := strconv.ParseInt(c.Param("order_id"), 10, 64)
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if err = c.ShouldBindJSON(&reqBody); err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	status, ok := entity.ParseTransactionStatus(reqBody.Status)
+	if len(reqBody.Status) > 0 && !ok {
+		reqErrors = append(reqErrors, fmt.Sprintf("unknown order status %s", reqBody.Status))
+	}
+	if len(reqErrors) > 0 {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(reqErrors),
+		)
+		return
+	}
+
+	order, err := a.servce.UpdateOrderStatus(c.Request.Context(), service.UpdateOrderStatusInput{
+		TransactionID: orderID,
+		Status:        status,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewOrderResponse(order), a.id))
+}
+
+func (a *api) HandleRefundOrder(c *gin.Context) {
+	var reqBody struct {
+		Items []struct {
+			GoodsID    int `json:"goods_id" binding:"required"`
+			VariantID  int `json:"variant_id"`
+			TotalGoods int `json:"total_goods" binding:"required"`
+		} `json:"items" binding:"dive"`
+		Reason string `json:"reason"`
+	}
+
+	var reqErrors []string
+	orderID, err := strconv.ParseInt(c.Param("order_id"), 10, 64)
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	// the body is optional for full refund
+	if err = c.ShouldBindJSON(&reqBody); err != nil && !errors.Is(err, io.EOF) {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if len(reqErrors) > 0 {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(reqErrors),
+		)
+		return
+	}
+
+	// no items means full refund
+	input := service.RefundOrderInput{
+		TransactionID: orderID,
+		Reason:        reqBody.Reason,
+	}
+	for _, item := range reqBody.Items {
+		input.Items = append(input.Items, entity.RefundItem{
+			GoodsID:    item.GoodsID,
+			VariantID:  item.VariantID,
+			TotalGoods: item.TotalGoods,
+		})
+	}
+
+	refund, err := a.servce.RefundOrder(c.Request.Context(), input)
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewRefundResponse(refund), a.id))
+}
+
+func (a *api) HandleCancelOrder(c *gin.Context) {
+	var reqBody struct {
+		Reason string `json:"reason"`
+	}
+
+	orderID, err := strconv.ParseInt(c.Param("order_id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+	// reason is optional, so the body could be empty
+	if err = c.ShouldBindJSON(&reqBody); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	refund, err := a.servce.CancelOrder(c.Request.Context(), orderID, reqBody.Reason)
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewRefundResponse(refund), a.id))
+}
+
+func (a *api) HandleGetDailySalesReport(c *gin.Context) {
+	date := time.Now()
+	if qpDate := c.Query("date"); len(qpDate) > 0 {
+		var err error
+		date, err = time.ParseInLocation("2006-01-02", qpDate, time.Local)
+		if err != nil {
+			c.JSON(
+				http.StatusBadRequest,
+				NewBadRequestErrorResponse(err.Error()),
+			)
+			return
+		}
+	}
+
+	summary, err := a.servce.GetDailySalesReport(c.Request.Context(), service.GetDailySalesReportInput{
+		Date: date,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	var respBody struct {
+		Date              string                `json:"date"`
+		TotalTransactions int                   `json:"total_transactions"`
+		GrossAmount       entity.Money          `json:"gross_amount"`
+		TotalRefunds      int                   `json:"total_refunds"`
+		RefundAmount      entity.Money          `json:"refund_amount"`
+		NetAmount         entity.Money          `json:"net_amount"`
+		CostOfGoodsSold   entity.Money          `json:"cost_of_goods_sold"`
+		GrossProfit       entity.Money          `json:"gross_profit"`
+		Tenders           []TenderTotalResponse `json:"tenders"`
+	}
+	respBody.Date = date.Format("2006-01-02")
+	respBody.TotalTransactions = summary.TotalTransactions
+	respBody.GrossAmount = summary.GrossAmount
+	respBody.TotalRefunds = summary.TotalRefunds
+	respBody.RefundAmount = summary.RefundAmount
+	respBody.NetAmount = summary.NetAmount()
+	respBody.CostOfGoodsSold = summary.CostOfGoodsSold
+	respBody.GrossProfit = summary.GrossProfit()
+	respBody.Tenders = NewTenderTotalResponses(summary.Tenders)
+
+	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
+}
+
+func (a *api) HandleGetMonthlyTaxRecap(c *gin.Context) {
+	month := time.Now()
+	if qpMonth := c.Query("month"); len(qpMonth) > 0 {
+		var err error
+		month, err = time.ParseInLocation("2006-01", qpMonth, time.Local)
+		if err != nil {
+			c.JSON(
+				http.StatusBadRequest,
+				NewBadRequestErrorResponse(err.Error()),
+			)
+			return
+		}
+	}
+
+	recap, err := a.servce.GetMonthlyTaxRecap(c.Request.Context(), service.GetMonthlyTaxRecapInput{
+		Month: month,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	var respBody struct {
+		Month               string       `json:"month"`
+		TotalTransactions   int          `json:"total_transactions"`
+		SubtotalAmount      entity.Money `json:"subtotal_amount"`
+		DiscountAmount      entity.Money `json:"discount_amount"`
+		ServiceChargeAmount entity.Money `json:"service_charge_amount"`
+		TaxBaseAmount       entity.Money `json:"tax_base_amount"`
+		TaxAmount           entity.Money `json:"tax_amount"`
+		GrandTotalAmount    entity.Money `json:"grand_total_amount"`
+		RefundAmount        entity.Money `json:"refund_amount"`
+		RefundedTaxAmount   entity.Money `json:"refunded_tax_amount"`
+		NetTaxAmount        entity.Money `json:"net_tax_amount"`
+	}
+	respBody.Month = month.Format("2006-01")
+	respBody.TotalTransactions = recap.TotalTransactions
+	respBody.SubtotalAmount = recap.SubtotalAmount
+	respBody.DiscountAmount = recap.DiscountAmount
+	respBody.ServiceChargeAmount = recap.ServiceChargeAmount
+	respBody.TaxBaseAmount = recap.TaxBaseAmount
+	respBody.TaxAmount = recap.TaxAmount
+	respBody.GrandTotalAmount = recap.GrandTotalAmount
+	respBody.RefundAmount = recap.RefundAmount
+	respBody.RefundedTaxAmount = recap.RefundedTaxAmount
+	respBody.NetTaxAmount = recap.NetTaxAmount()
+
+	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
+}
+
+func (a *api) HandleCalculateDeliveryPrice(c *gin.Context) {
+	var qpErrors []string
+	qpLocation, err := strconv.Atoi(c.Query("location"))
+	if err != nil {
+		qpErrors = append(qpErrors, err.Error())
+	}
+	qpWeight, err := strconv.ParseFloat(c.DefaultQuery("weight", "0"), 32)
+	if err != nil {
+		qpErrors = append(qpErrors, err.Error())
+	}
+	qpVolume, err := strconv.ParseFloat(c.DefaultQuery("volume", "0"), 32)
+	if err != nil {
+		qpErrors = append(qpErrors, err.Error())
+	}
+	if len(qpErrors) > 0 {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(qpErrors),
+		)
+		return
+	}
+
+	quote, err := a.servce.ReqCalculateDeliveryPrice(c.Request.Context(), service.ReqCalculateDeliveryPriceInput{
+		Location: qpLocation,
+		GoodsSpec: service.GoodsSpecification{
+			Weight: float32(qpWeight),
+			Volume: float32(qpVolume),
+		},
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	var respBody struct {
+		Origin           int          `json:"origin"`
+		Destination      int          `json:"destination"`
+		Zone             string       `json:"zone"`
+		ChargeableWeight float32      `json:"chargeable_weight"`
+		Price            entity.Money `json:"price"`
+	}
+	respBody.Origin = quote.Origin
+	respBody.Destination = quote.Destination
+	respBody.Zone = quote.Zone
+	respBody.ChargeableWeight = quote.ChargeableWeight
+	respBody.Price = quote.Price
+
+	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
+}
+
+func (a *api) HandlePickupDelivery(c *gin.Context) {
+	var reqBody struct {
+		TransactionID int64   `json:"transaction_id" binding:"required"`
+		Location      int     `json:"location" binding:"required"`
+		Weight        float32 `json:"weight"`
+		Volume        float32 `json:"volume"`
+	}
+
+	err := c.ShouldBindJSON(&reqBody)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	delivery, err := a.servce.ReqPickupDelivery(c.Request.Context(), service.ReqPickupDeliveryInput{
+		TransactionID: reqBody.TransactionID,
+		Location:      reqBody.Location,
+		GoodsSpec: service.GoodsSpecification{
+			Weight: reqBody.Weight,
+			Volume: reqBody.Volume,
+		},
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	var respBody struct {
+		TransactionID  int64        `json:"transaction_id"`
+		TrackingNumber string       `json:"tracking_number"`
+		Destination    int          `json:"destination"`
+		Price          entity.Money `json:"price"`
+		Status         string       `json:"status"`
+	}
+	respBody.TransactionID = delivery.TransactionID
+	respBody.TrackingNumber = delivery.TrackingNumber
+	respBody.Destination = delivery.Destination
+	respBody.Price = delivery.Price
+	respBody.Status = string(delivery.Status)
+
+	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
+}
+
+func (a *api) HandleUpdateStock(c *gin.Context) {
+	var reqBody struct {
+		Action    string `json:"action" binding:"required"`
+		VariantID int    `json:"variant_id"`
+		Total     int    `json:"total" binding:"required"`
+		// actor & reason is recorded in the stock movements
+		Actor  string `json:"actor"`
+		Reason string `json:"reason"`
+		// purchase price per unit of the increased stocks, it updates the average cost of the goods
+		UnitCost float64 `json:"unit_cost"`
+		// expiry date (YYYY-MM-DD) of the increased stocks, empty when the goods doesn't expire
+		ExpiryDate string `json:"expiry_date"`
+	}
+
+	var reqErrors []string
+	if err := c.ShouldBindJSON(&reqBody); err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	expiresAt, err := parseExpiryDate(reqBody.ExpiryDate)
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if len(reqErrors) > 0 {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(reqErrors),
+		)
+		return
+	}
+
+	// stuff name could be either goods ID or goods name
+	input := service.UpdateStockInput{
+		Action:    service.UpdateStockAction(reqBody.Action),
+		VariantID: reqBody.VariantID,
+		Total:     reqBody.Total,
+		Actor:     reqBody.Actor,
+		Reason:    reqBody.Reason,
+		UnitCost:  reqBody.UnitCost,
+		ExpiresAt: expiresAt,
+	}
+	stuffName := c.Param("stuff_name")
+	if goodsID, err := strconv.Atoi(stuffName); err == nil {
+		input.GoodsID = goodsID
+	} else {
+		input.GoodsName = stuffName
+	}
+
+	goods, err := a.servce.UpdateStock(c.Request.Context(), input)
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(goods, a.id))
+}
+
+func (a *api) HandleShowStockMovements(c *gin.Context) {
+	var reqErrors []string
+	goodsID, err := strconv.Atoi(c.Param("goods_id"))
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	variantID, err := strconv.Atoi(c.DefaultQuery("variant_id", "0"))
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	// the date range is today when it's not set
+	from, to := time.Now(), time.Now()
+	if qpFrom := c.Query("from"); len(qpFrom) > 0 {
+		if from, err = time.ParseInLocation("2006-01-02", qpFrom, time.Local); err != nil {
+			reqErrors = append(reqErrors, err.Error())
+		}
+	}
+	if qpTo := c.Query("to"); len(qpTo) > 0 {
+		if to, err = time.ParseInLocation("2006-01-02", qpTo, time.Local); err != nil {
+			reqErrors = append(reqErrors, err.Error())
+		}
+	}
+	if len(reqErrors) > 0 {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(reqErrors),
+		)
+		return
+	}
+
+	movements, err := a.servce.ShowStockMovements(c.Request.Context(), service.ShowStockMovementsInput{
+		GoodsID:   goodsID,
+		VariantID: variantID,
+		From:      from,
+		To:        to,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	respBody := []StockMovementResponse{}
+	for _, movement := range movements {
+		respBody = append(respBody, NewStockMovementResponse(movement))
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
+}
+
+func (a *api) HandleReconcileStocks(c *gin.Context) {
+	drifts, err := a.servce.ReconcileStocks(c.Request.Context())
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	var respBody struct {
+		IsBalanced bool                 `json:"is_balanced"`
+		Drifts     []StockDriftResponse `json:"drifts"`
+	}
+	respBody.IsBalanced = len(drifts) == 0
+	respBody.Drifts = []StockDriftResponse{}
+	for _, drift := range drifts {
+		respBody.Drifts = append(respBody.Drifts, StockDriftResponse{
+			GoodsID:      drift.GoodsID,
+			VariantID:    drift.VariantID,
+			Stocks:       drift.Stocks,
+			LedgerStocks: drift.LedgerStocks,
+			Drift:        drift.Drift(),
+		})
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
+}
+
+func (a *api) HandleShowLowStocks(c *gin.Context) {
+	lowStocks, err := a.servce.ShowLowStocks(c.Request.Context())
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	respBody := []LowStockResponse{}
+	for _, lowStock := range lowStocks {
+		respBody = append(respBody, LowStockResponse{
+			GoodsID:      lowStock.GoodsID,
+			VariantID:    lowStock.VariantID,
+			Name:         lowStock.Name,
+			Unit:         lowStock.Unit,
+			Stocks:       lowStock.Stocks,
+			ReorderPoint: lowStock.ReorderPoint,
+		})
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
+}
+
+func (a *api) HandleShowExpiringBatches(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "3"))
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	batches, err := a.servce.ShowExpiringBatches(c.Request.Context(), service.ShowExpiringBatchesInput{
+		Days: days,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	now := time.Now().Unix()
+	respBody := []StockBatchResponse{}
+	for _, batch := range batches {
+		respBody = append(respBody, NewStockBatchResponse(batch, now))
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
+}
+
+func (a *api) HandleWriteOffExpiredBatches(c *gin.Context) {
+	var reqBody struct {
+		// goods ID is empty when the expired batches of all goods is written off
+		GoodsID int    `json:"goods_id"`
+		Actor   string `json:"actor"`
+		Reason  string `json:"reason" binding:"required"`
+	}
+
+	err := c.ShouldBindJSON(&reqBody)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	movements, err := a.servce.WriteOffExpiredBatches(c.Request.Context(), service.WriteOffExpiredBatchesInput{
+		GoodsID: reqBody.GoodsID,
+		Actor:   reqBody.Actor,
+		Reason:  reqBody.Reason,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	respBody := []StockMovementResponse{}
+	for _, movement := range movements {
+		respBody = append(respBody, NewStockMovementResponse(movement))
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
+}
+
+func (a *api) HandleOpenStockCount(c *gin.Context) {
+	var reqBody struct {
+		OpenedBy string `json:"opened_by" binding:"required"`
+	}
+
+	err := c.ShouldBindJSON(&reqBody)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	stockCount, err := a.servce.OpenStockCount(c.Request.Context(), service.OpenStockCountInput{
+		OpenedBy: reqBody.OpenedBy,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewStockCountResponse(stockCount), a.id))
+}
+
+func (a *api) HandleGetStockCount(c *gin.Context) {
+	countID, err := strconv.ParseInt(c.Param("count_id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	stockCount, err := a.servce.GetStockCount(c.Request.Context(), countID)
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewStockCountResponse(stockCount), a.id))
+}
+
+func (a *api) HandleSubmitStockCount(c *gin.Context) {
+	var reqBody struct {
+		Items []struct {
+			GoodsID       int `json:"goods_id" binding:"required"`
+			VariantID     int `json:"variant_id"`
+			CountedStocks int `json:"counted_stocks" binding:"min=0"`
+		} `json:"items" binding:"required,dive"`
+	}
+
+	var reqErrors []string
+	countID, err := strconv.ParseInt(c.Param("count_id"), 10, 64)
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if err = c.ShouldBindJSON(&reqBody); err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if len(reqErrors) > 0 {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(reqErrors),
+		)
+		return
+	}
+
+	input := service.SubmitStockCountInput{CountID: countID}
+	for _, item := range reqBody.Items {
+		input.Items = append(input.Items, entity.StockCountItem{
+			GoodsID:       item.GoodsID,
+			VariantID:     item.VariantID,
+			CountedStocks: item.CountedStocks,
+		})
+	}
+	stockCount, err := a.servce.SubmitStockCount(c.Request.Context(), input)
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewStockCountResponse(stockCount), a.id))
+}
+
+func (a *api) HandleApproveStockCount(c *gin.Context) {
+	var reqBody struct {
+		ApprovedBy string `json:"approved_by" binding:"required"`
+	}
+
+	var reqErrors []string
+	countID, err := strconv.ParseInt(c.Param("count_id"), 10, 64)
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if err = c.ShouldBindJSON(&reqBody); err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if len(reqErrors) > 0 {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(reqErrors),
+		)
+		return
+	}
+
+	stockCount, err := a.servce.ApproveStockCount(c.Request.Context(), service.ApproveStockCountInput{
+		CountID:    countID,
+		ApprovedBy: reqBody.ApprovedBy,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewStockCountResponse(stockCount), a.id))
+}
+
+func (a *api) HandleCreateSupplier(c *gin.Context) {
+	var reqBody struct {
+		Name    string `json:"name" binding:"required"`
+		Phone   string `json:"phone"`
+		Address string `json:"address"`
+	}
+
+	err := c.ShouldBindJSON(&reqBody)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	supplier, err := a.servce.CreateSupplier(c.Request.Context(), service.CreateSupplierInput{
+		Name:    reqBody.Name,
+		Phone:   reqBody.Phone,
+		Address: reqBody.Address,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewSupplierResponse(*supplier), a.id))
+}
+
+func (a *api) HandleShowSuppliers(c *gin.Context) {
+	suppliers, err := a.servce.ShowSuppliers(c.Request.Context())
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	respBody := []SupplierResponse{}
+	for _, supplier := range suppliers {
+		respBody = append(respBody, NewSupplierResponse(supplier))
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
+}
+
+func (a *api) HandleCreatePromotion(c *gin.Context) {
+	var reqBody struct {
+		Name         string       `json:"name" binding:"required"`
+		Type         string       `json:"type" binding:"required"`
+		GoodsID      int          `json:"goods_id"`
+		Value        float64      `json:"value"`
+		Amount       entity.Money `json:"amount"`
+		BuyQuantity  int          `json:"buy_quantity"`
+		FreeQuantity int          `json:"free_quantity"`
+		MinPurchase  entity.Money `json:"min_purchase"`
+		// HappyHourStart & HappyHourEnd is in HH:MM format, leave them empty for promotion which applies all day
+		HappyHourStart string `json:"happy_hour_start"`
+		HappyHourEnd   string `json:"happy_hour_end"`
+		StartsAt       int64  `json:"starts_at"`
+		EndsAt         int64  `json:"ends_at"`
+		VoucherCode    string `json:"voucher_code"`
+		UsageLimit     int    `json:"usage_limit"`
+	}
+
+	err := c.ShouldBindJSON(&reqBody)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	promotion, err := a.servce.CreatePromotion(c.Request.Context(), service.CreatePromotionInput{
+		Name:           reqBody.Name,
+		Type:           reqBody.Type,
+		GoodsID:        reqBody.GoodsID,
+		Value:          reqBody.Value,
+		Amount:         reqBody.Amount,
+		BuyQuantity:    reqBody.BuyQuantity,
+		FreeQuantity:   reqBody.FreeQuantity,
+		MinPurchase:    reqBody.MinPurchase,
+		HappyHourStart: reqBody.HappyHourStart,
+		HappyHourEnd:   reqBody.HappyHourEnd,
+		StartsAt:       reqBody.StartsAt,
+		EndsAt:         reqBody.EndsAt,
+		VoucherCode:    reqBody.VoucherCode,
+		UsageLimit:     reqBody.UsageLimit,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewPromotionResponse(*promotion), a.id))
+}
+
+func (a *api) HandleShowPromotions(c *gin.Context) {
+	promotions, err := a.servce.ShowPromotions(c.Request.Context())
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	respBody := []PromotionResponse{}
+	for _, promotion := range promotions {
+		respBody = append(respBody, NewPromotionResponse(promotion))
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
+}
+
+func (a *api) HandleCreatePurchaseOrder(c *gin.Context) {
+	var reqBody struct {
+		SupplierID int    `json:"supplier_id" binding:"required"`
+		Note       string `json:"note"`
+		CreatedBy  string `json:"created_by"`
+		Items      []struct {
+			GoodsID   int     `json:"goods_id" binding:"required"`
+			VariantID int     `json:"variant_id"`
+			Quantity  int     `json:"quantity" binding:"required"`
+			UnitCost  float64 `json:"unit_cost"`
+		} `json:"items" binding:"required,dive"`
+	}
+
+	err := c.ShouldBindJSON(&reqBody)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	input := service.CreatePurchaseOrderInput{
+		SupplierID: reqBody.SupplierID,
+		Note:       reqBody.Note,
+		CreatedBy:  reqBody.CreatedBy,
+	}
+	for _, item := range reqBody.Items {
+		input.Items = append(input.Items, entity.PurchaseOrderItem{
+			GoodsID:   item.GoodsID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			UnitCost:  item.UnitCost,
+		})
+	}
+	purchaseOrder, err := a.servce.CreatePurchaseOrder(c.Request.Context(), input)
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewPurchaseOrderResponse(*purchaseOrder), a.id))
+}
+
+func (a *api) HandleShowPurchaseOrders(c *gin.Context) {
+	supplierID, err := strconv.Atoi(c.DefaultQuery("supplier_id", "0"))
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	purchaseOrders, err := a.servce.ShowPurchaseOrders(c.Request.Context(), service.ShowPurchaseOrdersInput{
+		SupplierID: supplierID,
+		Status:     entity.PurchaseOrderStatus(c.Query("status")),
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	respBody := []PurchaseOrderResponse{}
+	for _, purchaseOrder := range purchaseOrders {
+		respBody = append(respBody, NewPurchaseOrderResponse(purchaseOrder))
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(respBody, a.id))
+}
+
+func (a *api) HandleGetPurchaseOrder(c *gin.Context) {
+	orderID, err := strconv.ParseInt(c.Param("order_id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	purchaseOrder, err := a.servce.GetPurchaseOrder(c.Request.Context(), orderID)
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewPurchaseOrderResponse(*purchaseOrder), a.id))
+}
+
+func (a *api) HandleReceivePurchaseOrder(c *gin.Context) {
+	var reqBody struct {
+		ReceivedBy string `json:"received_by"`
+		Items      []struct {
+			GoodsID   int `json:"goods_id" binding:"required"`
+			VariantID int `json:"variant_id"`
+			Quantity  int `json:"quantity" binding:"required"`
+			// expiry date (YYYY-MM-DD) of the received goods, empty when the goods doesn't expire
+			ExpiryDate string `json:"expiry_date"`
+		} `json:"items" binding:"required,dive"`
+	}
+
+	var reqErrors []string
+	orderID, err := strconv.ParseInt(c.Param("order_id"), 10, 64)
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if err = c.ShouldBindJSON(&reqBody); err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if len(reqErrors) > 0 {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(reqErrors),
+		)
+		return
+	}
+
+	input := service.ReceivePurchaseOrderInput{
+		OrderID:    orderID,
+		ReceivedBy: reqBody.ReceivedBy,
+	}
+	for _, item := range reqBody.Items {
+		expiresAt, err := parseExpiryDate(item.ExpiryDate)
+		if err != nil {
+			c.JSON(
+				http.StatusBadRequest,
+				NewBadRequestErrorResponse(err.Error()),
+			)
+			return
+		}
+		input.Items = append(input.Items, entity.PurchaseOrderReceiptItem{
+			GoodsID:   item.GoodsID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			ExpiresAt: expiresAt,
+		})
+	}
+	purchaseOrder, err := a.servce.ReceivePurchaseOrder(c.Request.Context(), input)
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewPurchaseOrderResponse(*purchaseOrder), a.id))
+}
+
+func (a *api) HandleCancelPurchaseOrder(c *gin.Context) {
+	orderID, err := strconv.ParseInt(c.Param("order_id"), 10, 64)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	purchaseOrder, err := a.servce.CancelPurchaseOrder(c.Request.Context(), orderID)
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(NewPurchaseOrderResponse(*purchaseOrder), a.id))
+}
+
+func (a *api) HandleCreateGoods(c *gin.Context) {
+	var reqBody struct {
+		Name       string       `json:"name" binding:"required"`
+		Stocks     int          `json:"stocks"`
+		Price      entity.Money `json:"price" binding:"required"`
+		CategoryID int          `json:"category_id"`
+		// raw material is consumed by recipe instead of sold, e.g. coffee beans in gram
+		IsRawMaterial bool   `json:"is_raw_material"`
+		Unit          string `json:"unit"`
+		// low stock alert is sent when the stocks drop to the reorder point, zero means no alert
+		ReorderPoint int `json:"reorder_point"`
+		// the price already contains the PPN, otherwise the PPN is added on top of the price
+		PriceIncludesTax bool `json:"price_includes_tax"`
+	}
+
+	err := c.ShouldBindJSON(&reqBody)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	goods, err := a.servce.CreateGoods(c.Request.Context(), service.CreateGoodsInput{
+		Name:             reqBody.Name,
+		Stocks:           reqBody.Stocks,
+		Price:            reqBody.Price,
+		CategoryID:       reqBody.CategoryID,
+		IsRawMaterial:    reqBody.IsRawMaterial,
+		Unit:             reqBody.Unit,
+		ReorderPoint:     reqBody.ReorderPoint,
+		PriceIncludesTax: reqBody.PriceIncludesTax,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(goods, a.id))
+}
+
+func (a *api) HandleGetGoods(c *gin.Context) {
+	goodsID, err := strconv.Atoi(c.Param("goods_id"))
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	goods, err := a.servce.GetGoodsByID(c.Request.Context(), goodsID)
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(goods, a.id))
+}
+
+func (a *api) HandleUpdateGoods(c *gin.Context) {
+	var reqBody struct {
+		Name             string       `json:"name" binding:"required"`
+		Price            entity.Money `json:"price" binding:"required"`
+		CategoryID       int          `json:"category_id"`
+		ReorderPoint     int          `json:"reorder_point"`
+		PriceIncludesTax bool         `json:"price_includes_tax"`
+	}
+
+	var reqErrors []string
+	goodsID, err := strconv.Atoi(c.Param("goods_id"))
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if err = c.ShouldBindJSON(&reqBody); err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if len(reqErrors) > 0 {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(reqErrors),
+		)
+		return
+	}
+
+	goods, err := a.servce.UpdateGoods(c.Request.Context(), service.UpdateGoodsInput{
+		ID:               goodsID,
+		Name:             reqBody.Name,
+		Price:            reqBody.Price,
+		CategoryID:       reqBody.CategoryID,
+		ReorderPoint:     reqBody.ReorderPoint,
+		PriceIncludesTax: reqBody.PriceIncludesTax,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(goods, a.id))
+}
+
+func (a *api) HandleDeleteGoods(c *gin.Context) {
+	goodsID, err := strconv.Atoi(c.Param("goods_id"))
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	if err = a.servce.DeleteGoods(c.Request.Context(), goodsID); err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse("Goods deleted", a.id))
+}
+
+func (a *api) HandleCreateGoodsVariant(c *gin.Context) {
+	var reqBody struct {
+		Name       string       `json:"name" binding:"required"`
+		PriceDelta entity.Money `json:"price_delta"`
+		Stocks     int          `json:"stocks"`
+	}
+
+	var reqErrors []string
+	goodsID, err := strconv.Atoi(c.Param("goods_id"))
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if err = c.ShouldBindJSON(&reqBody); err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if len(reqErrors) > 0 {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(reqErrors),
+		)
+		return
+	}
+
+	variant, err := a.servce.CreateGoodsVariant(c.Request.Context(), service.CreateGoodsVariantInput{
+		GoodsID:    goodsID,
+		Name:       reqBody.Name,
+		PriceDelta: reqBody.PriceDelta,
+		Stocks:     reqBody.Stocks,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(variant, a.id))
+}
+
+func (a *api) HandleSetRecipe(c *gin.Context) {
+	var reqBody struct {
+		Ingredients []struct {
+			IngredientID int `json:"ingredient_id" binding:"required"`
+			Quantity     int `json:"quantity" binding:"required"`
+		} `json:"ingredients" binding:"dive"`
+	}
+
+	var reqErrors []string
+	goodsID, err := strconv.Atoi(c.Param("goods_id"))
+	if err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if err = c.ShouldBindJSON(&reqBody); err != nil {
+		reqErrors = append(reqErrors, err.Error())
+	}
+	if len(reqErrors) > 0 {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(reqErrors),
+		)
+		return
+	}
+
+	input := service.SetRecipeInput{GoodsID: goodsID}
+	for _, ingredient := range reqBody.Ingredients {
+		input.Ingredients = append(input.Ingredients, entity.RecipeIngredient{
+			IngredientID: ingredient.IngredientID,
+			Quantity:     ingredient.Quantity,
+		})
+	}
+	recipe, err := a.servce.SetRecipe(c.Request.Context(), input)
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(recipe, a.id))
+}
+
+func (a *api) HandleCreateCategory(c *gin.Context) {
+	var reqBody struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	err := c.ShouldBindJSON(&reqBody)
+	if err != nil {
+		c.JSON(
+			http.StatusBadRequest,
+			NewBadRequestErrorResponse(err.Error()),
+		)
+		return
+	}
+
+	category, err := a.servce.CreateCategory(c.Request.Context(), service.CreateCategoryInput{
+		Name: reqBody.Name,
+	})
+	if err != nil {
+		c.JSON(NewErrorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSuccessResponse(category, a.id))
+}
+
 func (a *api) HandleClearDB(c *gin.Context) {
 	if err := a.servce.ClearDatabase(c.Request.Context()); err != nil {
 		c.JSON(
@@ -1,8 +1,23 @@
 package rest
 
+import "time"
+
 type QueryParamListGoods struct {
 	Page   int
 	Limit  int
 	Sort   string
 	SortBy string
 }
+
+// parseExpiryDate convert the expiry date (YYYY-MM-DD) in the server local time into the time the goods no longer
+// sellable which is the end of that day, empty date means the goods doesn't expire
+func parseExpiryDate(date string) (int64, error) {
+	if len(date) == 0 {
+		return 0, nil
+	}
+	expiryDate, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return 0, err
+	}
+	return expiryDate.AddDate(0, 0, 1).Unix(), nil
+}
@@ -1,15 +1,638 @@
 package rest
 
-import "net/http"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/entity"
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/service"
+)
 
 type Response struct {
 	ServiceID string      `json:"svc_id"`
 	Code      int         `json:"code"`
 	Status    string      `json:"status"`
 	Data      interface{} `json:"data,omitempty"`
+	Meta      interface{} `json:"meta,omitempty"`
 	Errors    interface{} `json:"errors,omitempty"`
 }
 
+type PaginationResponse struct {
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit"`
+	TotalItems *int   `json:"total_items,omitempty"`
+	TotalPages *int   `json:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	Next       string `json:"next,omitempty"`
+	Prev       string `json:"prev,omitempty"`
+}
+
+// NewPaginationResponse build the pagination metadata, the links are the current request URL with
+// only the paging query parameters replaced so the filters and sorting are kept
+func NewPaginationResponse(pagination service.Pagination, reqURL *url.URL) PaginationResponse {
+	resp := PaginationResponse{
+		Page:  pagination.Page,
+		Limit: pagination.Limit,
+	}
+	link := func(key, value string) string {
+		query := reqURL.Query()
+		query.Set(key, value)
+		return reqURL.Path + "?" + query.Encode()
+	}
+
+	// cursor paging doesn't count the goods
+	if pagination.Page == 0 {
+		if pagination.HasNext {
+			resp.NextCursor = pagination.NextCursor
+			resp.Next = link("cursor", pagination.NextCursor)
+		}
+		if pagination.HasPrev {
+			resp.PrevCursor = pagination.PrevCursor
+			resp.Prev = link("cursor", pagination.PrevCursor)
+		}
+		return resp
+	}
+
+	resp.TotalItems = &pagination.TotalItems
+	resp.TotalPages = &pagination.TotalPages
+	if pagination.HasNext {
+		resp.Next = link("page", strconv.Itoa(pagination.Page+1))
+	}
+	if pagination.HasPrev {
+		resp.Prev = link("page", strconv.Itoa(pagination.Page-1))
+	}
+	return resp
+}
+
+type CartDetailResponse struct {
+	GoodsID    int          `json:"goods_id"`
+	VariantID  int          `json:"variant_id,omitempty"`
+	TotalGoods int          `json:"total_goods"`
+	GoodsPrice entity.Money `json:"goods_price"`
+	Subtotal   entity.Money `json:"subtotal"`
+}
+
+type CartResponse struct {
+	CartID              int64                `json:"cart_id"`
+	UserID              int                  `json:"user_id"`
+	TotalGoods          int                  `json:"total_goods"`
+	SubtotalAmount      entity.Money         `json:"subtotal_amount"`
+	DiscountAmount      entity.Money         `json:"discount_amount"`
+	TotalAmount         entity.Money         `json:"total_amount"`
+	ServiceChargeAmount entity.Money         `json:"service_charge_amount"`
+	TaxAmount           entity.Money         `json:"tax_amount"`
+	GrandTotalAmount    entity.Money         `json:"grand_total_amount"`
+	VoucherCode         string               `json:"voucher_code,omitempty"`
+	Details             []CartDetailResponse `json:"details"`
+	Discounts           []DiscountResponse   `json:"discounts"`
+}
+
+func NewCartResponse(cart *entity.ShoppingCart) CartResponse {
+	resp := CartResponse{
+		CartID:         cart.ID,
+		UserID:         cart.UserID,
+		TotalGoods:     cart.GetTotalGoods(),
+		SubtotalAmount: cart.GetSubtotalAmount(),
+		TotalAmount:    cart.GetTotalAmount(),
+		VoucherCode:    cart.VoucherCode,
+		Details:        []CartDetailResponse{},
+	}
+	resp.DiscountAmount = cart.GetDiscountAmount()
+	bill := cart.GetBill()
+	resp.ServiceChargeAmount = bill.ServiceChargeAmount
+	resp.TaxAmount = bill.TaxAmount
+	resp.GrandTotalAmount = bill.GrandTotalAmount
+	resp.Discounts = NewDiscountResponses(cart.Discounts)
+	for _, detail := range cart.Details {
+		resp.Details = append(resp.Details, CartDetailResponse{
+			GoodsID:    detail.GoodsID,
+			VariantID:  detail.VariantID,
+			TotalGoods: detail.TotalGoods,
+			GoodsPrice: detail.GoodsPrice,
+			Subtotal:   detail.GoodsPrice.Mul(detail.TotalGoods),
+		})
+	}
+
+	return resp
+}
+
+// DiscountResponse is a line of the bill, so the cashier could explain where the discount come from
+type DiscountResponse struct {
+	PromotionID int          `json:"promotion_id"`
+	Name        string       `json:"name"`
+	VoucherCode string       `json:"voucher_code,omitempty"`
+	Amount      entity.Money `json:"amount"`
+}
+
+func NewDiscountResponses(discounts []entity.Discount) []DiscountResponse {
+	resp := []DiscountResponse{}
+	for _, discount := range discounts {
+		resp = append(resp, DiscountResponse(discount))
+	}
+	return resp
+}
+
+type TenderResponse struct {
+	Method string       `json:"method"`
+	Amount entity.Money `json:"amount"`
+}
+
+func NewTenderResponses(tenders []entity.Tender) []TenderResponse {
+	resp := []TenderResponse{}
+	for _, tender := range tenders {
+		resp = append(resp, TenderResponse{
+			Method: string(tender.Method),
+			Amount: tender.Amount,
+		})
+	}
+	return resp
+}
+
+type PaymentResponse struct {
+	Reference     string       `json:"reference"`
+	TransactionID int64        `json:"transaction_id"`
+	Method        string       `json:"method"`
+	Amount        entity.Money `json:"amount"`
+	Status        string       `json:"status"`
+	QRPayload     string       `json:"qr_payload"`
+	VoucherCode   string       `json:"voucher_code"`
+	CreatedAt     int64        `json:"created_at"`
+	ExpiresAt     int64        `json:"expires_at"`
+	PaidAt        int64        `json:"paid_at"`
+}
+
+func NewPaymentResponse(payment *entity.Payment) PaymentResponse {
+	return PaymentResponse{
+		Reference:     payment.Reference,
+		TransactionID: payment.TransactionID,
+		Method:        string(payment.Method),
+		Amount:        payment.Amount,
+		Status:        string(payment.Status),
+		QRPayload:     payment.QRPayload,
+		VoucherCode:   payment.VoucherCode,
+		CreatedAt:     payment.CreatedAt,
+		ExpiresAt:     payment.ExpiresAt,
+		PaidAt:        payment.PaidAt,
+	}
+}
+
+type TenderTotalResponse struct {
+	Method            string       `json:"method"`
+	TotalTransactions int          `json:"total_transactions"`
+	Amount            entity.Money `json:"amount"`
+}
+
+func NewTenderTotalResponses(tenderTotals []entity.TenderTotal) []TenderTotalResponse {
+	resp := []TenderTotalResponse{}
+	for _, tenderTotal := range tenderTotals {
+		resp = append(resp, TenderTotalResponse{
+			Method:            string(tenderTotal.Method),
+			TotalTransactions: tenderTotal.TotalTransactions,
+			Amount:            tenderTotal.Amount,
+		})
+	}
+	return resp
+}
+
+type MenuVariantResponse struct {
+	VariantID       int          `json:"variant_id"`
+	Name            string       `json:"name"`
+	Price           entity.Money `json:"price"`
+	AvailableStocks int          `json:"available_stocks"`
+}
+
+type MenuGoodsResponse struct {
+	GoodsID         int                   `json:"goods_id"`
+	Name            string                `json:"name"`
+	Price           entity.Money          `json:"price"`
+	AvailableStocks int                   `json:"available_stocks"`
+	Variants        []MenuVariantResponse `json:"variants,omitempty"`
+}
+
+type MenuSectionResponse struct {
+	CategoryID   int                 `json:"category_id"`
+	CategoryName string              `json:"category_name"`
+	Goods        []MenuGoodsResponse `json:"goods"`
+}
+
+func NewMenuSectionResponse(section service.MenuSection) MenuSectionResponse {
+	resp := MenuSectionResponse{
+		CategoryID:   section.Category.ID,
+		CategoryName: section.Category.Name,
+		Goods:        []MenuGoodsResponse{},
+	}
+	for _, goods := range section.Goods {
+		goodsResp := MenuGoodsResponse{
+			GoodsID:         goods.ID,
+			Name:            goods.Name,
+			Price:           goods.Price,
+			AvailableStocks: goods.SellableStocks(),
+		}
+		// goods which has variants is sold through its variants, so the
+		// price & stocks shown to cashier are the variant ones
+		for _, variant := range goods.Variants {
+			variantResp := MenuVariantResponse{
+				VariantID:       variant.ID,
+				Name:            variant.Name,
+				Price:           variant.Price(goods.Price),
+				AvailableStocks: variant.AvailableStocks(),
+			}
+			// variants of goods made to order share the raw materials of the goods recipe
+			if goods.IsMadeToOrder() {
+				variantResp.AvailableStocks = goods.SellableStocks()
+			}
+			goodsResp.Variants = append(goodsResp.Variants, variantResp)
+		}
+		resp.Goods = append(resp.Goods, goodsResp)
+	}
+
+	return resp
+}
+
+type OrderStatusChangeResponse struct {
+	From      *string `json:"from"`
+	To        string  `json:"to"`
+	ChangedAt int64   `json:"changed_at"`
+}
+
+type OrderResponse struct {
+	OrderID       int64        `json:"order_id"`
+	Status        string       `json:"status"`
+	TotalGoods    int          `json:"total_goods"`
+	TotalAmount   entity.Money `json:"total_amount"`
+	PaymentAmount entity.Money `json:"payment_amount"`
+	RefundAmount  entity.Money `json:"refunded_amount"`
+	// SubtotalAmount is the price of the goods, the total amount is the grand total of the bill
+	SubtotalAmount      entity.Money                `json:"subtotal_amount"`
+	DiscountAmount      entity.Money                `json:"discount_amount"`
+	ServiceChargeAmount entity.Money                `json:"service_charge_amount"`
+	TaxBaseAmount       entity.Money                `json:"tax_base_amount"`
+	TaxAmount           entity.Money                `json:"tax_amount"`
+	Discounts           []DiscountResponse          `json:"discounts,omitempty"`
+	Tenders             []TenderResponse            `json:"tenders,omitempty"`
+	History             []OrderStatusChangeResponse `json:"history,omitempty"`
+}
+
+func NewOrderResponse(order *entity.Transaction) OrderResponse {
+	resp := OrderResponse{
+		OrderID:             order.ID,
+		Status:              order.Status.String(),
+		TotalGoods:          order.TotalGoods,
+		TotalAmount:         order.TotalAmount,
+		PaymentAmount:       order.PaymentAmount,
+		RefundAmount:        order.RefundedAmount,
+		SubtotalAmount:      order.SubtotalAmount,
+		DiscountAmount:      order.DiscountAmount,
+		ServiceChargeAmount: order.ServiceChargeAmount,
+		TaxBaseAmount:       order.TaxBaseAmount,
+		TaxAmount:           order.TaxAmount,
+	}
+	if len(order.Discounts) > 0 {
+		resp.Discounts = NewDiscountResponses(order.Discounts)
+	}
+	if len(order.Tenders) > 0 {
+		resp.Tenders = NewTenderResponses(order.Tenders)
+	}
+	for _, change := range order.StatusHistory {
+		changeResp := OrderStatusChangeResponse{
+			To:        change.To.String(),
+			ChangedAt: change.ChangedAt,
+		}
+		if change.From != nil {
+			from := change.From.String()
+			changeResp.From = &from
+		}
+		resp.History = append(resp.History, changeResp)
+	}
+
+	return resp
+}
+
+type RefundDetailResponse struct {
+	GoodsID    int          `json:"goods_id"`
+	VariantID  int          `json:"variant_id,omitempty"`
+	TotalGoods int          `json:"total_goods"`
+	GoodsPrice entity.Money `json:"goods_price"`
+	Subtotal   entity.Money `json:"subtotal"`
+}
+
+type RefundResponse struct {
+	RefundID  int64                  `json:"refund_id"`
+	OrderID   int64                  `json:"order_id"`
+	Amount    entity.Money           `json:"amount"`
+	Reason    string                 `json:"reason"`
+	Details   []RefundDetailResponse `json:"details"`
+	CreatedAt int64                  `json:"created_at"`
+}
+
+func NewRefundResponse(refund *entity.Refund) RefundResponse {
+	resp := RefundResponse{
+		RefundID:  refund.ID,
+		OrderID:   refund.TransactionID,
+		Amount:    refund.Amount,
+		Reason:    refund.Reason,
+		Details:   []RefundDetailResponse{},
+		CreatedAt: refund.CreatedAt,
+	}
+	for _, detail := range refund.Details {
+		resp.Details = append(resp.Details, RefundDetailResponse{
+			GoodsID:    detail.GoodsID,
+			VariantID:  detail.VariantID,
+			TotalGoods: detail.TotalGoods,
+			GoodsPrice: detail.GoodsPrice,
+			Subtotal:   detail.GoodsPrice.Mul(detail.TotalGoods),
+		})
+	}
+
+	return resp
+}
+
+type StockMovementResponse struct {
+	MovementID   int64   `json:"movement_id"`
+	GoodsID      int     `json:"goods_id"`
+	VariantID    int     `json:"variant_id,omitempty"`
+	Type         string  `json:"type"`
+	Quantity     int     `json:"quantity"`
+	UnitCost     float64 `json:"unit_cost"`
+	StocksBefore int     `json:"stocks_before"`
+	StocksAfter  int     `json:"stocks_after"`
+	Actor        string  `json:"actor"`
+	Reason       string  `json:"reason"`
+	OrderID      int64   `json:"order_id,omitempty"`
+	ExpiresAt    int64   `json:"expires_at,omitempty"`
+	CreatedAt    int64   `json:"created_at"`
+}
+
+func NewStockMovementResponse(movement entity.StockMovement) StockMovementResponse {
+	return StockMovementResponse{
+		MovementID:   movement.ID,
+		GoodsID:      movement.GoodsID,
+		VariantID:    movement.VariantID,
+		Type:         string(movement.Type),
+		Quantity:     movement.Quantity,
+		UnitCost:     movement.UnitCost,
+		StocksBefore: movement.Before,
+		StocksAfter:  movement.After,
+		Actor:        movement.Actor,
+		Reason:       movement.Reason,
+		OrderID:      movement.TransactionID,
+		ExpiresAt:    movement.ExpiresAt,
+		CreatedAt:    movement.CreatedAt,
+	}
+}
+
+type StockBatchResponse struct {
+	BatchID          int64   `json:"batch_id"`
+	GoodsID          int     `json:"goods_id"`
+	VariantID        int     `json:"variant_id,omitempty"`
+	Name             string  `json:"name"`
+	Unit             string  `json:"unit,omitempty"`
+	ReceivedQuantity int     `json:"received_quantity"`
+	Quantity         int     `json:"quantity"`
+	UnitCost         float64 `json:"unit_cost"`
+	ReceivedAt       int64   `json:"received_at"`
+	ExpiresAt        int64   `json:"expires_at"`
+	// ExpiryDate is the last day the batch still sellable
+	ExpiryDate string `json:"expiry_date"`
+	Expired    bool   `json:"expired"`
+}
+
+func NewStockBatchResponse(batch entity.StockBatch, now int64) StockBatchResponse {
+	resp := StockBatchResponse{
+		BatchID:          batch.ID,
+		GoodsID:          batch.GoodsID,
+		VariantID:        batch.VariantID,
+		Name:             batch.Name,
+		Unit:             batch.Unit,
+		ReceivedQuantity: batch.ReceivedQuantity,
+		Quantity:         batch.Quantity,
+		UnitCost:         batch.UnitCost,
+		ReceivedAt:       batch.ReceivedAt,
+		ExpiresAt:        batch.ExpiresAt,
+		Expired:          batch.IsExpired(now),
+	}
+	if batch.ExpiresAt > 0 {
+		resp.ExpiryDate = time.Unix(batch.ExpiresAt-1, 0).In(time.Local).Format("2006-01-02")
+	}
+	return resp
+}
+
+type StockDriftResponse struct {
+	GoodsID      int `json:"goods_id"`
+	VariantID    int `json:"variant_id,omitempty"`
+	Stocks       int `json:"stocks"`
+	LedgerStocks int `json:"ledger_stocks"`
+	Drift        int `json:"drift"`
+}
+
+type LowStockResponse struct {
+	GoodsID      int    `json:"goods_id"`
+	VariantID    int    `json:"variant_id,omitempty"`
+	Name         string `json:"name"`
+	Unit         string `json:"unit,omitempty"`
+	Stocks       int    `json:"stocks"`
+	ReorderPoint int    `json:"reorder_point"`
+}
+
+type StockCountItemResponse struct {
+	GoodsID       int          `json:"goods_id"`
+	VariantID     int          `json:"variant_id,omitempty"`
+	Name          string       `json:"name"`
+	CountedStocks int          `json:"counted_stocks"`
+	SystemStocks  int          `json:"system_stocks"`
+	Variance      int          `json:"variance"`
+	Price         entity.Money `json:"price"`
+	VarianceValue entity.Money `json:"variance_value"`
+}
+
+type StockCountResponse struct {
+	StockCountID       int64                    `json:"stock_count_id"`
+	Status             string                   `json:"status"`
+	OpenedBy           string                   `json:"opened_by"`
+	ApprovedBy         string                   `json:"approved_by,omitempty"`
+	Items              []StockCountItemResponse `json:"items"`
+	TotalVarianceValue entity.Money             `json:"total_variance_value"`
+	CreatedAt          int64                    `json:"created_at"`
+	ApprovedAt         int64                    `json:"approved_at,omitempty"`
+}
+
+func NewStockCountResponse(stockCount *entity.StockCount) StockCountResponse {
+	resp := StockCountResponse{
+		StockCountID:       stockCount.ID,
+		Status:             string(stockCount.Status),
+		OpenedBy:           stockCount.OpenedBy,
+		ApprovedBy:         stockCount.ApprovedBy,
+		Items:              []StockCountItemResponse{},
+		TotalVarianceValue: stockCount.TotalVarianceValue(),
+		CreatedAt:          stockCount.CreatedAt,
+		ApprovedAt:         stockCount.ApprovedAt,
+	}
+	for _, item := range stockCount.Items {
+		resp.Items = append(resp.Items, StockCountItemResponse{
+			GoodsID:       item.GoodsID,
+			VariantID:     item.VariantID,
+			Name:          item.Name,
+			CountedStocks: item.CountedStocks,
+			SystemStocks:  item.SystemStocks,
+			Variance:      item.Variance(),
+			Price:         item.Price,
+			VarianceValue: item.VarianceValue(),
+		})
+	}
+
+	return resp
+}
+
+type SupplierResponse struct {
+	SupplierID int    `json:"supplier_id"`
+	Name       string `json:"name"`
+	Phone      string `json:"phone"`
+	Address    string `json:"address"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+func NewSupplierResponse(supplier entity.Supplier) SupplierResponse {
+	return SupplierResponse{
+		SupplierID: supplier.ID,
+		Name:       supplier.Name,
+		Phone:      supplier.Phone,
+		Address:    supplier.Address,
+		CreatedAt:  supplier.CreatedAt,
+	}
+}
+
+type PromotionResponse struct {
+	PromotionID    int          `json:"promotion_id"`
+	Name           string       `json:"name"`
+	Type           string       `json:"type"`
+	GoodsID        int          `json:"goods_id,omitempty"`
+	Value          float64      `json:"value,omitempty"`
+	Amount         entity.Money `json:"amount,omitempty"`
+	BuyQuantity    int          `json:"buy_quantity,omitempty"`
+	FreeQuantity   int          `json:"free_quantity,omitempty"`
+	MinPurchase    entity.Money `json:"min_purchase"`
+	HappyHourStart string       `json:"happy_hour_start,omitempty"`
+	HappyHourEnd   string       `json:"happy_hour_end,omitempty"`
+	StartsAt       int64        `json:"starts_at,omitempty"`
+	EndsAt         int64        `json:"ends_at,omitempty"`
+	VoucherCode    string       `json:"voucher_code,omitempty"`
+	UsageLimit     int          `json:"usage_limit"`
+	UsedCount      int          `json:"used_count"`
+	CreatedAt      int64        `json:"created_at"`
+}
+
+func NewPromotionResponse(promotion entity.Promotion) PromotionResponse {
+	resp := PromotionResponse{
+		PromotionID:  promotion.ID,
+		Name:         promotion.Name,
+		Type:         string(promotion.Type),
+		GoodsID:      promotion.GoodsID,
+		Value:        promotion.Value,
+		Amount:       promotion.Amount,
+		BuyQuantity:  promotion.BuyQuantity,
+		FreeQuantity: promotion.FreeQuantity,
+		MinPurchase:  promotion.MinPurchase,
+		StartsAt:     promotion.StartsAt,
+		EndsAt:       promotion.EndsAt,
+		VoucherCode:  promotion.VoucherCode,
+		UsageLimit:   promotion.UsageLimit,
+		UsedCount:    promotion.UsedCount,
+		CreatedAt:    promotion.CreatedAt,
+	}
+	// promotion which applies all day has no happy hour
+	if promotion.HappyHourStart != promotion.HappyHourEnd {
+		resp.HappyHourStart = fmt.Sprintf("%02d:%02d", promotion.HappyHourStart/60, promotion.HappyHourStart%60)
+		resp.HappyHourEnd = fmt.Sprintf("%02d:%02d", promotion.HappyHourEnd/60, promotion.HappyHourEnd%60)
+	}
+
+	return resp
+}
+
+type PurchaseOrderItemResponse struct {
+	GoodsID           int     `json:"goods_id"`
+	VariantID         int     `json:"variant_id,omitempty"`
+	Name              string  `json:"name"`
+	Quantity          int     `json:"quantity"`
+	ReceivedQuantity  int     `json:"received_quantity"`
+	RemainingQuantity int     `json:"remaining_quantity"`
+	UnitCost          float64 `json:"unit_cost"`
+}
+
+type PurchaseOrderReceiptItemResponse struct {
+	GoodsID   int     `json:"goods_id"`
+	VariantID int     `json:"variant_id,omitempty"`
+	Quantity  int     `json:"quantity"`
+	UnitCost  float64 `json:"unit_cost"`
+	ExpiresAt int64   `json:"expires_at,omitempty"`
+}
+
+type PurchaseOrderReceiptResponse struct {
+	ReceiptID  int64                              `json:"receipt_id"`
+	ReceivedBy string                             `json:"received_by"`
+	Items      []PurchaseOrderReceiptItemResponse `json:"items"`
+	ReceivedAt int64                              `json:"received_at"`
+}
+
+type PurchaseOrderResponse struct {
+	PurchaseOrderID int64                          `json:"purchase_order_id"`
+	SupplierID      int                            `json:"supplier_id"`
+	Status          string                         `json:"status"`
+	Note            string                         `json:"note"`
+	CreatedBy       string                         `json:"created_by"`
+	Items           []PurchaseOrderItemResponse    `json:"items"`
+	Receipts        []PurchaseOrderReceiptResponse `json:"receipts"`
+	TotalCost       entity.Money                   `json:"total_cost"`
+	ReceivedCost    entity.Money                   `json:"received_cost"`
+	CreatedAt       int64                          `json:"created_at"`
+	UpdatedAt       int64                          `json:"updated_at"`
+}
+
+func NewPurchaseOrderResponse(purchaseOrder entity.PurchaseOrder) PurchaseOrderResponse {
+	resp := PurchaseOrderResponse{
+		PurchaseOrderID: purchaseOrder.ID,
+		SupplierID:      purchaseOrder.SupplierID,
+		Status:          string(purchaseOrder.Status),
+		Note:            purchaseOrder.Note,
+		CreatedBy:       purchaseOrder.CreatedBy,
+		Items:           []PurchaseOrderItemResponse{},
+		Receipts:        []PurchaseOrderReceiptResponse{},
+		TotalCost:       purchaseOrder.TotalCost(),
+		ReceivedCost:    purchaseOrder.ReceivedCost(),
+		CreatedAt:       purchaseOrder.CreatedAt,
+		UpdatedAt:       purchaseOrder.UpdatedAt,
+	}
+	for _, item := range purchaseOrder.Items {
+		resp.Items = append(resp.Items, PurchaseOrderItemResponse{
+			GoodsID:           item.GoodsID,
+			VariantID:         item.VariantID,
+			Name:              item.Name,
+			Quantity:          item.Quantity,
+			ReceivedQuantity:  item.ReceivedQuantity,
+			RemainingQuantity: item.RemainingQuantity(),
+			UnitCost:          item.UnitCost,
+		})
+	}
+	for _, receipt := range purchaseOrder.Receipts {
+		receiptResp := PurchaseOrderReceiptResponse{
+			ReceiptID:  receipt.ID,
+			ReceivedBy: receipt.ReceivedBy,
+			Items:      []PurchaseOrderReceiptItemResponse{},
+			ReceivedAt: receipt.ReceivedAt,
+		}
+		for _, item := range receipt.Items {
+			receiptResp.Items = append(receiptResp.Items, PurchaseOrderReceiptItemResponse(item))
+		}
+		resp.Receipts = append(resp.Receipts, receiptResp)
+	}
+
+	return resp
+}
+
 func NewSuccessResponse(data interface{}, svcID string) Response {
 	return Response{
 		ServiceID: svcID,
@@ -19,6 +642,12 @@ func NewSuccessResponse(data interface{}, svcID string) Response {
 	}
 }
 
+func NewPaginatedSuccessResponse(data interface{}, meta PaginationResponse, svcID string) Response {
+	resp := NewSuccessResponse(data, svcID)
+	resp.Meta = meta
+	return resp
+}
+
 func NewInternalServerErrorResponse(errorMessage interface{}) Response {
 	return Response{
 		Code:   http.StatusInternalServerError,
@@ -34,3 +663,297 @@ func NewBadRequestErrorResponse(errorMessage interface{}) Response {
 		Errors: errorMessage,
 	}
 }
+
+func NewNotFoundErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusNotFound,
+		Status: "ERR_NOT_FOUND",
+		Errors: errorMessage,
+	}
+}
+
+func NewInsufficientStockErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusConflict,
+		Status: "ERR_INSUFFICIENT_STOCK",
+		Errors: errorMessage,
+	}
+}
+
+func NewPriceMismatchErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusConflict,
+		Status: "ERR_PRICE_MISMATCH",
+		Errors: errorMessage,
+	}
+}
+
+func NewInsufficientPaymentErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusUnprocessableEntity,
+		Status: "ERR_INSUFFICIENT_PAYMENT",
+		Errors: errorMessage,
+	}
+}
+
+func NewNonCashOverpaymentErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusUnprocessableEntity,
+		Status: "ERR_NON_CASH_OVERPAYMENT",
+		Errors: errorMessage,
+	}
+}
+
+func NewEmptyCartErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusUnprocessableEntity,
+		Status: "ERR_EMPTY_CART",
+		Errors: errorMessage,
+	}
+}
+
+func NewCartAlreadyPaidErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusConflict,
+		Status: "ERR_CART_ALREADY_PAID",
+		Errors: errorMessage,
+	}
+}
+
+func NewIdempotencyKeyReusedErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusConflict,
+		Status: "ERR_IDEMPOTENCY_KEY_REUSED",
+		Errors: errorMessage,
+	}
+}
+
+func NewCartExpiredErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusGone,
+		Status: "ERR_CART_EXPIRED",
+		Errors: errorMessage,
+	}
+}
+
+func NewTransactionNotPaidErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusUnprocessableEntity,
+		Status: "ERR_TRANSACTION_NOT_PAID",
+		Errors: errorMessage,
+	}
+}
+
+func NewDeliveryAlreadyRequestedErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusConflict,
+		Status: "ERR_DELIVERY_ALREADY_REQUESTED",
+		Errors: errorMessage,
+	}
+}
+
+func NewInvalidStatusTransitionErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusConflict,
+		Status: "ERR_INVALID_STATUS_TRANSITION",
+		Errors: errorMessage,
+	}
+}
+
+func NewRefundExceedsPurchaseErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusUnprocessableEntity,
+		Status: "ERR_REFUND_EXCEEDS_PURCHASE",
+		Errors: errorMessage,
+	}
+}
+
+func NewRefundExceedsPaymentErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusUnprocessableEntity,
+		Status: "ERR_REFUND_EXCEEDS_PAYMENT",
+		Errors: errorMessage,
+	}
+}
+
+func NewNothingToRefundErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusUnprocessableEntity,
+		Status: "ERR_NOTHING_TO_REFUND",
+		Errors: errorMessage,
+	}
+}
+
+func NewGoodsNameAlreadyExistsErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusConflict,
+		Status: "ERR_GOODS_NAME_ALREADY_EXISTS",
+		Errors: errorMessage,
+	}
+}
+
+func NewCategoryAlreadyExistsErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusConflict,
+		Status: "ERR_CATEGORY_ALREADY_EXISTS",
+		Errors: errorMessage,
+	}
+}
+
+func NewVariantAlreadyExistsErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusConflict,
+		Status: "ERR_VARIANT_ALREADY_EXISTS",
+		Errors: errorMessage,
+	}
+}
+
+func NewStockCountApprovedErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusConflict,
+		Status: "ERR_STOCK_COUNT_APPROVED",
+		Errors: errorMessage,
+	}
+}
+
+func NewSupplierAlreadyExistsErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusConflict,
+		Status: "ERR_SUPPLIER_ALREADY_EXISTS",
+		Errors: errorMessage,
+	}
+}
+
+func NewPurchaseOrderClosedErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusConflict,
+		Status: "ERR_PURCHASE_ORDER_CLOSED",
+		Errors: errorMessage,
+	}
+}
+
+func NewVoucherNotApplicableErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusUnprocessableEntity,
+		Status: "ERR_VOUCHER_NOT_APPLICABLE",
+		Errors: errorMessage,
+	}
+}
+
+func NewVoucherAlreadyExistsErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusConflict,
+		Status: "ERR_VOUCHER_ALREADY_EXISTS",
+		Errors: errorMessage,
+	}
+}
+
+func NewCartChangedErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusConflict,
+		Status: "ERR_CART_CHANGED",
+		Errors: errorMessage,
+	}
+}
+
+func NewPaymentPendingErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusConflict,
+		Status: "ERR_PAYMENT_PENDING",
+		Errors: errorMessage,
+	}
+}
+
+func NewInvalidSignatureErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusUnauthorized,
+		Status: "ERR_INVALID_SIGNATURE",
+		Errors: errorMessage,
+	}
+}
+
+func NewPaymentUnavailableErrorResponse(errorMessage interface{}) Response {
+	return Response{
+		Code:   http.StatusServiceUnavailable,
+		Status: "ERR_PAYMENT_UNAVAILABLE",
+		Errors: errorMessage,
+	}
+}
+
+// NewErrorResponse map error returned by core service into its HTTP status code and response
+func NewErrorResponse(err error) (int, Response) {
+	var insufficientStockErr entity.InsufficientStockError
+	var insufficientPaymentErr entity.InsufficientPaymentError
+	var nonCashOverpaymentErr entity.NonCashOverpaymentError
+	var invalidStatusTransitionErr entity.InvalidStatusTransitionError
+	var refundExceedsPurchaseErr entity.RefundExceedsPurchaseError
+	var refundExceedsPaymentErr entity.RefundExceedsPaymentError
+	switch {
+	case errors.Is(err, service.ErrInvalidInput):
+		return http.StatusBadRequest, NewBadRequestErrorResponse(err.Error())
+	case errors.Is(err, service.ErrGoodsNotFound),
+		errors.Is(err, service.ErrVariantNotFound),
+		errors.Is(err, service.ErrCategoryNotFound),
+		errors.Is(err, service.ErrCartNotFound),
+		errors.Is(err, service.ErrStockCountNotFound),
+		errors.Is(err, service.ErrSupplierNotFound),
+		errors.Is(err, service.ErrPurchaseOrderNotFound),
+		errors.Is(err, service.ErrPaymentNotFound),
+		errors.Is(err, entity.ErrGoodsNotInCart):
+		return http.StatusNotFound, NewNotFoundErrorResponse(err.Error())
+	case errors.As(err, &insufficientStockErr):
+		return http.StatusConflict, NewInsufficientStockErrorResponse(err.Error())
+	case errors.Is(err, service.ErrPriceMismatch):
+		return http.StatusConflict, NewPriceMismatchErrorResponse(err.Error())
+	case errors.As(err, &insufficientPaymentErr):
+		return http.StatusUnprocessableEntity, NewInsufficientPaymentErrorResponse(err.Error())
+	case errors.As(err, &nonCashOverpaymentErr):
+		return http.StatusUnprocessableEntity, NewNonCashOverpaymentErrorResponse(err.Error())
+	case errors.Is(err, service.ErrEmptyCart):
+		return http.StatusUnprocessableEntity, NewEmptyCartErrorResponse(err.Error())
+	case errors.Is(err, service.ErrCartExpired):
+		return http.StatusGone, NewCartExpiredErrorResponse(err.Error())
+	case errors.Is(err, service.ErrCartAlreadyPaid):
+		return http.StatusConflict, NewCartAlreadyPaidErrorResponse(err.Error())
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		return http.StatusConflict, NewIdempotencyKeyReusedErrorResponse(err.Error())
+	case errors.As(err, &invalidStatusTransitionErr):
+		return http.StatusConflict, NewInvalidStatusTransitionErrorResponse(err.Error())
+	case errors.As(err, &refundExceedsPurchaseErr):
+		return http.StatusUnprocessableEntity, NewRefundExceedsPurchaseErrorResponse(err.Error())
+	case errors.As(err, &refundExceedsPaymentErr):
+		return http.StatusUnprocessableEntity, NewRefundExceedsPaymentErrorResponse(err.Error())
+	case errors.Is(err, entity.ErrNothingToRefund):
+		return http.StatusUnprocessableEntity, NewNothingToRefundErrorResponse(err.Error())
+	case errors.Is(err, service.ErrGoodsNameAlreadyExists):
+		return http.StatusConflict, NewGoodsNameAlreadyExistsErrorResponse(err.Error())
+	case errors.Is(err, service.ErrCategoryAlreadyExists):
+		return http.StatusConflict, NewCategoryAlreadyExistsErrorResponse(err.Error())
+	case errors.Is(err, service.ErrVariantAlreadyExists):
+		return http.StatusConflict, NewVariantAlreadyExistsErrorResponse(err.Error())
+	case errors.Is(err, service.ErrStockCountApproved):
+		return http.StatusConflict, NewStockCountApprovedErrorResponse(err.Error())
+	case errors.Is(err, service.ErrSupplierAlreadyExists):
+		return http.StatusConflict, NewSupplierAlreadyExistsErrorResponse(err.Error())
+	case errors.Is(err, service.ErrPurchaseOrderClosed):
+		return http.StatusConflict, NewPurchaseOrderClosedErrorResponse(err.Error())
+	case errors.Is(err, service.ErrVoucherNotApplicable):
+		return http.StatusUnprocessableEntity, NewVoucherNotApplicableErrorResponse(err.Error())
+	case errors.Is(err, service.ErrVoucherAlreadyExists):
+		return http.StatusConflict, NewVoucherAlreadyExistsErrorResponse(err.Error())
+	case errors.Is(err, service.ErrCartChanged):
+		return http.StatusConflict, NewCartChangedErrorResponse(err.Error())
+	case errors.Is(err, service.ErrPaymentPending):
+		return http.StatusConflict, NewPaymentPendingErrorResponse(err.Error())
+	case errors.Is(err, service.ErrTransactionNotPaid):
+		return http.StatusUnprocessableEntity, NewTransactionNotPaidErrorResponse(err.Error())
+	case errors.Is(err, service.ErrDeliveryAlreadyRequested):
+		return http.StatusConflict, NewDeliveryAlreadyRequestedErrorResponse(err.Error())
+	case errors.Is(err, service.ErrInvalidSignature):
+		return http.StatusUnauthorized, NewInvalidSignatureErrorResponse(err.Error())
+	case errors.Is(err, service.ErrPaymentUnavailable):
+		return http.StatusServiceUnavailable, NewPaymentUnavailableErrorResponse(err.Error())
+	default:
+		return http.StatusInternalServerError, NewInternalServerErrorResponse(err.Error())
+	}
+}
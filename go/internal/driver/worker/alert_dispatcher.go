@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gopkg.in/validator.v2"
+)
+
+// metrics of alert dispatcher, exposed through `/debug/vars` endpoint
+var (
+	dispatchedAlertsTotal = expvar.NewInt("alert_dispatcher_dispatched_total")
+	droppedAlertsTotal    = expvar.NewInt("alert_dispatcher_dropped_total")
+)
+
+// alertDispatcher run the alert checks one by one in the background, so the request which trigger them doesn't
+// wait for the storage and the notifier
+type alertDispatcher struct {
+	checks  chan func(ctx context.Context)
+	timeout time.Duration
+
+	mu      sync.Mutex
+	closed  bool
+	pending sync.WaitGroup
+	done    chan struct{}
+}
+
+type AlertDispatcherConfig struct {
+	// QueueSize is the maximum checks waiting to be run, the check is dropped when the queue is full
+	QueueSize int `validate:"min=1"`
+	// Timeout is the maximum duration of each check
+	Timeout time.Duration `validate:"min=1"`
+}
+
+// NewAlertDispatcher start the dispatcher, call Close to stop it
+func NewAlertDispatcher(config AlertDispatcherConfig) (*alertDispatcher, error) {
+	if err := validator.Validate(config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	d := &alertDispatcher{
+		checks:  make(chan func(ctx context.Context), config.QueueSize),
+		timeout: config.Timeout,
+		done:    make(chan struct{}),
+	}
+	go d.run()
+
+	return d, nil
+}
+
+// Dispatch queue the check to be run in the background, it never block the caller
+func (d *alertDispatcher) Dispatch(check func(ctx context.Context)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		droppedAlertsTotal.Add(1)
+		log.Printf("[WARN] alert dispatcher already closed, alert check dropped")
+		return
+	}
+	d.pending.Add(1)
+	select {
+	case d.checks <- check:
+		dispatchedAlertsTotal.Add(1)
+	default:
+		d.pending.Done()
+		droppedAlertsTotal.Add(1)
+		log.Printf("[WARN] alert dispatcher queue is full, alert check dropped")
+	}
+}
+
+// Wait block until every dispatched check is done
+func (d *alertDispatcher) Wait() {
+	d.pending.Wait()
+}
+
+// Close stop accepting new checks then wait the queued checks to be done
+func (d *alertDispatcher) Close() {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.checks)
+	}
+	d.mu.Unlock()
+
+	<-d.done
+}
+
+func (d *alertDispatcher) run() {
+	defer close(d.done)
+
+	for check := range d.checks {
+		d.runCheck(check)
+	}
+}
+
+func (d *alertDispatcher) runCheck(check func(ctx context.Context)) {
+	defer d.pending.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	check(ctx)
+}
@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/service"
+	"gopkg.in/validator.v2"
+)
+
+// metrics of cart sweeper, exposed through `/debug/vars` endpoint
+var (
+	sweptCartsTotal  = expvar.NewInt("cart_sweeper_expired_carts_total")
+	sweepRunsTotal   = expvar.NewInt("cart_sweeper_runs_total")
+	sweepErrorsTotal = expvar.NewInt("cart_sweeper_errors_total")
+)
+
+type cartSweeper struct {
+	service  service.Service
+	interval time.Duration
+	cartTTL  time.Duration
+}
+
+type CartSweeperConfig struct {
+	Service  service.Service `validate:"nonnil"`
+	Interval time.Duration   `validate:"min=1"`
+	CartTTL  time.Duration   `validate:"min=1"`
+}
+
+func NewCartSweeper(config CartSweeperConfig) (*cartSweeper, error) {
+	if err := validator.Validate(config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &cartSweeper{
+		service:  config.Service,
+		interval: config.Interval,
+		cartTTL:  config.CartTTL,
+	}, nil
+}
+
+// Run expire abandoned shopping carts periodically until the context is done
+func (w *cartSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.sweep(ctx)
+		}
+	}
+}
+
+func (w *cartSweeper) sweep(ctx context.Context) {
+	sweepRunsTotal.Add(1)
+
+	totalExpired, err := w.service.ExpireAbandonedCarts(ctx, w.cartTTL)
+	sweptCartsTotal.Add(int64(totalExpired))
+	if err != nil {
+		sweepErrorsTotal.Add(1)
+		log.Printf("[ERROR] unable to sweep abandoned shopping carts: %v", err)
+		return
+	}
+	if totalExpired > 0 {
+		log.Printf("[INFO] expired %d abandoned shopping carts", totalExpired)
+	}
+}
@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/izzdalfk/norma-research-pi-server-umkm-app/internal/core/service"
+	"gopkg.in/validator.v2"
+)
+
+// metrics of stock reconciler, exposed through `/debug/vars` endpoint
+var (
+	reconcileRunsTotal   = expvar.NewInt("stock_reconciler_runs_total")
+	reconcileErrorsTotal = expvar.NewInt("stock_reconciler_errors_total")
+	// driftedStocks is the number of goods & variants drifted from the stock ledger in the latest run
+	driftedStocks = expvar.NewInt("stock_reconciler_drifted_stocks")
+)
+
+type stockReconciler struct {
+	service  service.Service
+	interval time.Duration
+}
+
+type StockReconcilerConfig struct {
+	Service  service.Service `validate:"nonnil"`
+	Interval time.Duration   `validate:"min=1"`
+}
+
+func NewStockReconciler(config StockReconcilerConfig) (*stockReconciler, error) {
+	if err := validator.Validate(config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &stockReconciler{
+		service:  config.Service,
+		interval: config.Interval,
+	}, nil
+}
+
+// Run verify the stocks against the stock ledger periodically until the context is done
+func (w *stockReconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.reconcile(ctx)
+		}
+	}
+}
+
+func (w *stockReconciler) reconcile(ctx context.Context) {
+	reconcileRunsTotal.Add(1)
+
+	drifts, err := w.service.ReconcileStocks(ctx)
+	if err != nil {
+		reconcileErrorsTotal.Add(1)
+		log.Printf("[ERROR] unable to reconcile stocks: %v", err)
+		return
+	}
+	driftedStocks.Set(int64(len(drifts)))
+	for _, drift := range drifts {
+		log.Printf(
+			"[WARN] stocks of goods %d variant %d is %d but the ledger recorded %d (drift %d)",
+			drift.GoodsID,
+			drift.VariantID,
+			drift.Stocks,
+			drift.LedgerStocks,
+			drift.Drift(),
+		)
+	}
+}